@@ -0,0 +1,503 @@
+// Command fakeprovider is a local stand-in for the payment providers. It accepts
+// payouts on /v1/payments (default processor), /v1/transfers (bank transfer)
+// and /v1/disbursements (e-wallet) and their refunds, answers with a pending
+// status and confirms each of them later through a signed callback to the
+// payment webhook. The confirmed payouts are listed as settlement statements
+// on GET /v1/payments, /v1/statements and /v1/disbursements.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeProvider struct {
+	webhookURL string
+	secret     []byte
+	delay      time.Duration
+	failRate   float64
+	duplicates int
+	httpClient *http.Client
+	logger     *logrus.Logger
+
+	mu          sync.Mutex
+	payments    map[string]string      // idempotency key -> provider payment id
+	settlements map[string]*settlement // provider payment id -> settlement
+}
+
+// settlement is a payout as the provider's statement lists it. Refunds have a
+// negative amount.
+type settlement struct {
+	channel     string
+	id          string
+	externalID  string
+	amountIDR   float64
+	status      string
+	completedAt *time.Time
+}
+
+const (
+	channelPayments      = "payments"
+	channelTransfers     = "transfers"
+	channelDisbursements = "disbursements"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	webhookURL := flag.String("webhook-url", "http://localhost:8000/api/webhooks/payments", "payment webhook URL")
+	secret := flag.String("secret", os.Getenv("PAYMENT_WEBHOOK_SECRET"), "webhook signing secret")
+	delay := flag.Duration("delay", 2*time.Second, "delay before the callback is sent")
+	failRate := flag.Float64("fail-rate", 0, "fraction of payouts that fail (0-1)")
+	duplicates := flag.Int("duplicates", 1, "number of times each callback is delivered")
+	flag.Parse()
+
+	provider := &fakeProvider{
+		webhookURL:  *webhookURL,
+		secret:      []byte(*secret),
+		delay:       *delay,
+		failRate:    *failRate,
+		duplicates:  *duplicates,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		logger:      util.NewLogger(int(logrus.InfoLevel)),
+		payments:    make(map[string]string),
+		settlements: make(map[string]*settlement),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/payments", provider.createPayment)
+	mux.HandleFunc("/v1/transfers", provider.createTransfer)
+	mux.HandleFunc("/v1/disbursements", provider.createDisbursement)
+	mux.HandleFunc("/v1/payments/refunds", provider.createRefund)
+	mux.HandleFunc("/v1/transfers/{id}/reversals", provider.createReversal)
+	mux.HandleFunc("/v1/disbursements/{id}/refunds", provider.createDisbursementRefund)
+	mux.HandleFunc("/v1/callbacks", provider.triggerCallback)
+	mux.HandleFunc("GET /v1/payments", provider.listPayments)
+	mux.HandleFunc("GET /v1/statements", provider.listStatement)
+	mux.HandleFunc("GET /v1/disbursements", provider.listDisbursements)
+
+	provider.logger.WithField("addr", *addr).Info("Fake payment provider started")
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		provider.logger.WithError(err).Error("Fake payment provider stopped")
+	}
+}
+
+func (p *fakeProvider) createPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req entity.PaymentProcessorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = req.ExternalID
+	}
+
+	paymentID := p.accept(channelPayments, key, req.ExternalID, float64(req.AmountIDR))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]string{
+			"id":          paymentID,
+			"external_id": req.ExternalID,
+			"status":      "PENDING",
+		},
+		"message": "Payment accepted",
+	})
+}
+
+func (p *fakeProvider) createTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Reference string  `json:"reference"`
+		Amount    float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = req.Reference
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"transfer_id": p.accept(channelTransfers, key, req.Reference, req.Amount),
+		"reference":   req.Reference,
+		"state":       "PENDING",
+	})
+}
+
+func (p *fakeProvider) createDisbursement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PartnerReferenceNo string `json:"partner_reference_no"`
+		Amount             struct {
+			Value string `json:"value"`
+		} `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("X-Idempotency-Key")
+	if key == "" {
+		key = req.PartnerReferenceNo
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"response_code":        "2020000",
+		"response_message":     "Request in progress",
+		"disbursement_id":      p.accept(channelDisbursements, key, req.PartnerReferenceNo, parseAmount(req.Amount.Value)),
+		"partner_reference_no": req.PartnerReferenceNo,
+		"status":               "PENDING",
+	})
+}
+
+func (p *fakeProvider) createRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req entity.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = req.ExternalID
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]string{
+			"id":          p.accept(channelPayments, key, req.ExternalID, -float64(req.AmountIDR)),
+			"external_id": req.ExternalID,
+			"status":      "PENDING",
+		},
+		"message": "Refund accepted",
+	})
+}
+
+func (p *fakeProvider) createReversal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Reference string  `json:"reference"`
+		Amount    float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = req.Reference
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"reversal_id": p.accept(channelTransfers, key, req.Reference, -req.Amount),
+		"reference":   req.Reference,
+		"state":       "PENDING",
+	})
+}
+
+func (p *fakeProvider) createDisbursementRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PartnerRefundNo string `json:"partner_refund_no"`
+		Amount          struct {
+			Value string `json:"value"`
+		} `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := r.Header.Get("X-Idempotency-Key")
+	if key == "" {
+		key = req.PartnerRefundNo
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"response_code":     "2020000",
+		"response_message":  "Request in progress",
+		"refund_id":         p.accept(channelDisbursements, key, req.PartnerRefundNo, -parseAmount(req.Amount.Value)),
+		"partner_refund_no": req.PartnerRefundNo,
+		"status":            "PENDING",
+	})
+}
+
+// accept records the payout under its idempotency key and schedules its
+// callback. A repeated key returns the first payment ID without a new callback.
+func (p *fakeProvider) accept(channel, key, externalID string, amountIDR float64) string {
+	p.mu.Lock()
+	paymentID, seen := p.payments[key]
+	if !seen {
+		paymentID = "FAKE-" + uuid.New().String()
+		p.payments[key] = paymentID
+		p.settlements[paymentID] = &settlement{
+			channel:    channel,
+			id:         paymentID,
+			externalID: externalID,
+			amountIDR:  amountIDR,
+			status:     "PENDING",
+		}
+	}
+	p.mu.Unlock()
+
+	p.logger.WithField("external_id", externalID).WithField("payment_id", paymentID).WithField("duplicate", seen).Info("Payment accepted")
+
+	if !seen {
+		status := "COMPLETED"
+		failureReason := ""
+		if rand.Float64() < p.failRate {
+			status = "FAILED"
+			failureReason = "destination account rejected the transfer"
+		}
+		go func() {
+			time.Sleep(p.delay)
+			p.settle(paymentID, status)
+			p.sendCallback(model.PaymentWebhookRequest{
+				ID:            paymentID,
+				ExternalID:    externalID,
+				Status:        status,
+				FailureReason: failureReason,
+			})
+		}()
+	}
+
+	return paymentID
+}
+
+// triggerCallback sends a callback on demand, e.g.
+// POST /v1/callbacks?external_id=...&status=FAILED
+func (p *fakeProvider) triggerCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	externalID := r.URL.Query().Get("external_id")
+	if externalID == "" {
+		http.Error(w, "external_id is required", http.StatusBadRequest)
+		return
+	}
+
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	if status == "" {
+		status = "COMPLETED"
+	}
+
+	p.mu.Lock()
+	paymentID := p.payments[externalID]
+	p.mu.Unlock()
+	p.settle(paymentID, status)
+
+	go p.sendCallback(model.PaymentWebhookRequest{
+		ID:            paymentID,
+		ExternalID:    externalID,
+		Status:        status,
+		FailureReason: r.URL.Query().Get("reason"),
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// settle records the final status of a payout for the statements.
+func (p *fakeProvider) settle(paymentID, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if payout, ok := p.settlements[paymentID]; ok {
+		completedAt := time.Now().UTC()
+		payout.status = status
+		payout.completedAt = &completedAt
+	}
+}
+
+// settled returns the payouts of channel completed in the period given by the
+// query parameters fromKey and toKey.
+func (p *fakeProvider) settled(r *http.Request, channel, fromKey, toKey string) ([]settlement, error) {
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get(fromKey))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be RFC 3339", fromKey)
+	}
+	to, err := time.Parse(time.RFC3339, r.URL.Query().Get(toKey))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be RFC 3339", toKey)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payouts := make([]settlement, 0)
+	for _, payout := range p.settlements {
+		if payout.channel != channel || payout.completedAt == nil {
+			continue
+		}
+		if payout.completedAt.Before(from) || !payout.completedAt.Before(to) {
+			continue
+		}
+		payouts = append(payouts, *payout)
+	}
+	return payouts, nil
+}
+
+func (p *fakeProvider) listPayments(w http.ResponseWriter, r *http.Request) {
+	payouts, err := p.settled(r, channelPayments, "from", "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := make([]map[string]interface{}, 0, len(payouts))
+	for _, payout := range payouts {
+		data = append(data, map[string]interface{}{
+			"id":           payout.id,
+			"external_id":  payout.externalID,
+			"amount_idr":   payout.amountIDR,
+			"status":       payout.status,
+			"completed_at": payout.completedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func (p *fakeProvider) listStatement(w http.ResponseWriter, r *http.Request) {
+	payouts, err := p.settled(r, channelTransfers, "from", "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries := make([]map[string]interface{}, 0, len(payouts))
+	for _, payout := range payouts {
+		entries = append(entries, map[string]interface{}{
+			"id":        payout.id,
+			"reference": payout.externalID,
+			"amount":    payout.amountIDR,
+			"state":     payout.status,
+			"booked_at": payout.completedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+func (p *fakeProvider) listDisbursements(w http.ResponseWriter, r *http.Request) {
+	payouts, err := p.settled(r, channelDisbursements, "start_time", "end_time")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	disbursements := make([]map[string]interface{}, 0, len(payouts))
+	for _, payout := range payouts {
+		disbursements = append(disbursements, map[string]interface{}{
+			"disbursement_id":      payout.id,
+			"partner_reference_no": payout.externalID,
+			"amount":               map[string]string{"value": fmt.Sprintf("%.2f", payout.amountIDR), "currency": "IDR"},
+			"status":               payout.status,
+			"completed_at":         payout.completedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"response_code":    "2000000",
+		"response_message": "Successful",
+		"disbursements":    disbursements,
+	})
+}
+
+// sendCallback delivers the callback p.duplicates times. Each delivery is
+// retried with backoff until the webhook answers 2xx.
+func (p *fakeProvider) sendCallback(callback model.PaymentWebhookRequest) {
+	body, err := json.Marshal(callback)
+	if err != nil {
+		p.logger.WithError(err).Error("failed to marshal callback")
+		return
+	}
+
+	for i := 0; i < p.duplicates; i++ {
+		backoff := time.Second
+		for attempt := 1; attempt <= 5; attempt++ {
+			err = p.postCallback(body)
+			if err == nil {
+				p.logger.WithField("external_id", callback.ExternalID).WithField("status", callback.Status).Info("Callback delivered")
+				break
+			}
+			p.logger.WithError(err).WithField("attempt", attempt).Warn("Callback failed, retrying")
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func (p *fakeProvider) postCallback(body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, p.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(util.PaymentWebhookSignatureHeader, "sha256="+util.SignPayload(p.secret, body))
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", response.Status)
+	}
+	return nil
+}
+
+func parseAmount(value string) float64 {
+	amount, _ := strconv.ParseFloat(value, 64)
+	return amount
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -10,11 +10,22 @@ import (
 type Config struct {
 	Database            Database
 	Log                 Log
+	Outbox              Outbox
+	Consumer            Consumer
+	Publisher           Publisher
+	PaymentProviders    PaymentProviders
+	Payout              Payout
 	ServicePort         int
 	PaymentProcessorURL string
 	JWTKey              string
+	BrokerDriver        string
 	RabbitMQURL         string
 	TopicPaymentProcessor string
+	EventsExchange        string
+	PaymentWebhookSecret  string
+	PayoutDestinationKey  string
+	ReconciliationRunAt   string
+	PayableGLAccount      string
 }
 
 type Database struct {
@@ -30,6 +41,42 @@ type Log struct {
 	Level int
 }
 
+type Outbox struct {
+	RelayIntervalMs int
+	BatchSize       int
+}
+
+type Consumer struct {
+	MaxRetries        int
+	RetryBaseDelayMs  int
+	RetryMaxDelayMs   int
+	Prefetch          int
+	Workers           int
+	ShutdownTimeoutMs int
+}
+
+type Publisher struct {
+	ConfirmTimeoutMs int
+}
+
+type PaymentProviders struct {
+	BankTransferURL      string
+	EWalletURL           string
+	EWalletMaxAmountIDR  float64
+	TimeoutMs            int
+	RetryMaxAttempts     int
+	RetryBaseDelayMs     int
+	RetryMaxDelayMs      int
+	BreakerThreshold     int
+	BreakerOpenTimeoutMs int
+}
+
+type Payout struct {
+	Mode         string
+	BatchRunAt   string
+	BatchChannel string
+}
+
 func Load() *Config {
 	godotenv.Load()
 	return &Config{
@@ -44,11 +91,48 @@ func Load() *Config {
 		Log: Log{
 			Level: getEnvInt("LOG_LEVEL", -1),
 		},
+		Outbox: Outbox{
+			RelayIntervalMs: getEnvInt("OUTBOX_RELAY_INTERVAL_MS", 1000),
+			BatchSize:       getEnvInt("OUTBOX_BATCH_SIZE", 50),
+		},
+		Consumer: Consumer{
+			MaxRetries:        getEnvInt("CONSUMER_MAX_RETRIES", 5),
+			RetryBaseDelayMs:  getEnvInt("CONSUMER_RETRY_BASE_DELAY_MS", 1000),
+			RetryMaxDelayMs:   getEnvInt("CONSUMER_RETRY_MAX_DELAY_MS", 60000),
+			Prefetch:          getEnvInt("CONSUMER_PREFETCH", 10),
+			Workers:           getEnvInt("CONSUMER_WORKERS", 4),
+			ShutdownTimeoutMs: getEnvInt("CONSUMER_SHUTDOWN_TIMEOUT_MS", 35000),
+		},
+		Publisher: Publisher{
+			ConfirmTimeoutMs: getEnvInt("PUBLISHER_CONFIRM_TIMEOUT_MS", 5000),
+		},
+		PaymentProviders: PaymentProviders{
+			BankTransferURL:      getEnv("BANK_TRANSFER_PROVIDER_URL", ""),
+			EWalletURL:           getEnv("EWALLET_PROVIDER_URL", ""),
+			EWalletMaxAmountIDR:  float64(getEnvInt("EWALLET_MAX_AMOUNT_IDR", 10000000)),
+			TimeoutMs:            getEnvInt("PAYMENT_PROVIDER_TIMEOUT_MS", 10000),
+			RetryMaxAttempts:     getEnvInt("PAYMENT_RETRY_MAX_ATTEMPTS", 3),
+			RetryBaseDelayMs:     getEnvInt("PAYMENT_RETRY_BASE_DELAY_MS", 200),
+			RetryMaxDelayMs:      getEnvInt("PAYMENT_RETRY_MAX_DELAY_MS", 2000),
+			BreakerThreshold:     getEnvInt("PAYMENT_BREAKER_THRESHOLD", 5),
+			BreakerOpenTimeoutMs: getEnvInt("PAYMENT_BREAKER_OPEN_TIMEOUT_MS", 30000),
+		},
+		Payout: Payout{
+			Mode:         getEnv("PAYOUT_MODE", "immediate"),
+			BatchRunAt:   getEnv("PAYOUT_BATCH_RUN_AT", ""),
+			BatchChannel: getEnv("PAYOUT_BATCH_CHANNEL", "api"),
+		},
 		ServicePort:           getEnvInt("SERVICE_PORT", 8000),
 		PaymentProcessorURL:   getEnv("PAYMENT_PROCESSOR_URL", ""),
 		JWTKey:                getEnv("JWT_KEY", ""),
+		BrokerDriver:          getEnv("BROKER_DRIVER", "rabbitmq"),
 		RabbitMQURL:           getEnv("RABBITMQ_URL", ""),
 		TopicPaymentProcessor: getEnv("TOPIC_PAYMENT_PROCESSOR", "payment.processor"),
+		EventsExchange:        getEnv("EVENTS_EXCHANGE", "ems.events"),
+		PaymentWebhookSecret:  getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PayoutDestinationKey:  getEnv("PAYOUT_DESTINATION_KEY", ""),
+		ReconciliationRunAt:   getEnv("RECONCILIATION_RUN_AT", ""),
+		PayableGLAccount:      getEnv("ACCOUNTING_PAYABLE_GL_ACCOUNT", ""),
 	}
 }
 
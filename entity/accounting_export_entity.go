@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// GLAccountMapping maps expenses to a general ledger account code. An empty
+// Category or CostCenter matches any.
+type GLAccountMapping struct {
+	ID          int64
+	Category    string
+	CostCenter  string
+	AccountCode string
+	UpdatedAt   time.Time
+}
+
+// AccountingExport is a journal file of the approved expenses of a period that
+// were not exported before.
+type AccountingExport struct {
+	ID             int64
+	Format         string
+	PeriodFrom     time.Time
+	PeriodTo       time.Time // inclusive
+	ExpenseCount   int32
+	TotalAmountIDR float64
+	CreatedBy      int64
+	CreatedAt      time.Time
+	Items          []*AccountingExportItem
+}
+
+// AccountingExportItem is an exported expense. The account code is kept as it
+// was at export time, so the file can be downloaded again unchanged.
+type AccountingExportItem struct {
+	ID          int64
+	ExportID    int64
+	ExpenseID   int64
+	UserID      int64
+	UserName    string
+	UserEmail   string
+	Category    string
+	CostCenter  string
+	Description string
+	AmountIDR   float64
+	AccountCode string
+	ApprovedAt  time.Time
+}
+
+// AccountingExportBuilder sets the account code of the expenses to export. It
+// runs inside the transaction that locks them.
+type AccountingExportBuilder func(items []*AccountingExportItem) error
@@ -3,10 +3,13 @@ package entity
 import "time"
 
 type Approval struct {
-	ID         int64
-	ApproverID int64
-	ExpenseID  int64
-	Status     string
-	Notes      string
-	CreatedAt  time.Time
+	ID                int64
+	ApproverID        int64
+	ExpenseID         int64
+	Revision          int32
+	Status            int32
+	Notes             string
+	ApprovedAmountIDR float64
+	AdjustmentReason  string
+	CreatedAt         time.Time
 }
@@ -7,6 +7,8 @@ type AuditLog struct {
 	ExpenseID    int64
 	NewStatus    int32
 	StatusBefore int32
+	AmountBefore float64
+	AmountAfter  float64
 	Notes        string
 	CreatedAt    time.Time
 }
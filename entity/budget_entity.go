@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// Budget caps what a department spends on a category, or on every category
+// when Category is empty, through the expenses submitted in the period. The
+// spending is read from the expenses: a pending claim is not counted yet, an
+// approved amount is reserved until it is paid and consumed.
+type Budget struct {
+	ID          int64
+	Department  string
+	Category    string
+	PeriodFrom  time.Time
+	PeriodTo    time.Time // inclusive
+	AmountIDR   float64
+	Policy      string
+	CreatedBy   int64
+	UpdatedAt   time.Time
+	PendingIDR  float64
+	ReservedIDR float64
+	ConsumedIDR float64
+}
+
+// BudgetQuery selects budgets by the department of UserID or by Department,
+// those covering Category and those whose period covers Date, when set.
+type BudgetQuery struct {
+	UserID     int64
+	Department string
+	Category   string
+	Date       time.Time
+}
+
+// BudgetCheck holds an expense against the budgets covering it. It runs in the
+// transaction writing the expense while those budgets are locked, so concurrent
+// claims on the same budget are counted one after the other.
+type BudgetCheck func(budgets []*Budget) error
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type Comment struct {
+	ID         int64
+	ExpenseID  int64
+	AuthorID   int64
+	AuthorName string
+	Body       string
+	Mentions   []int64
+	CreatedAt  time.Time
+}
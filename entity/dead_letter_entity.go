@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type DeadLetter struct {
+	ID             string
+	Queue          string
+	Payload        []byte
+	RetryCount     int
+	LastError      string
+	DeadLetteredAt time.Time
+}
@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+// DomainEvent is the envelope of every event published to the events exchange.
+// SchemaVersion is bumped on breaking changes to Data.
+type DomainEvent struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	SchemaVersion int         `json:"schema_version"`
+	OccurredAt    time.Time   `json:"occurred_at"`
+	Actor         EventActor  `json:"actor"`
+	Data          interface{} `json:"data"`
+}
+
+type EventActor struct {
+	Type  string `json:"type"`
+	ID    int64  `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+type ExpenseEventData struct {
+	ExpenseID         int64   `json:"expense_id"`
+	UserID            int64   `json:"user_id"`
+	AmountIDR         float64 `json:"amount_idr"`
+	ApprovedAmountIDR float64 `json:"approved_amount_idr,omitempty"`
+	Description       string  `json:"description"`
+	Status            string  `json:"status"`
+	Revision          int32   `json:"revision"`
+	AutoApproved      bool    `json:"auto_approved"`
+	Notes             string  `json:"notes,omitempty"`
+}
+
+type PaymentEventData struct {
+	ExpenseID         int64   `json:"expense_id"`
+	UserID            int64   `json:"user_id"`
+	ExternalID        string  `json:"external_id"`
+	ProviderPaymentID string  `json:"provider_payment_id,omitempty"`
+	AmountIDR         float64 `json:"amount_idr"`
+	FailureReason     string  `json:"failure_reason,omitempty"`
+	ReversalOf        string  `json:"reversal_of,omitempty"` // external ID of the reversed payment
+}
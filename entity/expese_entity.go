@@ -3,22 +3,42 @@ package entity
 import "time"
 
 type Expense struct {
-	ID           int64
-	UserID       int64
-	AmountIDR    float64
-	Description  string
-	ReceiptURL   string
-	Status       int32
-	AutoApproved bool
-	SubmittedAt  time.Time
-	ProcessedAt  time.Time
+	ID                int64
+	UserID            int64
+	AmountIDR         float64
+	ApprovedAmountIDR float64
+	Description       string
+	ReceiptURL        string
+	Category          string
+	CostCenter        string
+	Status            int32
+	AutoApproved      bool
+	OverLimit         bool
+	Revision          int32
+	Version           int32
+	SubmittedAt       time.Time
+	ProcessedAt       time.Time
+}
+
+type ExpenseVersion struct {
+	ID          int64
+	ExpenseID   int64
+	Revision    int32
+	AmountIDR   float64
+	Description string
+	ReceiptURL  string
+	Notes       string
+	CreatedAt   time.Time
 }
 
 type ExpenseApproval struct {
-	ExpenseID  int64
-	ApproverID int64
-	Status     int32
-	Notes      string
+	ExpenseID         int64
+	ApproverID        int64
+	Status            int32
+	Notes             string
+	ApprovedAmountIDR float64
+	AdjustmentReason  string
+	Version           int32
 }
 
 type ExpenseListQuery struct {
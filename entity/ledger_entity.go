@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// JournalEntry is an immutable, balanced posting to the ledger. Mistakes are
+// corrected by posting another entry, never by changing one.
+type JournalEntry struct {
+	ID          int64
+	Reference   string // unique, so an event is posted once
+	Kind        string
+	UserID      int64 // the employee the entry concerns
+	ExpenseID   int64 // 0 for advances
+	Description string
+	CreatedBy   int64 // 0 for entries posted by the system
+	PostedAt    time.Time
+	Lines       []*JournalLine
+}
+
+// JournalLine debits or credits a single account, the other side is zero.
+type JournalLine struct {
+	ID        int64
+	EntryID   int64
+	Account   string
+	DebitIDR  float64
+	CreditIDR float64
+}
+
+type JournalEntryQuery struct {
+	UserID    int64
+	ExpenseID int64
+	Limit     int
+}
+
+// AccountBalance holds the debit and credit totals of an account, for one
+// employee when UserID is set.
+type AccountBalance struct {
+	UserID    int64
+	Account   string
+	DebitIDR  float64
+	CreditIDR float64
+}
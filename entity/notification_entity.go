@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type Notification struct {
+	ID        int64
+	UserID    int64
+	ExpenseID int64
+	CommentID int64
+	Type      int32
+	Message   string
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+type OutboxMessage struct {
+	ID            int64
+	AggregateID   int64
+	EventType     string
+	Payload       []byte
+	Status        int32
+	Attempts      int32
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	PublishedAt   *time.Time
+}
+
+// OutboxMessageBuilder builds the outbox messages for an expense whose ID is
+// only known once it has been inserted.
+type OutboxMessageBuilder func(expenseID int64) ([]*OutboxMessage, error)
+
+type OutboxStats struct {
+	Pending         int64
+	Failed          int64
+	Published       int64
+	OldestPendingAt *time.Time
+}
@@ -1,8 +1,50 @@
 package entity
 
+import "time"
+
+type Payment struct {
+	ID                int64
+	ExpenseID         int64
+	ExternalID        string
+	AmountIDR         float64
+	Status            int32
+	Attempts          int32
+	Provider          string
+	ProviderPaymentID string
+	LastError         string
+	RawResponse       string
+	BatchItemID       int64 // set when the expense is paid out in a payout batch
+	ReversalOf        int64 // set on the negative entry that reverses a payment
+	CreatedAt         time.Time
+	SentAt            *time.Time
+	CompletedAt       *time.Time
+	UpdatedAt         time.Time
+}
+
 type PaymentProcessorRequest struct {
-	AmountIDR  int64  `json:"amount_idr"`
-	ExternalID string `json:"external_id"`
+	AmountIDR      int64  `json:"amount_idr"`
+	ExternalID     string `json:"external_id"`
+	IdempotencyKey string `json:"-"`
+	// PayoutMethod and Provider are used to route the payout. Provider is set
+	// by the router once a provider has handled the payout.
+	PayoutMethod string `json:"-"`
+	Provider     string `json:"-"`
+	// Destinations holds the employee's destination per payout method. The
+	// router sets Destination to the one of the method it pays out with.
+	Destinations map[string]*PayoutDestination `json:"-"`
+	Destination  *PayoutDestination            `json:"destination,omitempty"`
+}
+
+// RefundRequest reverses a payout that succeeded. It is sent to the provider
+// that made the payout, which knows it by ProviderPaymentID.
+type RefundRequest struct {
+	AmountIDR         int64  `json:"amount_idr"`
+	ExternalID        string `json:"external_id"`
+	IdempotencyKey    string `json:"-"`
+	PaymentExternalID string `json:"payment_external_id"`
+	ProviderPaymentID string `json:"payment_id"`
+	Reason            string `json:"reason"`
+	Provider          string `json:"-"`
 }
 
 type PaymentProcessorResponse struct {
@@ -12,6 +54,7 @@ type PaymentProcessorResponse struct {
 		Status     string `json:"status"`
 	} `json:"data"`
 	Message string `json:"message"`
+	Raw     []byte `json:"-"`
 }
 
 type PublishPaymentRequest struct {
@@ -20,3 +63,22 @@ type PublishPaymentRequest struct {
 	Notes      string `json:"notes"`
 	Status     int32  `json:"status"`
 }
+
+type PaymentProviderStats struct {
+	Provider       string
+	CircuitState   string
+	Requests       int64
+	Failures       int64
+	Retries        int64
+	ShortCircuited int64
+}
+
+// SettlementRecord is one entry of a provider's settlement statement. Refunds
+// and reversals have a negative amount.
+type SettlementRecord struct {
+	ExternalID        string
+	ProviderPaymentID string
+	AmountIDR         float64
+	Status            string
+	SettledAt         *time.Time
+}
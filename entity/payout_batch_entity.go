@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+type PayoutBatch struct {
+	ID             int64
+	Status         int32
+	Channel        string
+	TotalAmountIDR float64
+	CreatedBy      int64 // 0 for scheduled runs
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+	UpdatedAt      time.Time
+	Items          []*PayoutBatchItem
+}
+
+// PayoutBatchItem is the single transfer that pays all expenses of one
+// employee in a batch. Every expense keeps its own Payment, linked to the item.
+type PayoutBatchItem struct {
+	ID                int64
+	BatchID           int64
+	UserID            int64
+	UserName          string
+	UserEmail         string
+	PayoutMethod      string
+	ExternalID        string
+	AmountIDR         float64
+	Status            int32
+	Provider          string
+	ProviderPaymentID string
+	LastError         string
+	RawResponse       string
+	CreatedAt         time.Time
+	CompletedAt       *time.Time
+	UpdatedAt         time.Time
+	Payments          []*Payment
+}
+
+// PayoutBatchBuilder groups the payable expenses into batch items. It runs
+// inside the transaction that locks the expenses.
+type PayoutBatchBuilder func(expenses []*Expense) ([]*PayoutBatchItem, error)
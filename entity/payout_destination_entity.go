@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// PayoutDestination is where an employee is paid for one payout method: a
+// bank account for bank transfers, a wallet for e-wallet payouts. The account
+// number and e-wallet ID are stored encrypted.
+type PayoutDestination struct {
+	ID                int64     `json:"-"`
+	UserID            int64     `json:"-"`
+	Method            string    `json:"method"`
+	BankCode          string    `json:"bank_code,omitempty"`
+	AccountNumber     string    `json:"account_number,omitempty"`
+	AccountHolderName string    `json:"account_holder_name,omitempty"`
+	EWalletID         string    `json:"ewallet_id,omitempty"`
+	CreatedAt         time.Time `json:"-"`
+	UpdatedAt         time.Time `json:"-"`
+}
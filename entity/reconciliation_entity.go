@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// Reconciliation is the result of matching a provider's settlement statement
+// against the payouts we recorded for that provider in the period.
+type Reconciliation struct {
+	ID                  int64
+	Provider            string
+	Source              string
+	PeriodFrom          time.Time
+	PeriodTo            time.Time // inclusive
+	MatchedCount        int32
+	MissingCount        int32
+	ExtraCount          int32
+	AmountMismatchCount int32
+	ExpectedTotalIDR    float64
+	StatementTotalIDR   float64
+	CreatedBy           int64 // 0 for scheduled runs
+	CreatedAt           time.Time
+	Items               []*ReconciliationItem
+}
+
+// ReconciliationItem is one discrepancy between the statement and our records.
+type ReconciliationItem struct {
+	ID                int64
+	ReconciliationID  int64
+	Kind              string
+	ExternalID        string
+	ProviderPaymentID string
+	ExpectedAmountIDR float64
+	ActualAmountIDR   float64
+	Note              string
+}
+
+// PayoutRecord is a transfer we sent to a provider: an immediate payment, a
+// reversal or a payout batch transfer.
+type PayoutRecord struct {
+	ExternalID        string
+	Provider          string
+	ProviderPaymentID string
+	AmountIDR         float64
+	Status            int32
+	CompletedAt       *time.Time
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// SpendingLimit caps what an employee claims in a calendar period. It is set
+// for a user, or for a role when UserID is zero; the limit of the user takes
+// precedence. ClaimedIDR totals the claims of the user in the period starting
+// at WindowStart, rejected and reversed claims aside.
+type SpendingLimit struct {
+	ID          int64
+	UserID      int64
+	Role        int32
+	AmountIDR   float64
+	Period      string
+	Action      string
+	UpdatedBy   int64
+	UpdatedAt   time.Time
+	WindowStart time.Time
+	WindowEnd   time.Time // exclusive
+	ClaimedIDR  float64
+}
+
+// SpendingLimitCheck holds an expense against the spending limit of its
+// submitter, nil when none applies. It runs in the transaction writing the
+// expense while the submitter is locked, so concurrent claims of the same user
+// are counted one after the other. It reports whether the expense is over a
+// review limit.
+type SpendingLimitCheck func(limit *SpendingLimit) (bool, error)
@@ -8,5 +8,7 @@ type User struct {
 	Name         string
 	Role         int // 1=admin, 2=manager, 3=employee
 	PasswordHash string
+	PayoutMethod string
+	Department   string
 	CreatedAt    time.Time
 }
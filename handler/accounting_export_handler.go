@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) CreateAccountingExport(c *fiber.Ctx) error {
+	var req model.AccountingExportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	if req.From == "" {
+		return BadRequestError(c, "Validation error", "From is required")
+	}
+
+	result, err := h.service.CreateAccountingExport(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrInvalidExport):
+			return BadRequestError(c, "Validation error", err.Error())
+		case errors.Is(err, util.ErrNothingToExport):
+			return ConflictError(c, "Nothing to export", err.Error())
+		case errors.Is(err, util.ErrUnmappedGLAccount):
+			return ConflictError(c, "Unmapped GL account", err.Error())
+		}
+		return InternalServerError(c, "Failed to create accounting export", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetAccountingExports(c *fiber.Ctx) error {
+	var query model.AccountingExportQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetAccountingExports(c.Context(), query.Limit)
+	if err != nil {
+		return InternalServerError(c, "Failed to get accounting exports", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetAccountingExport(c *fiber.Ctx) error {
+	exportID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid accounting export ID", "Accounting export ID must be a valid number")
+	}
+
+	result, err := h.service.GetAccountingExport(c.Context(), exportID)
+	if err != nil {
+		if errors.Is(err, util.ErrAccountingExportNotFound) {
+			return NotFoundError(c, "Accounting export not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to get accounting export", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetAccountingExportFile(c *fiber.Ctx) error {
+	exportID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid accounting export ID", "Accounting export ID must be a valid number")
+	}
+
+	file, err := h.service.GetAccountingExportFile(c.Context(), exportID)
+	if err != nil {
+		if errors.Is(err, util.ErrAccountingExportNotFound) {
+			return NotFoundError(c, "Accounting export not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to get accounting export file", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="accounting-export-%d.csv"`, exportID))
+	return c.Send(file)
+}
+
+func (h *ExpensesManagementHandler) GetGLAccountMappings(c *fiber.Ctx) error {
+	result, err := h.service.GetGLAccountMappings(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get GL account mappings", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) UpdateGLAccountMapping(c *fiber.Ctx) error {
+	var req model.GLAccountMappingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.UpdateGLAccountMapping(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidGLAccountMapping) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to update GL account mapping", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) GetOutboxStats(c *fiber.Ctx) error {
+	result, err := h.service.GetOutboxStats(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get outbox stats", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetDeadLetters(c *fiber.Ctx) error {
+	var query model.DeadLetterQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetDeadLetters(c.Context(), query.Limit)
+	if err != nil {
+		return InternalServerError(c, "Failed to get dead letters", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) ReplayDeadLetter(c *fiber.Ctx) error {
+	err := h.service.ReplayDeadLetter(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, util.ErrDeadLetterNotFound) {
+			return NotFoundError(c, "Dead letter not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to replay dead letter", err.Error())
+	}
+
+	return SuccessResponse(c, "success", nil)
+}
+
+func (h *ExpensesManagementHandler) DiscardDeadLetter(c *fiber.Ctx) error {
+	err := h.service.DiscardDeadLetter(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, util.ErrDeadLetterNotFound) {
+			return NotFoundError(c, "Dead letter not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to discard dead letter", err.Error())
+	}
+
+	return SuccessResponse(c, "success", nil)
+}
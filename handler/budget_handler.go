@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) SetBudget(c *fiber.Ctx) error {
+	var req model.BudgetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.SetBudget(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidBudget) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to set budget", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetBudgets(c *fiber.Ctx) error {
+	var query model.BudgetQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetBudgets(c.Context(), query)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidBudget) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to get budgets", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) CreateComment(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	var req model.CreateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.CreateComment(c.Context(), expenseID, req)
+	if err != nil {
+		return InternalServerError(c, "Failed to create comment", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetComments(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	result, err := h.service.GetComments(c.Context(), expenseID)
+	if err != nil {
+		return InternalServerError(c, "Failed to get comments", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
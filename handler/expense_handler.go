@@ -1,30 +1,34 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/budsx/expenses-management/model"
 	"github.com/budsx/expenses-management/service"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type ExpensesManagementHandler struct {
-	service *service.ExpensesManagementService
+	service       *service.ExpensesManagementService
+	webhookSecret []byte
 }
 
-func NewExpensesManagementHandler(service *service.ExpensesManagementService) *ExpensesManagementHandler {
-	return &ExpensesManagementHandler{service: service}
+func NewExpensesManagementHandler(service *service.ExpensesManagementService, webhookSecret string) *ExpensesManagementHandler {
+	return &ExpensesManagementHandler{service: service, webhookSecret: []byte(webhookSecret)}
 }
 
 func (h *ExpensesManagementHandler) HealthCheck(c *fiber.Ctx) error {
-	err := h.service.HealthCheck(c.Context())
+	result, err := h.service.HealthCheck(c.Context())
 	if err != nil {
 		return InternalServerError(c, "Failed to check health", err.Error())
 	}
-	return c.JSON(fiber.Map{
-		"message": "success",
-	})
+	return SuccessResponse(c, "success", result)
 }
 
 func (h *ExpensesManagementHandler) CreateExpense(c *fiber.Ctx) error {
@@ -35,6 +39,12 @@ func (h *ExpensesManagementHandler) CreateExpense(c *fiber.Ctx) error {
 	}
 
 	result, err := h.service.CreateExpense(c.Context(), req)
+	if errors.Is(err, util.ErrBudgetExceeded) {
+		return ConflictError(c, "Budget exceeded", err.Error())
+	}
+	if errors.Is(err, util.ErrSpendingLimitExceeded) {
+		return ConflictError(c, "Spending limit exceeded", err.Error())
+	}
 	if err != nil {
 		return InternalServerError(c, "Failed to create expense", err.Error())
 	}
@@ -76,6 +86,12 @@ func (h *ExpensesManagementHandler) GetExpenseByID(c *fiber.Ctx) error {
 		return InternalServerError(c, "Failed to get expense", err.Error())
 	}
 
+	etag := expenseETag(result.Version)
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return SuccessResponse(c, "success", result)
 }
 
@@ -91,12 +107,26 @@ func (h *ExpensesManagementHandler) ApproveExpense(c *fiber.Ctx) error {
 		return BadRequestError(c, "Invalid request body", err.Error())
 	}
 	req.ExpenseID = expenseID
+	req.Version, err = expectedVersion(c, req.Version)
+	if err != nil {
+		return BadRequestError(c, "Invalid If-Match header", err.Error())
+	}
 
 	result, err := h.service.ApproveExpense(c.Context(), req)
+	if errors.Is(err, util.ErrVersionConflict) {
+		return h.versionConflict(c, "Failed to approve expense", expenseID)
+	}
+	if errors.Is(err, statemachine.ErrIllegalTransition) {
+		return ConflictError(c, "Failed to approve expense", err.Error())
+	}
+	if errors.Is(err, util.ErrBudgetExceeded) {
+		return ConflictError(c, "Budget exceeded", err.Error())
+	}
 	if err != nil {
 		return InternalServerError(c, "Failed to approve expense", err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, expenseETag(result.Version))
 	return SuccessResponse(c, "success", result)
 }
 
@@ -112,11 +142,138 @@ func (h *ExpensesManagementHandler) RejectExpense(c *fiber.Ctx) error {
 		return BadRequestError(c, "Invalid request body", err.Error())
 	}
 	req.ExpenseID = expenseID
+	req.Version, err = expectedVersion(c, req.Version)
+	if err != nil {
+		return BadRequestError(c, "Invalid If-Match header", err.Error())
+	}
 
 	result, err := h.service.RejectExpense(c.Context(), req)
+	if errors.Is(err, util.ErrVersionConflict) {
+		return h.versionConflict(c, "Failed to reject expense", expenseID)
+	}
+	if errors.Is(err, statemachine.ErrIllegalTransition) {
+		return ConflictError(c, "Failed to reject expense", err.Error())
+	}
 	if err != nil {
 		return InternalServerError(c, "Failed to reject expense", err.Error())
 	}
 
+	c.Set(fiber.HeaderETag, expenseETag(result.Version))
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) RequestExpenseChanges(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	req := model.ApprovalRequest{}
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+	req.ExpenseID = expenseID
+	req.Version, err = expectedVersion(c, req.Version)
+	if err != nil {
+		return BadRequestError(c, "Invalid If-Match header", err.Error())
+	}
+
+	result, err := h.service.RequestExpenseChanges(c.Context(), req)
+	if errors.Is(err, util.ErrVersionConflict) {
+		return h.versionConflict(c, "Failed to request expense changes", expenseID)
+	}
+	if errors.Is(err, statemachine.ErrIllegalTransition) {
+		return ConflictError(c, "Failed to request expense changes", err.Error())
+	}
+	if err != nil {
+		return InternalServerError(c, "Failed to request expense changes", err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, expenseETag(result.Version))
 	return SuccessResponse(c, "success", result)
 }
+
+func (h *ExpensesManagementHandler) ResubmitExpense(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	var req model.ResubmitExpenseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+	req.Version, err = expectedVersion(c, req.Version)
+	if err != nil {
+		return BadRequestError(c, "Invalid If-Match header", err.Error())
+	}
+
+	result, err := h.service.ResubmitExpense(c.Context(), expenseID, req)
+	if errors.Is(err, util.ErrVersionConflict) {
+		return h.versionConflict(c, "Failed to resubmit expense", expenseID)
+	}
+	if errors.Is(err, statemachine.ErrIllegalTransition) {
+		return ConflictError(c, "Failed to resubmit expense", err.Error())
+	}
+	if errors.Is(err, util.ErrBudgetExceeded) {
+		return ConflictError(c, "Budget exceeded", err.Error())
+	}
+	if errors.Is(err, util.ErrSpendingLimitExceeded) {
+		return ConflictError(c, "Spending limit exceeded", err.Error())
+	}
+	if err != nil {
+		return InternalServerError(c, "Failed to resubmit expense", err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, expenseETag(result.Version))
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetExpenseHistory(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	result, err := h.service.GetExpenseHistory(c.Context(), expenseID)
+	if err != nil {
+		return InternalServerError(c, "Failed to get expense history", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+// versionConflict answers a stale update with 409 and the expense as it is now,
+// so the client can reload and retry.
+func (h *ExpensesManagementHandler) versionConflict(c *fiber.Ctx, errorType string, expenseID int64) error {
+	current, err := h.service.GetExpenseByID(c.Context(), expenseID)
+	if err != nil {
+		return ConflictError(c, errorType, util.ErrVersionConflict.Error())
+	}
+
+	c.Set(fiber.HeaderETag, expenseETag(current.Version))
+	return VersionConflictError(c, errorType, util.ErrVersionConflict.Error(), current)
+}
+
+func expenseETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// expectedVersion returns the expense version the client based its change on.
+// The If-Match header takes precedence over the version in the request body.
+func expectedVersion(c *fiber.Ctx, bodyVersion int32) (int32, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" || ifMatch == "*" {
+		return bodyVersion, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 32)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("If-Match must contain an expense version ETag")
+	}
+
+	return int32(version), nil
+}
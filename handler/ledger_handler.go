@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) RecordAdvance(c *fiber.Ctx) error {
+	var req model.AdvanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.RecordAdvance(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidAdvance) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to record advance", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetJournalEntries(c *fiber.Ctx) error {
+	var query model.JournalEntryQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetJournalEntries(c.Context(), query)
+	if err != nil {
+		return InternalServerError(c, "Failed to get journal entries", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetAccountBalances(c *fiber.Ctx) error {
+	var query model.LedgerBalanceQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetAccountBalances(c.Context(), query.UserID)
+	if err != nil {
+		return InternalServerError(c, "Failed to get account balances", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetEmployeeBalances(c *fiber.Ctx) error {
+	var query model.LedgerBalanceQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetEmployeeBalances(c.Context(), query.Account)
+	if err != nil {
+		return InternalServerError(c, "Failed to get employee balances", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetMyLedgerBalance(c *fiber.Ctx) error {
+	result, err := h.service.GetMyLedgerBalance(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get ledger balance", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+var circuitStateValues = map[string]int{
+	"closed":    0,
+	"half_open": 1,
+	"open":      2,
+}
+
+// Metrics serves the payment provider metrics in the Prometheus text format.
+func (h *ExpensesManagementHandler) Metrics(c *fiber.Ctx) error {
+	providers := h.service.GetPaymentProviderStats(c.Context())
+
+	var b strings.Builder
+	writeMetric(&b, "payment_provider_circuit_state", "gauge", "Circuit breaker state of the payment provider (0 closed, 1 half open, 2 open).", providers, func(p model.PaymentProviderStatus) int64 {
+		return int64(circuitStateValues[p.CircuitState])
+	})
+	writeMetric(&b, "payment_provider_requests_total", "counter", "Calls made to the payment provider.", providers, func(p model.PaymentProviderStatus) int64 {
+		return p.Requests
+	})
+	writeMetric(&b, "payment_provider_failures_total", "counter", "Calls to the payment provider that failed.", providers, func(p model.PaymentProviderStatus) int64 {
+		return p.Failures
+	})
+	writeMetric(&b, "payment_provider_retries_total", "counter", "Calls to the payment provider that were retries.", providers, func(p model.PaymentProviderStatus) int64 {
+		return p.Retries
+	})
+	writeMetric(&b, "payment_provider_short_circuited_total", "counter", "Calls to the payment provider skipped because its circuit was open.", providers, func(p model.PaymentProviderStatus) int64 {
+		return p.ShortCircuited
+	})
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4")
+	return c.SendString(b.String())
+}
+
+func writeMetric(b *strings.Builder, name, kind, help string, providers []model.PaymentProviderStatus, value func(model.PaymentProviderStatus) int64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, provider := range providers {
+		fmt.Fprintf(b, "%s{provider=%q} %d\n", name, provider.Provider, value(provider))
+	}
+}
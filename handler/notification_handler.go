@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) GetNotifications(c *fiber.Ctx) error {
+	var query model.NotificationQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetNotifications(c.Context(), query)
+	if err != nil {
+		return InternalServerError(c, "Failed to get notifications", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) MarkNotificationRead(c *fiber.Ctx) error {
+	notificationIDStr := c.Params("id")
+	notificationID, err := strconv.ParseInt(notificationIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid notification ID", "Notification ID must be a valid number")
+	}
+
+	err = h.service.MarkNotificationRead(c.Context(), notificationID)
+	if err != nil {
+		return InternalServerError(c, "Failed to mark notification as read", err.Error())
+	}
+
+	return SuccessResponse(c, "success", nil)
+}
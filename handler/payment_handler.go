@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) GetExpensePayments(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	result, err := h.service.GetExpensePayments(c.Context(), expenseID)
+	if err != nil {
+		return InternalServerError(c, "Failed to get payments", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) ReverseExpense(c *fiber.Ctx) error {
+	expenseIDStr := c.Params("id")
+	expenseID, err := strconv.ParseInt(expenseIDStr, 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid expense ID", "Expense ID must be a valid number")
+	}
+
+	req := model.ReverseExpenseRequest{}
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return BadRequestError(c, "Validation error", "Reason is required to reverse a payment")
+	}
+	req.ExpenseID = expenseID
+	req.Version, err = expectedVersion(c, req.Version)
+	if err != nil {
+		return BadRequestError(c, "Invalid If-Match header", err.Error())
+	}
+
+	result, err := h.service.ReverseExpense(c.Context(), req)
+	if errors.Is(err, util.ErrVersionConflict) {
+		return h.versionConflict(c, "Failed to reverse expense", expenseID)
+	}
+	if errors.Is(err, statemachine.ErrIllegalTransition) || errors.Is(err, util.ErrNothingToReverse) {
+		return ConflictError(c, "Failed to reverse expense", err.Error())
+	}
+	if err != nil {
+		return InternalServerError(c, "Failed to reverse expense", err.Error())
+	}
+
+	c.Set(fiber.HeaderETag, expenseETag(result.Version))
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) PaymentWebhook(c *fiber.Ctx) error {
+	body := c.Body()
+	if !util.VerifySignature(h.webhookSecret, body, c.Get(util.PaymentWebhookSignatureHeader)) {
+		return UnauthorizedError(c, "Invalid signature", "Webhook signature does not match")
+	}
+
+	var req model.PaymentWebhookRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	if req.ExternalID == "" {
+		return BadRequestError(c, "Validation error", "External ID is required")
+	}
+
+	result, err := h.service.HandlePaymentWebhook(c.Context(), req, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrUnknownPaymentStatus):
+			return BadRequestError(c, "Validation error", err.Error())
+		case errors.Is(err, util.ErrPaymentNotFound):
+			return NotFoundError(c, "Payment not found", err.Error())
+		case errors.Is(err, util.ErrVersionConflict):
+			return ConflictError(c, "Version conflict", err.Error())
+		}
+		return InternalServerError(c, "Failed to handle payment webhook", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
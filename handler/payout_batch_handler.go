@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) RunPayoutBatch(c *fiber.Ctx) error {
+	var req model.RunPayoutBatchRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return BadRequestError(c, "Invalid request body", err.Error())
+		}
+	}
+
+	if req.Channel != "" && !util.IsPayoutChannel(req.Channel) {
+		return BadRequestError(c, "Validation error", "Channel must be api or file")
+	}
+
+	result, err := h.service.RunPayoutBatch(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrNoPayableExpenses):
+			return ConflictError(c, "Nothing to pay out", err.Error())
+		case errors.Is(err, util.ErrPayoutBatchDisabled):
+			return ConflictError(c, "Payout batches disabled", err.Error())
+		}
+		return InternalServerError(c, "Failed to run payout batch", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetPayoutBatches(c *fiber.Ctx) error {
+	var query model.PayoutBatchQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetPayoutBatches(c.Context(), query.Limit)
+	if err != nil {
+		return InternalServerError(c, "Failed to get payout batches", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetPayoutBatch(c *fiber.Ctx) error {
+	batchID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid payout batch ID", "Payout batch ID must be a valid number")
+	}
+
+	result, err := h.service.GetPayoutBatch(c.Context(), batchID)
+	if err != nil {
+		if errors.Is(err, util.ErrPayoutBatchNotFound) {
+			return NotFoundError(c, "Payout batch not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to get payout batch", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetPayoutBatchFile(c *fiber.Ctx) error {
+	batchID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid payout batch ID", "Payout batch ID must be a valid number")
+	}
+
+	file, err := h.service.GetPayoutBatchFile(c.Context(), batchID)
+	if err != nil {
+		if errors.Is(err, util.ErrPayoutBatchNotFound) {
+			return NotFoundError(c, "Payout batch not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to get payout batch file", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="payout-batch-%d.csv"`, batchID))
+	return c.Send(file)
+}
+
+func (h *ExpensesManagementHandler) RecordPayoutBatchItemResult(c *fiber.Ctx) error {
+	batchID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid payout batch ID", "Payout batch ID must be a valid number")
+	}
+
+	itemID, err := strconv.ParseInt(c.Params("item_id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid payout batch item ID", "Payout batch item ID must be a valid number")
+	}
+
+	var req model.PayoutBatchItemResultRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	if req.Status == "" {
+		return BadRequestError(c, "Validation error", "Status is required")
+	}
+
+	result, err := h.service.RecordPayoutBatchItemResult(c.Context(), batchID, itemID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrUnknownPaymentStatus):
+			return BadRequestError(c, "Validation error", err.Error())
+		case errors.Is(err, util.ErrPayoutBatchNotFound):
+			return NotFoundError(c, "Payout batch not found", err.Error())
+		case errors.Is(err, util.ErrPaymentNotFound):
+			return NotFoundError(c, "Payout batch item not found", err.Error())
+		case errors.Is(err, util.ErrVersionConflict):
+			return ConflictError(c, "Version conflict", err.Error())
+		}
+		return InternalServerError(c, "Failed to record payout result", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportSettlement takes a multipart form with the provider, the period and
+// the statement as the file field.
+func (h *ExpensesManagementHandler) ImportSettlement(c *fiber.Ctx) error {
+	var req model.ReconcileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return BadRequestError(c, "Validation error", "Settlement file is required")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return BadRequestError(c, "Invalid settlement file", err.Error())
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return BadRequestError(c, "Invalid settlement file", err.Error())
+	}
+
+	result, err := h.service.ImportSettlement(c.Context(), req, content)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidReconciliation) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to reconcile settlement", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) ReconcileProvider(c *fiber.Ctx) error {
+	var req model.ReconcileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.ReconcileProvider(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidReconciliation) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to reconcile settlement", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetReconciliations(c *fiber.Ctx) error {
+	var query model.ReconciliationQuery
+	if err := c.QueryParser(&query); err != nil {
+		return BadRequestError(c, "Invalid query parameters", err.Error())
+	}
+
+	result, err := h.service.GetReconciliations(c.Context(), query.Limit)
+	if err != nil {
+		return InternalServerError(c, "Failed to get reconciliations", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetReconciliation(c *fiber.Ctx) error {
+	reconciliationID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid reconciliation ID", "Reconciliation ID must be a valid number")
+	}
+
+	result, err := h.service.GetReconciliation(c.Context(), reconciliationID)
+	if err != nil {
+		if errors.Is(err, util.ErrReconciliationNotFound) {
+			return NotFoundError(c, "Reconciliation not found", err.Error())
+		}
+		return InternalServerError(c, "Failed to get reconciliation", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
@@ -38,3 +38,15 @@ func NotFoundError(c *fiber.Ctx, errorType string, message string) error {
 func ForbiddenError(c *fiber.Ctx, errorType string, message string) error {
 	return ErrorResponse(c, fiber.StatusForbidden, errorType, message)
 }
+
+func ConflictError(c *fiber.Ctx, errorType string, message string) error {
+	return ErrorResponse(c, fiber.StatusConflict, errorType, message)
+}
+
+func VersionConflictError(c *fiber.Ctx, errorType string, message string, current interface{}) error {
+	return c.Status(fiber.StatusConflict).JSON(model.ConflictResponse{
+		Error:   errorType,
+		Message: message,
+		Current: current,
+	})
+}
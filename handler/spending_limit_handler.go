@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) SetSpendingLimit(c *fiber.Ctx) error {
+	var req model.SpendingLimitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.SetSpendingLimit(c.Context(), req)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidSpendingLimit) {
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to set spending limit", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetSpendingLimits(c *fiber.Ctx) error {
+	result, err := h.service.GetSpendingLimits(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get spending limits", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetMyAllowance(c *fiber.Ctx) error {
+	result, err := h.service.GetMyAllowance(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get allowance", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
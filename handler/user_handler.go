@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ExpensesManagementHandler) UpdatePayoutMethod(c *fiber.Ctx) error {
+	var req model.UpdatePayoutMethodRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	if !util.IsPayoutMethod(req.PayoutMethod) {
+		return BadRequestError(c, "Validation error", "Payout method must be bank_transfer or ewallet")
+	}
+
+	result, err := h.service.UpdatePayoutMethod(c.Context(), req)
+	if err != nil {
+		return InternalServerError(c, "Failed to update payout method", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) GetPayoutDestinations(c *fiber.Ctx) error {
+	result, err := h.service.GetPayoutDestinations(c.Context())
+	if err != nil {
+		return InternalServerError(c, "Failed to get payout destinations", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) UpdatePayoutDestination(c *fiber.Ctx) error {
+	var req model.UpdatePayoutDestinationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	if !util.IsPayoutMethod(req.Method) {
+		return BadRequestError(c, "Validation error", "Method must be bank_transfer or ewallet")
+	}
+	if req.Password == "" {
+		return BadRequestError(c, "Validation error", "Password is required to change a payout destination")
+	}
+
+	result, err := h.service.UpdatePayoutDestination(c.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrInvalidPassword):
+			return UnauthorizedError(c, "Invalid password", err.Error())
+		case errors.Is(err, util.ErrInvalidDestination):
+			return BadRequestError(c, "Validation error", err.Error())
+		}
+		return InternalServerError(c, "Failed to update payout destination", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
+
+func (h *ExpensesManagementHandler) UpdateUserDepartment(c *fiber.Ctx) error {
+	userID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return BadRequestError(c, "Invalid user ID", "User ID must be a valid number")
+	}
+
+	var req model.UpdateDepartmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return BadRequestError(c, "Invalid request body", err.Error())
+	}
+
+	result, err := h.service.UpdateUserDepartment(c.Context(), userID, req)
+	if err != nil {
+		return InternalServerError(c, "Failed to update department", err.Error())
+	}
+
+	return SuccessResponse(c, "success", result)
+}
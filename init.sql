@@ -8,6 +8,8 @@ CREATE TABLE IF NOT EXISTS users (
     name VARCHAR(255) NOT NULL,
     role SMALLINT NOT NULL, -- 1=admin, 2=manager, 3=employee
     password_hash VARCHAR(255) NOT NULL,
+    payout_method VARCHAR(20) NOT NULL DEFAULT 'bank_transfer', -- bank_transfer, ewallet
+    department VARCHAR(50), -- budgets are kept per department
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -15,11 +17,17 @@ CREATE TABLE IF NOT EXISTS users (
 CREATE TABLE IF NOT EXISTS expenses (
     id BIGSERIAL PRIMARY KEY,
     user_id BIGINT NOT NULL,
-    amount_idr DECIMAL(15,2) NOT NULL,
+    amount_idr DECIMAL(15,2) NOT NULL, -- claimed amount
+    approved_amount_idr DECIMAL(15,2), -- amount approved for payment, may be lower than claimed
     description TEXT NOT NULL,
     receipt_url VARCHAR(500),
-    status SMALLINT NOT NULL DEFAULT 3, -- 3 Pending, 1 Approved, -1 Rejected, 2 Auto Approved
+    category VARCHAR(50) NOT NULL DEFAULT 'other', -- travel, accommodation, meals, transport, office_supplies or other
+    cost_center VARCHAR(50),
+    status SMALLINT NOT NULL DEFAULT 3, -- 3 Pending, 1 Approved, -1 Rejected, 2 Auto Approved, 4 Needs Revision, 5 Paid, 6 Payment Failed, 7 Reversed
     auto_approved BOOLEAN DEFAULT FALSE,
+    over_limit BOOLEAN NOT NULL DEFAULT FALSE, -- claimed over a review spending limit, left to a manager
+    revision INT NOT NULL DEFAULT 1, -- incremented on every resubmission
+    version INT NOT NULL DEFAULT 1, -- incremented on every update, used for optimistic locking
     submitted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     processed_at TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(id)
@@ -31,10 +39,27 @@ CREATE TABLE IF NOT EXISTS approvals (
     id BIGSERIAL PRIMARY KEY,
     expense_id BIGINT NOT NULL,
     approver_id BIGINT NOT NULL, -- user_id
-    status SMALLINT NOT NULL, -- 1 Approved, -1 Rejected
+    revision INT NOT NULL DEFAULT 1, -- expense revision the decision was made on
+    status SMALLINT NOT NULL, -- 1 Approved, -1 Rejected, 4 Needs Changes
+    notes TEXT,
+    approved_amount_idr DECIMAL(15,2),
+    adjustment_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(expense_id, approver_id, revision)
+);
+
+-- Create Expense versions table, one row per submitted revision
+CREATE TABLE IF NOT EXISTS expense_versions (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    amount_idr DECIMAL(15,2) NOT NULL,
+    description TEXT NOT NULL,
+    receipt_url VARCHAR(500),
     notes TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(expense_id, approver_id)
+    FOREIGN KEY (expense_id) REFERENCES expenses(id),
+    UNIQUE(expense_id, revision)
 );
 
 -- Create Expenses status log
@@ -42,11 +67,273 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     id BIGSERIAL PRIMARY KEY,
     expense_id BIGINT NOT NULL,
     new_status SMALLINT NOT NULL,
-    status_before SMALLINT NOT NULL,
+    status_before SMALLINT NOT NULL, -- 0 for a newly created expense
+    amount_before DECIMAL(15,2), -- set when the approved amount differs from the claim
+    amount_after DECIMAL(15,2),
     notes TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Create Expense comments table
+CREATE TABLE IF NOT EXISTS expense_comments (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id BIGINT NOT NULL,
+    author_id BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (expense_id) REFERENCES expenses(id),
+    FOREIGN KEY (author_id) REFERENCES users(id)
+);
+
+-- Create Comment mentions table
+CREATE TABLE IF NOT EXISTS comment_mentions (
+    comment_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    PRIMARY KEY (comment_id, user_id),
+    FOREIGN KEY (comment_id) REFERENCES expense_comments(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Create Notifications table
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    expense_id BIGINT NOT NULL,
+    comment_id BIGINT,
+    type SMALLINT NOT NULL, -- 1 Comment, 2 Mention
+    message TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Create Outbox table, written in the same transaction as the expense change
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate_id BIGINT NOT NULL, -- expense_id
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 1, -- 1 Pending, 2 Published, -1 Failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+-- Create Payout destinations table, one per user and payout method
+CREATE TABLE IF NOT EXISTS payout_destinations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    method VARCHAR(20) NOT NULL, -- bank_transfer, ewallet
+    bank_code VARCHAR(20),
+    account_number_encrypted TEXT, -- AES-GCM, base64
+    account_holder_name VARCHAR(255),
+    ewallet_id_encrypted TEXT, -- AES-GCM, base64
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, method),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Create Payout batches table, one payment run over the approved expenses
+CREATE TABLE IF NOT EXISTS payout_batches (
+    id BIGSERIAL PRIMARY KEY,
+    status SMALLINT NOT NULL, -- 1 Processing, 2 Completed, 3 Partially Failed, -1 Failed
+    channel VARCHAR(20) NOT NULL, -- api or file
+    total_amount_idr DECIMAL(15,2) NOT NULL,
+    created_by BIGINT, -- NULL for scheduled runs
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Create Payout batch items table, one transfer per employee in a batch
+CREATE TABLE IF NOT EXISTS payout_batch_items (
+    id BIGSERIAL PRIMARY KEY,
+    batch_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    external_id VARCHAR(64) NOT NULL UNIQUE, -- sent to the provider as idempotency key and used as transfer reference
+    amount_idr DECIMAL(15,2) NOT NULL,
+    status SMALLINT NOT NULL, -- 1 Processing, 2 Succeeded, -1 Failed
+    provider VARCHAR(50),
+    provider_payment_id VARCHAR(255),
+    last_error TEXT,
+    raw_response TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (batch_id) REFERENCES payout_batches(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Create Payments table, one row per expense payout keyed by a deterministic external_id
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    expense_id BIGINT NOT NULL,
+    external_id VARCHAR(64) NOT NULL UNIQUE, -- also sent to the provider as idempotency key
+    amount_idr DECIMAL(15,2) NOT NULL,
+    status SMALLINT NOT NULL, -- 1 Processing, 2 Succeeded, -1 Failed
+    attempts INT NOT NULL DEFAULT 1,
+    provider VARCHAR(50), -- payment provider that handled the payout, retries stay on it
+    provider_payment_id VARCHAR(255),
+    last_error TEXT,
+    raw_response TEXT, -- last response body from the payment processor
+    batch_item_id BIGINT, -- payout batch item that pays the expense, NULL for immediate payouts
+    reversal_of BIGINT, -- payment reversed by this entry, its amount is negative
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP, -- last time the payment was sent to the processor
+    completed_at TIMESTAMP, -- when the processor confirmed the payout
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (expense_id) REFERENCES expenses(id),
+    FOREIGN KEY (batch_item_id) REFERENCES payout_batch_items(id),
+    FOREIGN KEY (reversal_of) REFERENCES payments(id)
+);
+
+-- Create Reconciliations table, one row per provider settlement statement matched against our payouts
+CREATE TABLE IF NOT EXISTS reconciliations (
+    id BIGSERIAL PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL, -- file for transfers made from a payout batch file
+    source VARCHAR(10) NOT NULL, -- file or api
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL, -- inclusive
+    matched_count INT NOT NULL,
+    missing_count INT NOT NULL,
+    extra_count INT NOT NULL,
+    amount_mismatch_count INT NOT NULL,
+    expected_total_idr DECIMAL(15,2) NOT NULL,
+    statement_total_idr DECIMAL(15,2) NOT NULL,
+    created_by BIGINT, -- NULL for scheduled runs
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Create Reconciliation items table, one row per discrepancy
+CREATE TABLE IF NOT EXISTS reconciliation_items (
+    id BIGSERIAL PRIMARY KEY,
+    reconciliation_id BIGINT NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- missing, extra or amount_mismatch
+    external_id VARCHAR(255) NOT NULL,
+    provider_payment_id VARCHAR(255),
+    expected_amount_idr DECIMAL(15,2), -- NULL when we have no payout
+    actual_amount_idr DECIMAL(15,2), -- NULL when the statement has no entry
+    note TEXT,
+    FOREIGN KEY (reconciliation_id) REFERENCES reconciliations(id)
+);
+
+-- Create Journal entries table, the double-entry ledger of reimbursements and advances
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    reference VARCHAR(255) NOT NULL UNIQUE, -- the business event, posted once
+    kind VARCHAR(50) NOT NULL, -- expense_approved, payment_sent, payment_reversed, advance_issued or advance_returned
+    user_id BIGINT NOT NULL, -- the employee the entry concerns
+    expense_id BIGINT, -- NULL for advances
+    description TEXT NOT NULL,
+    created_by BIGINT, -- NULL for entries posted by the system
+    posted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (expense_id) REFERENCES expenses(id),
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Create Journal lines table, each line debits or credits one account
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL,
+    account VARCHAR(50) NOT NULL, -- reimbursable_expense, employee_payable, bank or employee_advance
+    debit_idr DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (debit_idr >= 0),
+    credit_idr DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (credit_idr >= 0),
+    CHECK ((debit_idr = 0) <> (credit_idr = 0)),
+    FOREIGN KEY (entry_id) REFERENCES journal_entries(id)
+);
+
+-- Posted journal entries are immutable, corrections are posted as new entries
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'journal entries are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS journal_entries_immutable ON journal_entries;
+CREATE TRIGGER journal_entries_immutable BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+DROP TRIGGER IF EXISTS journal_lines_immutable ON journal_lines;
+CREATE TRIGGER journal_lines_immutable BEFORE UPDATE OR DELETE ON journal_lines
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+-- Create GL account mappings table, an empty category or cost center matches any
+CREATE TABLE IF NOT EXISTS gl_account_mappings (
+    id BIGSERIAL PRIMARY KEY,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    cost_center VARCHAR(50) NOT NULL DEFAULT '',
+    account_code VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (category, cost_center)
+);
+
+-- Create Accounting exports table, one row per journal file handed to the general ledger
+CREATE TABLE IF NOT EXISTS accounting_exports (
+    id BIGSERIAL PRIMARY KEY,
+    format VARCHAR(20) NOT NULL, -- csv or journal
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL, -- inclusive
+    expense_count INT NOT NULL,
+    total_amount_idr DECIMAL(15,2) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+-- Create Accounting export items table, an expense is exported once
+CREATE TABLE IF NOT EXISTS accounting_export_items (
+    id BIGSERIAL PRIMARY KEY,
+    export_id BIGINT NOT NULL,
+    expense_id BIGINT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    cost_center VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL,
+    amount_idr DECIMAL(15,2) NOT NULL,
+    account_code VARCHAR(50) NOT NULL, -- as mapped at export time
+    approved_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (export_id) REFERENCES accounting_exports(id),
+    FOREIGN KEY (expense_id) REFERENCES expenses(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Create Budgets table, an empty category covers every category of the department
+CREATE TABLE IF NOT EXISTS budgets (
+    id BIGSERIAL PRIMARY KEY,
+    department VARCHAR(50) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL, -- inclusive
+    amount_idr DECIMAL(15,2) NOT NULL CHECK (amount_idr > 0),
+    policy VARCHAR(10) NOT NULL DEFAULT 'warn', -- warn or block
+    created_by BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    UNIQUE (department, category, period_from, period_to),
+    CHECK (period_to >= period_from)
+);
+
+-- Create Spending Limits table, a limit is set for a user or for a role and the
+-- limit of the user takes precedence
+CREATE TABLE IF NOT EXISTS spending_limits (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    role SMALLINT,
+    amount_idr DECIMAL(15,2) NOT NULL CHECK (amount_idr > 0),
+    period VARCHAR(10) NOT NULL DEFAULT 'month', -- week, month or year, calendar aligned
+    action VARCHAR(10) NOT NULL DEFAULT 'reject', -- reject or review
+    updated_by BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (updated_by) REFERENCES users(id),
+    CHECK ((user_id IS NULL) <> (role IS NULL))
+);
 
 -- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
@@ -58,22 +345,50 @@ CREATE INDEX IF NOT EXISTS idx_approvals_expense_id ON approvals(expense_id);
 CREATE INDEX IF NOT EXISTS idx_approvals_approver_id ON approvals(approver_id);
 CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals(status);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_expense_id ON audit_logs(expense_id);
+CREATE INDEX IF NOT EXISTS idx_expense_versions_expense_id ON expense_versions(expense_id);
+CREATE INDEX IF NOT EXISTS idx_expense_comments_expense_id ON expense_comments(expense_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+CREATE INDEX IF NOT EXISTS idx_payments_expense_id ON payments(expense_id);
+CREATE INDEX IF NOT EXISTS idx_payments_batch_item_id ON payments(batch_item_id);
+CREATE INDEX IF NOT EXISTS idx_payout_batch_items_batch_id ON payout_batch_items(batch_id);
+CREATE INDEX IF NOT EXISTS idx_payments_provider_completed_at ON payments(provider, completed_at);
+CREATE INDEX IF NOT EXISTS idx_payout_batch_items_provider_completed_at ON payout_batch_items(provider, completed_at);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_items_reconciliation_id ON reconciliation_items(reconciliation_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_user_id ON journal_entries(user_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_expense_id ON journal_entries(expense_id);
+CREATE INDEX IF NOT EXISTS idx_journal_lines_entry_id ON journal_lines(entry_id);
+CREATE INDEX IF NOT EXISTS idx_approvals_expense_id_created_at ON approvals(expense_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_accounting_export_items_export_id ON accounting_export_items(export_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next_attempt_at ON outbox(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_users_department ON users(department);
+CREATE INDEX IF NOT EXISTS idx_budgets_department_period ON budgets(department, period_from, period_to);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_spending_limits_user_id ON spending_limits(user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_spending_limits_role ON spending_limits(role) WHERE role IS NOT NULL;
 
 -- Insert sample data with hashed passwords (bcrypt hash of "password123")
-INSERT INTO users (email, name, role, password_hash) VALUES
-    ('manager@company.com', 'Finance Manager', 2, '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi'),
-    ('john.doe@company.com', 'John Doe', 3, '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi')
+INSERT INTO users (email, name, role, password_hash, department) VALUES
+    ('manager@company.com', 'Finance Manager', 2, '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'finance'),
+    ('john.doe@company.com', 'John Doe', 3, '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'sales')
 ON CONFLICT (email) DO NOTHING;
 
 -- Insert sample expenses
-INSERT INTO expenses (user_id, amount_idr, description, receipt_url, status, auto_approved, submitted_at) VALUES
-    (2, 150000.00, 'Lunch meeting with client', 'https://example.com/receipts/receipt1.jpg', 1, TRUE, NOW() - INTERVAL '2 days'),
-    (2, 75000.00, 'Office supplies', 'https://example.com/receipts/receipt2.jpg', 1, TRUE, NOW() - INTERVAL '1 day'),
-    (2, 200000.00, 'Taxi for business trip', 'https://example.com/receipts/receipt3.jpg', 3, TRUE, NOW())
+INSERT INTO expenses (user_id, amount_idr, description, receipt_url, category, status, auto_approved, submitted_at) VALUES
+    (2, 150000.00, 'Lunch meeting with client', 'https://example.com/receipts/receipt1.jpg', 'meals', 1, TRUE, NOW() - INTERVAL '2 days'),
+    (2, 75000.00, 'Office supplies', 'https://example.com/receipts/receipt2.jpg', 'office_supplies', 1, TRUE, NOW() - INTERVAL '1 day'),
+    (2, 200000.00, 'Taxi for business trip', 'https://example.com/receipts/receipt3.jpg', 'transport', 3, TRUE, NOW())
+ON CONFLICT DO NOTHING;
+
+-- Insert initial versions for sample expenses
+INSERT INTO expense_versions (expense_id, revision, amount_idr, description, receipt_url)
+SELECT id, revision, amount_idr, description, receipt_url FROM expenses
 ON CONFLICT DO NOTHING;
 
 -- Insert sample approvals
 INSERT INTO approvals (expense_id, approver_id, status, notes) VALUES
     (1, 1, 1, 'Approved lunch meeting expense'),
     (2, 1, 1, 'Approved for office supplies purchase')
-ON CONFLICT DO NOTHING;
\ No newline at end of file
+ON CONFLICT DO NOTHING;
+-- Insert the default spending limit of employees
+INSERT INTO spending_limits (role, amount_idr, period, action, updated_by) VALUES
+    (3, 5000000.00, 'month', 'review', 1)
+ON CONFLICT DO NOTHING;
@@ -2,17 +2,19 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/budsx/expenses-management/config"
 	"github.com/budsx/expenses-management/handler"
 	"github.com/budsx/expenses-management/repository"
+	"github.com/budsx/expenses-management/repository/broker"
 	"github.com/budsx/expenses-management/repository/payment"
 	"github.com/budsx/expenses-management/repository/postgres"
-	"github.com/budsx/expenses-management/repository/rabbitmq"
 	"github.com/budsx/expenses-management/service"
 	"github.com/budsx/expenses-management/transport/http"
 	"github.com/budsx/expenses-management/transport/messaging"
 	"github.com/budsx/expenses-management/util"
+	utilBroker "github.com/budsx/expenses-management/util/broker"
 	utilRabbitmq "github.com/budsx/expenses-management/util/rabbitmq"
 )
 
@@ -25,33 +27,161 @@ func main() {
 		return
 	}
 
-	rabbitmqClient, err := utilRabbitmq.NewClient(conf.RabbitMQURL)
+	messageBroker, err := newBroker(conf)
 	if err != nil {
-		logger.WithError(err).Error("Failed to initialize rabbitmq client")
+		logger.WithError(err).Error("Failed to initialize message broker")
 		return
 	}
 
+	destinationKey, err := util.ParseEncryptionKey(conf.PayoutDestinationKey)
+	if err != nil {
+		logger.WithError(err).Error("Invalid PAYOUT_DESTINATION_KEY")
+		return
+	}
+
+	if conf.PaymentWebhookSecret == "" {
+		logger.Error("PAYMENT_WEBHOOK_SECRET is required")
+		return
+	}
+
+	queuePaymentProcessor := conf.TopicPaymentProcessor + ".ems.queue"
 	repos := repository.NewRepository(
-		payment.NewPaymentProcessor(conf.PaymentProcessorURL),
+		newPaymentRouter(conf),
 		postgres.NewUserRepository(conn),
+		postgres.NewPayoutDestinationRepository(conn, destinationKey),
 		postgres.NewExpensesRepository(conn),
-		rabbitmq.NewRabbitClient(rabbitmqClient, conf.TopicPaymentProcessor),
+		postgres.NewCommentRepository(conn),
+		postgres.NewNotificationRepository(conn),
+		postgres.NewOutboxRepository(conn),
+		postgres.NewPaymentRepository(conn),
+		postgres.NewPayoutBatchRepository(conn),
+		postgres.NewReconciliationRepository(conn),
+		postgres.NewLedgerRepository(conn),
+		postgres.NewAccountingExportRepository(conn),
+		postgres.NewBudgetRepository(conn),
+		postgres.NewSpendingLimitRepository(conn),
+		broker.NewMessageBroker(messageBroker, conf.TopicPaymentProcessor, queuePaymentProcessor, conf.EventsExchange),
 	)
-	service := service.NewExpensesManagementService(repos, logger)
-	expensesHandler := handler.NewExpensesManagementHandler(service)
+	var options []service.Option
+	switch conf.Payout.Mode {
+	case util.PAYOUT_MODE_IMMEDIATE:
+	case util.PAYOUT_MODE_BATCH:
+		if !util.IsPayoutChannel(conf.Payout.BatchChannel) {
+			logger.WithField("channel", conf.Payout.BatchChannel).Error("Unknown payout batch channel")
+			return
+		}
+		options = append(options, service.WithBatchPayouts(conf.Payout.BatchChannel))
+	default:
+		logger.WithField("mode", conf.Payout.Mode).Error("Unknown payout mode")
+		return
+	}
+	if conf.PayableGLAccount != "" {
+		options = append(options, service.WithPayableGLAccount(conf.PayableGLAccount))
+	}
+	service := service.NewExpensesManagementService(repos, logger, options...)
+	expensesHandler := handler.NewExpensesManagementHandler(service, conf.PaymentWebhookSecret)
 	authHandler := handler.NewAuthHandler(service)
 
 	server := http.NewExpensesManagementServer(service, expensesHandler, authHandler)
 
-	messaging.NewTransportListener(service, repos.RabbitMQClient, conf.TopicPaymentProcessor, conf.TopicPaymentProcessor+".ems.queue")
-	server.ServeHTTP(fmt.Sprintf(":%d", conf.ServicePort))
+	messaging.NewTransportListener(service, messageBroker, conf.TopicPaymentProcessor, queuePaymentProcessor)
+	outboxRelay := messaging.NewOutboxRelay(service, time.Duration(conf.Outbox.RelayIntervalMs)*time.Millisecond, conf.Outbox.BatchSize)
+	outboxRelay.Start()
+	var payoutScheduler *messaging.DailyScheduler
+	if conf.Payout.Mode == util.PAYOUT_MODE_BATCH && conf.Payout.BatchRunAt != "" {
+		payoutScheduler, err = messaging.NewPayoutScheduler(service, conf.Payout.BatchRunAt)
+		if err != nil {
+			logger.WithError(err).Error("Invalid PAYOUT_BATCH_RUN_AT, expected HH:MM")
+			return
+		}
+		payoutScheduler.Start()
+	}
+	var reconciliationScheduler *messaging.DailyScheduler
+	if conf.ReconciliationRunAt != "" {
+		reconciliationScheduler, err = messaging.NewReconciliationScheduler(service, conf.ReconciliationRunAt)
+		if err != nil {
+			logger.WithError(err).Error("Invalid RECONCILIATION_RUN_AT, expected HH:MM")
+			return
+		}
+		reconciliationScheduler.Start()
+	}
+	go func() {
+		if err := server.ServeHTTP(fmt.Sprintf(":%d", conf.ServicePort)); err != nil {
+			logger.WithError(err).Error("Server stopped")
+		}
+	}()
 	logger.Info("Server started...")
 
 	util.OnShutdown(func() {
 		logger.Info("Shutting down server...")
 		server.Shutdown()
 		logger.Info("Server shutdown...")
+		outboxRelay.Stop()
+		logger.Info("Outbox relay stopped...")
+		if payoutScheduler != nil {
+			payoutScheduler.Stop()
+			logger.Info("Payout scheduler stopped...")
+		}
+		if reconciliationScheduler != nil {
+			reconciliationScheduler.Stop()
+			logger.Info("Reconciliation scheduler stopped...")
+		}
+		if err := messageBroker.Drain(time.Duration(conf.Consumer.ShutdownTimeoutMs) * time.Millisecond); err != nil {
+			logger.WithError(err).Error("Failed to drain consumers")
+		}
+		logger.Info("Consumers drained...")
+		messageBroker.Close()
+		logger.Info("Message broker closed...")
 		conn.Close()
 		logger.Info("connection closed...")
 	})
 }
+
+func newBroker(conf *config.Config) (utilBroker.Broker, error) {
+	retry := utilBroker.RetryPolicy{
+		MaxRetries: conf.Consumer.MaxRetries,
+		BaseDelay:  time.Duration(conf.Consumer.RetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(conf.Consumer.RetryMaxDelayMs) * time.Millisecond,
+	}
+
+	switch conf.BrokerDriver {
+	case "rabbitmq":
+		return utilRabbitmq.NewClient(conf.RabbitMQURL, utilRabbitmq.Options{
+			Retry:          retry,
+			ConfirmTimeout: time.Duration(conf.Publisher.ConfirmTimeoutMs) * time.Millisecond,
+			Prefetch:       conf.Consumer.Prefetch,
+			Workers:        conf.Consumer.Workers,
+		})
+	case "memory":
+		return utilBroker.NewMemory(retry, conf.Consumer.Workers), nil
+	default:
+		return nil, fmt.Errorf("unknown broker driver %q", conf.BrokerDriver)
+	}
+}
+
+// newPaymentRouter registers the configured providers. The processor at
+// PAYMENT_PROCESSOR_URL is registered last so it only takes payouts the other
+// providers cannot.
+func newPaymentRouter(conf *config.Config) *payment.Router {
+	providers := make([]payment.Provider, 0, 3)
+	if conf.PaymentProviders.BankTransferURL != "" {
+		providers = append(providers, payment.NewBankTransferProvider(conf.PaymentProviders.BankTransferURL))
+	}
+	if conf.PaymentProviders.EWalletURL != "" {
+		providers = append(providers, payment.NewEWalletProvider(conf.PaymentProviders.EWalletURL, conf.PaymentProviders.EWalletMaxAmountIDR))
+	}
+	if conf.PaymentProcessorURL != "" {
+		providers = append(providers, payment.NewPaymentProcessor(conf.PaymentProcessorURL))
+	}
+
+	return payment.NewRouter(payment.RouterOptions{
+		AttemptTimeout: time.Duration(conf.PaymentProviders.TimeoutMs) * time.Millisecond,
+		Retry: payment.RetryPolicy{
+			MaxAttempts: conf.PaymentProviders.RetryMaxAttempts,
+			BaseDelay:   time.Duration(conf.PaymentProviders.RetryBaseDelayMs) * time.Millisecond,
+			MaxDelay:    time.Duration(conf.PaymentProviders.RetryMaxDelayMs) * time.Millisecond,
+		},
+		BreakerThreshold:   conf.PaymentProviders.BreakerThreshold,
+		BreakerOpenTimeout: time.Duration(conf.PaymentProviders.BreakerOpenTimeoutMs) * time.Millisecond,
+	}, providers...)
+}
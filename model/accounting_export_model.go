@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// AccountingExportRequest selects the days, from and to inclusive as
+// YYYY-MM-DD, in which the exported expenses were approved. To defaults to
+// From, Format to csv.
+type AccountingExportRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+type AccountingExportQuery struct {
+	Limit int `query:"limit"`
+}
+
+type AccountingExportResponse struct {
+	ID             int64                          `json:"id"`
+	Format         string                         `json:"format"`
+	PeriodFrom     string                         `json:"period_from"`
+	PeriodTo       string                         `json:"period_to"`
+	ExpenseCount   int32                          `json:"expense_count"`
+	TotalAmountIDR float64                        `json:"total_amount_idr"`
+	CreatedBy      int64                          `json:"created_by"`
+	CreatedAt      time.Time                      `json:"created_at"`
+	Items          []AccountingExportItemResponse `json:"items,omitempty"`
+}
+
+type AccountingExportItemResponse struct {
+	ExpenseID   int64     `json:"expense_id"`
+	UserID      int64     `json:"user_id"`
+	Category    string    `json:"category"`
+	CostCenter  string    `json:"cost_center,omitempty"`
+	Description string    `json:"description"`
+	AmountIDR   float64   `json:"amount_idr"`
+	AccountCode string    `json:"account_code"`
+	ApprovedAt  time.Time `json:"approved_at"`
+}
+
+type AccountingExportListResponse struct {
+	Exports []AccountingExportResponse `json:"exports"`
+}
+
+// GLAccountMappingRequest maps a category and cost center to a GL account
+// code. Leave either empty to match any.
+type GLAccountMappingRequest struct {
+	Category    string `json:"category"`
+	CostCenter  string `json:"cost_center"`
+	AccountCode string `json:"account_code"`
+}
+
+type GLAccountMappingResponse struct {
+	Category    string    `json:"category"`
+	CostCenter  string    `json:"cost_center"`
+	AccountCode string    `json:"account_code"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type GLAccountMappingListResponse struct {
+	PayableAccountCode string                     `json:"payable_account_code"`
+	Mappings           []GLAccountMappingResponse `json:"mappings"`
+}
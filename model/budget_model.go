@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// BudgetRequest sets the budget of a department for a category, or for every
+// category when Category is empty, over the days PeriodFrom to PeriodTo,
+// inclusive as YYYY-MM-DD. Policy is warn (default) or block.
+type BudgetRequest struct {
+	Department string  `json:"department"`
+	Category   string  `json:"category"`
+	PeriodFrom string  `json:"period_from"`
+	PeriodTo   string  `json:"period_to"`
+	AmountIDR  float64 `json:"amount_idr"`
+	Policy     string  `json:"policy"`
+}
+
+// BudgetQuery filters the budgets by department and by a day, as YYYY-MM-DD,
+// their period covers.
+type BudgetQuery struct {
+	Department string `query:"department"`
+	Date       string `query:"date"`
+}
+
+type BudgetResponse struct {
+	ID                 int64     `json:"id"`
+	Department         string    `json:"department"`
+	Category           string    `json:"category,omitempty"`
+	PeriodFrom         string    `json:"period_from"`
+	PeriodTo           string    `json:"period_to"`
+	AmountIDR          float64   `json:"amount_idr"`
+	Policy             string    `json:"policy"`
+	PendingIDR         float64   `json:"pending_idr"`
+	ReservedIDR        float64   `json:"reserved_idr"`
+	ConsumedIDR        float64   `json:"consumed_idr"`
+	RemainingIDR       float64   `json:"remaining_idr"`
+	UtilizationPercent float64   `json:"utilization_percent"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type BudgetListResponse struct {
+	Budgets []BudgetResponse `json:"budgets"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type CommentResponse struct {
+	ID         int64     `json:"id"`
+	ExpenseID  int64     `json:"expense_id"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Body       string    `json:"body"`
+	Mentions   []int64   `json:"mentions"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CommentListResponse struct {
+	Comments []CommentResponse `json:"comments"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type DeadLetterQuery struct {
+	Limit int `query:"limit"`
+}
+
+type DeadLetterResponse struct {
+	ID             string    `json:"id"`
+	Queue          string    `json:"queue"`
+	Payload        string    `json:"payload"`
+	RetryCount     int       `json:"retry_count"`
+	LastError      string    `json:"last_error"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+type DeadLetterListResponse struct {
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+}
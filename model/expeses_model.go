@@ -1,9 +1,21 @@
 package model
 
+import "time"
+
 type CreateExpenseRequest struct {
 	AmountIDR   float64 `json:"amount_idr" validate:"required,gt=0"`
 	Description string  `json:"description" validate:"required"`
 	ReceiptURL  string  `json:"receipt_url"`
+	Category    string  `json:"category"`
+	CostCenter  string  `json:"cost_center"`
+}
+
+type ResubmitExpenseRequest struct {
+	AmountIDR   float64 `json:"amount_idr" validate:"required,gt=0"`
+	Description string  `json:"description" validate:"required"`
+	ReceiptURL  string  `json:"receipt_url"`
+	Notes       string  `json:"notes"`
+	Version     int32   `json:"version"`
 }
 
 type UpdateExpenseStatusRequest struct {
@@ -12,13 +24,21 @@ type UpdateExpenseStatusRequest struct {
 }
 
 type ExpenseResponse struct {
-	ID           int64   `json:"id"`
-	UserID       int64   `json:"user_id"`
-	AmountIDR    float64 `json:"amount_idr"`
-	Description  string  `json:"description"`
-	ReceiptURL   string  `json:"receipt_url"`
-	Status       string  `json:"status"`
-	AutoApproved bool    `json:"auto_approved"`
+	ID                int64    `json:"id"`
+	UserID            int64    `json:"user_id"`
+	AmountIDR         float64  `json:"amount_idr"`
+	Description       string   `json:"description"`
+	ReceiptURL        string   `json:"receipt_url"`
+	Category          string   `json:"category"`
+	CostCenter        string   `json:"cost_center,omitempty"`
+	Status            string   `json:"status"`
+	AutoApproved      bool     `json:"auto_approved"`
+	OverLimit         bool     `json:"over_limit"`
+	ApprovedAmountIDR float64  `json:"approved_amount_idr,omitempty"`
+	Revision          int32    `json:"revision"`
+	Version           int32    `json:"version"`
+	BudgetWarnings    []string `json:"budget_warnings,omitempty"`
+	LimitWarning      string   `json:"limit_warning,omitempty"`
 }
 
 type ExpenseListResponse struct {
@@ -37,12 +57,43 @@ type ExpenseListQuery struct {
 }
 
 type ApprovalRequest struct {
-	ExpenseID  int64  `json:"expense_id"`
-	ApproverID int64  `json:"approver_id"`
-	Notes      string `json:"notes"`
-	Status     int32  `json:"status"`
+	ExpenseID         int64   `json:"expense_id"`
+	ApproverID        int64   `json:"approver_id"`
+	Notes             string  `json:"notes"`
+	Status            int32   `json:"status"`
+	ApprovedAmountIDR float64 `json:"approved_amount_idr"`
+	AdjustmentReason  string  `json:"adjustment_reason"`
+	Version           int32   `json:"version"`
 }
 
 type ApprovalResponse struct {
-	Message string `json:"message"`
+	Message        string   `json:"message"`
+	Version        int32    `json:"version,omitempty"`
+	BudgetWarnings []string `json:"budget_warnings,omitempty"`
+}
+
+type ExpenseVersionResponse struct {
+	Revision    int32     `json:"revision"`
+	AmountIDR   float64   `json:"amount_idr"`
+	Description string    `json:"description"`
+	ReceiptURL  string    `json:"receipt_url"`
+	Notes       string    `json:"notes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ApprovalFeedbackResponse struct {
+	ApproverID        int64     `json:"approver_id"`
+	Revision          int32     `json:"revision"`
+	Status            string    `json:"status"`
+	Notes             string    `json:"notes"`
+	ApprovedAmountIDR float64   `json:"approved_amount_idr,omitempty"`
+	AdjustmentReason  string    `json:"adjustment_reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type ExpenseHistoryResponse struct {
+	ExpenseID       int64                      `json:"expense_id"`
+	CurrentRevision int32                      `json:"current_revision"`
+	Versions        []ExpenseVersionResponse   `json:"versions"`
+	Feedback        []ApprovalFeedbackResponse `json:"feedback"`
 }
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+type JournalEntryQuery struct {
+	UserID    int64 `query:"user_id"`
+	ExpenseID int64 `query:"expense_id"`
+	Limit     int   `query:"limit"`
+}
+
+type LedgerBalanceQuery struct {
+	UserID  int64  `query:"user_id"`
+	Account string `query:"account"`
+}
+
+// AdvanceRequest records cash handed to an employee before they spend it, or
+// the unspent part they hand back when Kind is "return".
+type AdvanceRequest struct {
+	UserID      int64   `json:"user_id"`
+	AmountIDR   float64 `json:"amount_idr"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+}
+
+type JournalEntryResponse struct {
+	ID          int64                 `json:"id"`
+	Reference   string                `json:"reference"`
+	Kind        string                `json:"kind"`
+	UserID      int64                 `json:"user_id"`
+	ExpenseID   int64                 `json:"expense_id,omitempty"`
+	Description string                `json:"description"`
+	CreatedBy   int64                 `json:"created_by,omitempty"`
+	PostedAt    time.Time             `json:"posted_at"`
+	Lines       []JournalLineResponse `json:"lines"`
+}
+
+type JournalLineResponse struct {
+	Account   string  `json:"account"`
+	DebitIDR  float64 `json:"debit_idr"`
+	CreditIDR float64 `json:"credit_idr"`
+}
+
+type JournalEntryListResponse struct {
+	Entries []JournalEntryResponse `json:"entries"`
+}
+
+// AccountBalanceResponse reports the balance on the account's normal side,
+// credits minus debits for the payable and debits minus credits otherwise.
+type AccountBalanceResponse struct {
+	Account    string  `json:"account"`
+	DebitIDR   float64 `json:"debit_idr"`
+	CreditIDR  float64 `json:"credit_idr"`
+	BalanceIDR float64 `json:"balance_idr"`
+}
+
+type LedgerBalanceResponse struct {
+	UserID   int64                    `json:"user_id,omitempty"`
+	Accounts []AccountBalanceResponse `json:"accounts"`
+}
+
+type EmployeeBalanceListResponse struct {
+	Employees []LedgerBalanceResponse `json:"employees"`
+}
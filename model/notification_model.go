@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type NotificationQuery struct {
+	UnreadOnly bool `query:"unread_only"`
+}
+
+type NotificationResponse struct {
+	ID        int64     `json:"id"`
+	ExpenseID int64     `json:"expense_id"`
+	CommentID int64     `json:"comment_id,omitempty"`
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+}
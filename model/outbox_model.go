@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type OutboxStatsResponse struct {
+	Pending         int64      `json:"pending"`
+	Failed          int64      `json:"failed"`
+	Published       int64      `json:"published"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at,omitempty"`
+	LagSeconds      float64    `json:"lag_seconds"`
+}
@@ -10,3 +10,63 @@ type PaymentProcessorModel struct {
 	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type PaymentResponse struct {
+	ID                int64      `json:"id"`
+	ExternalID        string     `json:"external_id"`
+	ProviderPaymentID string     `json:"provider_payment_id,omitempty"`
+	AmountIDR         float64    `json:"amount_idr"`
+	Status            string     `json:"status"`
+	Attempts          int32      `json:"attempts"`
+	Provider          string     `json:"provider,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	ReversalOf        int64      `json:"reversal_of,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	SentAt            *time.Time `json:"sent_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type PaymentListResponse struct {
+	ExpenseID     int64             `json:"expense_id"`
+	ExpenseStatus string            `json:"expense_status"`
+	Payments      []PaymentResponse `json:"payments"`
+}
+
+type PaymentWebhookRequest struct {
+	ID            string `json:"id"`
+	ExternalID    string `json:"external_id"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+type PaymentWebhookResponse struct {
+	ExternalID    string `json:"external_id"`
+	PaymentStatus string `json:"payment_status"`
+	ExpenseStatus string `json:"expense_status,omitempty"`
+	BatchID       int64  `json:"batch_id,omitempty"`
+}
+
+type PaymentProviderStatus struct {
+	Provider       string `json:"provider"`
+	CircuitState   string `json:"circuit_state"`
+	Requests       int64  `json:"requests"`
+	Failures       int64  `json:"failures"`
+	Retries        int64  `json:"retries"`
+	ShortCircuited int64  `json:"short_circuited"`
+}
+
+type ReverseExpenseRequest struct {
+	ExpenseID int64  `json:"-"`
+	Reason    string `json:"reason"`
+	Version   int32  `json:"version,omitempty"`
+}
+
+// ReverseExpenseResponse reports the reversal entry. The expense stays paid
+// until the provider confirms a reversal that is still processing.
+type ReverseExpenseResponse struct {
+	ExpenseID     int64           `json:"expense_id"`
+	ExpenseStatus string          `json:"expense_status"`
+	Version       int32           `json:"version"`
+	Reversal      PaymentResponse `json:"reversal"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+type RunPayoutBatchRequest struct {
+	Channel string `json:"channel"`
+}
+
+type PayoutBatchQuery struct {
+	Limit int `query:"limit"`
+}
+
+type PayoutBatchItemResultRequest struct {
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+type PayoutBatchResponse struct {
+	ID             int64                     `json:"id"`
+	Status         string                    `json:"status"`
+	Channel        string                    `json:"channel"`
+	TotalAmountIDR float64                   `json:"total_amount_idr"`
+	CreatedBy      int64                     `json:"created_by,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	CompletedAt    *time.Time                `json:"completed_at,omitempty"`
+	Items          []PayoutBatchItemResponse `json:"items,omitempty"`
+}
+
+type PayoutBatchItemResponse struct {
+	ID                int64      `json:"id"`
+	UserID            int64      `json:"user_id"`
+	UserName          string     `json:"user_name"`
+	UserEmail         string     `json:"user_email"`
+	PayoutMethod      string     `json:"payout_method"`
+	ExternalID        string     `json:"external_id"`
+	AmountIDR         float64    `json:"amount_idr"`
+	Status            string     `json:"status"`
+	Provider          string     `json:"provider,omitempty"`
+	ProviderPaymentID string     `json:"provider_payment_id,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	ExpenseIDs        []int64    `json:"expense_ids"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+type PayoutBatchListResponse struct {
+	Batches []PayoutBatchResponse `json:"batches"`
+}
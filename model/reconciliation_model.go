@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// ReconcileRequest selects the provider and the days, from and to inclusive as
+// YYYY-MM-DD, a settlement statement covers. To defaults to From.
+type ReconcileRequest struct {
+	Provider string `json:"provider" form:"provider"`
+	From     string `json:"from" form:"from"`
+	To       string `json:"to" form:"to"`
+}
+
+type ReconciliationQuery struct {
+	Limit int `query:"limit"`
+}
+
+type ReconciliationResponse struct {
+	ID                  int64                        `json:"id"`
+	Provider            string                       `json:"provider"`
+	Source              string                       `json:"source"`
+	PeriodFrom          string                       `json:"period_from"`
+	PeriodTo            string                       `json:"period_to"`
+	MatchedCount        int32                        `json:"matched_count"`
+	MissingCount        int32                        `json:"missing_count"`
+	ExtraCount          int32                        `json:"extra_count"`
+	AmountMismatchCount int32                        `json:"amount_mismatch_count"`
+	ExpectedTotalIDR    float64                      `json:"expected_total_idr"`
+	StatementTotalIDR   float64                      `json:"statement_total_idr"`
+	CreatedBy           int64                        `json:"created_by,omitempty"`
+	CreatedAt           time.Time                    `json:"created_at"`
+	Items               []ReconciliationItemResponse `json:"items,omitempty"`
+}
+
+type ReconciliationItemResponse struct {
+	Kind              string   `json:"kind"`
+	ExternalID        string   `json:"external_id"`
+	ProviderPaymentID string   `json:"provider_payment_id,omitempty"`
+	ExpectedAmountIDR *float64 `json:"expected_amount_idr,omitempty"`
+	ActualAmountIDR   *float64 `json:"actual_amount_idr,omitempty"`
+	Note              string   `json:"note,omitempty"`
+}
+
+type ReconciliationListResponse struct {
+	Reconciliations []ReconciliationResponse `json:"reconciliations"`
+}
@@ -9,3 +9,16 @@ type ErrorResponse struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
 }
+
+type ConflictResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Current interface{} `json:"current"`
+}
+
+type HealthResponse struct {
+	Status           string            `json:"status"`
+	Database         string            `json:"database"`
+	Broker           string            `json:"broker"`
+	PaymentProviders map[string]string `json:"payment_providers"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// SpendingLimitRequest sets the limit of a user, or of a role when UserID is
+// not set, on what they claim per calendar Period: week, month (default) or
+// year. Action is reject (default) or review.
+type SpendingLimitRequest struct {
+	UserID    int64   `json:"user_id"`
+	Role      int     `json:"role"`
+	AmountIDR float64 `json:"amount_idr"`
+	Period    string  `json:"period"`
+	Action    string  `json:"action"`
+}
+
+type SpendingLimitResponse struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	AmountIDR float64   `json:"amount_idr"`
+	Period    string    `json:"period"`
+	Action    string    `json:"action"`
+	UpdatedBy int64     `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SpendingLimitListResponse struct {
+	Limits []SpendingLimitResponse `json:"limits"`
+}
+
+// AllowanceResponse is what the user may still claim in the current period,
+// the days PeriodFrom to PeriodTo inclusive. Limited is false when no limit
+// applies to the user.
+type AllowanceResponse struct {
+	Limited      bool    `json:"limited"`
+	LimitIDR     float64 `json:"limit_idr,omitempty"`
+	Period       string  `json:"period,omitempty"`
+	Action       string  `json:"action,omitempty"`
+	PeriodFrom   string  `json:"period_from,omitempty"`
+	PeriodTo     string  `json:"period_to,omitempty"`
+	ClaimedIDR   float64 `json:"claimed_idr"`
+	RemainingIDR float64 `json:"remaining_idr"`
+}
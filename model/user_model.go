@@ -19,3 +19,48 @@ type LoginResponse struct {
 	Token     string `json:"token"`
 	ExpiresAt int64  `json:"expires_at"`
 }
+
+type UpdatePayoutMethodRequest struct {
+	PayoutMethod string `json:"payout_method"`
+}
+
+type PayoutMethodResponse struct {
+	UserID       int64  `json:"user_id"`
+	PayoutMethod string `json:"payout_method"`
+}
+
+type UpdateDepartmentRequest struct {
+	Department string `json:"department"`
+}
+
+type DepartmentResponse struct {
+	UserID     int64  `json:"user_id"`
+	Department string `json:"department"`
+}
+
+// UpdatePayoutDestinationRequest sets the bank account or e-wallet for one
+// payout method. Password is the caller's current password.
+type UpdatePayoutDestinationRequest struct {
+	Method            string `json:"method"`
+	BankCode          string `json:"bank_code,omitempty"`
+	AccountNumber     string `json:"account_number,omitempty"`
+	AccountHolderName string `json:"account_holder_name,omitempty"`
+	EWalletID         string `json:"ewallet_id,omitempty"`
+	Password          string `json:"password"`
+}
+
+// PayoutDestinationResponse never contains the full account number or
+// e-wallet ID, only the last four digits.
+type PayoutDestinationResponse struct {
+	Method            string    `json:"method"`
+	BankCode          string    `json:"bank_code,omitempty"`
+	AccountNumber     string    `json:"account_number,omitempty"`
+	AccountHolderName string    `json:"account_holder_name,omitempty"`
+	EWalletID         string    `json:"ewallet_id,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type PayoutDestinationListResponse struct {
+	PayoutMethod string                      `json:"payout_method"`
+	Destinations []PayoutDestinationResponse `json:"destinations"`
+}
@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"fmt"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/broker"
+)
+
+type MessageBroker struct {
+	client                broker.Broker
+	topicPaymentProcessor string
+	queuePaymentProcessor string
+	eventsExchange        string
+}
+
+func NewMessageBroker(client broker.Broker, topicPaymentProcessor string, queuePaymentProcessor string, eventsExchange string) *MessageBroker {
+	return &MessageBroker{
+		client:                client,
+		topicPaymentProcessor: topicPaymentProcessor,
+		queuePaymentProcessor: queuePaymentProcessor,
+		eventsExchange:        eventsExchange,
+	}
+}
+
+func (c *MessageBroker) PublishMessage(message *entity.OutboxMessage) error {
+	switch message.EventType {
+	case util.OUTBOX_EVENT_PAYMENT_REQUESTED:
+		return c.client.Publish(c.topicPaymentProcessor, message.Payload)
+	case util.EVENT_EXPENSE_CREATED,
+		util.EVENT_EXPENSE_APPROVED,
+		util.EVENT_EXPENSE_REJECTED,
+		util.EVENT_PAYMENT_SUCCEEDED,
+		util.EVENT_PAYMENT_FAILED,
+		util.EVENT_PAYMENT_REVERSED:
+		return c.client.PublishEvent(c.eventsExchange, message.EventType, message.Payload)
+	}
+	return fmt.Errorf("unknown outbox event type %q", message.EventType)
+}
+
+func (c *MessageBroker) GetDeadLetters(limit int) ([]*entity.DeadLetter, error) {
+	deadLetters, err := c.client.DeadLetters(c.queuePaymentProcessor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.DeadLetter, 0, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		result = append(result, &entity.DeadLetter{
+			ID:             deadLetter.ID,
+			Queue:          deadLetter.Queue,
+			Payload:        deadLetter.Body,
+			RetryCount:     deadLetter.RetryCount,
+			LastError:      deadLetter.LastError,
+			DeadLetteredAt: deadLetter.DeadLetteredAt,
+		})
+	}
+	return result, nil
+}
+
+func (c *MessageBroker) ReplayDeadLetter(id string) error {
+	found, err := c.client.ReplayDeadLetter(c.queuePaymentProcessor, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return util.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (c *MessageBroker) DiscardDeadLetter(id string) error {
+	found, err := c.client.DiscardDeadLetter(c.queuePaymentProcessor, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return util.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (c *MessageBroker) GetConnectionState() string {
+	return string(c.client.State())
+}
@@ -0,0 +1,61 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/broker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBroker_PublishMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		wantTopic string
+		wantErr   string
+	}{
+		{name: "payment request", eventType: util.OUTBOX_EVENT_PAYMENT_REQUESTED, wantTopic: "payment.processor"},
+		{name: "expense created", eventType: util.EVENT_EXPENSE_CREATED, wantTopic: "ems.events"},
+		{name: "payment succeeded", eventType: util.EVENT_PAYMENT_SUCCEEDED, wantTopic: "ems.events"},
+		{name: "payment reversed", eventType: util.EVENT_PAYMENT_REVERSED, wantTopic: "ems.events"},
+		{name: "unknown event", eventType: "payment.refunded", wantErr: `unknown outbox event type "payment.refunded"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := broker.NewMemory(broker.RetryPolicy{}, 1)
+			defer client.Close()
+
+			received := make(chan string, 1)
+			for _, topic := range []string{"payment.processor", "ems.events"} {
+				topic := topic
+				err := client.Subscribe(topic, topic+".queue", func(body []byte) error {
+					received <- topic + " " + string(body)
+					return nil
+				}, nil)
+				assert.NoError(t, err)
+			}
+
+			messageBroker := NewMessageBroker(client, "payment.processor", "payment.processor.queue", "ems.events")
+			err := messageBroker.PublishMessage(&entity.OutboxMessage{
+				EventType: tt.eventType,
+				Payload:   []byte(`{"expense_id":7}`),
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			select {
+			case got := <-received:
+				assert.Equal(t, tt.wantTopic+` {"expense_id":7}`, got)
+			case <-time.After(time.Second):
+				t.Fatal("message was not delivered")
+			}
+		})
+	}
+}
@@ -2,30 +2,116 @@ package _interface
 
 import (
 	"context"
+	"time"
 
 	"github.com/budsx/expenses-management/entity"
-	"github.com/budsx/expenses-management/util/rabbitmq"
 )
 
 type PaymentProcessor interface {
 	ProcessPayment(context.Context, *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error)
+	RefundPayment(context.Context, *entity.RefundRequest) (*entity.PaymentProcessorResponse, error)
+	ListPayments(context.Context, string, time.Time, time.Time) ([]*entity.SettlementRecord, error)
+	GetProviderStats() []*entity.PaymentProviderStats
 }
 
 type UserRepository interface {
 	GetUserWithPassword(context.Context, string) (*entity.User, error)
+	GetUsersByEmails(context.Context, []string) ([]*entity.User, error)
+	GetUserByID(context.Context, int64) (*entity.User, error)
+	UpdatePayoutMethod(context.Context, int64, string) error
+	UpdateDepartment(context.Context, int64, string) error
+}
+
+type PayoutDestinationRepository interface {
+	GetPayoutDestinations(context.Context, int64) ([]*entity.PayoutDestination, error)
+	UpsertPayoutDestination(context.Context, *entity.PayoutDestination) error
 }
 
 type ExpensesRepository interface {
-	WriteExpense(context.Context, *entity.Expense) (int64, error)
-	ApprovalExpense(context.Context, *entity.ExpenseApproval) error
-	UpdateExpenseStatus(context.Context, int64, int32) error
+	WriteExpense(context.Context, *entity.Expense, *entity.AuditLog, entity.SpendingLimitCheck, entity.BudgetCheck, entity.OutboxMessageBuilder) (int64, error)
+	ApprovalExpense(context.Context, *entity.ExpenseApproval, *entity.AuditLog, entity.BudgetCheck, ...*entity.OutboxMessage) error
+	UpdateExpenseStatus(context.Context, int64, int32, int32, *entity.AuditLog, ...*entity.OutboxMessage) error
 	GetExpenseByID(context.Context, int64) (*entity.Expense, error)
 	GetExpensesWithPagination(context.Context, *entity.ExpenseListQuery) ([]*entity.Expense, int64, error)
 	WriteAuditLog(context.Context, *entity.AuditLog) error
+	ReviseExpense(context.Context, *entity.Expense, string, *entity.AuditLog, entity.SpendingLimitCheck, entity.BudgetCheck, entity.OutboxMessageBuilder) (int32, error)
+	GetExpenseVersions(context.Context, int64) ([]*entity.ExpenseVersion, error)
+	GetApprovalsByExpenseID(context.Context, int64) ([]*entity.Approval, error)
 	PingContext(context.Context) error
 }
 
-type RabbitMQClient interface {
-	PublishPayment(*entity.PublishPaymentRequest) error
-	GetClient() *rabbitmq.RabbitMQClient
+type CommentRepository interface {
+	WriteComment(context.Context, *entity.Comment) (int64, error)
+	GetCommentsByExpenseID(context.Context, int64) ([]*entity.Comment, error)
+}
+
+type NotificationRepository interface {
+	WriteNotifications(context.Context, []*entity.Notification) error
+	GetNotifications(context.Context, int64, bool) ([]*entity.Notification, error)
+	MarkNotificationRead(context.Context, int64, int64) error
+}
+
+type PaymentRepository interface {
+	StartPaymentAttempt(context.Context, *entity.Payment) (*entity.Payment, error)
+	UpdatePayment(context.Context, *entity.Payment) error
+	GetPaymentsByExpenseID(context.Context, int64) ([]*entity.Payment, error)
+	GetPaymentByExternalID(context.Context, string) (*entity.Payment, error)
+}
+
+type PayoutBatchRepository interface {
+	CreatePayoutBatch(context.Context, *entity.PayoutBatch, entity.PayoutBatchBuilder) (int64, error)
+	GetPayoutBatch(context.Context, int64) (*entity.PayoutBatch, error)
+	GetPayoutBatches(context.Context, int) ([]*entity.PayoutBatch, error)
+	GetPayoutBatchItemByExternalID(context.Context, string) (*entity.PayoutBatchItem, error)
+	UpdatePayoutBatchItem(context.Context, *entity.PayoutBatchItem) error
+	UpdatePayoutBatchStatus(context.Context, int64, int32) error
+}
+
+type ReconciliationRepository interface {
+	GetSettledPayouts(context.Context, string, time.Time, time.Time) ([]*entity.PayoutRecord, error)
+	GetPayoutsByExternalIDs(context.Context, []string) ([]*entity.PayoutRecord, error)
+	CreateReconciliation(context.Context, *entity.Reconciliation) (int64, error)
+	GetReconciliation(context.Context, int64) (*entity.Reconciliation, error)
+	GetReconciliations(context.Context, int) ([]*entity.Reconciliation, error)
+}
+
+type LedgerRepository interface {
+	PostJournalEntry(context.Context, *entity.JournalEntry) (int64, error)
+	GetJournalEntries(context.Context, *entity.JournalEntryQuery) ([]*entity.JournalEntry, error)
+	GetAccountBalances(context.Context, int64) ([]*entity.AccountBalance, error)
+	GetEmployeeBalances(context.Context, string) ([]*entity.AccountBalance, error)
+}
+
+type AccountingExportRepository interface {
+	GetGLAccountMappings(context.Context) ([]*entity.GLAccountMapping, error)
+	UpsertGLAccountMapping(context.Context, *entity.GLAccountMapping) error
+	CreateAccountingExport(context.Context, *entity.AccountingExport, entity.AccountingExportBuilder) (int64, error)
+	GetAccountingExport(context.Context, int64) (*entity.AccountingExport, error)
+	GetAccountingExports(context.Context, int) ([]*entity.AccountingExport, error)
+}
+
+type BudgetRepository interface {
+	GetBudgets(context.Context, *entity.BudgetQuery) ([]*entity.Budget, error)
+	UpsertBudget(context.Context, *entity.Budget) (int64, error)
+}
+
+type SpendingLimitRepository interface {
+	GetSpendingLimits(context.Context) ([]*entity.SpendingLimit, error)
+	GetSpendingLimit(context.Context, int64, int32, time.Time) (*entity.SpendingLimit, error)
+	UpsertSpendingLimit(context.Context, *entity.SpendingLimit) (int64, error)
+}
+
+type OutboxRepository interface {
+	ClaimOutboxMessages(context.Context, int, time.Duration) ([]*entity.OutboxMessage, error)
+	MarkOutboxPublished(context.Context, int64) error
+	UpdateOutboxAttempt(context.Context, *entity.OutboxMessage) error
+	GetOutboxStats(context.Context) (*entity.OutboxStats, error)
+}
+
+type MessageBroker interface {
+	PublishMessage(*entity.OutboxMessage) error
+	GetDeadLetters(int) ([]*entity.DeadLetter, error)
+	ReplayDeadLetter(string) error
+	DiscardDeadLetter(string) error
+	GetConnectionState() string
 }
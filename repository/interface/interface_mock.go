@@ -7,9 +7,9 @@ package _interface
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	entity "github.com/budsx/expenses-management/entity"
-	rabbitmq "github.com/budsx/expenses-management/util/rabbitmq"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -36,6 +36,35 @@ func (m *MockPaymentProcessor) EXPECT() *MockPaymentProcessorMockRecorder {
 	return m.recorder
 }
 
+// GetProviderStats mocks base method.
+func (m *MockPaymentProcessor) GetProviderStats() []*entity.PaymentProviderStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProviderStats")
+	ret0, _ := ret[0].([]*entity.PaymentProviderStats)
+	return ret0
+}
+
+// GetProviderStats indicates an expected call of GetProviderStats.
+func (mr *MockPaymentProcessorMockRecorder) GetProviderStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProviderStats", reflect.TypeOf((*MockPaymentProcessor)(nil).GetProviderStats))
+}
+
+// ListPayments mocks base method.
+func (m *MockPaymentProcessor) ListPayments(arg0 context.Context, arg1 string, arg2, arg3 time.Time) ([]*entity.SettlementRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPayments", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*entity.SettlementRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPayments indicates an expected call of ListPayments.
+func (mr *MockPaymentProcessorMockRecorder) ListPayments(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPayments", reflect.TypeOf((*MockPaymentProcessor)(nil).ListPayments), arg0, arg1, arg2, arg3)
+}
+
 // ProcessPayment mocks base method.
 func (m *MockPaymentProcessor) ProcessPayment(arg0 context.Context, arg1 *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +80,21 @@ func (mr *MockPaymentProcessorMockRecorder) ProcessPayment(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessPayment", reflect.TypeOf((*MockPaymentProcessor)(nil).ProcessPayment), arg0, arg1)
 }
 
+// RefundPayment mocks base method.
+func (m *MockPaymentProcessor) RefundPayment(arg0 context.Context, arg1 *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundPayment", arg0, arg1)
+	ret0, _ := ret[0].(*entity.PaymentProcessorResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundPayment indicates an expected call of RefundPayment.
+func (mr *MockPaymentProcessorMockRecorder) RefundPayment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundPayment", reflect.TypeOf((*MockPaymentProcessor)(nil).RefundPayment), arg0, arg1)
+}
+
 // MockUserRepository is a mock of UserRepository interface.
 type MockUserRepository struct {
 	ctrl     *gomock.Controller
@@ -74,6 +118,21 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// GetUserByID mocks base method.
+func (m *MockUserRepository) GetUserByID(arg0 context.Context, arg1 int64) (*entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
+	ret0, _ := ret[0].(*entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepository)(nil).GetUserByID), arg0, arg1)
+}
+
 // GetUserWithPassword mocks base method.
 func (m *MockUserRepository) GetUserWithPassword(arg0 context.Context, arg1 string) (*entity.User, error) {
 	m.ctrl.T.Helper()
@@ -89,6 +148,101 @@ func (mr *MockUserRepositoryMockRecorder) GetUserWithPassword(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserWithPassword", reflect.TypeOf((*MockUserRepository)(nil).GetUserWithPassword), arg0, arg1)
 }
 
+// GetUsersByEmails mocks base method.
+func (m *MockUserRepository) GetUsersByEmails(arg0 context.Context, arg1 []string) ([]*entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByEmails", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByEmails indicates an expected call of GetUsersByEmails.
+func (mr *MockUserRepositoryMockRecorder) GetUsersByEmails(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByEmails", reflect.TypeOf((*MockUserRepository)(nil).GetUsersByEmails), arg0, arg1)
+}
+
+// UpdateDepartment mocks base method.
+func (m *MockUserRepository) UpdateDepartment(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDepartment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDepartment indicates an expected call of UpdateDepartment.
+func (mr *MockUserRepositoryMockRecorder) UpdateDepartment(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDepartment", reflect.TypeOf((*MockUserRepository)(nil).UpdateDepartment), arg0, arg1, arg2)
+}
+
+// UpdatePayoutMethod mocks base method.
+func (m *MockUserRepository) UpdatePayoutMethod(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePayoutMethod", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePayoutMethod indicates an expected call of UpdatePayoutMethod.
+func (mr *MockUserRepositoryMockRecorder) UpdatePayoutMethod(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePayoutMethod", reflect.TypeOf((*MockUserRepository)(nil).UpdatePayoutMethod), arg0, arg1, arg2)
+}
+
+// MockPayoutDestinationRepository is a mock of PayoutDestinationRepository interface.
+type MockPayoutDestinationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPayoutDestinationRepositoryMockRecorder
+}
+
+// MockPayoutDestinationRepositoryMockRecorder is the mock recorder for MockPayoutDestinationRepository.
+type MockPayoutDestinationRepositoryMockRecorder struct {
+	mock *MockPayoutDestinationRepository
+}
+
+// NewMockPayoutDestinationRepository creates a new mock instance.
+func NewMockPayoutDestinationRepository(ctrl *gomock.Controller) *MockPayoutDestinationRepository {
+	mock := &MockPayoutDestinationRepository{ctrl: ctrl}
+	mock.recorder = &MockPayoutDestinationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayoutDestinationRepository) EXPECT() *MockPayoutDestinationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetPayoutDestinations mocks base method.
+func (m *MockPayoutDestinationRepository) GetPayoutDestinations(arg0 context.Context, arg1 int64) ([]*entity.PayoutDestination, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayoutDestinations", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.PayoutDestination)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayoutDestinations indicates an expected call of GetPayoutDestinations.
+func (mr *MockPayoutDestinationRepositoryMockRecorder) GetPayoutDestinations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayoutDestinations", reflect.TypeOf((*MockPayoutDestinationRepository)(nil).GetPayoutDestinations), arg0, arg1)
+}
+
+// UpsertPayoutDestination mocks base method.
+func (m *MockPayoutDestinationRepository) UpsertPayoutDestination(arg0 context.Context, arg1 *entity.PayoutDestination) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertPayoutDestination", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertPayoutDestination indicates an expected call of UpsertPayoutDestination.
+func (mr *MockPayoutDestinationRepositoryMockRecorder) UpsertPayoutDestination(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertPayoutDestination", reflect.TypeOf((*MockPayoutDestinationRepository)(nil).UpsertPayoutDestination), arg0, arg1)
+}
+
 // MockExpensesRepository is a mock of ExpensesRepository interface.
 type MockExpensesRepository struct {
 	ctrl     *gomock.Controller
@@ -113,17 +267,37 @@ func (m *MockExpensesRepository) EXPECT() *MockExpensesRepositoryMockRecorder {
 }
 
 // ApprovalExpense mocks base method.
-func (m *MockExpensesRepository) ApprovalExpense(arg0 context.Context, arg1 *entity.ExpenseApproval) error {
+func (m *MockExpensesRepository) ApprovalExpense(arg0 context.Context, arg1 *entity.ExpenseApproval, arg2 *entity.AuditLog, arg3 entity.BudgetCheck, arg4 ...*entity.OutboxMessage) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ApprovalExpense", arg0, arg1)
+	varargs := []interface{}{arg0, arg1, arg2, arg3}
+	for _, a := range arg4 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ApprovalExpense", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ApprovalExpense indicates an expected call of ApprovalExpense.
-func (mr *MockExpensesRepositoryMockRecorder) ApprovalExpense(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockExpensesRepositoryMockRecorder) ApprovalExpense(arg0, arg1, arg2, arg3 interface{}, arg4 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1, arg2, arg3}, arg4...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApprovalExpense", reflect.TypeOf((*MockExpensesRepository)(nil).ApprovalExpense), varargs...)
+}
+
+// GetApprovalsByExpenseID mocks base method.
+func (m *MockExpensesRepository) GetApprovalsByExpenseID(arg0 context.Context, arg1 int64) ([]*entity.Approval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApprovalsByExpenseID", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.Approval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApprovalsByExpenseID indicates an expected call of GetApprovalsByExpenseID.
+func (mr *MockExpensesRepositoryMockRecorder) GetApprovalsByExpenseID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApprovalExpense", reflect.TypeOf((*MockExpensesRepository)(nil).ApprovalExpense), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApprovalsByExpenseID", reflect.TypeOf((*MockExpensesRepository)(nil).GetApprovalsByExpenseID), arg0, arg1)
 }
 
 // GetExpenseByID mocks base method.
@@ -141,6 +315,21 @@ func (mr *MockExpensesRepositoryMockRecorder) GetExpenseByID(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpenseByID", reflect.TypeOf((*MockExpensesRepository)(nil).GetExpenseByID), arg0, arg1)
 }
 
+// GetExpenseVersions mocks base method.
+func (m *MockExpensesRepository) GetExpenseVersions(arg0 context.Context, arg1 int64) ([]*entity.ExpenseVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpenseVersions", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.ExpenseVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpenseVersions indicates an expected call of GetExpenseVersions.
+func (mr *MockExpensesRepositoryMockRecorder) GetExpenseVersions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpenseVersions", reflect.TypeOf((*MockExpensesRepository)(nil).GetExpenseVersions), arg0, arg1)
+}
+
 // GetExpensesWithPagination mocks base method.
 func (m *MockExpensesRepository) GetExpensesWithPagination(arg0 context.Context, arg1 *entity.ExpenseListQuery) ([]*entity.Expense, int64, error) {
 	m.ctrl.T.Helper()
@@ -171,18 +360,38 @@ func (mr *MockExpensesRepositoryMockRecorder) PingContext(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PingContext", reflect.TypeOf((*MockExpensesRepository)(nil).PingContext), arg0)
 }
 
+// ReviseExpense mocks base method.
+func (m *MockExpensesRepository) ReviseExpense(arg0 context.Context, arg1 *entity.Expense, arg2 string, arg3 *entity.AuditLog, arg4 entity.SpendingLimitCheck, arg5 entity.BudgetCheck, arg6 entity.OutboxMessageBuilder) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviseExpense", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviseExpense indicates an expected call of ReviseExpense.
+func (mr *MockExpensesRepositoryMockRecorder) ReviseExpense(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviseExpense", reflect.TypeOf((*MockExpensesRepository)(nil).ReviseExpense), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // UpdateExpenseStatus mocks base method.
-func (m *MockExpensesRepository) UpdateExpenseStatus(arg0 context.Context, arg1 int64, arg2 int32) error {
+func (m *MockExpensesRepository) UpdateExpenseStatus(arg0 context.Context, arg1 int64, arg2, arg3 int32, arg4 *entity.AuditLog, arg5 ...*entity.OutboxMessage) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateExpenseStatus", arg0, arg1, arg2)
+	varargs := []interface{}{arg0, arg1, arg2, arg3, arg4}
+	for _, a := range arg5 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateExpenseStatus", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateExpenseStatus indicates an expected call of UpdateExpenseStatus.
-func (mr *MockExpensesRepositoryMockRecorder) UpdateExpenseStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockExpensesRepositoryMockRecorder) UpdateExpenseStatus(arg0, arg1, arg2, arg3, arg4 interface{}, arg5 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExpenseStatus", reflect.TypeOf((*MockExpensesRepository)(nil).UpdateExpenseStatus), arg0, arg1, arg2)
+	varargs := append([]interface{}{arg0, arg1, arg2, arg3, arg4}, arg5...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExpenseStatus", reflect.TypeOf((*MockExpensesRepository)(nil).UpdateExpenseStatus), varargs...)
 }
 
 // WriteAuditLog mocks base method.
@@ -200,67 +409,902 @@ func (mr *MockExpensesRepositoryMockRecorder) WriteAuditLog(arg0, arg1 interface
 }
 
 // WriteExpense mocks base method.
-func (m *MockExpensesRepository) WriteExpense(arg0 context.Context, arg1 *entity.Expense) (int64, error) {
+func (m *MockExpensesRepository) WriteExpense(arg0 context.Context, arg1 *entity.Expense, arg2 *entity.AuditLog, arg3 entity.SpendingLimitCheck, arg4 entity.BudgetCheck, arg5 entity.OutboxMessageBuilder) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WriteExpense", arg0, arg1)
+	ret := m.ctrl.Call(m, "WriteExpense", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // WriteExpense indicates an expected call of WriteExpense.
-func (mr *MockExpensesRepositoryMockRecorder) WriteExpense(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockExpensesRepositoryMockRecorder) WriteExpense(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteExpense", reflect.TypeOf((*MockExpensesRepository)(nil).WriteExpense), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// MockCommentRepository is a mock of CommentRepository interface.
+type MockCommentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommentRepositoryMockRecorder
+}
+
+// MockCommentRepositoryMockRecorder is the mock recorder for MockCommentRepository.
+type MockCommentRepositoryMockRecorder struct {
+	mock *MockCommentRepository
+}
+
+// NewMockCommentRepository creates a new mock instance.
+func NewMockCommentRepository(ctrl *gomock.Controller) *MockCommentRepository {
+	mock := &MockCommentRepository{ctrl: ctrl}
+	mock.recorder = &MockCommentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommentRepository) EXPECT() *MockCommentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetCommentsByExpenseID mocks base method.
+func (m *MockCommentRepository) GetCommentsByExpenseID(arg0 context.Context, arg1 int64) ([]*entity.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommentsByExpenseID", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommentsByExpenseID indicates an expected call of GetCommentsByExpenseID.
+func (mr *MockCommentRepositoryMockRecorder) GetCommentsByExpenseID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommentsByExpenseID", reflect.TypeOf((*MockCommentRepository)(nil).GetCommentsByExpenseID), arg0, arg1)
+}
+
+// WriteComment mocks base method.
+func (m *MockCommentRepository) WriteComment(arg0 context.Context, arg1 *entity.Comment) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteComment", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteComment indicates an expected call of WriteComment.
+func (mr *MockCommentRepositoryMockRecorder) WriteComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteComment", reflect.TypeOf((*MockCommentRepository)(nil).WriteComment), arg0, arg1)
+}
+
+// MockNotificationRepository is a mock of NotificationRepository interface.
+type MockNotificationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRepositoryMockRecorder
+}
+
+// MockNotificationRepositoryMockRecorder is the mock recorder for MockNotificationRepository.
+type MockNotificationRepositoryMockRecorder struct {
+	mock *MockNotificationRepository
+}
+
+// NewMockNotificationRepository creates a new mock instance.
+func NewMockNotificationRepository(ctrl *gomock.Controller) *MockNotificationRepository {
+	mock := &MockNotificationRepository{ctrl: ctrl}
+	mock.recorder = &MockNotificationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationRepository) EXPECT() *MockNotificationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetNotifications mocks base method.
+func (m *MockNotificationRepository) GetNotifications(arg0 context.Context, arg1 int64, arg2 bool) ([]*entity.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotifications", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*entity.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotifications indicates an expected call of GetNotifications.
+func (mr *MockNotificationRepositoryMockRecorder) GetNotifications(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotifications", reflect.TypeOf((*MockNotificationRepository)(nil).GetNotifications), arg0, arg1, arg2)
+}
+
+// MarkNotificationRead mocks base method.
+func (m *MockNotificationRepository) MarkNotificationRead(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkNotificationRead", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkNotificationRead indicates an expected call of MarkNotificationRead.
+func (mr *MockNotificationRepositoryMockRecorder) MarkNotificationRead(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkNotificationRead", reflect.TypeOf((*MockNotificationRepository)(nil).MarkNotificationRead), arg0, arg1, arg2)
+}
+
+// WriteNotifications mocks base method.
+func (m *MockNotificationRepository) WriteNotifications(arg0 context.Context, arg1 []*entity.Notification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteNotifications", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteNotifications indicates an expected call of WriteNotifications.
+func (mr *MockNotificationRepositoryMockRecorder) WriteNotifications(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteNotifications", reflect.TypeOf((*MockNotificationRepository)(nil).WriteNotifications), arg0, arg1)
+}
+
+// MockPaymentRepository is a mock of PaymentRepository interface.
+type MockPaymentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentRepositoryMockRecorder
+}
+
+// MockPaymentRepositoryMockRecorder is the mock recorder for MockPaymentRepository.
+type MockPaymentRepositoryMockRecorder struct {
+	mock *MockPaymentRepository
+}
+
+// NewMockPaymentRepository creates a new mock instance.
+func NewMockPaymentRepository(ctrl *gomock.Controller) *MockPaymentRepository {
+	mock := &MockPaymentRepository{ctrl: ctrl}
+	mock.recorder = &MockPaymentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentRepository) EXPECT() *MockPaymentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetPaymentByExternalID mocks base method.
+func (m *MockPaymentRepository) GetPaymentByExternalID(arg0 context.Context, arg1 string) (*entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPaymentByExternalID", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPaymentByExternalID indicates an expected call of GetPaymentByExternalID.
+func (mr *MockPaymentRepositoryMockRecorder) GetPaymentByExternalID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentByExternalID", reflect.TypeOf((*MockPaymentRepository)(nil).GetPaymentByExternalID), arg0, arg1)
+}
+
+// GetPaymentsByExpenseID mocks base method.
+func (m *MockPaymentRepository) GetPaymentsByExpenseID(arg0 context.Context, arg1 int64) ([]*entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPaymentsByExpenseID", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPaymentsByExpenseID indicates an expected call of GetPaymentsByExpenseID.
+func (mr *MockPaymentRepositoryMockRecorder) GetPaymentsByExpenseID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsByExpenseID", reflect.TypeOf((*MockPaymentRepository)(nil).GetPaymentsByExpenseID), arg0, arg1)
+}
+
+// StartPaymentAttempt mocks base method.
+func (m *MockPaymentRepository) StartPaymentAttempt(arg0 context.Context, arg1 *entity.Payment) (*entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartPaymentAttempt", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartPaymentAttempt indicates an expected call of StartPaymentAttempt.
+func (mr *MockPaymentRepositoryMockRecorder) StartPaymentAttempt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPaymentAttempt", reflect.TypeOf((*MockPaymentRepository)(nil).StartPaymentAttempt), arg0, arg1)
+}
+
+// UpdatePayment mocks base method.
+func (m *MockPaymentRepository) UpdatePayment(arg0 context.Context, arg1 *entity.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePayment", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePayment indicates an expected call of UpdatePayment.
+func (mr *MockPaymentRepositoryMockRecorder) UpdatePayment(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteExpense", reflect.TypeOf((*MockExpensesRepository)(nil).WriteExpense), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePayment", reflect.TypeOf((*MockPaymentRepository)(nil).UpdatePayment), arg0, arg1)
 }
 
-// MockRabbitMQClient is a mock of RabbitMQClient interface.
-type MockRabbitMQClient struct {
+// MockPayoutBatchRepository is a mock of PayoutBatchRepository interface.
+type MockPayoutBatchRepository struct {
 	ctrl     *gomock.Controller
-	recorder *MockRabbitMQClientMockRecorder
+	recorder *MockPayoutBatchRepositoryMockRecorder
 }
 
-// MockRabbitMQClientMockRecorder is the mock recorder for MockRabbitMQClient.
-type MockRabbitMQClientMockRecorder struct {
-	mock *MockRabbitMQClient
+// MockPayoutBatchRepositoryMockRecorder is the mock recorder for MockPayoutBatchRepository.
+type MockPayoutBatchRepositoryMockRecorder struct {
+	mock *MockPayoutBatchRepository
 }
 
-// NewMockRabbitMQClient creates a new mock instance.
-func NewMockRabbitMQClient(ctrl *gomock.Controller) *MockRabbitMQClient {
-	mock := &MockRabbitMQClient{ctrl: ctrl}
-	mock.recorder = &MockRabbitMQClientMockRecorder{mock}
+// NewMockPayoutBatchRepository creates a new mock instance.
+func NewMockPayoutBatchRepository(ctrl *gomock.Controller) *MockPayoutBatchRepository {
+	mock := &MockPayoutBatchRepository{ctrl: ctrl}
+	mock.recorder = &MockPayoutBatchRepositoryMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockRabbitMQClient) EXPECT() *MockRabbitMQClientMockRecorder {
+func (m *MockPayoutBatchRepository) EXPECT() *MockPayoutBatchRepositoryMockRecorder {
 	return m.recorder
 }
 
-// GetClient mocks base method.
-func (m *MockRabbitMQClient) GetClient() *rabbitmq.RabbitMQClient {
+// CreatePayoutBatch mocks base method.
+func (m *MockPayoutBatchRepository) CreatePayoutBatch(arg0 context.Context, arg1 *entity.PayoutBatch, arg2 entity.PayoutBatchBuilder) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePayoutBatch", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePayoutBatch indicates an expected call of CreatePayoutBatch.
+func (mr *MockPayoutBatchRepositoryMockRecorder) CreatePayoutBatch(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePayoutBatch", reflect.TypeOf((*MockPayoutBatchRepository)(nil).CreatePayoutBatch), arg0, arg1, arg2)
+}
+
+// GetPayoutBatch mocks base method.
+func (m *MockPayoutBatchRepository) GetPayoutBatch(arg0 context.Context, arg1 int64) (*entity.PayoutBatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayoutBatch", arg0, arg1)
+	ret0, _ := ret[0].(*entity.PayoutBatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayoutBatch indicates an expected call of GetPayoutBatch.
+func (mr *MockPayoutBatchRepositoryMockRecorder) GetPayoutBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayoutBatch", reflect.TypeOf((*MockPayoutBatchRepository)(nil).GetPayoutBatch), arg0, arg1)
+}
+
+// GetPayoutBatchItemByExternalID mocks base method.
+func (m *MockPayoutBatchRepository) GetPayoutBatchItemByExternalID(arg0 context.Context, arg1 string) (*entity.PayoutBatchItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayoutBatchItemByExternalID", arg0, arg1)
+	ret0, _ := ret[0].(*entity.PayoutBatchItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayoutBatchItemByExternalID indicates an expected call of GetPayoutBatchItemByExternalID.
+func (mr *MockPayoutBatchRepositoryMockRecorder) GetPayoutBatchItemByExternalID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayoutBatchItemByExternalID", reflect.TypeOf((*MockPayoutBatchRepository)(nil).GetPayoutBatchItemByExternalID), arg0, arg1)
+}
+
+// GetPayoutBatches mocks base method.
+func (m *MockPayoutBatchRepository) GetPayoutBatches(arg0 context.Context, arg1 int) ([]*entity.PayoutBatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayoutBatches", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.PayoutBatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayoutBatches indicates an expected call of GetPayoutBatches.
+func (mr *MockPayoutBatchRepositoryMockRecorder) GetPayoutBatches(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayoutBatches", reflect.TypeOf((*MockPayoutBatchRepository)(nil).GetPayoutBatches), arg0, arg1)
+}
+
+// UpdatePayoutBatchItem mocks base method.
+func (m *MockPayoutBatchRepository) UpdatePayoutBatchItem(arg0 context.Context, arg1 *entity.PayoutBatchItem) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetClient")
-	ret0, _ := ret[0].(*rabbitmq.RabbitMQClient)
+	ret := m.ctrl.Call(m, "UpdatePayoutBatchItem", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePayoutBatchItem indicates an expected call of UpdatePayoutBatchItem.
+func (mr *MockPayoutBatchRepositoryMockRecorder) UpdatePayoutBatchItem(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePayoutBatchItem", reflect.TypeOf((*MockPayoutBatchRepository)(nil).UpdatePayoutBatchItem), arg0, arg1)
+}
+
+// UpdatePayoutBatchStatus mocks base method.
+func (m *MockPayoutBatchRepository) UpdatePayoutBatchStatus(arg0 context.Context, arg1 int64, arg2 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePayoutBatchStatus", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePayoutBatchStatus indicates an expected call of UpdatePayoutBatchStatus.
+func (mr *MockPayoutBatchRepositoryMockRecorder) UpdatePayoutBatchStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePayoutBatchStatus", reflect.TypeOf((*MockPayoutBatchRepository)(nil).UpdatePayoutBatchStatus), arg0, arg1, arg2)
+}
+
+// MockReconciliationRepository is a mock of ReconciliationRepository interface.
+type MockReconciliationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationRepositoryMockRecorder
+}
+
+// MockReconciliationRepositoryMockRecorder is the mock recorder for MockReconciliationRepository.
+type MockReconciliationRepositoryMockRecorder struct {
+	mock *MockReconciliationRepository
+}
+
+// NewMockReconciliationRepository creates a new mock instance.
+func NewMockReconciliationRepository(ctrl *gomock.Controller) *MockReconciliationRepository {
+	mock := &MockReconciliationRepository{ctrl: ctrl}
+	mock.recorder = &MockReconciliationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciliationRepository) EXPECT() *MockReconciliationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateReconciliation mocks base method.
+func (m *MockReconciliationRepository) CreateReconciliation(arg0 context.Context, arg1 *entity.Reconciliation) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReconciliation", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReconciliation indicates an expected call of CreateReconciliation.
+func (mr *MockReconciliationRepositoryMockRecorder) CreateReconciliation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReconciliation", reflect.TypeOf((*MockReconciliationRepository)(nil).CreateReconciliation), arg0, arg1)
+}
+
+// GetPayoutsByExternalIDs mocks base method.
+func (m *MockReconciliationRepository) GetPayoutsByExternalIDs(arg0 context.Context, arg1 []string) ([]*entity.PayoutRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayoutsByExternalIDs", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.PayoutRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayoutsByExternalIDs indicates an expected call of GetPayoutsByExternalIDs.
+func (mr *MockReconciliationRepositoryMockRecorder) GetPayoutsByExternalIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayoutsByExternalIDs", reflect.TypeOf((*MockReconciliationRepository)(nil).GetPayoutsByExternalIDs), arg0, arg1)
+}
+
+// GetReconciliation mocks base method.
+func (m *MockReconciliationRepository) GetReconciliation(arg0 context.Context, arg1 int64) (*entity.Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliation", arg0, arg1)
+	ret0, _ := ret[0].(*entity.Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliation indicates an expected call of GetReconciliation.
+func (mr *MockReconciliationRepositoryMockRecorder) GetReconciliation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliation", reflect.TypeOf((*MockReconciliationRepository)(nil).GetReconciliation), arg0, arg1)
+}
+
+// GetReconciliations mocks base method.
+func (m *MockReconciliationRepository) GetReconciliations(arg0 context.Context, arg1 int) ([]*entity.Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliations", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliations indicates an expected call of GetReconciliations.
+func (mr *MockReconciliationRepositoryMockRecorder) GetReconciliations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliations", reflect.TypeOf((*MockReconciliationRepository)(nil).GetReconciliations), arg0, arg1)
+}
+
+// GetSettledPayouts mocks base method.
+func (m *MockReconciliationRepository) GetSettledPayouts(arg0 context.Context, arg1 string, arg2, arg3 time.Time) ([]*entity.PayoutRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSettledPayouts", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*entity.PayoutRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSettledPayouts indicates an expected call of GetSettledPayouts.
+func (mr *MockReconciliationRepositoryMockRecorder) GetSettledPayouts(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettledPayouts", reflect.TypeOf((*MockReconciliationRepository)(nil).GetSettledPayouts), arg0, arg1, arg2, arg3)
+}
+
+// MockLedgerRepository is a mock of LedgerRepository interface.
+type MockLedgerRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLedgerRepositoryMockRecorder
+}
+
+// MockLedgerRepositoryMockRecorder is the mock recorder for MockLedgerRepository.
+type MockLedgerRepositoryMockRecorder struct {
+	mock *MockLedgerRepository
+}
+
+// NewMockLedgerRepository creates a new mock instance.
+func NewMockLedgerRepository(ctrl *gomock.Controller) *MockLedgerRepository {
+	mock := &MockLedgerRepository{ctrl: ctrl}
+	mock.recorder = &MockLedgerRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLedgerRepository) EXPECT() *MockLedgerRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAccountBalances mocks base method.
+func (m *MockLedgerRepository) GetAccountBalances(arg0 context.Context, arg1 int64) ([]*entity.AccountBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountBalances", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.AccountBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountBalances indicates an expected call of GetAccountBalances.
+func (mr *MockLedgerRepositoryMockRecorder) GetAccountBalances(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountBalances", reflect.TypeOf((*MockLedgerRepository)(nil).GetAccountBalances), arg0, arg1)
+}
+
+// GetEmployeeBalances mocks base method.
+func (m *MockLedgerRepository) GetEmployeeBalances(arg0 context.Context, arg1 string) ([]*entity.AccountBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEmployeeBalances", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.AccountBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEmployeeBalances indicates an expected call of GetEmployeeBalances.
+func (mr *MockLedgerRepositoryMockRecorder) GetEmployeeBalances(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeBalances", reflect.TypeOf((*MockLedgerRepository)(nil).GetEmployeeBalances), arg0, arg1)
+}
+
+// GetJournalEntries mocks base method.
+func (m *MockLedgerRepository) GetJournalEntries(arg0 context.Context, arg1 *entity.JournalEntryQuery) ([]*entity.JournalEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJournalEntries", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.JournalEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJournalEntries indicates an expected call of GetJournalEntries.
+func (mr *MockLedgerRepositoryMockRecorder) GetJournalEntries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJournalEntries", reflect.TypeOf((*MockLedgerRepository)(nil).GetJournalEntries), arg0, arg1)
+}
+
+// PostJournalEntry mocks base method.
+func (m *MockLedgerRepository) PostJournalEntry(arg0 context.Context, arg1 *entity.JournalEntry) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostJournalEntry", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostJournalEntry indicates an expected call of PostJournalEntry.
+func (mr *MockLedgerRepositoryMockRecorder) PostJournalEntry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostJournalEntry", reflect.TypeOf((*MockLedgerRepository)(nil).PostJournalEntry), arg0, arg1)
+}
+
+// MockAccountingExportRepository is a mock of AccountingExportRepository interface.
+type MockAccountingExportRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountingExportRepositoryMockRecorder
+}
+
+// MockAccountingExportRepositoryMockRecorder is the mock recorder for MockAccountingExportRepository.
+type MockAccountingExportRepositoryMockRecorder struct {
+	mock *MockAccountingExportRepository
+}
+
+// NewMockAccountingExportRepository creates a new mock instance.
+func NewMockAccountingExportRepository(ctrl *gomock.Controller) *MockAccountingExportRepository {
+	mock := &MockAccountingExportRepository{ctrl: ctrl}
+	mock.recorder = &MockAccountingExportRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountingExportRepository) EXPECT() *MockAccountingExportRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateAccountingExport mocks base method.
+func (m *MockAccountingExportRepository) CreateAccountingExport(arg0 context.Context, arg1 *entity.AccountingExport, arg2 entity.AccountingExportBuilder) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountingExport", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountingExport indicates an expected call of CreateAccountingExport.
+func (mr *MockAccountingExportRepositoryMockRecorder) CreateAccountingExport(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountingExport", reflect.TypeOf((*MockAccountingExportRepository)(nil).CreateAccountingExport), arg0, arg1, arg2)
+}
+
+// GetAccountingExport mocks base method.
+func (m *MockAccountingExportRepository) GetAccountingExport(arg0 context.Context, arg1 int64) (*entity.AccountingExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountingExport", arg0, arg1)
+	ret0, _ := ret[0].(*entity.AccountingExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountingExport indicates an expected call of GetAccountingExport.
+func (mr *MockAccountingExportRepositoryMockRecorder) GetAccountingExport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountingExport", reflect.TypeOf((*MockAccountingExportRepository)(nil).GetAccountingExport), arg0, arg1)
+}
+
+// GetAccountingExports mocks base method.
+func (m *MockAccountingExportRepository) GetAccountingExports(arg0 context.Context, arg1 int) ([]*entity.AccountingExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountingExports", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.AccountingExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountingExports indicates an expected call of GetAccountingExports.
+func (mr *MockAccountingExportRepositoryMockRecorder) GetAccountingExports(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountingExports", reflect.TypeOf((*MockAccountingExportRepository)(nil).GetAccountingExports), arg0, arg1)
+}
+
+// GetGLAccountMappings mocks base method.
+func (m *MockAccountingExportRepository) GetGLAccountMappings(arg0 context.Context) ([]*entity.GLAccountMapping, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGLAccountMappings", arg0)
+	ret0, _ := ret[0].([]*entity.GLAccountMapping)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGLAccountMappings indicates an expected call of GetGLAccountMappings.
+func (mr *MockAccountingExportRepositoryMockRecorder) GetGLAccountMappings(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGLAccountMappings", reflect.TypeOf((*MockAccountingExportRepository)(nil).GetGLAccountMappings), arg0)
+}
+
+// UpsertGLAccountMapping mocks base method.
+func (m *MockAccountingExportRepository) UpsertGLAccountMapping(arg0 context.Context, arg1 *entity.GLAccountMapping) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertGLAccountMapping", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertGLAccountMapping indicates an expected call of UpsertGLAccountMapping.
+func (mr *MockAccountingExportRepositoryMockRecorder) UpsertGLAccountMapping(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertGLAccountMapping", reflect.TypeOf((*MockAccountingExportRepository)(nil).UpsertGLAccountMapping), arg0, arg1)
+}
+
+// MockBudgetRepository is a mock of BudgetRepository interface.
+type MockBudgetRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBudgetRepositoryMockRecorder
+}
+
+// MockBudgetRepositoryMockRecorder is the mock recorder for MockBudgetRepository.
+type MockBudgetRepositoryMockRecorder struct {
+	mock *MockBudgetRepository
+}
+
+// NewMockBudgetRepository creates a new mock instance.
+func NewMockBudgetRepository(ctrl *gomock.Controller) *MockBudgetRepository {
+	mock := &MockBudgetRepository{ctrl: ctrl}
+	mock.recorder = &MockBudgetRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBudgetRepository) EXPECT() *MockBudgetRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetBudgets mocks base method.
+func (m *MockBudgetRepository) GetBudgets(arg0 context.Context, arg1 *entity.BudgetQuery) ([]*entity.Budget, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBudgets", arg0, arg1)
+	ret0, _ := ret[0].([]*entity.Budget)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBudgets indicates an expected call of GetBudgets.
+func (mr *MockBudgetRepositoryMockRecorder) GetBudgets(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBudgets", reflect.TypeOf((*MockBudgetRepository)(nil).GetBudgets), arg0, arg1)
+}
+
+// UpsertBudget mocks base method.
+func (m *MockBudgetRepository) UpsertBudget(arg0 context.Context, arg1 *entity.Budget) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertBudget", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertBudget indicates an expected call of UpsertBudget.
+func (mr *MockBudgetRepositoryMockRecorder) UpsertBudget(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertBudget", reflect.TypeOf((*MockBudgetRepository)(nil).UpsertBudget), arg0, arg1)
+}
+
+// MockSpendingLimitRepository is a mock of SpendingLimitRepository interface.
+type MockSpendingLimitRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSpendingLimitRepositoryMockRecorder
+}
+
+// MockSpendingLimitRepositoryMockRecorder is the mock recorder for MockSpendingLimitRepository.
+type MockSpendingLimitRepositoryMockRecorder struct {
+	mock *MockSpendingLimitRepository
+}
+
+// NewMockSpendingLimitRepository creates a new mock instance.
+func NewMockSpendingLimitRepository(ctrl *gomock.Controller) *MockSpendingLimitRepository {
+	mock := &MockSpendingLimitRepository{ctrl: ctrl}
+	mock.recorder = &MockSpendingLimitRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSpendingLimitRepository) EXPECT() *MockSpendingLimitRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetSpendingLimit mocks base method.
+func (m *MockSpendingLimitRepository) GetSpendingLimit(arg0 context.Context, arg1 int64, arg2 int32, arg3 time.Time) (*entity.SpendingLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpendingLimit", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*entity.SpendingLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpendingLimit indicates an expected call of GetSpendingLimit.
+func (mr *MockSpendingLimitRepositoryMockRecorder) GetSpendingLimit(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpendingLimit", reflect.TypeOf((*MockSpendingLimitRepository)(nil).GetSpendingLimit), arg0, arg1, arg2, arg3)
+}
+
+// GetSpendingLimits mocks base method.
+func (m *MockSpendingLimitRepository) GetSpendingLimits(arg0 context.Context) ([]*entity.SpendingLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpendingLimits", arg0)
+	ret0, _ := ret[0].([]*entity.SpendingLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpendingLimits indicates an expected call of GetSpendingLimits.
+func (mr *MockSpendingLimitRepositoryMockRecorder) GetSpendingLimits(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpendingLimits", reflect.TypeOf((*MockSpendingLimitRepository)(nil).GetSpendingLimits), arg0)
+}
+
+// UpsertSpendingLimit mocks base method.
+func (m *MockSpendingLimitRepository) UpsertSpendingLimit(arg0 context.Context, arg1 *entity.SpendingLimit) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertSpendingLimit", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertSpendingLimit indicates an expected call of UpsertSpendingLimit.
+func (mr *MockSpendingLimitRepositoryMockRecorder) UpsertSpendingLimit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertSpendingLimit", reflect.TypeOf((*MockSpendingLimitRepository)(nil).UpsertSpendingLimit), arg0, arg1)
+}
+
+// MockOutboxRepository is a mock of OutboxRepository interface.
+type MockOutboxRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepositoryMockRecorder
+}
+
+// MockOutboxRepositoryMockRecorder is the mock recorder for MockOutboxRepository.
+type MockOutboxRepositoryMockRecorder struct {
+	mock *MockOutboxRepository
+}
+
+// NewMockOutboxRepository creates a new mock instance.
+func NewMockOutboxRepository(ctrl *gomock.Controller) *MockOutboxRepository {
+	mock := &MockOutboxRepository{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepository) EXPECT() *MockOutboxRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimOutboxMessages mocks base method.
+func (m *MockOutboxRepository) ClaimOutboxMessages(arg0 context.Context, arg1 int, arg2 time.Duration) ([]*entity.OutboxMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*entity.OutboxMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutboxMessages indicates an expected call of ClaimOutboxMessages.
+func (mr *MockOutboxRepositoryMockRecorder) ClaimOutboxMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxMessages", reflect.TypeOf((*MockOutboxRepository)(nil).ClaimOutboxMessages), arg0, arg1, arg2)
+}
+
+// GetOutboxStats mocks base method.
+func (m *MockOutboxRepository) GetOutboxStats(arg0 context.Context) (*entity.OutboxStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxStats", arg0)
+	ret0, _ := ret[0].(*entity.OutboxStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxStats indicates an expected call of GetOutboxStats.
+func (mr *MockOutboxRepositoryMockRecorder) GetOutboxStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxStats", reflect.TypeOf((*MockOutboxRepository)(nil).GetOutboxStats), arg0)
+}
+
+// MarkOutboxPublished mocks base method.
+func (m *MockOutboxRepository) MarkOutboxPublished(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxPublished", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxPublished indicates an expected call of MarkOutboxPublished.
+func (mr *MockOutboxRepositoryMockRecorder) MarkOutboxPublished(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxPublished", reflect.TypeOf((*MockOutboxRepository)(nil).MarkOutboxPublished), arg0, arg1)
+}
+
+// UpdateOutboxAttempt mocks base method.
+func (m *MockOutboxRepository) UpdateOutboxAttempt(arg0 context.Context, arg1 *entity.OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutboxAttempt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutboxAttempt indicates an expected call of UpdateOutboxAttempt.
+func (mr *MockOutboxRepositoryMockRecorder) UpdateOutboxAttempt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutboxAttempt", reflect.TypeOf((*MockOutboxRepository)(nil).UpdateOutboxAttempt), arg0, arg1)
+}
+
+// MockMessageBroker is a mock of MessageBroker interface.
+type MockMessageBroker struct {
+	ctrl     *gomock.Controller
+	recorder *MockMessageBrokerMockRecorder
+}
+
+// MockMessageBrokerMockRecorder is the mock recorder for MockMessageBroker.
+type MockMessageBrokerMockRecorder struct {
+	mock *MockMessageBroker
+}
+
+// NewMockMessageBroker creates a new mock instance.
+func NewMockMessageBroker(ctrl *gomock.Controller) *MockMessageBroker {
+	mock := &MockMessageBroker{ctrl: ctrl}
+	mock.recorder = &MockMessageBrokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMessageBroker) EXPECT() *MockMessageBrokerMockRecorder {
+	return m.recorder
+}
+
+// DiscardDeadLetter mocks base method.
+func (m *MockMessageBroker) DiscardDeadLetter(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiscardDeadLetter", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DiscardDeadLetter indicates an expected call of DiscardDeadLetter.
+func (mr *MockMessageBrokerMockRecorder) DiscardDeadLetter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscardDeadLetter", reflect.TypeOf((*MockMessageBroker)(nil).DiscardDeadLetter), arg0)
+}
+
+// GetConnectionState mocks base method.
+func (m *MockMessageBroker) GetConnectionState() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnectionState")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetConnectionState indicates an expected call of GetConnectionState.
+func (mr *MockMessageBrokerMockRecorder) GetConnectionState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnectionState", reflect.TypeOf((*MockMessageBroker)(nil).GetConnectionState))
+}
+
+// GetDeadLetters mocks base method.
+func (m *MockMessageBroker) GetDeadLetters(arg0 int) ([]*entity.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeadLetters", arg0)
+	ret0, _ := ret[0].([]*entity.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeadLetters indicates an expected call of GetDeadLetters.
+func (mr *MockMessageBrokerMockRecorder) GetDeadLetters(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeadLetters", reflect.TypeOf((*MockMessageBroker)(nil).GetDeadLetters), arg0)
+}
+
+// PublishMessage mocks base method.
+func (m *MockMessageBroker) PublishMessage(arg0 *entity.OutboxMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishMessage", arg0)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetClient indicates an expected call of GetClient.
-func (mr *MockRabbitMQClientMockRecorder) GetClient() *gomock.Call {
+// PublishMessage indicates an expected call of PublishMessage.
+func (mr *MockMessageBrokerMockRecorder) PublishMessage(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockRabbitMQClient)(nil).GetClient))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishMessage", reflect.TypeOf((*MockMessageBroker)(nil).PublishMessage), arg0)
 }
 
-// PublishPayment mocks base method.
-func (m *MockRabbitMQClient) PublishPayment(arg0 *entity.PublishPaymentRequest) error {
+// ReplayDeadLetter mocks base method.
+func (m *MockMessageBroker) ReplayDeadLetter(arg0 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PublishPayment", arg0)
+	ret := m.ctrl.Call(m, "ReplayDeadLetter", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// PublishPayment indicates an expected call of PublishPayment.
-func (mr *MockRabbitMQClientMockRecorder) PublishPayment(arg0 interface{}) *gomock.Call {
+// ReplayDeadLetter indicates an expected call of ReplayDeadLetter.
+func (mr *MockMessageBrokerMockRecorder) ReplayDeadLetter(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishPayment", reflect.TypeOf((*MockRabbitMQClient)(nil).PublishPayment), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayDeadLetter", reflect.TypeOf((*MockMessageBroker)(nil).ReplayDeadLetter), arg0)
 }
@@ -0,0 +1,175 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+const ProviderBankTransfer = "bank_transfer"
+
+type bankTransferProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+type bankTransferRequest struct {
+	Reference   string                  `json:"reference"`
+	Amount      float64                 `json:"amount"`
+	Currency    string                  `json:"currency"`
+	Beneficiary bankTransferBeneficiary `json:"beneficiary"`
+}
+
+type bankTransferBeneficiary struct {
+	BankCode      string `json:"bank_code"`
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+}
+
+type bankTransferReversalRequest struct {
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Reason    string  `json:"reason"`
+}
+
+type bankTransferResponse struct {
+	TransferID string `json:"transfer_id"`
+	Reference  string `json:"reference"`
+	State      string `json:"state"`
+}
+
+type bankTransferReversalResponse struct {
+	ReversalID string `json:"reversal_id"`
+	Reference  string `json:"reference"`
+	State      string `json:"state"`
+}
+
+type bankStatementResponse struct {
+	Entries []bankStatementEntry `json:"entries"`
+}
+
+type bankStatementEntry struct {
+	ID        string     `json:"id"`
+	Reference string     `json:"reference"`
+	Amount    float64    `json:"amount"`
+	State     string     `json:"state"`
+	BookedAt  *time.Time `json:"booked_at"`
+}
+
+// NewBankTransferProvider pays out to bank accounts through POST
+// {baseURL}/v1/transfers. It has no amount limit.
+func NewBankTransferProvider(baseURL string) *bankTransferProvider {
+	return &bankTransferProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: newHTTPClient(),
+	}
+}
+
+func (p *bankTransferProvider) Name() string {
+	return ProviderBankTransfer
+}
+
+func (p *bankTransferProvider) Supports(method string, amountIDR float64) bool {
+	return method == util.PAYOUT_METHOD_BANK_TRANSFER
+}
+
+func (p *bankTransferProvider) ProcessPayment(ctx context.Context, payment *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+	destination := payment.Destination
+	if destination == nil || destination.AccountNumber == "" {
+		return nil, fmt.Errorf("%w for %s", ErrNoDestination, util.PAYOUT_METHOD_BANK_TRANSFER)
+	}
+
+	raw, err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/transfers", map[string]string{
+		"Idempotency-Key": payment.IdempotencyKey,
+	}, &bankTransferRequest{
+		Reference: payment.ExternalID,
+		Amount:    float64(payment.AmountIDR),
+		Currency:  "IDR",
+		Beneficiary: bankTransferBeneficiary{
+			BankCode:      destination.BankCode,
+			AccountNumber: destination.AccountNumber,
+			AccountName:   destination.AccountHolderName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var transfer bankTransferResponse
+	err = json.Unmarshal(raw, &transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &entity.PaymentProcessorResponse{Message: "Transfer " + strings.ToLower(transfer.State), Raw: raw}
+	response.Data.ID = transfer.TransferID
+	response.Data.ExternalID = transfer.Reference
+	response.Data.Status = transfer.State
+	return response, nil
+}
+
+// RefundPayment asks the bank to reverse a transfer through POST
+// {baseURL}/v1/transfers/{transfer_id}/reversals.
+func (p *bankTransferProvider) RefundPayment(ctx context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	raw, err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/transfers/"+url.PathEscape(refund.ProviderPaymentID)+"/reversals", map[string]string{
+		"Idempotency-Key": refund.IdempotencyKey,
+	}, &bankTransferReversalRequest{
+		Reference: refund.ExternalID,
+		Amount:    float64(refund.AmountIDR),
+		Currency:  "IDR",
+		Reason:    refund.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reversal bankTransferReversalResponse
+	err = json.Unmarshal(raw, &reversal)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &entity.PaymentProcessorResponse{Message: "Reversal " + strings.ToLower(reversal.State), Raw: raw}
+	response.Data.ID = reversal.ReversalID
+	response.Data.ExternalID = reversal.Reference
+	response.Data.Status = reversal.State
+	return response, nil
+}
+
+// ListPayments reads the account statement through GET
+// {baseURL}/v1/statements. Reversals are booked as negative entries.
+func (p *bankTransferProvider) ListPayments(ctx context.Context, from, to time.Time) ([]*entity.SettlementRecord, error) {
+	raw, err := getJSON(ctx, p.httpClient, p.baseURL+"/v1/statements", url.Values{
+		"from": {from.Format(time.RFC3339)},
+		"to":   {to.Format(time.RFC3339)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var statement bankStatementResponse
+	err = json.Unmarshal(raw, &statement)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*entity.SettlementRecord, 0, len(statement.Entries))
+	for _, entry := range statement.Entries {
+		records = append(records, &entity.SettlementRecord{
+			ExternalID:        entry.Reference,
+			ProviderPaymentID: entry.ID,
+			AmountIDR:         entry.Amount,
+			Status:            entry.State,
+			SettledAt:         entry.BookedAt,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,187 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBankTransferProvider_ProcessPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		want        *entity.PaymentProcessorResponse
+		wantErr     bool
+		unavailable bool
+	}{
+		{
+			name:   "success - transfer accepted",
+			status: http.StatusAccepted,
+			body:   `{"transfer_id":"TRF-1","reference":"ext-1","state":"PENDING"}`,
+			want: func() *entity.PaymentProcessorResponse {
+				response := &entity.PaymentProcessorResponse{Message: "Transfer pending"}
+				response.Data.ID = "TRF-1"
+				response.Data.ExternalID = "ext-1"
+				response.Data.Status = "PENDING"
+				return response
+			}(),
+		},
+		{
+			name:    "failure - transfer rejected",
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"error":"invalid account"}`,
+			wantErr: true,
+		},
+		{
+			name:        "failure - provider under maintenance",
+			status:      http.StatusServiceUnavailable,
+			wantErr:     true,
+			unavailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/v1/transfers", r.URL.Path)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, "ext-1", r.Header.Get("Idempotency-Key"))
+
+				var body map[string]interface{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]interface{}{
+					"reference": "ext-1",
+					"amount":    float64(150000),
+					"currency":  "IDR",
+					"beneficiary": map[string]interface{}{
+						"bank_code":      "BCA",
+						"account_number": "1234567890",
+						"account_name":   "John Doe",
+					},
+				}, body)
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewBankTransferProvider(server.URL + "/")
+			got, err := provider.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{
+				AmountIDR:      150000,
+				ExternalID:     "ext-1",
+				IdempotencyKey: "ext-1",
+				Destination: &entity.PayoutDestination{
+					Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+					BankCode:          "BCA",
+					AccountNumber:     "1234567890",
+					AccountHolderName: "John Doe",
+				},
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.unavailable, errors.Is(err, ErrProviderUnavailable))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(tt.body), got.Raw)
+			got.Raw = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBankTransferProvider_NoDestination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("provider must not be called without a bank account")
+	}))
+	defer server.Close()
+
+	provider := NewBankTransferProvider(server.URL)
+	_, err := provider.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{AmountIDR: 150000, ExternalID: "ext-1"})
+
+	assert.ErrorIs(t, err, ErrNoDestination)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+}
+
+func TestBankTransferProvider_RefundPayment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/transfers/TRF-1/reversals", r.URL.Path)
+		assert.Equal(t, "reversal-ext-1", r.Header.Get("Idempotency-Key"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{
+			"reference": "reversal-ext-1",
+			"amount":    float64(150000),
+			"currency":  "IDR",
+			"reason":    "duplicate claim",
+		}, body)
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"reversal_id":"REV-1","reference":"reversal-ext-1","state":"COMPLETED"}`))
+	}))
+	defer server.Close()
+
+	provider := NewBankTransferProvider(server.URL)
+	got, err := provider.RefundPayment(context.Background(), &entity.RefundRequest{
+		AmountIDR:         150000,
+		ExternalID:        "reversal-ext-1",
+		IdempotencyKey:    "reversal-ext-1",
+		PaymentExternalID: "ext-1",
+		ProviderPaymentID: "TRF-1",
+		Reason:            "duplicate claim",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "REV-1", got.Data.ID)
+	assert.Equal(t, "reversal-ext-1", got.Data.ExternalID)
+	assert.Equal(t, "COMPLETED", got.Data.Status)
+	assert.Equal(t, "Reversal completed", got.Message)
+}
+
+func TestBankTransferProvider_ListPayments(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/statements", r.URL.Path)
+		assert.Equal(t, "2024-03-01T00:00:00Z", r.URL.Query().Get("from"))
+		assert.Equal(t, "2024-03-02T00:00:00Z", r.URL.Query().Get("to"))
+
+		w.Write([]byte(`{"entries":[
+			{"id":"TRF-1","reference":"ext-1","amount":150000,"state":"COMPLETED","booked_at":"2024-03-01T09:30:00Z"},
+			{"id":"REV-1","reference":"reversal-ext-1","amount":-150000,"state":"COMPLETED","booked_at":"2024-03-01T11:00:00Z"}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider := NewBankTransferProvider(server.URL)
+	got, err := provider.ListPayments(context.Background(), from, from.AddDate(0, 0, 1))
+
+	assert.NoError(t, err)
+	bookedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	reversedAt := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)
+	assert.Equal(t, []*entity.SettlementRecord{
+		{ExternalID: "ext-1", ProviderPaymentID: "TRF-1", AmountIDR: 150000, Status: "COMPLETED", SettledAt: &bookedAt},
+		{ExternalID: "reversal-ext-1", ProviderPaymentID: "REV-1", AmountIDR: -150000, Status: "COMPLETED", SettledAt: &reversedAt},
+	}, got)
+}
+
+func TestBankTransferProvider_Supports(t *testing.T) {
+	provider := NewBankTransferProvider("http://localhost")
+
+	assert.True(t, provider.Supports(util.PAYOUT_METHOD_BANK_TRANSFER, 50000000))
+	assert.False(t, provider.Supports(util.PAYOUT_METHOD_EWALLET, 50000))
+}
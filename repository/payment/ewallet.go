@@ -0,0 +1,200 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+const ProviderEWallet = "ewallet"
+
+type eWalletProvider struct {
+	baseURL      string
+	maxAmountIDR float64
+	httpClient   *http.Client
+}
+
+type eWalletAmount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+type eWalletRequest struct {
+	PartnerReferenceNo string        `json:"partner_reference_no"`
+	CustomerNumber     string        `json:"customer_number"`
+	Amount             eWalletAmount `json:"amount"`
+}
+
+type eWalletRefundRequest struct {
+	PartnerRefundNo string        `json:"partner_refund_no"`
+	Amount          eWalletAmount `json:"amount"`
+	Reason          string        `json:"reason"`
+}
+
+type eWalletRefundResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	RefundID        string `json:"refund_id"`
+	PartnerRefundNo string `json:"partner_refund_no"`
+	Status          string `json:"status"`
+}
+
+type eWalletResponse struct {
+	ResponseCode       string `json:"response_code"`
+	ResponseMessage    string `json:"response_message"`
+	DisbursementID     string `json:"disbursement_id"`
+	PartnerReferenceNo string `json:"partner_reference_no"`
+	Status             string `json:"status"`
+}
+
+type eWalletDisbursementListResponse struct {
+	ResponseCode    string                `json:"response_code"`
+	ResponseMessage string                `json:"response_message"`
+	Disbursements   []eWalletDisbursement `json:"disbursements"`
+}
+
+type eWalletDisbursement struct {
+	DisbursementID     string        `json:"disbursement_id"`
+	PartnerReferenceNo string        `json:"partner_reference_no"`
+	Amount             eWalletAmount `json:"amount"`
+	Status             string        `json:"status"`
+	CompletedAt        *time.Time    `json:"completed_at"`
+}
+
+// NewEWalletProvider pays out to e-wallets through POST
+// {baseURL}/v1/disbursements. E-wallets cap the balance they can receive, so
+// payouts above maxAmountIDR are routed elsewhere.
+func NewEWalletProvider(baseURL string, maxAmountIDR float64) *eWalletProvider {
+	return &eWalletProvider{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		maxAmountIDR: maxAmountIDR,
+		httpClient:   newHTTPClient(),
+	}
+}
+
+func (p *eWalletProvider) Name() string {
+	return ProviderEWallet
+}
+
+func (p *eWalletProvider) Supports(method string, amountIDR float64) bool {
+	return method == util.PAYOUT_METHOD_EWALLET && (p.maxAmountIDR <= 0 || amountIDR <= p.maxAmountIDR)
+}
+
+func (p *eWalletProvider) ProcessPayment(ctx context.Context, payment *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+	if payment.Destination == nil || payment.Destination.EWalletID == "" {
+		return nil, fmt.Errorf("%w for %s", ErrNoDestination, util.PAYOUT_METHOD_EWALLET)
+	}
+
+	raw, err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/disbursements", map[string]string{
+		"X-Idempotency-Key": payment.IdempotencyKey,
+	}, &eWalletRequest{
+		PartnerReferenceNo: payment.ExternalID,
+		CustomerNumber:     payment.Destination.EWalletID,
+		Amount: eWalletAmount{
+			Value:    fmt.Sprintf("%d.00", payment.AmountIDR),
+			Currency: "IDR",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var disbursement eWalletResponse
+	err = json.Unmarshal(raw, &disbursement)
+	if err != nil {
+		return nil, err
+	}
+
+	// The provider answers 200 with a non-success response code when it
+	// declines the disbursement.
+	if !strings.HasPrefix(disbursement.ResponseCode, "200") && !strings.HasPrefix(disbursement.ResponseCode, "202") {
+		return nil, fmt.Errorf("%w: %s %s", ErrPaymentDeclined, disbursement.ResponseCode, disbursement.ResponseMessage)
+	}
+
+	response := &entity.PaymentProcessorResponse{Message: disbursement.ResponseMessage, Raw: raw}
+	response.Data.ID = disbursement.DisbursementID
+	response.Data.ExternalID = disbursement.PartnerReferenceNo
+	response.Data.Status = disbursement.Status
+	return response, nil
+}
+
+// RefundPayment pulls a disbursement back from the wallet through POST
+// {baseURL}/v1/disbursements/{disbursement_id}/refunds.
+func (p *eWalletProvider) RefundPayment(ctx context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	raw, err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/disbursements/"+url.PathEscape(refund.ProviderPaymentID)+"/refunds", map[string]string{
+		"X-Idempotency-Key": refund.IdempotencyKey,
+	}, &eWalletRefundRequest{
+		PartnerRefundNo: refund.ExternalID,
+		Amount: eWalletAmount{
+			Value:    fmt.Sprintf("%d.00", refund.AmountIDR),
+			Currency: "IDR",
+		},
+		Reason: refund.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refundResponse eWalletRefundResponse
+	err = json.Unmarshal(raw, &refundResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(refundResponse.ResponseCode, "200") && !strings.HasPrefix(refundResponse.ResponseCode, "202") {
+		return nil, fmt.Errorf("%w: %s %s", ErrPaymentDeclined, refundResponse.ResponseCode, refundResponse.ResponseMessage)
+	}
+
+	response := &entity.PaymentProcessorResponse{Message: refundResponse.ResponseMessage, Raw: raw}
+	response.Data.ID = refundResponse.RefundID
+	response.Data.ExternalID = refundResponse.PartnerRefundNo
+	response.Data.Status = refundResponse.Status
+	return response, nil
+}
+
+// ListPayments reads the disbursements completed in the period through GET
+// {baseURL}/v1/disbursements. Refunds are listed with a negative amount.
+func (p *eWalletProvider) ListPayments(ctx context.Context, from, to time.Time) ([]*entity.SettlementRecord, error) {
+	raw, err := getJSON(ctx, p.httpClient, p.baseURL+"/v1/disbursements", url.Values{
+		"start_time": {from.Format(time.RFC3339)},
+		"end_time":   {to.Format(time.RFC3339)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list eWalletDisbursementListResponse
+	err = json.Unmarshal(raw, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(list.ResponseCode, "200") {
+		return nil, fmt.Errorf("failed to list disbursements: %s %s", list.ResponseCode, list.ResponseMessage)
+	}
+
+	records := make([]*entity.SettlementRecord, 0, len(list.Disbursements))
+	for _, disbursement := range list.Disbursements {
+		amount, err := strconv.ParseFloat(disbursement.Amount.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q for %s", disbursement.Amount.Value, disbursement.PartnerReferenceNo)
+		}
+
+		records = append(records, &entity.SettlementRecord{
+			ExternalID:        disbursement.PartnerReferenceNo,
+			ProviderPaymentID: disbursement.DisbursementID,
+			AmountIDR:         amount,
+			Status:            disbursement.Status,
+			SettledAt:         disbursement.CompletedAt,
+		})
+	}
+	return records, nil
+}
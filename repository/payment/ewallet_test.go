@@ -0,0 +1,213 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEWalletProvider_ProcessPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		want        *entity.PaymentProcessorResponse
+		wantErr     bool
+		unavailable bool
+	}{
+		{
+			name:   "success - disbursement completed",
+			status: http.StatusOK,
+			body:   `{"response_code":"2000000","response_message":"Successful","disbursement_id":"DSB-1","partner_reference_no":"ext-1","status":"SUCCESS"}`,
+			want: func() *entity.PaymentProcessorResponse {
+				response := &entity.PaymentProcessorResponse{Message: "Successful"}
+				response.Data.ID = "DSB-1"
+				response.Data.ExternalID = "ext-1"
+				response.Data.Status = "SUCCESS"
+				return response
+			}(),
+		},
+		{
+			name:    "failure - declined with a non-success response code",
+			status:  http.StatusOK,
+			body:    `{"response_code":"4030001","response_message":"Account not found","partner_reference_no":"ext-1","status":"FAILED"}`,
+			wantErr: true,
+		},
+		{
+			name:        "failure - rate limited",
+			status:      http.StatusTooManyRequests,
+			wantErr:     true,
+			unavailable: true,
+		},
+		{
+			name:    "failure - internal error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/v1/disbursements", r.URL.Path)
+				assert.Equal(t, "ext-1", r.Header.Get("X-Idempotency-Key"))
+
+				var body map[string]interface{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]interface{}{
+					"partner_reference_no": "ext-1",
+					"customer_number":      "081234567890",
+					"amount":               map[string]interface{}{"value": "75000.00", "currency": "IDR"},
+				}, body)
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewEWalletProvider(server.URL, 10000000)
+			got, err := provider.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{
+				AmountIDR:      75000,
+				ExternalID:     "ext-1",
+				IdempotencyKey: "ext-1",
+				Destination:    &entity.PayoutDestination{Method: util.PAYOUT_METHOD_EWALLET, EWalletID: "081234567890"},
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.unavailable, errors.Is(err, ErrProviderUnavailable))
+				return
+			}
+
+			assert.NoError(t, err)
+			got.Raw = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEWalletProvider_RefundPayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{
+			name: "success - refund accepted",
+			body: `{"response_code":"2020000","response_message":"Request in progress","refund_id":"RFD-1","partner_refund_no":"reversal-ext-1","status":"PENDING"}`,
+		},
+		{
+			name:    "failure - wallet balance already spent",
+			body:    `{"response_code":"4030014","response_message":"Insufficient balance","partner_refund_no":"reversal-ext-1","status":"FAILED"}`,
+			wantErr: ErrPaymentDeclined,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/disbursements/DSB-1/refunds", r.URL.Path)
+				assert.Equal(t, "reversal-ext-1", r.Header.Get("X-Idempotency-Key"))
+
+				var body map[string]interface{}
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]interface{}{
+					"partner_refund_no": "reversal-ext-1",
+					"amount":            map[string]interface{}{"value": "75000.00", "currency": "IDR"},
+					"reason":            "wrong amount",
+				}, body)
+
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewEWalletProvider(server.URL, 10000000)
+			got, err := provider.RefundPayment(context.Background(), &entity.RefundRequest{
+				AmountIDR:         75000,
+				ExternalID:        "reversal-ext-1",
+				IdempotencyKey:    "reversal-ext-1",
+				ProviderPaymentID: "DSB-1",
+				Reason:            "wrong amount",
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "RFD-1", got.Data.ID)
+			assert.Equal(t, "PENDING", got.Data.Status)
+		})
+	}
+}
+
+func TestEWalletProvider_ListPayments(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []*entity.SettlementRecord
+		wantErr string
+	}{
+		{
+			name: "success - disbursement and refund",
+			body: `{"response_code":"2000000","response_message":"Successful","disbursements":[
+				{"disbursement_id":"DSB-1","partner_reference_no":"ext-1","amount":{"value":"75000.00","currency":"IDR"},"status":"SUCCESS"},
+				{"disbursement_id":"RFD-1","partner_reference_no":"reversal-ext-1","amount":{"value":"-75000.00","currency":"IDR"},"status":"SUCCESS"}
+			]}`,
+			want: []*entity.SettlementRecord{
+				{ExternalID: "ext-1", ProviderPaymentID: "DSB-1", AmountIDR: 75000, Status: "SUCCESS"},
+				{ExternalID: "reversal-ext-1", ProviderPaymentID: "RFD-1", AmountIDR: -75000, Status: "SUCCESS"},
+			},
+		},
+		{
+			name:    "failure - request rejected",
+			body:    `{"response_code":"4010000","response_message":"Unauthorized"}`,
+			wantErr: "failed to list disbursements: 4010000 Unauthorized",
+		},
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/v1/disbursements", r.URL.Path)
+				assert.Equal(t, "2024-03-01T00:00:00Z", r.URL.Query().Get("start_time"))
+				assert.Equal(t, "2024-03-02T00:00:00Z", r.URL.Query().Get("end_time"))
+
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewEWalletProvider(server.URL, 10000000)
+			got, err := provider.ListPayments(context.Background(), from, from.AddDate(0, 0, 1))
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEWalletProvider_Supports(t *testing.T) {
+	provider := NewEWalletProvider("http://localhost", 10000000)
+
+	assert.True(t, provider.Supports(util.PAYOUT_METHOD_EWALLET, 10000000))
+	assert.False(t, provider.Supports(util.PAYOUT_METHOD_EWALLET, 10000001))
+	assert.False(t, provider.Supports(util.PAYOUT_METHOD_BANK_TRANSFER, 50000))
+}
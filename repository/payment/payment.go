@@ -1,23 +1,36 @@
 package payment
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/budsx/expenses-management/entity"
 )
 
+// ProviderDefault is the payment processor at PAYMENT_PROCESSOR_URL. It pays
+// out with every method and is the last resort when routing.
+const ProviderDefault = "default"
+
 type paymentProcessor struct {
 	paymentProcessorURL string
 	httpClient          *http.Client
 }
 
+type paymentListResponse struct {
+	Data []struct {
+		ID          string     `json:"id"`
+		ExternalID  string     `json:"external_id"`
+		AmountIDR   float64    `json:"amount_idr"`
+		Status      string     `json:"status"`
+		CompletedAt *time.Time `json:"completed_at"`
+	} `json:"data"`
+}
+
 var (
 	once   sync.Once
 	client *paymentProcessor
@@ -27,16 +40,7 @@ func NewPaymentProcessor(paymentProcessorURL string) *paymentProcessor {
 	once.Do(func() {
 		client = &paymentProcessor{
 			paymentProcessorURL: paymentProcessorURL,
-			httpClient: &http.Client{
-				Timeout: time.Second * 30,
-				Transport: &http.Transport{
-					Dial: (&net.Dialer{
-						Timeout:   time.Second * 30,
-						KeepAlive: time.Second * 30,
-					}).Dial,
-					TLSHandshakeTimeout: time.Second * 10,
-				},
-			},
+			httpClient:          newHTTPClient(),
 		}
 	})
 	return client
@@ -46,34 +50,83 @@ func (p *paymentProcessor) GetClient() *http.Client {
 	return p.httpClient
 }
 
+func (p *paymentProcessor) Name() string {
+	return ProviderDefault
+}
+
+func (p *paymentProcessor) Supports(method string, amountIDR float64) bool {
+	return true
+}
+
 func (p *paymentProcessor) ProcessPayment(ctx context.Context, payment *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
-	client := p.GetClient()
-	requestBody, err := json.Marshal(payment)
-	if err != nil {
-		return nil, err
+	headers := map[string]string{}
+	if payment.IdempotencyKey != "" {
+		headers["Idempotency-Key"] = payment.IdempotencyKey
 	}
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.paymentProcessorURL, bytes.NewBuffer(requestBody))
+	raw, err := postJSON(ctx, p.GetClient(), p.paymentProcessorURL, headers, payment)
 	if err != nil {
 		return nil, err
 	}
 
-	request.Header.Set("Content-Type", "application/json")
-	response, err := client.Do(request)
+	var responseBody *entity.PaymentProcessorResponse
+	err = json.Unmarshal(raw, &responseBody)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
+	responseBody.Raw = raw
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to process payment: %s", response.Status)
+	return responseBody, nil
+}
+
+// RefundPayment posts the refund to {PAYMENT_PROCESSOR_URL}/refunds.
+func (p *paymentProcessor) RefundPayment(ctx context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	headers := map[string]string{}
+	if refund.IdempotencyKey != "" {
+		headers["Idempotency-Key"] = refund.IdempotencyKey
+	}
+
+	raw, err := postJSON(ctx, p.GetClient(), strings.TrimRight(p.paymentProcessorURL, "/")+"/refunds", headers, refund)
+	if err != nil {
+		return nil, err
 	}
 
 	var responseBody *entity.PaymentProcessorResponse
-	err = json.NewDecoder(response.Body).Decode(&responseBody)
+	err = json.Unmarshal(raw, &responseBody)
 	if err != nil {
 		return nil, err
 	}
+	responseBody.Raw = raw
 
 	return responseBody, nil
 }
+
+// ListPayments reads the payments and refunds completed in the period through
+// GET {PAYMENT_PROCESSOR_URL}.
+func (p *paymentProcessor) ListPayments(ctx context.Context, from, to time.Time) ([]*entity.SettlementRecord, error) {
+	raw, err := getJSON(ctx, p.GetClient(), p.paymentProcessorURL, url.Values{
+		"from": {from.Format(time.RFC3339)},
+		"to":   {to.Format(time.RFC3339)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list paymentListResponse
+	err = json.Unmarshal(raw, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*entity.SettlementRecord, 0, len(list.Data))
+	for _, payment := range list.Data {
+		records = append(records, &entity.SettlementRecord{
+			ExternalID:        payment.ExternalID,
+			ProviderPaymentID: payment.ID,
+			AmountIDR:         payment.AmountIDR,
+			Status:            payment.Status,
+			SettledAt:         payment.CompletedAt,
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentProcessor_ProcessPayment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/payments", r.URL.Path)
+		assert.Equal(t, "ext-1", r.Header.Get("Idempotency-Key"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"amount_idr": float64(150000), "external_id": "ext-1"}, body)
+
+		w.Write([]byte(`{"data":{"id":"PAY-1","external_id":"ext-1","status":"PENDING"},"message":"Payment accepted"}`))
+	}))
+	defer server.Close()
+
+	// NewPaymentProcessor returns a process-wide instance, so this is the only
+	// test that creates one.
+	processor := NewPaymentProcessor(server.URL + "/v1/payments")
+	got, err := processor.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{
+		AmountIDR:      150000,
+		ExternalID:     "ext-1",
+		IdempotencyKey: "ext-1",
+		PayoutMethod:   "ewallet",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PAY-1", got.Data.ID)
+	assert.Equal(t, "ext-1", got.Data.ExternalID)
+	assert.Equal(t, "PENDING", got.Data.Status)
+	assert.Equal(t, "Payment accepted", got.Message)
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+)
+
+var (
+	// ErrProviderUnavailable means the provider did not accept the payout, so
+	// it is safe to send it to another provider.
+	ErrProviderUnavailable = errors.New("payment provider unavailable")
+	// ErrPaymentDeclined means the provider answered and refused the payout.
+	ErrPaymentDeclined = errors.New("payment declined by provider")
+	ErrNoProvider      = errors.New("no payment provider available")
+	// ErrNoDestination is returned without calling the provider when the
+	// employee has no destination for the payout method.
+	ErrNoDestination = fmt.Errorf("%w: no payout destination", ErrPaymentDeclined)
+)
+
+// Provider is an adapter for one payment provider. It translates the payout
+// to the provider's API and its response back to a PaymentProcessorResponse.
+type Provider interface {
+	Name() string
+	// Supports reports whether the provider can pay amountIDR out with method.
+	Supports(method string, amountIDR float64) bool
+	ProcessPayment(context.Context, *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error)
+	// RefundPayment reverses a payout the provider made earlier.
+	RefundPayment(context.Context, *entity.RefundRequest) (*entity.PaymentProcessorResponse, error)
+	// ListPayments returns the provider's settlement statement for payouts
+	// settled from from up to to. Refunds are listed with a negative amount.
+	ListPayments(ctx context.Context, from, to time.Time) ([]*entity.SettlementRecord, error)
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: time.Second * 30,
+		Transport: &http.Transport{
+			Dial: (&net.Dialer{
+				Timeout:   time.Second * 30,
+				KeepAlive: time.Second * 30,
+			}).Dial,
+			TLSHandshakeTimeout: time.Second * 10,
+		},
+	}
+}
+
+// postJSON sends body to url and returns the raw response body. Errors that
+// guarantee the request was not processed wrap ErrProviderUnavailable.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) ([]byte, error) {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	return send(client, request)
+}
+
+// getJSON reads endpoint with query and returns the raw response body.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, query url.Values) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	return send(client, request)
+}
+
+func send(client *http.Client, request *http.Request) ([]byte, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+		}
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	raw, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case response.StatusCode == http.StatusServiceUnavailable || response.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: %s", ErrProviderUnavailable, response.Status)
+	case response.StatusCode >= 400 && response.StatusCode < 500:
+		return nil, fmt.Errorf("%w: %s", ErrPaymentDeclined, response.Status)
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		return nil, &statusError{status: response.Status, code: response.StatusCode}
+	}
+
+	return raw, nil
+}
+
+type statusError struct {
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failed to process payment: %s", e.status)
+}
+
+// isProviderFailure reports whether err counts against the provider's
+// circuit. A declined payout is an answer, not a failure of the provider.
+func isProviderFailure(err error) bool {
+	return !errors.Is(err, ErrPaymentDeclined)
+}
+
+// isRetriable reports whether the payout may be sent to the same provider
+// again: it never reached the provider, timed out or failed with a 5xx.
+func isRetriable(err error) bool {
+	if errors.Is(err, ErrProviderUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code >= 500
+}
@@ -0,0 +1,307 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/circuitbreaker"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls made to one provider, including the
+	// first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay returns a random delay up to the exponential backoff of the given
+// retry (full jitter), so retries of many payouts do not hit the provider at
+// the same time.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	backoff := p.BaseDelay
+	for i := 1; i < retry && backoff < p.MaxDelay; i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+type RouterOptions struct {
+	// AttemptTimeout bounds a single call to a provider.
+	AttemptTimeout time.Duration
+	Retry          RetryPolicy
+	// BreakerThreshold consecutive failures open the provider's circuit for
+	// BreakerOpenTimeout.
+	BreakerThreshold   int
+	BreakerOpenTimeout time.Duration
+}
+
+// Router is the PaymentProcessor used by the service. It picks a provider by
+// the employee's payout method and the amount, retries retriable errors with
+// jitter, skips providers whose circuit is open and fails over to the next
+// provider when one is unavailable.
+type Router struct {
+	providers []Provider
+	options   RouterOptions
+	breakers  map[string]*circuitbreaker.Breaker
+	stats     map[string]*providerStats
+}
+
+type providerStats struct {
+	mu             sync.Mutex
+	requests       int64
+	failures       int64
+	retries        int64
+	shortCircuited int64
+}
+
+func NewRouter(options RouterOptions, providers ...Provider) *Router {
+	if options.Retry.MaxAttempts <= 0 {
+		options.Retry.MaxAttempts = 1
+	}
+
+	breakers := make(map[string]*circuitbreaker.Breaker, len(providers))
+	stats := make(map[string]*providerStats, len(providers))
+	for _, provider := range providers {
+		breakers[provider.Name()] = circuitbreaker.New(options.BreakerThreshold, options.BreakerOpenTimeout)
+		stats[provider.Name()] = &providerStats{}
+	}
+
+	return &Router{
+		providers: providers,
+		options:   options,
+		breakers:  breakers,
+		stats:     stats,
+	}
+}
+
+// ProcessPayment sends the payout to the first provider that accepts it and
+// records that provider in payment.Provider. A payout that already has a
+// provider is only sent there again: it may have been processed already, and
+// the idempotency key is only known to that provider.
+func (r *Router) ProcessPayment(ctx context.Context, payment *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+	candidates, err := r.route(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, provider := range candidates {
+		payment.Destination = destinationFor(provider, payment)
+		response, err := r.call(ctx, provider, func(ctx context.Context) (*entity.PaymentProcessorResponse, error) {
+			return provider.ProcessPayment(ctx, payment)
+		})
+		if err == nil {
+			payment.Provider = provider.Name()
+			return response, nil
+		}
+
+		if !errors.Is(err, ErrProviderUnavailable) && !errors.Is(err, circuitbreaker.ErrOpen) {
+			payment.Provider = provider.Name()
+			return nil, err
+		}
+
+		log.Printf("payment provider %s unavailable for %s, failing over: %v", provider.Name(), payment.ExternalID, err)
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// RefundPayment sends the refund to the provider that made the payout. There
+// is no failover: no other provider knows the payout.
+func (r *Router) RefundPayment(ctx context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	for _, provider := range r.providers {
+		if provider.Name() == refund.Provider {
+			return r.call(ctx, provider, func(ctx context.Context) (*entity.PaymentProcessorResponse, error) {
+				return provider.RefundPayment(ctx, refund)
+			})
+		}
+	}
+	return nil, fmt.Errorf("%w: provider %q is not configured", ErrNoProvider, refund.Provider)
+}
+
+// ListPayments reads the settlement statement of one provider. It is not on
+// the payout path, so it is neither retried nor counted against the circuit.
+func (r *Router) ListPayments(ctx context.Context, provider string, from, to time.Time) ([]*entity.SettlementRecord, error) {
+	for _, p := range r.providers {
+		if p.Name() != provider {
+			continue
+		}
+
+		if r.options.AttemptTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.options.AttemptTimeout)
+			defer cancel()
+		}
+		return p.ListPayments(ctx, from, to)
+	}
+	return nil, fmt.Errorf("%w: provider %q is not configured", ErrNoProvider, provider)
+}
+
+// call sends a request to one provider and retries retriable errors. The
+// provider deduplicates on the idempotency key, so a retry after a timeout
+// does not pay twice.
+func (r *Router) call(ctx context.Context, provider Provider, send func(context.Context) (*entity.PaymentProcessorResponse, error)) (*entity.PaymentProcessorResponse, error) {
+	breaker := r.breakers[provider.Name()]
+	stats := r.stats[provider.Name()]
+
+	var err error
+	for attempt := 1; attempt <= r.options.Retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			stats.add(&stats.retries)
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(r.options.Retry.Delay(attempt - 1)):
+			}
+		}
+
+		if allowErr := breaker.Allow(); allowErr != nil {
+			stats.add(&stats.shortCircuited)
+			if err == nil {
+				err = fmt.Errorf("%w: %s", allowErr, provider.Name())
+			}
+			return nil, err
+		}
+
+		var response *entity.PaymentProcessorResponse
+		response, err = r.attempt(ctx, send)
+		stats.add(&stats.requests)
+		if err == nil {
+			breaker.Success()
+			return response, nil
+		}
+
+		if !isProviderFailure(err) {
+			// The provider answered, it just declined the payout.
+			breaker.Success()
+			return nil, err
+		}
+		breaker.Failure()
+		stats.add(&stats.failures)
+
+		if !isRetriable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (r *Router) attempt(ctx context.Context, send func(context.Context) (*entity.PaymentProcessorResponse, error)) (*entity.PaymentProcessorResponse, error) {
+	if r.options.AttemptTimeout <= 0 {
+		return send(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.options.AttemptTimeout)
+	defer cancel()
+	return send(ctx)
+}
+
+// route returns the providers for the payout method, leaving out providers
+// whose circuit is open. A payout that exceeds every provider of the
+// preferred method goes out by bank transfer.
+func (r *Router) route(payment *entity.PaymentProcessorRequest) ([]Provider, error) {
+	if payment.Provider != "" {
+		for _, provider := range r.providers {
+			if provider.Name() == payment.Provider {
+				return []Provider{provider}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: provider %s is not configured", ErrNoProvider, payment.Provider)
+	}
+
+	method := payment.PayoutMethod
+	if method == "" {
+		method = util.PAYOUT_METHOD_BANK_TRANSFER
+	}
+
+	candidates := r.supporting(method, float64(payment.AmountIDR))
+	if len(candidates) == 0 && method != util.PAYOUT_METHOD_BANK_TRANSFER {
+		candidates = r.supporting(util.PAYOUT_METHOD_BANK_TRANSFER, float64(payment.AmountIDR))
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: method %s, amount %d", ErrNoProvider, method, payment.AmountIDR)
+	}
+
+	available := make([]Provider, 0, len(candidates))
+	for _, provider := range candidates {
+		if r.breakers[provider.Name()].State() == circuitbreaker.StateOpen {
+			r.stats[provider.Name()].add(&r.stats[provider.Name()].shortCircuited)
+			continue
+		}
+		available = append(available, provider)
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("%w: every provider for method %s", circuitbreaker.ErrOpen, method)
+	}
+
+	return available, nil
+}
+
+// destinationFor returns the employee's destination for the method provider
+// pays out with: the payout method, or bank transfer when the payout went
+// over to bank transfer.
+func destinationFor(provider Provider, payment *entity.PaymentProcessorRequest) *entity.PayoutDestination {
+	method := payment.PayoutMethod
+	if method == "" {
+		method = util.PAYOUT_METHOD_BANK_TRANSFER
+	}
+
+	for _, candidate := range []string{method, util.PAYOUT_METHOD_BANK_TRANSFER} {
+		if provider.Supports(candidate, float64(payment.AmountIDR)) {
+			return payment.Destinations[candidate]
+		}
+	}
+	return nil
+}
+
+func (r *Router) supporting(method string, amountIDR float64) []Provider {
+	providers := make([]Provider, 0, len(r.providers))
+	for _, provider := range r.providers {
+		if provider.Supports(method, amountIDR) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// GetProviderStats returns the circuit state and call counters of every
+// provider in registration order.
+func (r *Router) GetProviderStats() []*entity.PaymentProviderStats {
+	result := make([]*entity.PaymentProviderStats, 0, len(r.providers))
+	for _, provider := range r.providers {
+		stats := r.stats[provider.Name()]
+		stats.mu.Lock()
+		result = append(result, &entity.PaymentProviderStats{
+			Provider:       provider.Name(),
+			CircuitState:   string(r.breakers[provider.Name()].State()),
+			Requests:       stats.requests,
+			Failures:       stats.failures,
+			Retries:        stats.retries,
+			ShortCircuited: stats.shortCircuited,
+		})
+		stats.mu.Unlock()
+	}
+	return result
+}
+
+func (s *providerStats) add(counter *int64) {
+	s.mu.Lock()
+	*counter++
+	s.mu.Unlock()
+}
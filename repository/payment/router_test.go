@@ -0,0 +1,355 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubProvider struct {
+	name         string
+	method       string
+	maxAmountIDR float64
+	err          error
+	// errs are returned by the first calls, one per call, before err.
+	errs        []error
+	calls       int
+	destination *entity.PayoutDestination
+	statement   []*entity.SettlementRecord
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) Supports(method string, amountIDR float64) bool {
+	return method == p.method && (p.maxAmountIDR == 0 || amountIDR <= p.maxAmountIDR)
+}
+
+func (p *stubProvider) ProcessPayment(ctx context.Context, payment *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+	p.destination = payment.Destination
+	return p.answer()
+}
+
+func (p *stubProvider) RefundPayment(ctx context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+	return p.answer()
+}
+
+func (p *stubProvider) ListPayments(ctx context.Context, from, to time.Time) ([]*entity.SettlementRecord, error) {
+	p.calls++
+	return p.statement, p.err
+}
+
+func (p *stubProvider) answer() (*entity.PaymentProcessorResponse, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	} else if p.err != nil {
+		return nil, p.err
+	}
+	response := &entity.PaymentProcessorResponse{}
+	response.Data.ID = p.name + "-1"
+	return response, nil
+}
+
+func TestRouter_ProcessPayment(t *testing.T) {
+	unavailable := fmt.Errorf("%w: 503 Service Unavailable", ErrProviderUnavailable)
+
+	tests := []struct {
+		name         string
+		providers    func() []*stubProvider
+		request      entity.PaymentProcessorRequest
+		wantProvider string
+		wantCalls    []int
+		wantErr      error
+	}{
+		{
+			name: "routes by payout method",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+					{name: "wallet", method: util.PAYOUT_METHOD_EWALLET, maxAmountIDR: 10000000},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000, PayoutMethod: util.PAYOUT_METHOD_EWALLET},
+			wantProvider: "wallet",
+			wantCalls:    []int{0, 1},
+		},
+		{
+			name: "defaults to bank transfer without a preference",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "wallet", method: util.PAYOUT_METHOD_EWALLET},
+					{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantProvider: "bank",
+			wantCalls:    []int{0, 1},
+		},
+		{
+			name: "amount above the e-wallet limit goes out by bank transfer",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+					{name: "wallet", method: util.PAYOUT_METHOD_EWALLET, maxAmountIDR: 10000000},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 15000000, PayoutMethod: util.PAYOUT_METHOD_EWALLET},
+			wantProvider: "bank",
+			wantCalls:    []int{1, 0},
+		},
+		{
+			name: "fails over when a provider is unavailable",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, err: unavailable},
+					{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantProvider: "bank-b",
+			wantCalls:    []int{3, 1},
+		},
+		{
+			name: "retries a server error on the same provider",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, errs: []error{&statusError{status: "502 Bad Gateway", code: 502}, context.DeadlineExceeded}},
+					{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantProvider: "bank-a",
+			wantCalls:    []int{3, 0},
+		},
+		{
+			name: "does not retry a declined payout",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, err: fmt.Errorf("%w: 422 Unprocessable Entity", ErrPaymentDeclined)},
+					{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantProvider: "bank-a",
+			wantCalls:    []int{1, 0},
+			wantErr:      errors.New("payment declined by provider: 422 Unprocessable Entity"),
+		},
+		{
+			name: "does not fail over when the outcome is unknown",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, err: errors.New("context deadline exceeded")},
+					{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantProvider: "bank-a",
+			wantCalls:    []int{1, 0},
+			wantErr:      errors.New("context deadline exceeded"),
+		},
+		{
+			name: "retry stays on the pinned provider",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+					{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER},
+				}
+			},
+			request:      entity.PaymentProcessorRequest{AmountIDR: 75000, Provider: "bank-b"},
+			wantProvider: "bank-b",
+			wantCalls:    []int{0, 1},
+		},
+		{
+			name: "no provider for the payout",
+			providers: func() []*stubProvider {
+				return []*stubProvider{
+					{name: "wallet", method: util.PAYOUT_METHOD_EWALLET},
+				}
+			},
+			request:   entity.PaymentProcessorRequest{AmountIDR: 75000},
+			wantCalls: []int{0},
+			wantErr:   ErrNoProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := tt.providers()
+			providers := make([]Provider, 0, len(stubs))
+			for _, stub := range stubs {
+				providers = append(providers, stub)
+			}
+			router := NewRouter(RouterOptions{
+				Retry:              RetryPolicy{MaxAttempts: 3},
+				BreakerThreshold:   5,
+				BreakerOpenTimeout: time.Minute,
+			}, providers...)
+
+			request := tt.request
+			_, err := router.ProcessPayment(context.Background(), &request)
+
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				if errors.Is(tt.wantErr, ErrNoProvider) {
+					assert.ErrorIs(t, err, ErrNoProvider)
+				} else {
+					assert.EqualError(t, err, tt.wantErr.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantProvider, request.Provider)
+			for i, stub := range stubs {
+				assert.Equal(t, tt.wantCalls[i], stub.calls, stub.name)
+			}
+		})
+	}
+}
+
+func TestRouter_Destination(t *testing.T) {
+	bank := &entity.PayoutDestination{Method: util.PAYOUT_METHOD_BANK_TRANSFER, BankCode: "BCA", AccountNumber: "1234567890"}
+	wallet := &entity.PayoutDestination{Method: util.PAYOUT_METHOD_EWALLET, EWalletID: "081234567890"}
+	bankProvider := &stubProvider{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER}
+	walletProvider := &stubProvider{name: "wallet", method: util.PAYOUT_METHOD_EWALLET, maxAmountIDR: 10000000}
+	router := NewRouter(RouterOptions{}, bankProvider, walletProvider)
+	destinations := map[string]*entity.PayoutDestination{
+		util.PAYOUT_METHOD_BANK_TRANSFER: bank,
+		util.PAYOUT_METHOD_EWALLET:       wallet,
+	}
+
+	request := &entity.PaymentProcessorRequest{AmountIDR: 75000, PayoutMethod: util.PAYOUT_METHOD_EWALLET, Destinations: destinations}
+	_, err := router.ProcessPayment(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Same(t, wallet, walletProvider.destination)
+	assert.Same(t, wallet, request.Destination)
+
+	// Above the e-wallet limit the payout goes to the bank account.
+	request = &entity.PaymentProcessorRequest{AmountIDR: 15000000, PayoutMethod: util.PAYOUT_METHOD_EWALLET, Destinations: destinations}
+	_, err = router.ProcessPayment(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "bank", request.Provider)
+	assert.Same(t, bank, bankProvider.destination)
+}
+
+func TestRouter_RefundPayment(t *testing.T) {
+	timeout := fmt.Errorf("request timed out: %w", context.DeadlineExceeded)
+	bank := &stubProvider{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER, errs: []error{timeout}}
+	backup := &stubProvider{name: "backup", method: util.PAYOUT_METHOD_BANK_TRANSFER}
+	router := NewRouter(RouterOptions{Retry: RetryPolicy{MaxAttempts: 2}}, bank, backup)
+
+	// The refund stays on the provider of the payout and is retried there.
+	response, err := router.RefundPayment(context.Background(), &entity.RefundRequest{ExternalID: "reversal-1", Provider: "bank"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bank-1", response.Data.ID)
+	assert.Equal(t, 2, bank.calls)
+	assert.Equal(t, 0, backup.calls)
+
+	bank.err = fmt.Errorf("%w: connection refused", ErrProviderUnavailable)
+	_, err = router.RefundPayment(context.Background(), &entity.RefundRequest{ExternalID: "reversal-2", Provider: "bank"})
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+	assert.Equal(t, 0, backup.calls)
+
+	_, err = router.RefundPayment(context.Background(), &entity.RefundRequest{ExternalID: "reversal-3"})
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
+
+func TestRouter_ListPayments(t *testing.T) {
+	statement := []*entity.SettlementRecord{{ExternalID: "ext-1", AmountIDR: 75000, Status: "COMPLETED"}}
+	bank := &stubProvider{name: "bank", method: util.PAYOUT_METHOD_BANK_TRANSFER, statement: statement}
+	wallet := &stubProvider{name: "wallet", method: util.PAYOUT_METHOD_EWALLET}
+	router := NewRouter(RouterOptions{}, bank, wallet)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	records, err := router.ListPayments(context.Background(), "bank", from, from.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, statement, records)
+	assert.Equal(t, 1, bank.calls)
+	assert.Equal(t, 0, wallet.calls)
+
+	_, err = router.ListPayments(context.Background(), "cash", from, from.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
+
+func TestRouter_CircuitBreaker(t *testing.T) {
+	down := &stubProvider{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, err: fmt.Errorf("%w: connection refused", ErrProviderUnavailable)}
+	backup := &stubProvider{name: "bank-b", method: util.PAYOUT_METHOD_BANK_TRANSFER}
+	router := NewRouter(RouterOptions{
+		Retry:              RetryPolicy{MaxAttempts: 1},
+		BreakerThreshold:   2,
+		BreakerOpenTimeout: 50 * time.Millisecond,
+	}, down, backup)
+
+	for i := 0; i < 4; i++ {
+		request := &entity.PaymentProcessorRequest{AmountIDR: 75000}
+		_, err := router.ProcessPayment(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, "bank-b", request.Provider)
+	}
+
+	// After two failures bank-a is not called while its circuit is open.
+	assert.Equal(t, 2, down.calls)
+	assert.Equal(t, 4, backup.calls)
+	assert.Equal(t, "open", router.GetProviderStats()[0].CircuitState)
+	assert.Equal(t, int64(2), router.GetProviderStats()[0].ShortCircuited)
+
+	// A pinned payout is not sent while the circuit is open.
+	_, err := router.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{AmountIDR: 75000, Provider: "bank-a"})
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	assert.Equal(t, 2, down.calls)
+
+	// Once the open timeout has passed a single probe goes through and closes
+	// the circuit again when it succeeds.
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, "half_open", router.GetProviderStats()[0].CircuitState)
+	down.err = nil
+	request := &entity.PaymentProcessorRequest{AmountIDR: 75000}
+	_, err = router.ProcessPayment(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "bank-a", request.Provider)
+	assert.Equal(t, "closed", router.GetProviderStats()[0].CircuitState)
+}
+
+func TestRouter_EveryCircuitOpen(t *testing.T) {
+	down := &stubProvider{name: "bank-a", method: util.PAYOUT_METHOD_BANK_TRANSFER, err: &statusError{status: "500 Internal Server Error", code: 500}}
+	router := NewRouter(RouterOptions{
+		Retry:              RetryPolicy{MaxAttempts: 2},
+		BreakerThreshold:   2,
+		BreakerOpenTimeout: time.Minute,
+	}, down)
+
+	_, err := router.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{AmountIDR: 75000})
+	assert.EqualError(t, err, "failed to process payment: 500 Internal Server Error")
+
+	_, err = router.ProcessPayment(context.Background(), &entity.PaymentProcessorRequest{AmountIDR: 75000})
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	assert.Equal(t, 2, down.calls)
+
+	stats := router.GetProviderStats()[0]
+	assert.Equal(t, int64(2), stats.Requests)
+	assert.Equal(t, int64(2), stats.Failures)
+	assert.Equal(t, int64(1), stats.Retries)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, policy.Delay(1), 100*time.Millisecond)
+		assert.LessOrEqual(t, policy.Delay(3), 400*time.Millisecond)
+		assert.LessOrEqual(t, policy.Delay(10), time.Second)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.Delay(3))
+}
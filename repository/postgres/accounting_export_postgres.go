@@ -0,0 +1,284 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+const accountingExportColumns = `id, format, period_from, period_to, expense_count, total_amount_idr, created_by, created_at`
+
+const accountingExportItemColumns = `i.id, i.export_id, i.expense_id, i.user_id, u.name, u.email, i.category, i.cost_center, i.description, i.amount_idr, i.account_code, i.approved_at`
+
+type accountingExportRepository struct {
+	db *sql.DB
+}
+
+func NewAccountingExportRepository(db *sql.DB) *accountingExportRepository {
+	return &accountingExportRepository{db: db}
+}
+
+func (r *accountingExportRepository) GetGLAccountMappings(ctx context.Context) ([]*entity.GLAccountMapping, error) {
+	query := `
+		SELECT id, category, cost_center, account_code, updated_at FROM gl_account_mappings
+		ORDER BY category, cost_center
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := make([]*entity.GLAccountMapping, 0)
+	for rows.Next() {
+		var mapping entity.GLAccountMapping
+		err := rows.Scan(&mapping.ID, &mapping.Category, &mapping.CostCenter, &mapping.AccountCode, &mapping.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, &mapping)
+	}
+
+	return mappings, rows.Err()
+}
+
+func (r *accountingExportRepository) UpsertGLAccountMapping(ctx context.Context, mapping *entity.GLAccountMapping) error {
+	query := `
+		INSERT INTO gl_account_mappings (category, cost_center, account_code, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (category, cost_center) DO UPDATE
+		SET account_code = EXCLUDED.account_code,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, mapping.Category, mapping.CostCenter, mapping.AccountCode, time.Now())
+	return err
+}
+
+// CreateAccountingExport locks the approved expenses of the period that were
+// not exported yet, maps them through build and writes the export and its
+// items in a single transaction. An expense is exported at most once: locked
+// expenses are skipped and the items are unique per expense.
+func (r *accountingExportRepository) CreateAccountingExport(ctx context.Context, export *entity.AccountingExport, build entity.AccountingExportBuilder) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queryExpenses := `
+		SELECT e.id, e.user_id, u.name, u.email, e.category, COALESCE(e.cost_center, ''), e.description,
+			COALESCE(NULLIF(e.approved_amount_idr, 0), e.amount_idr), a.created_at
+		FROM expenses e
+		JOIN users u ON u.id = e.user_id
+		JOIN LATERAL (
+			SELECT created_at FROM approvals WHERE expense_id = e.id AND status IN ($1, $2)
+			ORDER BY created_at DESC LIMIT 1
+		) a ON TRUE
+		WHERE e.status IN ($1, $2, $3, $4) AND a.created_at >= $5 AND a.created_at < $6
+		AND NOT EXISTS (SELECT 1 FROM accounting_export_items i WHERE i.expense_id = e.id)
+		ORDER BY a.created_at, e.id
+		FOR UPDATE OF e SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(
+		ctx,
+		queryExpenses,
+		util.EXPENSE_APPROVED,
+		util.EXPENSE_AUTO_APPROVED,
+		util.EXPENSE_PAID,
+		util.EXPENSE_PAYMENT_FAILED,
+		export.PeriodFrom,
+		export.PeriodTo.AddDate(0, 0, 1),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]*entity.AccountingExportItem, 0)
+	for rows.Next() {
+		var item entity.AccountingExportItem
+		err = rows.Scan(
+			&item.ExpenseID,
+			&item.UserID,
+			&item.UserName,
+			&item.UserEmail,
+			&item.Category,
+			&item.CostCenter,
+			&item.Description,
+			&item.AmountIDR,
+			&item.ApprovedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, &item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(items) == 0 {
+		return 0, util.ErrNothingToExport
+	}
+
+	err = build(items)
+	if err != nil {
+		return 0, err
+	}
+
+	export.ExpenseCount = int32(len(items))
+	export.TotalAmountIDR = 0
+	for _, item := range items {
+		export.TotalAmountIDR += item.AmountIDR
+	}
+
+	queryExport := `
+		INSERT INTO accounting_exports (format, period_from, period_to, expense_count, total_amount_idr, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`
+
+	var exportID int64
+	err = tx.QueryRowContext(
+		ctx,
+		queryExport,
+		export.Format,
+		export.PeriodFrom,
+		export.PeriodTo,
+		export.ExpenseCount,
+		export.TotalAmountIDR,
+		export.CreatedBy,
+		time.Now(),
+	).Scan(&exportID)
+	if err != nil {
+		return 0, err
+	}
+
+	queryItem := `
+		INSERT INTO accounting_export_items (export_id, expense_id, user_id, category, cost_center, description, amount_idr, account_code, approved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	for _, item := range items {
+		_, err = tx.ExecContext(
+			ctx,
+			queryItem,
+			exportID,
+			item.ExpenseID,
+			item.UserID,
+			item.Category,
+			item.CostCenter,
+			item.Description,
+			item.AmountIDR,
+			item.AccountCode,
+			item.ApprovedAt,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	export.Items = items
+	return exportID, nil
+}
+
+func (r *accountingExportRepository) GetAccountingExport(ctx context.Context, id int64) (*entity.AccountingExport, error) {
+	query := `SELECT ` + accountingExportColumns + ` FROM accounting_exports WHERE id = $1`
+
+	export, err := scanAccountingExport(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, util.ErrAccountingExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queryItems := `
+		SELECT ` + accountingExportItemColumns + `
+		FROM accounting_export_items i JOIN users u ON u.id = i.user_id
+		WHERE i.export_id = $1 ORDER BY i.approved_at ASC, i.expense_id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, queryItems, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	export.Items = make([]*entity.AccountingExportItem, 0)
+	for rows.Next() {
+		var item entity.AccountingExportItem
+		err := rows.Scan(
+			&item.ID,
+			&item.ExportID,
+			&item.ExpenseID,
+			&item.UserID,
+			&item.UserName,
+			&item.UserEmail,
+			&item.Category,
+			&item.CostCenter,
+			&item.Description,
+			&item.AmountIDR,
+			&item.AccountCode,
+			&item.ApprovedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		export.Items = append(export.Items, &item)
+	}
+
+	return export, rows.Err()
+}
+
+func (r *accountingExportRepository) GetAccountingExports(ctx context.Context, limit int) ([]*entity.AccountingExport, error) {
+	query := `SELECT ` + accountingExportColumns + ` FROM accounting_exports ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := make([]*entity.AccountingExport, 0)
+	for rows.Next() {
+		export, err := scanAccountingExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func scanAccountingExport(row rowScanner) (*entity.AccountingExport, error) {
+	var export entity.AccountingExport
+	err := row.Scan(
+		&export.ID,
+		&export.Format,
+		&export.PeriodFrom,
+		&export.PeriodTo,
+		&export.ExpenseCount,
+		&export.TotalAmountIDR,
+		&export.CreatedBy,
+		&export.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
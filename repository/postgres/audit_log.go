@@ -2,25 +2,37 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/budsx/expenses-management/entity"
 )
 
 func (r *expensesRepository) WriteAuditLog(ctx context.Context, auditLog *entity.AuditLog) error {
-	query := `
-		INSERT INTO audit_logs (expense_id, new_status, status_before, notes, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, query, auditLog.ExpenseID, auditLog.NewStatus, auditLog.StatusBefore, auditLog.Notes, auditLog.CreatedAt)
+	err = writeAuditLog(ctx, tx, auditLog)
 	if err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
+
+// writeAuditLog records auditLog in the transaction changing the expense, so a
+// change is never committed without its audit trail.
+func writeAuditLog(ctx context.Context, tx *sql.Tx, auditLog *entity.AuditLog) error {
+	if auditLog == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO audit_logs (expense_id, new_status, status_before, amount_before, amount_after, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.ExecContext(ctx, query, auditLog.ExpenseID, auditLog.NewStatus, auditLog.StatusBefore, nullFloat64(auditLog.AmountBefore), nullFloat64(auditLog.AmountAfter), auditLog.Notes, auditLog.CreatedAt)
+	return err
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+type budgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(db *sql.DB) *budgetRepository {
+	return &budgetRepository{db: db}
+}
+
+const queryBudgets = `
+	SELECT b.id, b.department, b.category, b.period_from, b.period_to, b.amount_idr, b.policy, b.created_by, b.updated_at,
+		s.pending, s.reserved, s.consumed
+	FROM budgets b
+	CROSS JOIN LATERAL (
+		SELECT
+			COALESCE(SUM(e.amount_idr) FILTER (WHERE e.status = $1), 0) AS pending,
+			COALESCE(SUM(COALESCE(NULLIF(e.approved_amount_idr, 0), e.amount_idr)) FILTER (WHERE e.status IN ($2, $3, $4)), 0) AS reserved,
+			COALESCE(SUM(COALESCE(NULLIF(e.approved_amount_idr, 0), e.amount_idr)) FILTER (WHERE e.status = $5), 0) AS consumed
+		FROM expenses e JOIN users u ON u.id = e.user_id
+		WHERE u.department = b.department AND (b.category = '' OR e.category = b.category)
+		AND e.submitted_at >= b.period_from AND e.submitted_at < b.period_to + 1
+	) s
+	WHERE ($6 = 0 OR b.department = (SELECT department FROM users WHERE id = $6))
+	AND ($7 = '' OR b.department = $7)
+	AND ($8 = '' OR b.category = '' OR b.category = $8)
+	AND ($9::DATE IS NULL OR $9::DATE BETWEEN b.period_from AND b.period_to)
+	ORDER BY b.period_from DESC, b.department, b.category
+`
+
+// GetBudgets returns the budgets with what was spent against them, totalled
+// over the expenses submitted in the period by the department's employees.
+func (r *budgetRepository) GetBudgets(ctx context.Context, query *entity.BudgetQuery) ([]*entity.Budget, error) {
+	return getBudgets(ctx, r.db, query)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func getBudgets(ctx context.Context, db queryer, query *entity.BudgetQuery) ([]*entity.Budget, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		queryBudgets,
+		util.EXPENSE_PENDING,
+		util.EXPENSE_APPROVED,
+		util.EXPENSE_AUTO_APPROVED,
+		util.EXPENSE_PAYMENT_FAILED,
+		util.EXPENSE_PAID,
+		query.UserID,
+		query.Department,
+		query.Category,
+		sql.NullTime{Time: query.Date, Valid: !query.Date.IsZero()},
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := make([]*entity.Budget, 0)
+	for rows.Next() {
+		var budget entity.Budget
+		err := rows.Scan(
+			&budget.ID,
+			&budget.Department,
+			&budget.Category,
+			&budget.PeriodFrom,
+			&budget.PeriodTo,
+			&budget.AmountIDR,
+			&budget.Policy,
+			&budget.CreatedBy,
+			&budget.UpdatedAt,
+			&budget.PendingIDR,
+			&budget.ReservedIDR,
+			&budget.ConsumedIDR,
+		)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, &budget)
+	}
+
+	return budgets, rows.Err()
+}
+
+// checkBudgets locks the budgets covering query until the transaction ends and
+// hands them, with what was spent against them, to check.
+func checkBudgets(ctx context.Context, tx *sql.Tx, query *entity.BudgetQuery, check entity.BudgetCheck) error {
+	if check == nil {
+		return nil
+	}
+
+	queryLock := `
+		SELECT id FROM budgets
+		WHERE department = (SELECT department FROM users WHERE id = $1)
+		AND (category = '' OR category = $2)
+		AND $3::DATE BETWEEN period_from AND period_to
+		ORDER BY id
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, queryLock, query.UserID, query.Category, query.Date)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	budgets, err := getBudgets(ctx, tx, query)
+	if err != nil {
+		return err
+	}
+
+	return check(budgets)
+}
+
+// UpsertBudget sets the amount and policy of the budget of a department,
+// category and period.
+func (r *budgetRepository) UpsertBudget(ctx context.Context, budget *entity.Budget) (int64, error) {
+	query := `
+		INSERT INTO budgets (department, category, period_from, period_to, amount_idr, policy, created_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (department, category, period_from, period_to) DO UPDATE
+		SET amount_idr = EXCLUDED.amount_idr,
+			policy = EXCLUDED.policy,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		budget.Department,
+		budget.Category,
+		budget.PeriodFrom,
+		budget.PeriodTo,
+		budget.AmountIDR,
+		budget.Policy,
+		budget.CreatedBy,
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
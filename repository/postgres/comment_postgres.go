@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/lib/pq"
+)
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *commentRepository {
+	return &commentRepository{db: db}
+}
+
+func (r *commentRepository) WriteComment(ctx context.Context, comment *entity.Comment) (int64, error) {
+	queryComment := `
+		INSERT INTO expense_comments (expense_id, author_id, body, created_at)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`
+
+	queryMention := `
+		INSERT INTO comment_mentions (comment_id, user_id)
+		VALUES ($1, $2) ON CONFLICT DO NOTHING
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		queryComment,
+		comment.ExpenseID,
+		comment.AuthorID,
+		comment.Body,
+		comment.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userID := range comment.Mentions {
+		_, err = tx.ExecContext(ctx, queryMention, id, userID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *commentRepository) GetCommentsByExpenseID(ctx context.Context, expenseID int64) ([]*entity.Comment, error) {
+	query := `
+		SELECT c.id, c.expense_id, c.author_id, u.name, c.body, c.created_at,
+			COALESCE(ARRAY(SELECT m.user_id FROM comment_mentions m WHERE m.comment_id = c.id ORDER BY m.user_id), '{}')
+		FROM expense_comments c
+		JOIN users u ON u.id = c.author_id
+		WHERE c.expense_id = $1
+		ORDER BY c.created_at ASC, c.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*entity.Comment, 0)
+	for rows.Next() {
+		var comment entity.Comment
+		mentions := pq.Int64Array{}
+		err := rows.Scan(
+			&comment.ID,
+			&comment.ExpenseID,
+			&comment.AuthorID,
+			&comment.AuthorName,
+			&comment.Body,
+			&comment.CreatedAt,
+			&mentions,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comment.Mentions = []int64(mentions)
+		comments = append(comments, &comment)
+	}
+
+	return comments, rows.Err()
+}
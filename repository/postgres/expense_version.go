@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+func (r *expensesRepository) ReviseExpense(ctx context.Context, expense *entity.Expense, notes string, auditLog *entity.AuditLog, checkLimit entity.SpendingLimitCheck, checkBudget entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int32, error) {
+	query := `
+		UPDATE expenses
+		SET amount_idr = $1, description = $2, receipt_url = $3, status = $4, over_limit = $5, approved_amount_idr = NULL, revision = revision + 1, version = version + 1, submitted_at = $6
+		WHERE id = $7 AND version = $8
+		RETURNING revision
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	overLimit, err := checkSpendingLimit(ctx, tx, expense, now, checkLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkBudgets(ctx, tx, &entity.BudgetQuery{UserID: expense.UserID, Category: expense.Category, Date: now}, checkBudget)
+	if err != nil {
+		return 0, err
+	}
+
+	var revision int32
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		expense.AmountIDR,
+		expense.Description,
+		expense.ReceiptURL,
+		expense.Status,
+		overLimit,
+		now,
+		expense.ID,
+		expense.Version,
+	).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, util.ErrVersionConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = writeExpenseVersion(ctx, tx, &entity.ExpenseVersion{
+		ExpenseID:   expense.ID,
+		Revision:    revision,
+		AmountIDR:   expense.AmountIDR,
+		Description: expense.Description,
+		ReceiptURL:  expense.ReceiptURL,
+		Notes:       notes,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = writeAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return 0, err
+	}
+
+	if buildOutbox != nil {
+		outbox, err := buildOutbox(expense.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = writeOutboxMessages(ctx, tx, outbox)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return revision, tx.Commit()
+}
+
+func (r *expensesRepository) GetExpenseVersions(ctx context.Context, expenseID int64) ([]*entity.ExpenseVersion, error) {
+	query := `
+		SELECT id, expense_id, revision, amount_idr, description, receipt_url, notes, created_at
+		FROM expense_versions WHERE expense_id = $1 ORDER BY revision ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]*entity.ExpenseVersion, 0)
+	for rows.Next() {
+		var version entity.ExpenseVersion
+		receiptURL := sql.NullString{}
+		notes := sql.NullString{}
+		err := rows.Scan(
+			&version.ID,
+			&version.ExpenseID,
+			&version.Revision,
+			&version.AmountIDR,
+			&version.Description,
+			&receiptURL,
+			&notes,
+			&version.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		version.ReceiptURL = receiptURL.String
+		version.Notes = notes.String
+		versions = append(versions, &version)
+	}
+
+	return versions, rows.Err()
+}
+
+func (r *expensesRepository) GetApprovalsByExpenseID(ctx context.Context, expenseID int64) ([]*entity.Approval, error) {
+	query := `
+		SELECT id, expense_id, approver_id, revision, status, notes, approved_amount_idr, adjustment_reason, created_at
+		FROM approvals WHERE expense_id = $1 ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]*entity.Approval, 0)
+	for rows.Next() {
+		var approval entity.Approval
+		notes := sql.NullString{}
+		approvedAmount := sql.NullFloat64{}
+		adjustmentReason := sql.NullString{}
+		err := rows.Scan(
+			&approval.ID,
+			&approval.ExpenseID,
+			&approval.ApproverID,
+			&approval.Revision,
+			&approval.Status,
+			&notes,
+			&approvedAmount,
+			&adjustmentReason,
+			&approval.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		approval.Notes = notes.String
+		approval.ApprovedAmountIDR = approvedAmount.Float64
+		approval.AdjustmentReason = adjustmentReason.String
+		approvals = append(approvals, &approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+func writeExpenseVersion(ctx context.Context, tx *sql.Tx, version *entity.ExpenseVersion) error {
+	query := `
+		INSERT INTO expense_versions (expense_id, revision, amount_idr, description, receipt_url, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		version.ExpenseID,
+		version.Revision,
+		version.AmountIDR,
+		version.Description,
+		version.ReceiptURL,
+		version.Notes,
+		version.CreatedAt,
+	)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
 )
 
 type expensesRepository struct {
@@ -21,7 +22,7 @@ func (r *expensesRepository) PingContext(ctx context.Context) error {
 	return r.db.PingContext(ctx)
 }
 
-func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.Expense) (int64, error) {
+func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.Expense, auditLog *entity.AuditLog, checkLimit entity.SpendingLimitCheck, checkBudget entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int64, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
@@ -29,11 +30,21 @@ func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.E
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO expenses (user_id, amount_idr, description, receipt_url, status, submitted_at, processed_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+		INSERT INTO expenses (user_id, amount_idr, description, receipt_url, category, cost_center, status, over_limit, submitted_at, processed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id
 	`
 
 	now := time.Now()
+	overLimit, err := checkSpendingLimit(ctx, tx, expense, now, checkLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkBudgets(ctx, tx, &entity.BudgetQuery{UserID: expense.UserID, Category: expense.Category, Date: now}, checkBudget)
+	if err != nil {
+		return 0, err
+	}
+
 	var id int64
 	err = tx.QueryRowContext(
 		ctx,
@@ -42,7 +53,10 @@ func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.E
 		expense.AmountIDR,
 		expense.Description,
 		expense.ReceiptURL,
+		expense.Category,
+		sql.NullString{String: expense.CostCenter, Valid: expense.CostCenter != ""},
 		expense.Status,
+		overLimit,
 		now,
 		now,
 	).Scan(&id)
@@ -50,6 +64,38 @@ func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.E
 		return 0, err
 	}
 
+	err = writeExpenseVersion(ctx, tx, &entity.ExpenseVersion{
+		ExpenseID:   id,
+		Revision:    1,
+		AmountIDR:   expense.AmountIDR,
+		Description: expense.Description,
+		ReceiptURL:  expense.ReceiptURL,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if auditLog != nil {
+		auditLog.ExpenseID = id
+		err = writeAuditLog(ctx, tx, auditLog)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if buildOutbox != nil {
+		outbox, err := buildOutbox(id)
+		if err != nil {
+			return 0, err
+		}
+
+		err = writeOutboxMessages(ctx, tx, outbox)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return 0, err
@@ -58,14 +104,15 @@ func (r *expensesRepository) WriteExpense(ctx context.Context, expense *entity.E
 	return id, nil
 }
 
-func (r *expensesRepository) ApprovalExpense(ctx context.Context, expenseApproval *entity.ExpenseApproval) error {
+func (r *expensesRepository) ApprovalExpense(ctx context.Context, expenseApproval *entity.ExpenseApproval, auditLog *entity.AuditLog, checkBudget entity.BudgetCheck, outbox ...*entity.OutboxMessage) error {
 	queryExpense := `
-		UPDATE expenses SET status = $1 WHERE id = $2
+		UPDATE expenses SET status = $1, approved_amount_idr = $2, version = version + 1
+		WHERE id = $3 AND version = $4
 	`
 
 	queryApproval := `
-		INSERT INTO approvals (expense_id, approver_id, status, notes, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO approvals (expense_id, approver_id, revision, status, notes, approved_amount_idr, adjustment_reason, created_at)
+		VALUES ($1, $2, (SELECT revision FROM expenses WHERE id = $1), $3, $4, $5, $6, $7)
 	`
 
 	tx, err := r.db.BeginTx(ctx, nil)
@@ -74,16 +121,37 @@ func (r *expensesRepository) ApprovalExpense(ctx context.Context, expenseApprova
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(
+	if checkBudget != nil {
+		budgetQuery := &entity.BudgetQuery{}
+		err = tx.QueryRowContext(ctx, `SELECT user_id, category, submitted_at FROM expenses WHERE id = $1`, expenseApproval.ExpenseID).
+			Scan(&budgetQuery.UserID, &budgetQuery.Category, &budgetQuery.Date)
+		if err != nil {
+			return err
+		}
+
+		err = checkBudgets(ctx, tx, budgetQuery, checkBudget)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(
 		ctx,
 		queryExpense,
 		expenseApproval.Status,
+		nullFloat64(expenseApproval.ApprovedAmountIDR),
 		expenseApproval.ExpenseID,
+		expenseApproval.Version,
 	)
 	if err != nil {
 		return err
 	}
 
+	err = checkRowsAffected(result)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.ExecContext(
 		ctx,
 		queryApproval,
@@ -91,48 +159,91 @@ func (r *expensesRepository) ApprovalExpense(ctx context.Context, expenseApprova
 		expenseApproval.ApproverID,
 		expenseApproval.Status,
 		expenseApproval.Notes,
+		nullFloat64(expenseApproval.ApprovedAmountIDR),
+		expenseApproval.AdjustmentReason,
 		time.Now(),
 	)
 	if err != nil {
 		return err
 	}
 
+	err = writeAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return err
+	}
+
+	err = writeOutboxMessages(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (r *expensesRepository) UpdateExpenseStatus(ctx context.Context, expenseID int64, status int32) error {
+func (r *expensesRepository) UpdateExpenseStatus(ctx context.Context, expenseID int64, status int32, version int32, auditLog *entity.AuditLog, outbox ...*entity.OutboxMessage) error {
 	query := `
-		UPDATE expenses SET status = $1 WHERE id = $2
+		UPDATE expenses SET status = $1, version = version + 1 WHERE id = $2 AND version = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, status, expenseID)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, status, expenseID, version)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = checkRowsAffected(result)
+	if err != nil {
+		return err
+	}
+
+	err = writeAuditLog(ctx, tx, auditLog)
+	if err != nil {
+		return err
+	}
+
+	err = writeOutboxMessages(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *expensesRepository) GetExpenseByID(ctx context.Context, expenseID int64) (*entity.Expense, error) {
 	query := `
-		SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved, submitted_at, processed_at FROM expenses WHERE id = $1
+		SELECT id, user_id, amount_idr, approved_amount_idr, description, receipt_url, category, cost_center, status, auto_approved, over_limit, revision, version, submitted_at, processed_at FROM expenses WHERE id = $1
 	`
 
 	var expense entity.Expense
+	approvedAmount := sql.NullFloat64{}
+	costCenter := sql.NullString{}
 	err := r.db.QueryRowContext(ctx, query, expenseID).Scan(
 		&expense.ID,
 		&expense.UserID,
 		&expense.AmountIDR,
+		&approvedAmount,
 		&expense.Description,
 		&expense.ReceiptURL,
+		&expense.Category,
+		&costCenter,
 		&expense.Status,
 		&expense.AutoApproved,
+		&expense.OverLimit,
+		&expense.Revision,
+		&expense.Version,
 		&expense.SubmittedAt,
 		&sql.NullTime{},
 	)
 	if err != nil {
 		return nil, err
 	}
+	expense.ApprovedAmountIDR = approvedAmount.Float64
+	expense.CostCenter = costCenter.String
 
 	return &expense, nil
 }
@@ -149,20 +260,30 @@ func (r *expensesRepository) GetExpensesWithPagination(ctx context.Context, quer
 	sqlNullTime := sql.NullTime{}
 	for rows.Next() {
 		var expense entity.Expense
+		approvedAmount := sql.NullFloat64{}
+		costCenter := sql.NullString{}
 		err := rows.Scan(
 			&expense.ID,
 			&expense.UserID,
 			&expense.AmountIDR,
+			&approvedAmount,
 			&expense.Description,
 			&expense.ReceiptURL,
+			&expense.Category,
+			&costCenter,
 			&expense.Status,
 			&expense.AutoApproved,
+			&expense.OverLimit,
+			&expense.Revision,
+			&expense.Version,
 			&expense.SubmittedAt,
 			&sqlNullTime,
 		)
 		if err != nil {
 			return nil, 0, err
 		}
+		expense.ApprovedAmountIDR = approvedAmount.Float64
+		expense.CostCenter = costCenter.String
 		expenses = append(expenses, &expense)
 	}
 
@@ -176,7 +297,7 @@ func (r *expensesRepository) GetExpensesWithPagination(ctx context.Context, quer
 }
 
 func buildDataQuery(query *entity.ExpenseListQuery) string {
-	queryString := "SELECT id, user_id, amount_idr, description, receipt_url, status, auto_approved, submitted_at, processed_at FROM expenses"
+	queryString := "SELECT id, user_id, amount_idr, approved_amount_idr, description, receipt_url, category, cost_center, status, auto_approved, over_limit, revision, version, submitted_at, processed_at FROM expenses"
 
 	var conditions []string
 	if query.UserID != 0 {
@@ -222,3 +343,20 @@ func buildQueryCount(query *entity.ExpenseListQuery) string {
 
 	return queryString
 }
+
+// checkRowsAffected reports a version conflict when a compare-and-set update
+// matched no rows.
+func checkRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return util.ErrVersionConflict
+	}
+	return nil
+}
+
+func nullFloat64(value float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: value, Valid: value != 0}
+}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/lib/pq"
+)
+
+const journalEntryColumns = `id, reference, kind, user_id, expense_id, description, created_by, posted_at`
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *ledgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+// PostJournalEntry writes the entry and its lines in a single transaction. An
+// entry whose reference was posted before is not written again.
+func (r *ledgerRepository) PostJournalEntry(ctx context.Context, entry *entity.JournalEntry) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO journal_entries (reference, kind, user_id, expense_id, description, created_by, posted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reference) DO NOTHING RETURNING id
+	`
+
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		entry.Reference,
+		entry.Kind,
+		entry.UserID,
+		sql.NullInt64{Int64: entry.ExpenseID, Valid: entry.ExpenseID != 0},
+		entry.Description,
+		sql.NullInt64{Int64: entry.CreatedBy, Valid: entry.CreatedBy != 0},
+		entry.PostedAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, util.ErrJournalEntryExists
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	queryLine := `
+		INSERT INTO journal_lines (entry_id, account, debit_idr, credit_idr)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	for _, line := range entry.Lines {
+		_, err = tx.ExecContext(ctx, queryLine, id, line.Account, line.DebitIDR, line.CreditIDR)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetJournalEntries returns the latest entries with their lines, filtered by
+// employee and expense when set.
+func (r *ledgerRepository) GetJournalEntries(ctx context.Context, query *entity.JournalEntryQuery) ([]*entity.JournalEntry, error) {
+	queryEntries := `
+		SELECT ` + journalEntryColumns + ` FROM journal_entries
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = 0 OR expense_id = $2)
+		ORDER BY posted_at DESC, id DESC LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, queryEntries, query.UserID, query.ExpenseID, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.JournalEntry, 0)
+	entryByID := make(map[int64]*entity.JournalEntry)
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var entry entity.JournalEntry
+		expenseID := sql.NullInt64{}
+		createdBy := sql.NullInt64{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Reference,
+			&entry.Kind,
+			&entry.UserID,
+			&expenseID,
+			&entry.Description,
+			&createdBy,
+			&entry.PostedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.ExpenseID = expenseID.Int64
+		entry.CreatedBy = createdBy.Int64
+		entry.Lines = make([]*entity.JournalLine, 0)
+		entries = append(entries, &entry)
+		entryByID[entry.ID] = &entry
+		ids = append(ids, entry.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	queryLines := `
+		SELECT id, entry_id, account, debit_idr, credit_idr FROM journal_lines
+		WHERE entry_id = ANY($1) ORDER BY id ASC
+	`
+
+	lineRows, err := r.db.QueryContext(ctx, queryLines, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var line entity.JournalLine
+		err := lineRows.Scan(&line.ID, &line.EntryID, &line.Account, &line.DebitIDR, &line.CreditIDR)
+		if err != nil {
+			return nil, err
+		}
+		entry := entryByID[line.EntryID]
+		entry.Lines = append(entry.Lines, &line)
+	}
+
+	return entries, lineRows.Err()
+}
+
+// GetAccountBalances totals every account, over the entries of userID only
+// when it is set.
+func (r *ledgerRepository) GetAccountBalances(ctx context.Context, userID int64) ([]*entity.AccountBalance, error) {
+	query := `
+		SELECT l.account, SUM(l.debit_idr), SUM(l.credit_idr)
+		FROM journal_lines l JOIN journal_entries e ON e.id = l.entry_id
+		WHERE ($1 = 0 OR e.user_id = $1)
+		GROUP BY l.account ORDER BY l.account
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make([]*entity.AccountBalance, 0)
+	for rows.Next() {
+		balance := entity.AccountBalance{UserID: userID}
+		err := rows.Scan(&balance.Account, &balance.DebitIDR, &balance.CreditIDR)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, &balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// GetEmployeeBalances totals the accounts per employee, only account when it
+// is set.
+func (r *ledgerRepository) GetEmployeeBalances(ctx context.Context, account string) ([]*entity.AccountBalance, error) {
+	query := `
+		SELECT e.user_id, l.account, SUM(l.debit_idr), SUM(l.credit_idr)
+		FROM journal_lines l JOIN journal_entries e ON e.id = l.entry_id
+		WHERE ($1 = '' OR l.account = $1)
+		GROUP BY e.user_id, l.account ORDER BY e.user_id, l.account
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make([]*entity.AccountBalance, 0)
+	for rows.Next() {
+		var balance entity.AccountBalance
+		err := rows.Scan(&balance.UserID, &balance.Account, &balance.DebitIDR, &balance.CreditIDR)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, &balance)
+	}
+
+	return balances, rows.Err()
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+)
+
+type notificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *notificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) WriteNotifications(ctx context.Context, notifications []*entity.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, expense_id, comment_id, type, message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, notification := range notifications {
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			notification.UserID,
+			notification.ExpenseID,
+			sql.NullInt64{Int64: notification.CommentID, Valid: notification.CommentID != 0},
+			notification.Type,
+			notification.Message,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *notificationRepository) GetNotifications(ctx context.Context, userID int64, unreadOnly bool) ([]*entity.Notification, error) {
+	query := `
+		SELECT id, user_id, expense_id, comment_id, type, message, read_at, created_at
+		FROM notifications WHERE user_id = $1
+	`
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC LIMIT 100"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*entity.Notification, 0)
+	for rows.Next() {
+		var notification entity.Notification
+		commentID := sql.NullInt64{}
+		readAt := sql.NullTime{}
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.ExpenseID,
+			&commentID,
+			&notification.Type,
+			&notification.Message,
+			&readAt,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notification.CommentID = commentID.Int64
+		if readAt.Valid {
+			notification.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *notificationRepository) MarkNotificationRead(ctx context.Context, userID int64, notificationID int64) error {
+	query := `
+		UPDATE notifications SET read_at = $1 WHERE id = $2 AND user_id = $3 AND read_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), notificationID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("notification not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *outboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// ClaimOutboxMessages picks pending messages that are due and pushes their
+// next attempt past the lease, so concurrent relays do not publish them twice.
+func (r *outboxRepository) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]*entity.OutboxMessage, error) {
+	query := `
+		UPDATE outbox SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY id ASC
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, aggregate_id, event_type, payload, status, attempts, last_error, next_attempt_at, created_at
+	`
+
+	now := time.Now()
+	rows, err := r.db.QueryContext(ctx, query, now.Add(lease), util.OUTBOX_PENDING, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*entity.OutboxMessage, 0)
+	for rows.Next() {
+		var message entity.OutboxMessage
+		lastError := sql.NullString{}
+		err := rows.Scan(
+			&message.ID,
+			&message.AggregateID,
+			&message.EventType,
+			&message.Payload,
+			&message.Status,
+			&message.Attempts,
+			&lastError,
+			&message.NextAttemptAt,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		message.LastError = lastError.String
+		messages = append(messages, &message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *outboxRepository) MarkOutboxPublished(ctx context.Context, id int64) error {
+	query := `
+		UPDATE outbox SET status = $1, published_at = $2 WHERE id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, util.OUTBOX_PUBLISHED, time.Now(), id)
+	return err
+}
+
+func (r *outboxRepository) UpdateOutboxAttempt(ctx context.Context, message *entity.OutboxMessage) error {
+	query := `
+		UPDATE outbox SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4 WHERE id = $5
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		message.Status,
+		message.Attempts,
+		message.LastError,
+		message.NextAttemptAt,
+		message.ID,
+	)
+	return err
+}
+
+func (r *outboxRepository) GetOutboxStats(ctx context.Context) (*entity.OutboxStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = $1),
+			COUNT(*) FILTER (WHERE status = $2),
+			COUNT(*) FILTER (WHERE status = $3),
+			MIN(created_at) FILTER (WHERE status = $1)
+		FROM outbox
+	`
+
+	var stats entity.OutboxStats
+	oldestPendingAt := sql.NullTime{}
+	err := r.db.QueryRowContext(ctx, query, util.OUTBOX_PENDING, util.OUTBOX_FAILED, util.OUTBOX_PUBLISHED).Scan(
+		&stats.Pending,
+		&stats.Failed,
+		&stats.Published,
+		&oldestPendingAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if oldestPendingAt.Valid {
+		stats.OldestPendingAt = &oldestPendingAt.Time
+	}
+
+	return &stats, nil
+}
+
+func writeOutboxMessages(ctx context.Context, tx *sql.Tx, messages []*entity.OutboxMessage) error {
+	query := `
+		INSERT INTO outbox (aggregate_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+
+	now := time.Now()
+	for _, message := range messages {
+		_, err := tx.ExecContext(
+			ctx,
+			query,
+			message.AggregateID,
+			message.EventType,
+			string(message.Payload),
+			util.OUTBOX_PENDING,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+const paymentColumns = `id, expense_id, external_id, amount_idr, status, attempts, provider, provider_payment_id, last_error, raw_response, batch_item_id, reversal_of, created_at, sent_at, completed_at, updated_at`
+
+type paymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) *paymentRepository {
+	return &paymentRepository{db: db}
+}
+
+// StartPaymentAttempt records a payment attempt for payment.ExternalID. A
+// repeated attempt on an unfinished payment only bumps the attempt counter; a
+// payment that already succeeded, or that the provider accepted and is still
+// processing, is returned unchanged.
+func (r *paymentRepository) StartPaymentAttempt(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	query := `
+		INSERT INTO payments (expense_id, external_id, amount_idr, status, attempts, reversal_of, created_at, sent_at, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $7, $5, $5, $5)
+		ON CONFLICT (external_id) DO UPDATE
+		SET attempts = CASE WHEN payments.status = $6 OR (payments.status = $4 AND payments.provider_payment_id IS NOT NULL)
+				THEN payments.attempts ELSE payments.attempts + 1 END,
+			status = CASE WHEN payments.status = $6 OR (payments.status = $4 AND payments.provider_payment_id IS NOT NULL)
+				THEN payments.status ELSE EXCLUDED.status END,
+			sent_at = CASE WHEN payments.status = $6 OR (payments.status = $4 AND payments.provider_payment_id IS NOT NULL)
+				THEN payments.sent_at ELSE EXCLUDED.sent_at END,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + paymentColumns
+
+	row := r.db.QueryRowContext(
+		ctx,
+		query,
+		payment.ExpenseID,
+		payment.ExternalID,
+		payment.AmountIDR,
+		util.PAYMENT_PROCESSING,
+		time.Now(),
+		util.PAYMENT_SUCCEEDED,
+		sql.NullInt64{Int64: payment.ReversalOf, Valid: payment.ReversalOf != 0},
+	)
+	return scanPayment(row)
+}
+
+func (r *paymentRepository) UpdatePayment(ctx context.Context, payment *entity.Payment) error {
+	query := `
+		UPDATE payments
+		SET status = $1, provider = $2, provider_payment_id = $3, last_error = $4, raw_response = $5, completed_at = $6, updated_at = $7
+		WHERE id = $8
+	`
+
+	completedAt := sql.NullTime{}
+	if payment.CompletedAt != nil {
+		completedAt = sql.NullTime{Time: *payment.CompletedAt, Valid: true}
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		payment.Status,
+		sql.NullString{String: payment.Provider, Valid: payment.Provider != ""},
+		sql.NullString{String: payment.ProviderPaymentID, Valid: payment.ProviderPaymentID != ""},
+		sql.NullString{String: payment.LastError, Valid: payment.LastError != ""},
+		sql.NullString{String: payment.RawResponse, Valid: payment.RawResponse != ""},
+		completedAt,
+		time.Now(),
+		payment.ID,
+	)
+	return err
+}
+
+func (r *paymentRepository) GetPaymentsByExpenseID(ctx context.Context, expenseID int64) ([]*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE expense_id = $1 ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]*entity.Payment, 0)
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (r *paymentRepository) GetPaymentByExternalID(ctx context.Context, externalID string) (*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE external_id = $1`
+
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, externalID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, util.ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPayment(row rowScanner) (*entity.Payment, error) {
+	var payment entity.Payment
+	provider := sql.NullString{}
+	providerPaymentID := sql.NullString{}
+	lastError := sql.NullString{}
+	rawResponse := sql.NullString{}
+	batchItemID := sql.NullInt64{}
+	reversalOf := sql.NullInt64{}
+	sentAt := sql.NullTime{}
+	completedAt := sql.NullTime{}
+	err := row.Scan(
+		&payment.ID,
+		&payment.ExpenseID,
+		&payment.ExternalID,
+		&payment.AmountIDR,
+		&payment.Status,
+		&payment.Attempts,
+		&provider,
+		&providerPaymentID,
+		&lastError,
+		&rawResponse,
+		&batchItemID,
+		&reversalOf,
+		&payment.CreatedAt,
+		&sentAt,
+		&completedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	payment.Provider = provider.String
+	payment.ProviderPaymentID = providerPaymentID.String
+	payment.LastError = lastError.String
+	payment.RawResponse = rawResponse.String
+	payment.BatchItemID = batchItemID.Int64
+	payment.ReversalOf = reversalOf.Int64
+	if sentAt.Valid {
+		payment.SentAt = &sentAt.Time
+	}
+	if completedAt.Valid {
+		payment.CompletedAt = &completedAt.Time
+	}
+
+	return &payment, nil
+}
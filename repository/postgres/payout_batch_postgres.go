@@ -0,0 +1,386 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+const payoutBatchColumns = `id, status, channel, total_amount_idr, created_by, created_at, completed_at, updated_at`
+
+const payoutBatchItemColumns = `i.id, i.batch_id, i.user_id, u.name, u.email, u.payout_method, i.external_id, i.amount_idr, i.status, i.provider, i.provider_payment_id, i.last_error, i.raw_response, i.created_at, i.completed_at, i.updated_at`
+
+type payoutBatchRepository struct {
+	db *sql.DB
+}
+
+func NewPayoutBatchRepository(db *sql.DB) *payoutBatchRepository {
+	return &payoutBatchRepository{db: db}
+}
+
+// CreatePayoutBatch locks the payable expenses that have no payment in flight,
+// groups them into items through build and writes the batch, its items and one
+// payment per expense in a single transaction. Expenses locked by a concurrent
+// run are skipped, so an expense never ends up in two batches.
+func (r *payoutBatchRepository) CreatePayoutBatch(ctx context.Context, batch *entity.PayoutBatch, build entity.PayoutBatchBuilder) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queryExpenses := `
+		SELECT e.id, e.user_id, e.amount_idr, e.approved_amount_idr, e.status, e.version
+		FROM expenses e
+		WHERE e.status IN ($1, $2, $3)
+		AND NOT EXISTS (
+			SELECT 1 FROM payments p WHERE p.expense_id = e.id AND p.status IN ($4, $5)
+		)
+		ORDER BY e.user_id, e.id
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(
+		ctx,
+		queryExpenses,
+		util.EXPENSE_APPROVED,
+		util.EXPENSE_AUTO_APPROVED,
+		util.EXPENSE_PAYMENT_FAILED,
+		util.PAYMENT_PROCESSING,
+		util.PAYMENT_SUCCEEDED,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	expenses := make([]*entity.Expense, 0)
+	for rows.Next() {
+		var expense entity.Expense
+		approvedAmount := sql.NullFloat64{}
+		err = rows.Scan(&expense.ID, &expense.UserID, &expense.AmountIDR, &approvedAmount, &expense.Status, &expense.Version)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		expense.ApprovedAmountIDR = approvedAmount.Float64
+		expenses = append(expenses, &expense)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(expenses) == 0 {
+		return 0, util.ErrNoPayableExpenses
+	}
+
+	items, err := build(expenses)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	batch.TotalAmountIDR = 0
+	for _, item := range items {
+		batch.TotalAmountIDR += item.AmountIDR
+	}
+
+	queryBatch := `
+		INSERT INTO payout_batches (status, channel, total_amount_idr, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5) RETURNING id
+	`
+
+	var batchID int64
+	err = tx.QueryRowContext(
+		ctx,
+		queryBatch,
+		batch.Status,
+		batch.Channel,
+		batch.TotalAmountIDR,
+		sql.NullInt64{Int64: batch.CreatedBy, Valid: batch.CreatedBy != 0},
+		now,
+	).Scan(&batchID)
+	if err != nil {
+		return 0, err
+	}
+
+	queryItem := `
+		INSERT INTO payout_batch_items (batch_id, user_id, external_id, amount_idr, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id
+	`
+
+	// A payment that failed before is reused for the new attempt, the expense
+	// keeps one payment row.
+	queryPayment := `
+		INSERT INTO payments (expense_id, external_id, amount_idr, status, attempts, batch_item_id, created_at, sent_at, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $5, $6, $6, $6)
+		ON CONFLICT (external_id) DO UPDATE
+		SET amount_idr = EXCLUDED.amount_idr,
+			status = EXCLUDED.status,
+			attempts = payments.attempts + 1,
+			batch_item_id = EXCLUDED.batch_item_id,
+			provider = NULL,
+			provider_payment_id = NULL,
+			last_error = NULL,
+			completed_at = NULL,
+			sent_at = EXCLUDED.sent_at,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	for _, item := range items {
+		err = tx.QueryRowContext(ctx, queryItem, batchID, item.UserID, item.ExternalID, item.AmountIDR, util.PAYMENT_PROCESSING, now).Scan(&item.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, payment := range item.Payments {
+			_, err = tx.ExecContext(ctx, queryPayment, payment.ExpenseID, payment.ExternalID, payment.AmountIDR, util.PAYMENT_PROCESSING, item.ID, now)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return batchID, nil
+}
+
+func (r *payoutBatchRepository) GetPayoutBatch(ctx context.Context, id int64) (*entity.PayoutBatch, error) {
+	query := `SELECT ` + payoutBatchColumns + ` FROM payout_batches WHERE id = $1`
+
+	batch, err := scanPayoutBatch(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, util.ErrPayoutBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queryItems := `
+		SELECT ` + payoutBatchItemColumns + `
+		FROM payout_batch_items i JOIN users u ON u.id = i.user_id
+		WHERE i.batch_id = $1 ORDER BY i.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, queryItems, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch.Items = make([]*entity.PayoutBatchItem, 0)
+	for rows.Next() {
+		item, err := scanPayoutBatchItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		batch.Items = append(batch.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, item := range batch.Items {
+		item.Payments, err = r.getItemPayments(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return batch, nil
+}
+
+func (r *payoutBatchRepository) GetPayoutBatches(ctx context.Context, limit int) ([]*entity.PayoutBatch, error) {
+	query := `SELECT ` + payoutBatchColumns + ` FROM payout_batches ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]*entity.PayoutBatch, 0)
+	for rows.Next() {
+		batch, err := scanPayoutBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, rows.Err()
+}
+
+func (r *payoutBatchRepository) GetPayoutBatchItemByExternalID(ctx context.Context, externalID string) (*entity.PayoutBatchItem, error) {
+	query := `
+		SELECT ` + payoutBatchItemColumns + `
+		FROM payout_batch_items i JOIN users u ON u.id = i.user_id
+		WHERE i.external_id = $1
+	`
+
+	item, err := scanPayoutBatchItem(r.db.QueryRowContext(ctx, query, externalID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, util.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item.Payments, err = r.getItemPayments(ctx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// UpdatePayoutBatchItem writes the result of the transfer to the item and to
+// the payments of all expenses it pays.
+func (r *payoutBatchRepository) UpdatePayoutBatchItem(ctx context.Context, item *entity.PayoutBatchItem) error {
+	queryItem := `
+		UPDATE payout_batch_items
+		SET status = $1, provider = $2, provider_payment_id = $3, last_error = $4, raw_response = $5, completed_at = $6, updated_at = $7
+		WHERE id = $8
+	`
+
+	queryPayments := `
+		UPDATE payments
+		SET status = $1, provider = $2, provider_payment_id = $3, last_error = $4, raw_response = $5, completed_at = $6, updated_at = $7
+		WHERE batch_item_id = $8
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	completedAt := sql.NullTime{}
+	if item.CompletedAt != nil {
+		completedAt = sql.NullTime{Time: *item.CompletedAt, Valid: true}
+	}
+
+	for _, query := range []string{queryItem, queryPayments} {
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			item.Status,
+			sql.NullString{String: item.Provider, Valid: item.Provider != ""},
+			sql.NullString{String: item.ProviderPaymentID, Valid: item.ProviderPaymentID != ""},
+			sql.NullString{String: item.LastError, Valid: item.LastError != ""},
+			sql.NullString{String: item.RawResponse, Valid: item.RawResponse != ""},
+			completedAt,
+			time.Now(),
+			item.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *payoutBatchRepository) UpdatePayoutBatchStatus(ctx context.Context, id int64, status int32) error {
+	query := `
+		UPDATE payout_batches SET status = $1, completed_at = $2, updated_at = $3 WHERE id = $4
+	`
+
+	now := time.Now()
+	completedAt := sql.NullTime{Time: now, Valid: status != int32(util.PAYOUT_BATCH_PROCESSING)}
+	_, err := r.db.ExecContext(ctx, query, status, completedAt, now, id)
+	return err
+}
+
+func (r *payoutBatchRepository) getItemPayments(ctx context.Context, itemID int64) ([]*entity.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE batch_item_id = $1 ORDER BY expense_id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]*entity.Payment, 0)
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func scanPayoutBatch(row rowScanner) (*entity.PayoutBatch, error) {
+	var batch entity.PayoutBatch
+	createdBy := sql.NullInt64{}
+	completedAt := sql.NullTime{}
+	err := row.Scan(
+		&batch.ID,
+		&batch.Status,
+		&batch.Channel,
+		&batch.TotalAmountIDR,
+		&createdBy,
+		&batch.CreatedAt,
+		&completedAt,
+		&batch.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	batch.CreatedBy = createdBy.Int64
+	if completedAt.Valid {
+		batch.CompletedAt = &completedAt.Time
+	}
+
+	return &batch, nil
+}
+
+func scanPayoutBatchItem(row rowScanner) (*entity.PayoutBatchItem, error) {
+	var item entity.PayoutBatchItem
+	provider := sql.NullString{}
+	providerPaymentID := sql.NullString{}
+	lastError := sql.NullString{}
+	rawResponse := sql.NullString{}
+	completedAt := sql.NullTime{}
+	err := row.Scan(
+		&item.ID,
+		&item.BatchID,
+		&item.UserID,
+		&item.UserName,
+		&item.UserEmail,
+		&item.PayoutMethod,
+		&item.ExternalID,
+		&item.AmountIDR,
+		&item.Status,
+		&provider,
+		&providerPaymentID,
+		&lastError,
+		&rawResponse,
+		&item.CreatedAt,
+		&completedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	item.Provider = provider.String
+	item.ProviderPaymentID = providerPaymentID.String
+	item.LastError = lastError.String
+	item.RawResponse = rawResponse.String
+	if completedAt.Valid {
+		item.CompletedAt = &completedAt.Time
+	}
+
+	return &item, nil
+}
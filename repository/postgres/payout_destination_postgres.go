@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+type payoutDestinationRepository struct {
+	db  *sql.DB
+	key []byte
+}
+
+// NewPayoutDestinationRepository encrypts account numbers and e-wallet IDs
+// with key before they are written and decrypts them on read.
+func NewPayoutDestinationRepository(db *sql.DB, key []byte) *payoutDestinationRepository {
+	return &payoutDestinationRepository{db: db, key: key}
+}
+
+func (r *payoutDestinationRepository) GetPayoutDestinations(ctx context.Context, userID int64) ([]*entity.PayoutDestination, error) {
+	query := `
+		SELECT id, user_id, method, bank_code, account_number_encrypted, account_holder_name, ewallet_id_encrypted, created_at, updated_at
+		FROM payout_destinations WHERE user_id = $1 ORDER BY method ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	destinations := make([]*entity.PayoutDestination, 0)
+	for rows.Next() {
+		var destination entity.PayoutDestination
+		bankCode := sql.NullString{}
+		accountNumber := sql.NullString{}
+		accountHolderName := sql.NullString{}
+		eWalletID := sql.NullString{}
+		err = rows.Scan(
+			&destination.ID,
+			&destination.UserID,
+			&destination.Method,
+			&bankCode,
+			&accountNumber,
+			&accountHolderName,
+			&eWalletID,
+			&destination.CreatedAt,
+			&destination.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		destination.BankCode = bankCode.String
+		destination.AccountHolderName = accountHolderName.String
+		destination.AccountNumber, err = r.decrypt(accountNumber)
+		if err != nil {
+			return nil, err
+		}
+		destination.EWalletID, err = r.decrypt(eWalletID)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, &destination)
+	}
+
+	return destinations, rows.Err()
+}
+
+func (r *payoutDestinationRepository) UpsertPayoutDestination(ctx context.Context, destination *entity.PayoutDestination) error {
+	query := `
+		INSERT INTO payout_destinations (user_id, method, bank_code, account_number_encrypted, account_holder_name, ewallet_id_encrypted, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (user_id, method) DO UPDATE
+		SET bank_code = EXCLUDED.bank_code,
+			account_number_encrypted = EXCLUDED.account_number_encrypted,
+			account_holder_name = EXCLUDED.account_holder_name,
+			ewallet_id_encrypted = EXCLUDED.ewallet_id_encrypted,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	accountNumber, err := r.encrypt(destination.AccountNumber)
+	if err != nil {
+		return err
+	}
+	eWalletID, err := r.encrypt(destination.EWalletID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		query,
+		destination.UserID,
+		destination.Method,
+		sql.NullString{String: destination.BankCode, Valid: destination.BankCode != ""},
+		accountNumber,
+		sql.NullString{String: destination.AccountHolderName, Valid: destination.AccountHolderName != ""},
+		eWalletID,
+		time.Now(),
+	)
+	return err
+}
+
+func (r *payoutDestinationRepository) encrypt(value string) (sql.NullString, error) {
+	if value == "" {
+		return sql.NullString{}, nil
+	}
+
+	encrypted, err := util.Encrypt(r.key, value)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: encrypted, Valid: true}, nil
+}
+
+func (r *payoutDestinationRepository) decrypt(value sql.NullString) (string, error) {
+	if !value.Valid {
+		return "", nil
+	}
+	return util.Decrypt(r.key, value.String)
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/lib/pq"
+)
+
+const reconciliationColumns = `id, provider, source, period_from, period_to, matched_count, missing_count, extra_count, amount_mismatch_count, expected_total_idr, statement_total_idr, created_by, created_at`
+
+const reconciliationItemColumns = `id, reconciliation_id, kind, external_id, provider_payment_id, expected_amount_idr, actual_amount_idr, note`
+
+// Payments of a payout batch are left out, the provider only knows the batch
+// transfer. Transfers made from a batch file have no provider and are
+// reconciled as provider "file".
+const payoutRecordsQuery = `
+	SELECT external_id, COALESCE(provider, $1) AS provider, provider_payment_id, amount_idr, status, completed_at
+	FROM payments WHERE batch_item_id IS NULL
+	UNION ALL
+	SELECT external_id, COALESCE(provider, $1) AS provider, provider_payment_id, amount_idr, status, completed_at
+	FROM payout_batch_items
+`
+
+type reconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) *reconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+// GetSettledPayouts returns the succeeded payouts of provider completed from
+// from up to, but not including, to.
+func (r *reconciliationRepository) GetSettledPayouts(ctx context.Context, provider string, from, to time.Time) ([]*entity.PayoutRecord, error) {
+	query := `
+		SELECT * FROM (` + payoutRecordsQuery + `) p
+		WHERE p.provider = $2 AND p.status = $3 AND p.completed_at >= $4 AND p.completed_at < $5
+		ORDER BY p.completed_at ASC, p.external_id ASC
+	`
+
+	return r.queryPayoutRecords(ctx, query, util.PAYOUT_CHANNEL_FILE, provider, util.PAYMENT_SUCCEEDED, from, to)
+}
+
+// GetPayoutsByExternalIDs returns the payouts with the given external IDs in
+// any status.
+func (r *reconciliationRepository) GetPayoutsByExternalIDs(ctx context.Context, externalIDs []string) ([]*entity.PayoutRecord, error) {
+	query := `SELECT * FROM (` + payoutRecordsQuery + `) p WHERE p.external_id = ANY($2)`
+
+	return r.queryPayoutRecords(ctx, query, util.PAYOUT_CHANNEL_FILE, pq.Array(externalIDs))
+}
+
+func (r *reconciliationRepository) queryPayoutRecords(ctx context.Context, query string, args ...interface{}) ([]*entity.PayoutRecord, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*entity.PayoutRecord, 0)
+	for rows.Next() {
+		var record entity.PayoutRecord
+		providerPaymentID := sql.NullString{}
+		completedAt := sql.NullTime{}
+		err := rows.Scan(
+			&record.ExternalID,
+			&record.Provider,
+			&providerPaymentID,
+			&record.AmountIDR,
+			&record.Status,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.ProviderPaymentID = providerPaymentID.String
+		if completedAt.Valid {
+			record.CompletedAt = &completedAt.Time
+		}
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
+// CreateReconciliation writes the reconciliation and its items in a single
+// transaction.
+func (r *reconciliationRepository) CreateReconciliation(ctx context.Context, reconciliation *entity.Reconciliation) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reconciliations (provider, source, period_from, period_to, matched_count, missing_count, extra_count, amount_mismatch_count, expected_total_idr, statement_total_idr, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id
+	`
+
+	var id int64
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		reconciliation.Provider,
+		reconciliation.Source,
+		reconciliation.PeriodFrom,
+		reconciliation.PeriodTo,
+		reconciliation.MatchedCount,
+		reconciliation.MissingCount,
+		reconciliation.ExtraCount,
+		reconciliation.AmountMismatchCount,
+		reconciliation.ExpectedTotalIDR,
+		reconciliation.StatementTotalIDR,
+		sql.NullInt64{Int64: reconciliation.CreatedBy, Valid: reconciliation.CreatedBy != 0},
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	queryItem := `
+		INSERT INTO reconciliation_items (reconciliation_id, kind, external_id, provider_payment_id, expected_amount_idr, actual_amount_idr, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for _, item := range reconciliation.Items {
+		_, err = tx.ExecContext(
+			ctx,
+			queryItem,
+			id,
+			item.Kind,
+			item.ExternalID,
+			sql.NullString{String: item.ProviderPaymentID, Valid: item.ProviderPaymentID != ""},
+			sql.NullFloat64{Float64: item.ExpectedAmountIDR, Valid: item.Kind != util.RECONCILIATION_EXTRA},
+			sql.NullFloat64{Float64: item.ActualAmountIDR, Valid: item.Kind != util.RECONCILIATION_MISSING},
+			sql.NullString{String: item.Note, Valid: item.Note != ""},
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *reconciliationRepository) GetReconciliation(ctx context.Context, id int64) (*entity.Reconciliation, error) {
+	query := `SELECT ` + reconciliationColumns + ` FROM reconciliations WHERE id = $1`
+
+	reconciliation, err := scanReconciliation(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, util.ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queryItems := `SELECT ` + reconciliationItemColumns + ` FROM reconciliation_items WHERE reconciliation_id = $1 ORDER BY id ASC`
+
+	rows, err := r.db.QueryContext(ctx, queryItems, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reconciliation.Items = make([]*entity.ReconciliationItem, 0)
+	for rows.Next() {
+		var item entity.ReconciliationItem
+		providerPaymentID := sql.NullString{}
+		expectedAmount := sql.NullFloat64{}
+		actualAmount := sql.NullFloat64{}
+		note := sql.NullString{}
+		err := rows.Scan(
+			&item.ID,
+			&item.ReconciliationID,
+			&item.Kind,
+			&item.ExternalID,
+			&providerPaymentID,
+			&expectedAmount,
+			&actualAmount,
+			&note,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.ProviderPaymentID = providerPaymentID.String
+		item.ExpectedAmountIDR = expectedAmount.Float64
+		item.ActualAmountIDR = actualAmount.Float64
+		item.Note = note.String
+		reconciliation.Items = append(reconciliation.Items, &item)
+	}
+
+	return reconciliation, rows.Err()
+}
+
+func (r *reconciliationRepository) GetReconciliations(ctx context.Context, limit int) ([]*entity.Reconciliation, error) {
+	query := `SELECT ` + reconciliationColumns + ` FROM reconciliations ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reconciliations := make([]*entity.Reconciliation, 0)
+	for rows.Next() {
+		reconciliation, err := scanReconciliation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reconciliations = append(reconciliations, reconciliation)
+	}
+
+	return reconciliations, rows.Err()
+}
+
+func scanReconciliation(row rowScanner) (*entity.Reconciliation, error) {
+	var reconciliation entity.Reconciliation
+	createdBy := sql.NullInt64{}
+	err := row.Scan(
+		&reconciliation.ID,
+		&reconciliation.Provider,
+		&reconciliation.Source,
+		&reconciliation.PeriodFrom,
+		&reconciliation.PeriodTo,
+		&reconciliation.MatchedCount,
+		&reconciliation.MissingCount,
+		&reconciliation.ExtraCount,
+		&reconciliation.AmountMismatchCount,
+		&reconciliation.ExpectedTotalIDR,
+		&reconciliation.StatementTotalIDR,
+		&createdBy,
+		&reconciliation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation.CreatedBy = createdBy.Int64
+
+	return &reconciliation, nil
+}
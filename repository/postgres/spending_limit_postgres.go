@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+)
+
+type spendingLimitRepository struct {
+	db *sql.DB
+}
+
+func NewSpendingLimitRepository(db *sql.DB) *spendingLimitRepository {
+	return &spendingLimitRepository{db: db}
+}
+
+func (r *spendingLimitRepository) GetSpendingLimits(ctx context.Context) ([]*entity.SpendingLimit, error) {
+	query := `
+		SELECT id, COALESCE(user_id, 0), COALESCE(role, 0), amount_idr, period, action, updated_by, updated_at
+		FROM spending_limits
+		ORDER BY user_id NULLS FIRST, role
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make([]*entity.SpendingLimit, 0)
+	for rows.Next() {
+		var limit entity.SpendingLimit
+		err := rows.Scan(
+			&limit.ID,
+			&limit.UserID,
+			&limit.Role,
+			&limit.AmountIDR,
+			&limit.Period,
+			&limit.Action,
+			&limit.UpdatedBy,
+			&limit.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		limits = append(limits, &limit)
+	}
+
+	return limits, rows.Err()
+}
+
+// querySpendingLimit selects the limit of the user, or else of the role, with
+// the claims of the user in the period covering $3. The expense $6 is left out
+// of the claims, so a revision is not counted twice.
+const querySpendingLimit = `
+	SELECT l.id, COALESCE(l.user_id, 0), COALESCE(l.role, 0), l.amount_idr, l.period, l.action, l.updated_by, l.updated_at,
+		w.window_start, w.window_end, c.claimed
+	FROM spending_limits l
+	CROSS JOIN LATERAL (
+		SELECT date_trunc(l.period, $3::TIMESTAMP) AS window_start,
+			date_trunc(l.period, $3::TIMESTAMP) + ('1 ' || l.period)::INTERVAL AS window_end
+	) w
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(SUM(COALESCE(NULLIF(e.approved_amount_idr, 0), e.amount_idr)), 0) AS claimed
+		FROM expenses e
+		WHERE e.user_id = $1 AND e.status NOT IN ($4, $5) AND e.id <> $6
+		AND e.submitted_at >= w.window_start AND e.submitted_at < w.window_end
+	) c
+	WHERE l.user_id = $1 OR l.role = $2
+	ORDER BY l.user_id NULLS LAST
+	LIMIT 1
+`
+
+// GetSpendingLimit returns the limit of the user, or else of the role, with the
+// claims of the user in the period covering at.
+func (r *spendingLimitRepository) GetSpendingLimit(ctx context.Context, userID int64, role int32, at time.Time) (*entity.SpendingLimit, error) {
+	return scanSpendingLimit(r.db.QueryRowContext(ctx, querySpendingLimit, userID, role, at, util.EXPENSE_REJECTED, util.EXPENSE_REVERSED, 0))
+}
+
+// checkSpendingLimit locks the submitter of the expense until the transaction
+// ends and hands their spending limit, with what they claimed in its period
+// besides the expense, to check.
+func checkSpendingLimit(ctx context.Context, tx *sql.Tx, expense *entity.Expense, at time.Time, check entity.SpendingLimitCheck) (bool, error) {
+	if check == nil {
+		return false, nil
+	}
+
+	var role int32
+	err := tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, expense.UserID).Scan(&role)
+	if err != nil {
+		return false, err
+	}
+
+	limit, err := scanSpendingLimit(tx.QueryRowContext(ctx, querySpendingLimit, expense.UserID, role, at, util.EXPENSE_REJECTED, util.EXPENSE_REVERSED, expense.ID))
+	if errors.Is(err, util.ErrSpendingLimitNotFound) {
+		return check(nil)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return check(limit)
+}
+
+func scanSpendingLimit(row rowScanner) (*entity.SpendingLimit, error) {
+	var limit entity.SpendingLimit
+	err := row.Scan(
+		&limit.ID,
+		&limit.UserID,
+		&limit.Role,
+		&limit.AmountIDR,
+		&limit.Period,
+		&limit.Action,
+		&limit.UpdatedBy,
+		&limit.UpdatedAt,
+		&limit.WindowStart,
+		&limit.WindowEnd,
+		&limit.ClaimedIDR,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, util.ErrSpendingLimitNotFound
+		}
+		return nil, err
+	}
+
+	return &limit, nil
+}
+
+// UpsertSpendingLimit sets the limit of the user, or of the role when the user
+// is not set.
+func (r *spendingLimitRepository) UpsertSpendingLimit(ctx context.Context, limit *entity.SpendingLimit) (int64, error) {
+	conflict := "(role) WHERE role IS NOT NULL"
+	if limit.UserID != 0 {
+		conflict = "(user_id) WHERE user_id IS NOT NULL"
+	}
+	query := `
+		INSERT INTO spending_limits (user_id, role, amount_idr, period, action, updated_by, updated_at)
+		VALUES (NULLIF($1, 0), NULLIF($2, 0), $3, $4, $5, $6, $7)
+		ON CONFLICT ` + conflict + ` DO UPDATE
+		SET amount_idr = EXCLUDED.amount_idr,
+			period = EXCLUDED.period,
+			action = EXCLUDED.action,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		limit.UserID,
+		limit.Role,
+		limit.AmountIDR,
+		limit.Period,
+		limit.Action,
+		limit.UpdatedBy,
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/budsx/expenses-management/entity"
+	"github.com/lib/pq"
 )
 
 type userRepository struct {
@@ -40,3 +41,80 @@ func (r *userRepository) GetUserWithPassword(ctx context.Context, email string)
 
 	return &user, nil
 }
+
+func (r *userRepository) GetUsersByEmails(ctx context.Context, emails []string) ([]*entity.User, error) {
+	query := `SELECT id, email, name, role, created_at FROM users WHERE LOWER(email) = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.Role,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *userRepository) GetUserByID(ctx context.Context, id int64) (*entity.User, error) {
+	query := `SELECT id, email, name, role, payout_method, COALESCE(department, ''), created_at FROM users WHERE id = $1`
+
+	var user entity.User
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.Role,
+		&user.PayoutMethod,
+		&user.Department,
+		&user.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) UpdatePayoutMethod(ctx context.Context, id int64, payoutMethod string) error {
+	query := `UPDATE users SET payout_method = $1 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, payoutMethod, id)
+	return err
+}
+
+func (r *userRepository) UpdateDepartment(ctx context.Context, id int64, department string) error {
+	query := `UPDATE users SET department = NULLIF($1, '') WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, department, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -5,17 +5,55 @@ import (
 )
 
 type Repository struct {
-	PaymentProcessor   iface.PaymentProcessor
-	UserRepository     iface.UserRepository
-	ExpensesRepository iface.ExpensesRepository
-	RabbitMQClient     iface.RabbitMQClient
+	PaymentProcessor            iface.PaymentProcessor
+	UserRepository              iface.UserRepository
+	PayoutDestinationRepository iface.PayoutDestinationRepository
+	ExpensesRepository          iface.ExpensesRepository
+	CommentRepository           iface.CommentRepository
+	NotificationRepository      iface.NotificationRepository
+	OutboxRepository            iface.OutboxRepository
+	PaymentRepository           iface.PaymentRepository
+	PayoutBatchRepository       iface.PayoutBatchRepository
+	ReconciliationRepository    iface.ReconciliationRepository
+	LedgerRepository            iface.LedgerRepository
+	AccountingExportRepository  iface.AccountingExportRepository
+	BudgetRepository            iface.BudgetRepository
+	SpendingLimitRepository     iface.SpendingLimitRepository
+	MessageBroker               iface.MessageBroker
 }
 
-func NewRepository(paymentProcessor iface.PaymentProcessor, userRepository iface.UserRepository, expensesRepository iface.ExpensesRepository, rabbitmqClient iface.RabbitMQClient) *Repository {
+func NewRepository(
+	paymentProcessor iface.PaymentProcessor,
+	userRepository iface.UserRepository,
+	payoutDestinationRepository iface.PayoutDestinationRepository,
+	expensesRepository iface.ExpensesRepository,
+	commentRepository iface.CommentRepository,
+	notificationRepository iface.NotificationRepository,
+	outboxRepository iface.OutboxRepository,
+	paymentRepository iface.PaymentRepository,
+	payoutBatchRepository iface.PayoutBatchRepository,
+	reconciliationRepository iface.ReconciliationRepository,
+	ledgerRepository iface.LedgerRepository,
+	accountingExportRepository iface.AccountingExportRepository,
+	budgetRepository iface.BudgetRepository,
+	spendingLimitRepository iface.SpendingLimitRepository,
+	messageBroker iface.MessageBroker,
+) *Repository {
 	return &Repository{
-		PaymentProcessor:   paymentProcessor,
-		UserRepository:     userRepository,
-		ExpensesRepository: expensesRepository,
-		RabbitMQClient:     rabbitmqClient,
+		PaymentProcessor:            paymentProcessor,
+		UserRepository:              userRepository,
+		PayoutDestinationRepository: payoutDestinationRepository,
+		ExpensesRepository:          expensesRepository,
+		CommentRepository:           commentRepository,
+		NotificationRepository:      notificationRepository,
+		OutboxRepository:            outboxRepository,
+		PaymentRepository:           paymentRepository,
+		PayoutBatchRepository:       payoutBatchRepository,
+		ReconciliationRepository:    reconciliationRepository,
+		LedgerRepository:            ledgerRepository,
+		AccountingExportRepository:  accountingExportRepository,
+		BudgetRepository:            budgetRepository,
+		SpendingLimitRepository:     spendingLimitRepository,
+		MessageBroker:               messageBroker,
 	}
 }
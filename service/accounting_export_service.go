@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+const (
+	defaultAccountingExportLimit = 20
+	maxAccountingExportDays      = 92
+	defaultPayableGLAccount      = "2100"
+)
+
+// CreateAccountingExport exports the expenses approved in the period that were
+// not exported before, each booked on the GL account its category and cost
+// center map to. Nothing is exported while an expense has no account.
+func (s *ExpensesManagementService) CreateAccountingExport(ctx context.Context, req model.AccountingExportRequest) (*model.AccountingExportResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).Info("CreateAccountingExport")
+
+	format := req.Format
+	if format == "" {
+		format = util.ACCOUNTING_EXPORT_CSV
+	}
+	if !util.IsAccountingExportFormat(format) {
+		return nil, fmt.Errorf("%w: format must be csv or journal", util.ErrInvalidExport)
+	}
+
+	from, to, err := parsePeriod(req.From, req.To, maxAccountingExportDays)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", util.ErrInvalidExport, err)
+	}
+
+	mappings, err := s.repo.AccountingExportRepository.GetGLAccountMappings(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get GL account mappings")
+		return nil, fmt.Errorf("failed to get GL account mappings")
+	}
+
+	export := &entity.AccountingExport{
+		Format:     format,
+		PeriodFrom: from,
+		PeriodTo:   to,
+		CreatedBy:  userInfo.ID,
+	}
+	id, err := s.repo.AccountingExportRepository.CreateAccountingExport(ctx, export, func(items []*entity.AccountingExportItem) error {
+		return mapGLAccounts(mappings, items)
+	})
+	if err != nil {
+		if errors.Is(err, util.ErrNothingToExport) || errors.Is(err, util.ErrUnmappedGLAccount) {
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to create accounting export")
+		return nil, fmt.Errorf("failed to create accounting export")
+	}
+	export.ID = id
+	export.CreatedAt = time.Now()
+
+	response := toAccountingExportResponse(export)
+	return &response, nil
+}
+
+func (s *ExpensesManagementService) GetAccountingExports(ctx context.Context, limit int) (*model.AccountingExportListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultAccountingExportLimit
+	}
+
+	exports, err := s.repo.AccountingExportRepository.GetAccountingExports(ctx, limit)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get accounting exports")
+		return nil, fmt.Errorf("failed to get accounting exports")
+	}
+
+	response := &model.AccountingExportListResponse{Exports: make([]model.AccountingExportResponse, 0)}
+	for _, export := range exports {
+		response.Exports = append(response.Exports, toAccountingExportResponse(export))
+	}
+
+	return response, nil
+}
+
+func (s *ExpensesManagementService) GetAccountingExport(ctx context.Context, id int64) (*model.AccountingExportResponse, error) {
+	export, err := s.getAccountingExport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toAccountingExportResponse(export)
+	return &response, nil
+}
+
+// GetAccountingExportFile returns the export in its format. The csv format has
+// a row per expense; the journal format has a balanced entry per expense,
+// debiting its GL account and crediting the payable account.
+func (s *ExpensesManagementService) GetAccountingExportFile(ctx context.Context, id int64) ([]byte, error) {
+	export, err := s.getAccountingExport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	switch export.Format {
+	case util.ACCOUNTING_EXPORT_JOURNAL:
+		writer.Write([]string{"journal_id", "date", "account_code", "description", "debit_idr", "credit_idr"})
+		for _, item := range export.Items {
+			journalID := fmt.Sprintf("EXP-%d", item.ExpenseID)
+			date := item.ApprovedAt.Format(time.DateOnly)
+			description := fmt.Sprintf("%s - %s", item.UserName, item.Description)
+			amount := formatAmountIDR(item.AmountIDR)
+			writer.Write([]string{journalID, date, item.AccountCode, description, amount, "0.00"})
+			writer.Write([]string{journalID, date, s.payableGLCode, description, "0.00", amount})
+		}
+	default:
+		writer.Write([]string{"expense_id", "approved_at", "employee_email", "employee_name", "category", "cost_center", "description", "amount_idr", "gl_account"})
+		for _, item := range export.Items {
+			writer.Write([]string{
+				strconv.FormatInt(item.ExpenseID, 10),
+				item.ApprovedAt.Format(time.DateOnly),
+				item.UserEmail,
+				item.UserName,
+				item.Category,
+				item.CostCenter,
+				item.Description,
+				formatAmountIDR(item.AmountIDR),
+				item.AccountCode,
+			})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		s.logger.WithError(err).Error("failed to write accounting export file")
+		return nil, fmt.Errorf("failed to write accounting export file")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *ExpensesManagementService) GetGLAccountMappings(ctx context.Context) (*model.GLAccountMappingListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	mappings, err := s.repo.AccountingExportRepository.GetGLAccountMappings(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get GL account mappings")
+		return nil, fmt.Errorf("failed to get GL account mappings")
+	}
+
+	response := &model.GLAccountMappingListResponse{
+		PayableAccountCode: s.payableGLCode,
+		Mappings:           make([]model.GLAccountMappingResponse, 0),
+	}
+	for _, mapping := range mappings {
+		response.Mappings = append(response.Mappings, model.GLAccountMappingResponse{
+			Category:    mapping.Category,
+			CostCenter:  mapping.CostCenter,
+			AccountCode: mapping.AccountCode,
+			UpdatedAt:   mapping.UpdatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+// UpdateGLAccountMapping sets the GL account of a category and cost center.
+// Exports made before keep the account they were made with.
+func (s *ExpensesManagementService) UpdateGLAccountMapping(ctx context.Context, req model.GLAccountMappingRequest) (*model.GLAccountMappingListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("request", req).Info("UpdateGLAccountMapping")
+
+	mapping := &entity.GLAccountMapping{
+		Category:    strings.TrimSpace(req.Category),
+		CostCenter:  strings.TrimSpace(req.CostCenter),
+		AccountCode: strings.TrimSpace(req.AccountCode),
+	}
+	if mapping.Category != "" && !util.IsExpenseCategory(mapping.Category) {
+		return nil, fmt.Errorf("%w: category %q is not valid", util.ErrInvalidGLAccountMapping, mapping.Category)
+	}
+	if mapping.AccountCode == "" {
+		return nil, fmt.Errorf("%w: account code is required", util.ErrInvalidGLAccountMapping)
+	}
+
+	err := s.repo.AccountingExportRepository.UpsertGLAccountMapping(ctx, mapping)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to update GL account mapping")
+		return nil, fmt.Errorf("failed to update GL account mapping")
+	}
+
+	return s.GetGLAccountMappings(ctx)
+}
+
+func (s *ExpensesManagementService) getAccountingExport(ctx context.Context, id int64) (*entity.AccountingExport, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	export, err := s.repo.AccountingExportRepository.GetAccountingExport(ctx, id)
+	if err != nil {
+		if errors.Is(err, util.ErrAccountingExportNotFound) {
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to get accounting export")
+		return nil, fmt.Errorf("failed to get accounting export")
+	}
+
+	return export, nil
+}
+
+// mapGLAccounts sets the account code of every item. The most specific mapping
+// wins: category and cost center, then category alone, then cost center alone,
+// then the catch-all.
+func mapGLAccounts(mappings []*entity.GLAccountMapping, items []*entity.AccountingExportItem) error {
+	accounts := make(map[[2]string]string, len(mappings))
+	for _, mapping := range mappings {
+		accounts[[2]string{mapping.Category, mapping.CostCenter}] = mapping.AccountCode
+	}
+
+	unmapped := make(map[string]bool)
+	for _, item := range items {
+		for _, key := range [][2]string{
+			{item.Category, item.CostCenter},
+			{item.Category, ""},
+			{"", item.CostCenter},
+			{"", ""},
+		} {
+			if code, ok := accounts[key]; ok {
+				item.AccountCode = code
+				break
+			}
+		}
+		if item.AccountCode == "" {
+			unmapped[fmt.Sprintf("%s/%s", item.Category, item.CostCenter)] = true
+		}
+	}
+
+	if len(unmapped) > 0 {
+		combinations := make([]string, 0, len(unmapped))
+		for combination := range unmapped {
+			combinations = append(combinations, combination)
+		}
+		sort.Strings(combinations)
+		return fmt.Errorf("%w: map category/cost center %s", util.ErrUnmappedGLAccount, strings.Join(combinations, ", "))
+	}
+	return nil
+}
+
+func formatAmountIDR(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func toAccountingExportResponse(export *entity.AccountingExport) model.AccountingExportResponse {
+	response := model.AccountingExportResponse{
+		ID:             export.ID,
+		Format:         export.Format,
+		PeriodFrom:     export.PeriodFrom.Format(time.DateOnly),
+		PeriodTo:       export.PeriodTo.Format(time.DateOnly),
+		ExpenseCount:   export.ExpenseCount,
+		TotalAmountIDR: export.TotalAmountIDR,
+		CreatedBy:      export.CreatedBy,
+		CreatedAt:      export.CreatedAt,
+	}
+	for _, item := range export.Items {
+		response.Items = append(response.Items, model.AccountingExportItemResponse{
+			ExpenseID:   item.ExpenseID,
+			UserID:      item.UserID,
+			Category:    item.Category,
+			CostCenter:  item.CostCenter,
+			Description: item.Description,
+			AmountIDR:   item.AmountIDR,
+			AccountCode: item.AccountCode,
+			ApprovedAt:  item.ApprovedAt,
+		})
+	}
+	return response
+}
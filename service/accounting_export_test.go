@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapGLAccounts(t *testing.T) {
+	mappings := []*entity.GLAccountMapping{
+		{Category: "", CostCenter: "", AccountCode: "6900"},
+		{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "", AccountCode: "6100"},
+		{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"},
+		{Category: "", CostCenter: "RND", AccountCode: "6500"},
+	}
+
+	tests := []struct {
+		name       string
+		mappings   []*entity.GLAccountMapping
+		category   string
+		costCenter string
+		want       string
+		wantErr    string
+	}{
+		{name: "category and cost center", mappings: mappings, category: util.EXPENSE_CATEGORY_TRAVEL, costCenter: "SALES", want: "6110"},
+		{name: "category alone", mappings: mappings, category: util.EXPENSE_CATEGORY_TRAVEL, costCenter: "RND", want: "6100"},
+		{name: "cost center alone", mappings: mappings, category: util.EXPENSE_CATEGORY_MEALS, costCenter: "RND", want: "6500"},
+		{name: "catch-all", mappings: mappings, category: util.EXPENSE_CATEGORY_MEALS, costCenter: "", want: "6900"},
+		{
+			name:       "unmapped",
+			mappings:   mappings[1:],
+			category:   util.EXPENSE_CATEGORY_MEALS,
+			costCenter: "SALES",
+			wantErr:    "no GL account is mapped for the expense: map category/cost center meals/SALES",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := []*entity.AccountingExportItem{{Category: tt.category, CostCenter: tt.costCenter}}
+
+			err := mapGLAccounts(tt.mappings, items)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, util.ErrUnmappedGLAccount)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, items[0].AccountCode)
+		})
+	}
+}
+
+func TestAccountingExportService_CreateAccountingExport(t *testing.T) {
+	approvedAt := time.Date(2024, 3, 4, 10, 0, 0, 0, time.Local)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.AccountingExportRequest
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.AccountingExportResponse)
+	}{
+		{
+			name:    "success - expenses booked on their mapped accounts",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AccountingExportRequest{From: "2024-03-01", To: "2024-03-31"},
+			mock: func(server *TestService) {
+				server.MockExportRepo.EXPECT().
+					GetGLAccountMappings(gomock.Any()).
+					Return([]*entity.GLAccountMapping{
+						{Category: "", CostCenter: "", AccountCode: "6900"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "", AccountCode: "6100"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"},
+						{Category: "", CostCenter: "RND", AccountCode: "6500"},
+					}, nil).
+					Times(1)
+
+				server.MockExportRepo.EXPECT().
+					CreateAccountingExport(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, export *entity.AccountingExport, build entity.AccountingExportBuilder) (int64, error) {
+						assert.Equal(t, util.ACCOUNTING_EXPORT_CSV, export.Format)
+						assert.Equal(t, from, export.PeriodFrom)
+						assert.Equal(t, from.AddDate(0, 0, 30), export.PeriodTo)
+						assert.Equal(t, int64(1), export.CreatedBy)
+
+						items := []*entity.AccountingExportItem{
+							{ExpenseID: 10, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", Description: "Flight to Surabaya", AmountIDR: 1500000, ApprovedAt: approvedAt},
+							{ExpenseID: 11, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_MEALS, CostCenter: "SALES", Description: "Client dinner", AmountIDR: 350000, ApprovedAt: approvedAt},
+						}
+						assert.NoError(t, build(items))
+						export.ExpenseCount = int32(len(items))
+						export.TotalAmountIDR = 1850000
+						export.Items = items
+						return 5, nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.AccountingExportResponse) {
+				assert.Equal(t, int64(5), got.ID)
+				assert.Equal(t, "2024-03-01", got.PeriodFrom)
+				assert.Equal(t, "2024-03-31", got.PeriodTo)
+				assert.Equal(t, int32(2), got.ExpenseCount)
+				assert.Equal(t, float64(1850000), got.TotalAmountIDR)
+				assert.Equal(t, "6110", got.Items[0].AccountCode)
+				assert.Equal(t, "6900", got.Items[1].AccountCode)
+			},
+		},
+		{
+			name:    "failure - an expense has no account",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AccountingExportRequest{From: "2024-03-01", Format: util.ACCOUNTING_EXPORT_JOURNAL},
+			mock: func(server *TestService) {
+				server.MockExportRepo.EXPECT().
+					GetGLAccountMappings(gomock.Any()).
+					Return([]*entity.GLAccountMapping{
+						{Category: "", CostCenter: "", AccountCode: "6900"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "", AccountCode: "6100"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"},
+						{Category: "", CostCenter: "RND", AccountCode: "6500"},
+					}[1:], nil).
+					Times(1)
+
+				server.MockExportRepo.EXPECT().
+					CreateAccountingExport(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ *entity.AccountingExport, build entity.AccountingExportBuilder) (int64, error) {
+						return 0, build([]*entity.AccountingExportItem{
+							{ExpenseID: 10, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", Description: "Flight to Surabaya", AmountIDR: 1500000, ApprovedAt: approvedAt},
+							{ExpenseID: 11, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_MEALS, CostCenter: "SALES", Description: "Client dinner", AmountIDR: 350000, ApprovedAt: approvedAt},
+						})
+					}).
+					Times(1)
+			},
+			wantErr: errors.New("no GL account is mapped for the expense: map category/cost center meals/SALES"),
+		},
+		{
+			name:    "failure - nothing to export",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AccountingExportRequest{From: "2024-03-01"},
+			mock: func(server *TestService) {
+				server.MockExportRepo.EXPECT().
+					GetGLAccountMappings(gomock.Any()).
+					Return([]*entity.GLAccountMapping{
+						{Category: "", CostCenter: "", AccountCode: "6900"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "", AccountCode: "6100"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"},
+						{Category: "", CostCenter: "RND", AccountCode: "6500"},
+					}, nil).
+					Times(1)
+
+				server.MockExportRepo.EXPECT().
+					CreateAccountingExport(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), util.ErrNothingToExport).
+					Times(1)
+			},
+			wantErr: util.ErrNothingToExport,
+		},
+		{
+			name:    "failure - unknown format",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AccountingExportRequest{From: "2024-03-01", Format: "xlsx"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("accounting export request is not valid: format must be csv or journal"),
+		},
+		{
+			name:    "failure - period too long",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AccountingExportRequest{From: "2024-01-01", To: "2024-06-30"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("accounting export request is not valid: the period must run forward and cover at most 92 days"),
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.AccountingExportRequest{From: "2024-03-01"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.CreateAccountingExport(roleContext(tt.role), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestAccountingExportService_GetAccountingExportFile(t *testing.T) {
+	approvedAt := time.Date(2024, 3, 4, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		format  string
+		options []Option
+		want    string
+	}{
+		{
+			name:   "csv",
+			format: util.ACCOUNTING_EXPORT_CSV,
+			want: "expense_id,approved_at,employee_email,employee_name,category,cost_center,description,amount_idr,gl_account\n" +
+				"10,2024-03-04,john.doe@company.com,John Doe,travel,SALES,Flight to Surabaya,1500000.00,6110\n" +
+				"11,2024-03-04,john.doe@company.com,John Doe,meals,SALES,Client dinner,350000.00,6900\n",
+		},
+		{
+			name:    "journal",
+			format:  util.ACCOUNTING_EXPORT_JOURNAL,
+			options: []Option{WithPayableGLAccount("2150")},
+			want: "journal_id,date,account_code,description,debit_idr,credit_idr\n" +
+				"EXP-10,2024-03-04,6110,John Doe - Flight to Surabaya,1500000.00,0.00\n" +
+				"EXP-10,2024-03-04,2150,John Doe - Flight to Surabaya,0.00,1500000.00\n" +
+				"EXP-11,2024-03-04,6900,John Doe - Client dinner,350000.00,0.00\n" +
+				"EXP-11,2024-03-04,2150,John Doe - Client dinner,0.00,350000.00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t, tt.options...)
+			defer server.MockCtrl.Finish()
+
+			items := []*entity.AccountingExportItem{
+				{ExpenseID: 10, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", Description: "Flight to Surabaya", AmountIDR: 1500000, ApprovedAt: approvedAt, AccountCode: "6110"},
+				{ExpenseID: 11, UserID: 2, UserName: "John Doe", UserEmail: "john.doe@company.com", Category: util.EXPENSE_CATEGORY_MEALS, CostCenter: "SALES", Description: "Client dinner", AmountIDR: 350000, ApprovedAt: approvedAt, AccountCode: "6900"},
+			}
+
+			server.MockExportRepo.EXPECT().
+				GetAccountingExport(gomock.Any(), int64(5)).
+				Return(&entity.AccountingExport{ID: 5, Format: tt.format, Items: items}, nil).
+				Times(1)
+
+			got, err := server.Service.GetAccountingExportFile(roleContext(util.USER_ROLE_ADMIN), 5)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestAccountingExportService_UpdateGLAccountMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		request model.GLAccountMappingRequest
+		mock    func(server *TestService)
+		wantErr error
+	}{
+		{
+			name:    "success - mapping upserted",
+			request: model.GLAccountMappingRequest{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: " SALES ", AccountCode: "6110"},
+			mock: func(server *TestService) {
+				server.MockExportRepo.EXPECT().
+					UpsertGLAccountMapping(gomock.Any(), &entity.GLAccountMapping{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"}).
+					Return(nil).
+					Times(1)
+
+				server.MockExportRepo.EXPECT().
+					GetGLAccountMappings(gomock.Any()).
+					Return([]*entity.GLAccountMapping{
+						{Category: "", CostCenter: "", AccountCode: "6900"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "", AccountCode: "6100"},
+						{Category: util.EXPENSE_CATEGORY_TRAVEL, CostCenter: "SALES", AccountCode: "6110"},
+						{Category: "", CostCenter: "RND", AccountCode: "6500"},
+					}, nil).
+					Times(1)
+			},
+		},
+		{
+			name:    "failure - unknown category",
+			request: model.GLAccountMappingRequest{Category: "gifts", AccountCode: "6800"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("GL account mapping is not valid: category \"gifts\" is not valid"),
+		},
+		{
+			name:    "failure - missing account code",
+			request: model.GLAccountMappingRequest{Category: util.EXPENSE_CATEGORY_TRAVEL},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("GL account mapping is not valid: account code is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.UpdateGLAccountMapping(roleContext(util.USER_ROLE_ADMIN), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, defaultPayableGLAccount, got.PayableAccountCode)
+			assert.Len(t, got.Mappings, 4)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+const maxBudgetDays = 366
+
+// SetBudget creates or replaces the budget of a department, category and
+// period, and returns the budgets of the department.
+func (s *ExpensesManagementService) SetBudget(ctx context.Context, req model.BudgetRequest) (*model.BudgetListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).Info("SetBudget")
+
+	department := strings.TrimSpace(req.Department)
+	if department == "" {
+		return nil, fmt.Errorf("%w: department is required", util.ErrInvalidBudget)
+	}
+	if req.Category != "" && !util.IsExpenseCategory(req.Category) {
+		return nil, fmt.Errorf("%w: category %q is not valid", util.ErrInvalidBudget, req.Category)
+	}
+	if toCents(req.AmountIDR) <= 0 {
+		return nil, fmt.Errorf("%w: amount_idr must be positive", util.ErrInvalidBudget)
+	}
+
+	policy := req.Policy
+	if policy == "" {
+		policy = util.BUDGET_POLICY_WARN
+	}
+	if !util.IsBudgetPolicy(policy) {
+		return nil, fmt.Errorf("%w: policy must be warn or block", util.ErrInvalidBudget)
+	}
+
+	from, to, err := parsePeriod(req.PeriodFrom, req.PeriodTo, maxBudgetDays)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", util.ErrInvalidBudget, err)
+	}
+
+	_, err = s.repo.BudgetRepository.UpsertBudget(ctx, &entity.Budget{
+		Department: department,
+		Category:   req.Category,
+		PeriodFrom: from,
+		PeriodTo:   to,
+		AmountIDR:  req.AmountIDR,
+		Policy:     policy,
+		CreatedBy:  userInfo.ID,
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to set budget")
+		return nil, fmt.Errorf("failed to set budget")
+	}
+
+	return s.getBudgets(ctx, &entity.BudgetQuery{Department: department})
+}
+
+// GetBudgets reports every budget against what was spent on it, for admins and
+// managers.
+func (s *ExpensesManagementService) GetBudgets(ctx context.Context, query model.BudgetQuery) (*model.BudgetListResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	if userInfo.Role == int(util.USER_ROLE_EMPLOYEE) {
+		s.logger.WithField("user_id", userInfo.ID).Error("user cannot view budgets")
+		return nil, fmt.Errorf("user cannot view budgets")
+	}
+
+	budgetQuery := &entity.BudgetQuery{Department: strings.TrimSpace(query.Department)}
+	if query.Date != "" {
+		budgetQuery.Date, err = time.ParseInLocation(time.DateOnly, query.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date must be a date as YYYY-MM-DD", util.ErrInvalidBudget)
+		}
+	}
+
+	return s.getBudgets(ctx, budgetQuery)
+}
+
+func (s *ExpensesManagementService) getBudgets(ctx context.Context, query *entity.BudgetQuery) (*model.BudgetListResponse, error) {
+	budgets, err := s.repo.BudgetRepository.GetBudgets(ctx, query)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get budgets")
+		return nil, fmt.Errorf("failed to get budgets")
+	}
+
+	response := &model.BudgetListResponse{Budgets: make([]model.BudgetResponse, 0, len(budgets))}
+	for _, budget := range budgets {
+		response.Budgets = append(response.Budgets, toBudgetResponse(budget))
+	}
+
+	return response, nil
+}
+
+// budgetCheck holds amount against the budgets of the employee's department
+// covering the expense's category on the day it was submitted, which the
+// repository reads while the expense is written. It sets a warning for every
+// warn budget the amount takes over its limit, and fails with
+// ErrBudgetExceeded for a block budget. Pending claims are not counted, they
+// may still be rejected or cut down.
+func (s *ExpensesManagementService) budgetCheck(expenseID int64, amount float64, warnings *[]string) entity.BudgetCheck {
+	return func(budgets []*entity.Budget) error {
+		*warnings = nil
+		for _, budget := range budgets {
+			spent := budget.ReservedIDR + budget.ConsumedIDR + amount
+			if toCents(spent) <= toCents(budget.AmountIDR) {
+				continue
+			}
+
+			message := fmt.Sprintf("%s would reach %.2f of %.2f IDR", budgetName(budget), spent, budget.AmountIDR)
+			if budget.Policy == util.BUDGET_POLICY_BLOCK {
+				s.logger.WithField("expense_id", expenseID).WithField("budget_id", budget.ID).Error("budget exceeded")
+				return fmt.Errorf("%w: %s", util.ErrBudgetExceeded, message)
+			}
+			s.logger.WithField("expense_id", expenseID).WithField("budget_id", budget.ID).Warn("budget exceeded")
+			*warnings = append(*warnings, message)
+		}
+		return nil
+	}
+}
+
+func budgetName(budget *entity.Budget) string {
+	category := budget.Category
+	if category == "" {
+		category = "all categories"
+	}
+	return fmt.Sprintf("the %s budget for %s from %s to %s", budget.Department, category,
+		budget.PeriodFrom.Format(time.DateOnly), budget.PeriodTo.Format(time.DateOnly))
+}
+
+func toBudgetResponse(budget *entity.Budget) model.BudgetResponse {
+	spent := budget.ReservedIDR + budget.ConsumedIDR
+	return model.BudgetResponse{
+		ID:                 budget.ID,
+		Department:         budget.Department,
+		Category:           budget.Category,
+		PeriodFrom:         budget.PeriodFrom.Format(time.DateOnly),
+		PeriodTo:           budget.PeriodTo.Format(time.DateOnly),
+		AmountIDR:          budget.AmountIDR,
+		Policy:             budget.Policy,
+		PendingIDR:         budget.PendingIDR,
+		ReservedIDR:        budget.ReservedIDR,
+		ConsumedIDR:        budget.ConsumedIDR,
+		RemainingIDR:       budget.AmountIDR - spent,
+		UtilizationPercent: utilizationPercent(spent, budget.AmountIDR),
+		UpdatedAt:          budget.UpdatedAt,
+	}
+}
+
+func utilizationPercent(spent, amount float64) float64 {
+	if toCents(amount) <= 0 {
+		return 0
+	}
+	return float64(toCents(spent)*10000/toCents(amount)) / 100
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetService_SetBudget(t *testing.T) {
+	periodFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	periodTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.BudgetRequest
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.BudgetListResponse)
+	}{
+		{
+			name:    "success - monthly travel budget",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.BudgetRequest{Department: " sales ", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: "2024-03-01", PeriodTo: "2024-03-31", AmountIDR: 5000000},
+			mock: func(server *TestService) {
+				server.MockBudgetRepo.EXPECT().
+					UpsertBudget(gomock.Any(), &entity.Budget{
+						Department: "sales",
+						Category:   util.EXPENSE_CATEGORY_TRAVEL,
+						PeriodFrom: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+						PeriodTo:   time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local),
+						AmountIDR:  5000000,
+						Policy:     util.BUDGET_POLICY_WARN,
+						CreatedBy:  1,
+					}).
+					Return(int64(4), nil).
+					Times(1)
+
+				server.MockBudgetRepo.EXPECT().
+					GetBudgets(gomock.Any(), &entity.BudgetQuery{Department: "sales"}).
+					Return([]*entity.Budget{
+						{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, PendingIDR: 750000, ReservedIDR: 1250000, ConsumedIDR: 2500000},
+						{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 3000000},
+					}, nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.BudgetListResponse) {
+				assert.Len(t, got.Budgets, 2)
+				assert.Equal(t, int64(4), got.Budgets[0].ID)
+			},
+		},
+		{
+			name:    "failure - unknown policy",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.BudgetRequest{Department: "sales", PeriodFrom: "2024-03-01", AmountIDR: 5000000, Policy: "notify"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("budget is not valid: policy must be warn or block"),
+		},
+		{
+			name:    "failure - amount rounds to zero",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.BudgetRequest{Department: "sales", PeriodFrom: "2024-03-01", AmountIDR: 0.001},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("budget is not valid: amount_idr must be positive"),
+		},
+		{
+			name:    "failure - missing department",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.BudgetRequest{PeriodFrom: "2024-03-01", AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("budget is not valid: department is required"),
+		},
+		{
+			name:    "failure - period longer than a year",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.BudgetRequest{Department: "sales", PeriodFrom: "2024-01-01", PeriodTo: "2025-03-31", AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("budget is not valid: the period must run forward and cover at most 366 days"),
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.BudgetRequest{Department: "sales", PeriodFrom: "2024-03-01", AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.SetBudget(roleContext(tt.role), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestBudgetService_GetBudgets(t *testing.T) {
+	periodFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	periodTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		query    model.BudgetQuery
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.BudgetListResponse)
+	}{
+		{
+			name:  "success - budget vs. actual",
+			role:  util.USER_ROLE_MANAGER,
+			query: model.BudgetQuery{Department: "sales", Date: "2024-03-15"},
+			mock: func(server *TestService) {
+				server.MockBudgetRepo.EXPECT().
+					GetBudgets(gomock.Any(), &entity.BudgetQuery{Department: "sales", Date: time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)}).
+					Return([]*entity.Budget{
+						{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, PendingIDR: 750000, ReservedIDR: 1250000, ConsumedIDR: 2500000},
+						{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 3000000},
+					}, nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.BudgetListResponse) {
+				assert.Equal(t, model.BudgetResponse{
+					ID:                 4,
+					Department:         "sales",
+					Category:           util.EXPENSE_CATEGORY_TRAVEL,
+					PeriodFrom:         "2024-03-01",
+					PeriodTo:           "2024-03-31",
+					AmountIDR:          5000000,
+					Policy:             util.BUDGET_POLICY_WARN,
+					PendingIDR:         750000,
+					ReservedIDR:        1250000,
+					ConsumedIDR:        2500000,
+					RemainingIDR:       1250000,
+					UtilizationPercent: 75,
+				}, got.Budgets[0])
+				assert.Equal(t, float64(17000000), got.Budgets[1].RemainingIDR)
+				assert.Equal(t, float64(15), got.Budgets[1].UtilizationPercent)
+			},
+		},
+		{
+			name:  "success - budget below a cent",
+			role:  util.USER_ROLE_ADMIN,
+			query: model.BudgetQuery{Department: "sales"},
+			mock: func(server *TestService) {
+				server.MockBudgetRepo.EXPECT().
+					GetBudgets(gomock.Any(), &entity.BudgetQuery{Department: "sales"}).
+					Return([]*entity.Budget{
+						{ID: 6, Department: "sales", AmountIDR: 0.001, Policy: util.BUDGET_POLICY_WARN, ReservedIDR: 50000},
+					}, nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.BudgetListResponse) {
+				assert.Equal(t, float64(0), got.Budgets[0].UtilizationPercent)
+			},
+		},
+		{
+			name:    "failure - invalid date",
+			role:    util.USER_ROLE_ADMIN,
+			query:   model.BudgetQuery{Date: "15-03-2024"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("budget is not valid: date must be a date as YYYY-MM-DD"),
+		},
+		{
+			name:    "failure - employee",
+			role:    util.USER_ROLE_EMPLOYEE,
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user cannot view budgets"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.GetBudgets(roleContext(tt.role), tt.query)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestBudgetService_CreateExpenseOverBudget(t *testing.T) {
+	periodFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	periodTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		budgets      []*entity.Budget
+		wantWarnings []string
+		wantErr      error
+	}{
+		{
+			name: "within every budget",
+			budgets: []*entity.Budget{
+				{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, PendingIDR: 750000, ReservedIDR: 1250000, ConsumedIDR: 2500000},
+				{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 3000000},
+			},
+		},
+		{
+			name: "warn budget exceeded",
+			budgets: []*entity.Budget{
+				{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, PendingIDR: 750000, ReservedIDR: 2000000, ConsumedIDR: 2500000},
+				{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 3000000},
+			},
+			wantWarnings: []string{"the sales budget for travel from 2024-03-01 to 2024-03-31 would reach 5600000.00 of 5000000.00 IDR"},
+		},
+		{
+			name: "block budget exceeded",
+			budgets: []*entity.Budget{
+				{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, PendingIDR: 750000, ReservedIDR: 1250000, ConsumedIDR: 2500000},
+				{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 19500000},
+			},
+			wantErr: errors.New("expense would exceed the budget: the sales budget for all categories from 2024-03-01 to 2024-03-31 would reach 20600000.00 of 20000000.00 IDR"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			server.MockRepo.EXPECT().
+				WriteExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, expense *entity.Expense, _ *entity.AuditLog, _ entity.SpendingLimitCheck, checkBudget entity.BudgetCheck, _ entity.OutboxMessageBuilder) (int64, error) {
+					assert.Equal(t, int64(1), expense.UserID)
+					assert.Equal(t, util.EXPENSE_CATEGORY_TRAVEL, expense.Category)
+					if err := checkBudget(tt.budgets); err != nil {
+						return 0, err
+					}
+					return int64(42), nil
+				}).
+				Times(1)
+
+			got, err := server.Service.CreateExpense(roleContext(util.USER_ROLE_EMPLOYEE), model.CreateExpenseRequest{
+				AmountIDR:   1100000,
+				Description: "Train to Bandung",
+				Category:    util.EXPENSE_CATEGORY_TRAVEL,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, util.ErrBudgetExceeded)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantWarnings, got.BudgetWarnings)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+func (s *ExpensesManagementService) CreateComment(ctx context.Context, expenseID int64, req model.CreateCommentRequest) (*model.CommentResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", expenseID).Info("CreateComment")
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		s.logger.WithField("expense_id", expenseID).Error("comment body is required")
+		return nil, fmt.Errorf("comment body is required")
+	}
+
+	expense, err := s.getVisibleExpense(ctx, userInfo, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentionedUsers := make([]*entity.User, 0)
+	if emails := util.ParseMentions(body); len(emails) > 0 {
+		users, err := s.repo.UserRepository.GetUsersByEmails(ctx, emails)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to get mentioned users")
+			return nil, fmt.Errorf("failed to get mentioned users")
+		}
+		// Only users who can see the expense can be mentioned on it
+		for _, user := range users {
+			if canViewExpense(user.ID, user.Role, expense) {
+				mentionedUsers = append(mentionedUsers, user)
+			}
+		}
+	}
+
+	mentions := make([]int64, 0, len(mentionedUsers))
+	for _, user := range mentionedUsers {
+		mentions = append(mentions, user.ID)
+	}
+
+	previousComments, err := s.repo.CommentRepository.GetCommentsByExpenseID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get comments")
+		return nil, fmt.Errorf("failed to get comments")
+	}
+
+	comment := &entity.Comment{
+		ExpenseID: expenseID,
+		AuthorID:  userInfo.ID,
+		Body:      body,
+		Mentions:  mentions,
+		CreatedAt: time.Now(),
+	}
+	commentID, err := s.repo.CommentRepository.WriteComment(ctx, comment)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to write comment")
+		return nil, fmt.Errorf("failed to write comment")
+	}
+
+	notifications := buildCommentNotifications(userInfo, expense, commentID, mentions, previousComments)
+	if len(notifications) > 0 {
+		err = s.repo.NotificationRepository.WriteNotifications(ctx, notifications)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to write notifications")
+		}
+	}
+
+	return &model.CommentResponse{
+		ID:        commentID,
+		ExpenseID: expenseID,
+		AuthorID:  userInfo.ID,
+		Body:      body,
+		Mentions:  mentions,
+		CreatedAt: comment.CreatedAt,
+	}, nil
+}
+
+func (s *ExpensesManagementService) GetComments(ctx context.Context, expenseID int64) (*model.CommentListResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", expenseID).Info("GetComments")
+
+	if _, err := s.getVisibleExpense(ctx, userInfo, expenseID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.repo.CommentRepository.GetCommentsByExpenseID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get comments")
+		return nil, fmt.Errorf("failed to get comments")
+	}
+
+	commentsResponse := make([]model.CommentResponse, 0)
+	for _, comment := range comments {
+		commentsResponse = append(commentsResponse, model.CommentResponse{
+			ID:         comment.ID,
+			ExpenseID:  comment.ExpenseID,
+			AuthorID:   comment.AuthorID,
+			AuthorName: comment.AuthorName,
+			Body:       comment.Body,
+			Mentions:   comment.Mentions,
+			CreatedAt:  comment.CreatedAt,
+		})
+	}
+
+	return &model.CommentListResponse{
+		Comments: commentsResponse,
+	}, nil
+}
+
+// buildCommentNotifications notifies mentioned users, the submitter and everyone who
+// already took part in the thread, never the author and never the same user twice.
+func buildCommentNotifications(author model.User, expense *entity.Expense, commentID int64, mentions []int64, previousComments []*entity.Comment) []*entity.Notification {
+	notifications := make([]*entity.Notification, 0)
+	notified := map[int64]bool{author.ID: true}
+
+	for _, userID := range mentions {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		notifications = append(notifications, &entity.Notification{
+			UserID:    userID,
+			ExpenseID: expense.ID,
+			CommentID: commentID,
+			Type:      int32(util.NOTIFICATION_MENTION),
+			Message:   fmt.Sprintf("%s mentioned you on expense %d", author.Email, expense.ID),
+		})
+	}
+
+	participants := []int64{expense.UserID}
+	for _, comment := range previousComments {
+		participants = append(participants, comment.AuthorID)
+	}
+
+	for _, userID := range participants {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		notifications = append(notifications, &entity.Notification{
+			UserID:    userID,
+			ExpenseID: expense.ID,
+			CommentID: commentID,
+			Type:      int32(util.NOTIFICATION_COMMENT),
+			Message:   fmt.Sprintf("%s commented on expense %d", author.Email, expense.ID),
+		})
+	}
+
+	return notifications
+}
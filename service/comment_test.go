@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentService_CreateComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		expenseID int64
+		request   model.CreateCommentRequest
+		userCtx   model.User
+		mock      func(server *TestService)
+		want      *model.CommentResponse
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "success - manager mentions another manager",
+			expenseID: 123,
+			request: model.CreateCommentRequest{
+				Body: "@finance@company.com can you double check the receipt?",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@company.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUsersByEmails(gomock.Any(), []string{"finance@company.com"}).
+					Return([]*entity.User{
+						{ID: 3, Email: "finance@company.com", Role: int(util.USER_ROLE_MANAGER)},
+					}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					GetCommentsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Comment{}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					WriteComment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, comment *entity.Comment) (int64, error) {
+						assert.Equal(t, int64(123), comment.ExpenseID)
+						assert.Equal(t, int64(2), comment.AuthorID)
+						assert.Equal(t, []int64{3}, comment.Mentions)
+						return 10, nil
+					}).
+					Times(1)
+
+				server.MockNotificationRepo.EXPECT().
+					WriteNotifications(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, notifications []*entity.Notification) error {
+						assert.Len(t, notifications, 2)
+						assert.Equal(t, int64(3), notifications[0].UserID)
+						assert.Equal(t, int32(util.NOTIFICATION_MENTION), notifications[0].Type)
+						assert.Equal(t, int64(1), notifications[1].UserID)
+						assert.Equal(t, int32(util.NOTIFICATION_COMMENT), notifications[1].Type)
+						return nil
+					}).
+					Times(1)
+			},
+			want: &model.CommentResponse{
+				ID:        10,
+				ExpenseID: 123,
+				AuthorID:  2,
+				Body:      "@finance@company.com can you double check the receipt?",
+				Mentions:  []int64{3},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "success - mention of employee who cannot see the expense is ignored",
+			expenseID: 123,
+			request: model.CreateCommentRequest{
+				Body: "Receipt attached @other@company.com",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "john.doe@company.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUsersByEmails(gomock.Any(), []string{"other@company.com"}).
+					Return([]*entity.User{
+						{ID: 5, Email: "other@company.com", Role: int(util.USER_ROLE_EMPLOYEE)},
+					}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					GetCommentsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Comment{
+						{ID: 9, ExpenseID: 123, AuthorID: 2},
+					}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					WriteComment(gomock.Any(), gomock.Any()).
+					Return(int64(11), nil).
+					Times(1)
+
+				server.MockNotificationRepo.EXPECT().
+					WriteNotifications(gomock.Any(), []*entity.Notification{
+						{
+							UserID:    2,
+							ExpenseID: 123,
+							CommentID: 11,
+							Type:      int32(util.NOTIFICATION_COMMENT),
+							Message:   "john.doe@company.com commented on expense 123",
+						},
+					}).
+					Return(nil).
+					Times(1)
+			},
+			want: &model.CommentResponse{
+				ID:        11,
+				ExpenseID: 123,
+				AuthorID:  1,
+				Body:      "Receipt attached @other@company.com",
+				Mentions:  []int64{},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failure - employee cannot comment on other employee's expense",
+			expenseID: 123,
+			request: model.CreateCommentRequest{
+				Body: "Hello",
+			},
+			userCtx: model.User{
+				ID:    5,
+				Email: "other@company.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "user is not allowed to view this expense",
+		},
+		{
+			name:      "failure - empty body",
+			expenseID: 123,
+			request: model.CreateCommentRequest{
+				Body: "   ",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@company.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock:    func(server *TestService) {},
+			wantErr: true,
+			errMsg:  "comment body is required",
+		},
+		{
+			name:      "failure - write comment error",
+			expenseID: 123,
+			request: model.CreateCommentRequest{
+				Body: "Looks good",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@company.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					GetCommentsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Comment{}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					WriteComment(gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("database error")).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "failed to write comment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithComments(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
+			tt.mock(server)
+
+			got, err := server.Service.CreateComment(ctx, tt.expenseID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				if tt.errMsg != "" {
+					assert.Contains(t, err.Error(), tt.errMsg)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotZero(t, got.CreatedAt)
+			got.CreatedAt = time.Time{}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCommentService_GetComments(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name      string
+		expenseID int64
+		userCtx   model.User
+		mock      func(server *TestService)
+		want      *model.CommentListResponse
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    1,
+				Email: "john.doe@company.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+
+				server.MockCommentRepo.EXPECT().
+					GetCommentsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Comment{
+						{ID: 9, ExpenseID: 123, AuthorID: 2, AuthorName: "Finance Manager", Body: "Please attach the invoice", Mentions: []int64{1}, CreatedAt: createdAt},
+					}, nil).
+					Times(1)
+			},
+			want: &model.CommentListResponse{
+				Comments: []model.CommentResponse{
+					{ID: 9, ExpenseID: 123, AuthorID: 2, AuthorName: "Finance Manager", Body: "Please attach the invoice", Mentions: []int64{1}, CreatedAt: createdAt},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failure - not visible to other employees",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    5,
+				Email: "other@company.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithComments(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
+			tt.mock(server)
+
+			got, err := server.Service.GetComments(ctx, tt.expenseID)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	got := util.ParseMentions("@John.Doe@company.com please check, cc @manager@company.com. and again @john.doe@company.com; not@mention.com")
+	assert.Equal(t, []string{"john.doe@company.com", "manager@company.com"}, got)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+const defaultDeadLetterLimit = 50
+
+func (s *ExpensesManagementService) GetDeadLetters(ctx context.Context, limit int) (*model.DeadLetterListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+
+	deadLetters, err := s.repo.MessageBroker.GetDeadLetters(limit)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get dead letters")
+		return nil, fmt.Errorf("failed to get dead letters")
+	}
+
+	response := &model.DeadLetterListResponse{DeadLetters: make([]model.DeadLetterResponse, 0)}
+	for _, deadLetter := range deadLetters {
+		response.DeadLetters = append(response.DeadLetters, model.DeadLetterResponse{
+			ID:             deadLetter.ID,
+			Queue:          deadLetter.Queue,
+			Payload:        string(deadLetter.Payload),
+			RetryCount:     deadLetter.RetryCount,
+			LastError:      deadLetter.LastError,
+			DeadLetteredAt: deadLetter.DeadLetteredAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *ExpensesManagementService) ReplayDeadLetter(ctx context.Context, id string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	s.logger.WithField("dead_letter_id", id).Info("ReplayDeadLetter")
+
+	err := s.repo.MessageBroker.ReplayDeadLetter(id)
+	if err != nil {
+		if errors.Is(err, util.ErrDeadLetterNotFound) {
+			return err
+		}
+		s.logger.WithError(err).Error("failed to replay dead letter")
+		return fmt.Errorf("failed to replay dead letter")
+	}
+
+	return nil
+}
+
+func (s *ExpensesManagementService) DiscardDeadLetter(ctx context.Context, id string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	s.logger.WithField("dead_letter_id", id).Info("DiscardDeadLetter")
+
+	err := s.repo.MessageBroker.DiscardDeadLetter(id)
+	if err != nil {
+		if errors.Is(err, util.ErrDeadLetterNotFound) {
+			return err
+		}
+		s.logger.WithError(err).Error("failed to discard dead letter")
+		return fmt.Errorf("failed to discard dead letter")
+	}
+
+	return nil
+}
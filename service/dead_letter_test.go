@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func roleContext(role util.UserRole) context.Context {
+	ctx := context.WithValue(context.Background(), "user_id", int64(1))
+	ctx = context.WithValue(ctx, "user_email", "admin@example.com")
+	return context.WithValue(ctx, "user_role", int(role))
+}
+
+func TestDeadLetterService_GetDeadLetters(t *testing.T) {
+	deadLetteredAt := time.Now()
+
+	tests := []struct {
+		name    string
+		role    util.UserRole
+		limit   int
+		mock    func(server *TestService)
+		want    *model.DeadLetterListResponse
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:  "success - admin lists dead letters with default limit",
+			role:  util.USER_ROLE_ADMIN,
+			limit: 0,
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().
+					GetDeadLetters(defaultDeadLetterLimit).
+					Return([]*entity.DeadLetter{
+						{
+							ID:             "msg-1",
+							Queue:          "payment.processor.ems.queue",
+							Payload:        []byte(`not json`),
+							RetryCount:     0,
+							LastError:      "invalid character 'o' in literal null (expecting 'u')",
+							DeadLetteredAt: deadLetteredAt,
+						},
+					}, nil).
+					Times(1)
+			},
+			want: &model.DeadLetterListResponse{
+				DeadLetters: []model.DeadLetterResponse{
+					{
+						ID:             "msg-1",
+						Queue:          "payment.processor.ems.queue",
+						Payload:        "not json",
+						RetryCount:     0,
+						LastError:      "invalid character 'o' in literal null (expecting 'u')",
+						DeadLetteredAt: deadLetteredAt,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:  "failure - broker error",
+			role:  util.USER_ROLE_ADMIN,
+			limit: 10,
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().
+					GetDeadLetters(10).
+					Return(nil, errors.New("channel closed")).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "failed to get dead letters",
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			limit:   10,
+			mock:    func(server *TestService) {},
+			wantErr: true,
+			errMsg:  "user is not an admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.GetDeadLetters(roleContext(tt.role), tt.limit)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDeadLetterService_ReplayAndDiscard(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    util.UserRole
+		action  func(s *ExpensesManagementService, ctx context.Context) error
+		mock    func(server *TestService)
+		wantErr error
+		errMsg  string
+	}{
+		{
+			name: "success - replay dead letter",
+			role: util.USER_ROLE_ADMIN,
+			action: func(s *ExpensesManagementService, ctx context.Context) error {
+				return s.ReplayDeadLetter(ctx, "msg-1")
+			},
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().ReplayDeadLetter("msg-1").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "success - discard dead letter",
+			role: util.USER_ROLE_ADMIN,
+			action: func(s *ExpensesManagementService, ctx context.Context) error {
+				return s.DiscardDeadLetter(ctx, "msg-1")
+			},
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().DiscardDeadLetter("msg-1").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "failure - replay unknown dead letter",
+			role: util.USER_ROLE_ADMIN,
+			action: func(s *ExpensesManagementService, ctx context.Context) error {
+				return s.ReplayDeadLetter(ctx, "missing")
+			},
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().ReplayDeadLetter("missing").Return(util.ErrDeadLetterNotFound).Times(1)
+			},
+			wantErr: util.ErrDeadLetterNotFound,
+		},
+		{
+			name: "failure - discard broker error",
+			role: util.USER_ROLE_ADMIN,
+			action: func(s *ExpensesManagementService, ctx context.Context) error {
+				return s.DiscardDeadLetter(ctx, "msg-1")
+			},
+			mock: func(server *TestService) {
+				server.MockBroker.EXPECT().DiscardDeadLetter("msg-1").Return(errors.New("channel closed")).Times(1)
+			},
+			errMsg: "failed to discard dead letter",
+		},
+		{
+			name: "failure - not an admin",
+			role: util.USER_ROLE_EMPLOYEE,
+			action: func(s *ExpensesManagementService, ctx context.Context) error {
+				return s.ReplayDeadLetter(ctx, "msg-1")
+			},
+			mock:   func(server *TestService) {},
+			errMsg: "user is not an admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			err := tt.action(server.Service, roleContext(tt.role))
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.errMsg != "":
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
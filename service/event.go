@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/google/uuid"
+)
+
+const (
+	eventActorUser   = "user"
+	eventActorSystem = "system"
+)
+
+// newEventOutboxMessage wraps data in a domain event envelope. The event is
+// written to the outbox with the change it describes and published to the
+// events exchange by the outbox relay.
+func newEventOutboxMessage(eventType string, aggregateID int64, actor entity.EventActor, data interface{}) (*entity.OutboxMessage, error) {
+	payload, err := json.Marshal(&entity.DomainEvent{
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		SchemaVersion: util.EventSchemaVersion,
+		OccurredAt:    time.Now().UTC(),
+		Actor:         actor,
+		Data:          data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.OutboxMessage{
+		AggregateID: aggregateID,
+		EventType:   eventType,
+		Payload:     payload,
+	}, nil
+}
+
+// eventActor returns the user behind a change, or the system for changes made
+// by the consumer or a provider callback.
+func eventActor(user model.User) entity.EventActor {
+	if user.ID == 0 {
+		return entity.EventActor{Type: eventActorSystem}
+	}
+	return entity.EventActor{
+		Type:  eventActorUser,
+		ID:    user.ID,
+		Email: user.Email,
+		Role:  util.GetUserRoleString(util.UserRole(user.Role)),
+	}
+}
+
+func newExpenseEventMessage(eventType string, expense *entity.Expense, actor model.User, notes string) (*entity.OutboxMessage, error) {
+	return newEventOutboxMessage(eventType, expense.ID, eventActor(actor), &entity.ExpenseEventData{
+		ExpenseID:         expense.ID,
+		UserID:            expense.UserID,
+		AmountIDR:         expense.AmountIDR,
+		ApprovedAmountIDR: expense.ApprovedAmountIDR,
+		Description:       expense.Description,
+		Status:            util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+		Revision:          expense.Revision,
+		AutoApproved:      expense.AutoApproved,
+		Notes:             notes,
+	})
+}
+
+func newPaymentEventMessage(eventType string, expense *entity.Expense, payment *entity.Payment) (*entity.OutboxMessage, error) {
+	return newEventOutboxMessage(eventType, expense.ID, eventActor(model.User{}), &entity.PaymentEventData{
+		ExpenseID:         expense.ID,
+		UserID:            expense.UserID,
+		ExternalID:        payment.ExternalID,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		AmountIDR:         payment.AmountIDR,
+		FailureReason:     payment.LastError,
+	})
+}
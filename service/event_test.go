@@ -0,0 +1,86 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOutboxMessage(t *testing.T) {
+	expense := &entity.Expense{
+		ID:                123,
+		UserID:            1,
+		AmountIDR:         1500000,
+		ApprovedAmountIDR: 1200000,
+		Description:       "Client dinner",
+		Status:            int32(util.EXPENSE_APPROVED),
+		Revision:          2,
+	}
+	payment := &entity.Payment{
+		ExpenseID:         123,
+		ExternalID:        "ext-123",
+		ProviderPaymentID: "pay-1",
+		AmountIDR:         1200000,
+		LastError:         "account closed",
+	}
+
+	tests := []struct {
+		name      string
+		build     func() (*entity.OutboxMessage, error)
+		eventType string
+		actor     string
+		data      string
+	}{
+		{
+			name: "expense approved by a manager",
+			build: func() (*entity.OutboxMessage, error) {
+				return newExpenseEventMessage(util.EVENT_EXPENSE_APPROVED, expense, model.User{
+					ID:    2,
+					Email: "manager@example.com",
+					Role:  int(util.USER_ROLE_MANAGER),
+				}, "Looks good")
+			},
+			eventType: util.EVENT_EXPENSE_APPROVED,
+			actor:     `{"type":"user","id":2,"email":"manager@example.com","role":"manager"}`,
+			data:      `{"expense_id":123,"user_id":1,"amount_idr":1500000,"approved_amount_idr":1200000,"description":"Client dinner","status":"approved","revision":2,"auto_approved":false,"notes":"Looks good"}`,
+		},
+		{
+			name: "payment failed reported by the system",
+			build: func() (*entity.OutboxMessage, error) {
+				return newPaymentEventMessage(util.EVENT_PAYMENT_FAILED, expense, payment)
+			},
+			eventType: util.EVENT_PAYMENT_FAILED,
+			actor:     `{"type":"system"}`,
+			data:      `{"expense_id":123,"user_id":1,"external_id":"ext-123","provider_payment_id":"pay-1","amount_idr":1200000,"failure_reason":"account closed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := tt.build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.eventType, message.EventType)
+			assert.Equal(t, int64(123), message.AggregateID)
+
+			var event struct {
+				ID            string          `json:"id"`
+				Type          string          `json:"type"`
+				SchemaVersion int             `json:"schema_version"`
+				OccurredAt    string          `json:"occurred_at"`
+				Actor         json.RawMessage `json:"actor"`
+				Data          json.RawMessage `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(message.Payload, &event))
+			assert.NotEmpty(t, event.ID)
+			assert.NotEmpty(t, event.OccurredAt)
+			assert.Equal(t, tt.eventType, event.Type)
+			assert.Equal(t, util.EventSchemaVersion, event.SchemaVersion)
+			assert.JSONEq(t, tt.actor, string(event.Actor))
+			assert.JSONEq(t, tt.data, string(event.Data))
+		})
+	}
+}
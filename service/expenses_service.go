@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/budsx/expenses-management/entity"
 	"github.com/budsx/expenses-management/model"
 	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/circuitbreaker"
+	"github.com/budsx/expenses-management/util/statemachine"
 	"github.com/google/uuid"
 )
 
@@ -27,52 +31,92 @@ func (s *ExpensesManagementService) CreateExpense(ctx context.Context, req model
 		return nil, fmt.Errorf("amount is not valid")
 	}
 
-	expenseID, err := s.repo.ExpensesRepository.WriteExpense(ctx, &entity.Expense{
+	category := req.Category
+	if category == "" {
+		category = util.EXPENSE_CATEGORY_OTHER
+	}
+	if !util.IsExpenseCategory(category) {
+		s.logger.WithField("category", req.Category).Error("category is not valid")
+		return nil, fmt.Errorf("category is not valid")
+	}
+
+	expense := &entity.Expense{
 		UserID:      userInfo.ID,
 		AmountIDR:   req.AmountIDR,
 		Description: req.Description,
 		ReceiptURL:  req.ReceiptURL,
-		Status:      int32(util.EXPENSE_PENDING),
-	})
-	if err != nil {
-		s.logger.WithError(err).Error("failed to write expense")
-		return nil, err
+		Category:    category,
+		CostCenter:  strings.TrimSpace(req.CostCenter),
+		Status:      int32(statemachine.StatusNew),
+		Revision:    1,
 	}
 
-	if autoApproved {
-		util.GoWithRecover(func() {
-			err = s.repo.RabbitMQClient.PublishPayment(&entity.PublishPaymentRequest{
+	var limitWarning string
+	var budgetWarnings []string
+	checkLimit := s.spendingLimitCheck(userInfo.ID, req.AmountIDR, &limitWarning)
+	checkBudget := s.budgetCheck(0, req.AmountIDR, &budgetWarnings)
+
+	buildOutbox := func(expenseID int64) ([]*entity.OutboxMessage, error) {
+		if limitWarning != "" {
+			// A claim over the spending limit needs a manager's approval
+			// however small it is.
+			autoApproved = false
+		}
+
+		created := *expense
+		created.ID = expenseID
+		createdEvent, err := newExpenseEventMessage(util.EVENT_EXPENSE_CREATED, &created, userInfo, "")
+		if err != nil {
+			return nil, err
+		}
+		outbox := []*entity.OutboxMessage{createdEvent}
+
+		if autoApproved {
+			message, err := newPaymentOutboxMessage(&entity.PublishPaymentRequest{
 				ExpenseID:  expenseID,
 				ApproverID: 0, // Auto approved, no approver
 				Notes:      "Auto Approved",
 				Status:     int32(util.EXPENSE_AUTO_APPROVED),
 			})
 			if err != nil {
-				s.logger.WithError(err).Error("failed to publish payment")
+				return nil, err
 			}
-			s.logger.Info("Publish to payment processor")
-		})
+			outbox = append(outbox, message)
+		}
+		return outbox, nil
 	}
 
-	err = s.repo.ExpensesRepository.WriteAuditLog(ctx, &entity.AuditLog{
-		ExpenseID:    expenseID,
-		NewStatus:    int32(util.EXPENSE_PENDING),
-		StatusBefore: int32(util.EXPENSE_PENDING),
-		Notes:        "Expense created",
-		CreatedAt:    time.Now(),
+	_, err = s.transitionExpense(ctx, statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventSubmit,
+		Actor:   userInfo,
+		Notes:   "Expense created",
+	}, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		expense.Status = int32(to)
+		expense.ID, err = s.repo.ExpensesRepository.WriteExpense(ctx, expense, auditLog, checkLimit, checkBudget, buildOutbox)
+		return err
 	})
 	if err != nil {
-		s.logger.WithError(err).Error("failed to write audit log")
+		s.logger.WithError(err).Error("failed to write expense")
+		return nil, err
 	}
+	expenseID := expense.ID
 
 	return &model.ExpenseResponse{
-		ID:           expenseID,
-		UserID:       userInfo.ID,
-		AmountIDR:    req.AmountIDR,
-		Description:  req.Description,
-		ReceiptURL:   req.ReceiptURL,
-		Status:       util.GetExpenseStatusString(util.EXPENSE_PENDING),
-		AutoApproved: autoApproved,
+		ID:             expenseID,
+		UserID:         userInfo.ID,
+		AmountIDR:      req.AmountIDR,
+		Description:    req.Description,
+		ReceiptURL:     req.ReceiptURL,
+		Category:       expense.Category,
+		CostCenter:     expense.CostCenter,
+		Status:         util.GetExpenseStatusString(util.EXPENSE_PENDING),
+		AutoApproved:   autoApproved,
+		OverLimit:      limitWarning != "",
+		Revision:       1,
+		Version:        1,
+		BudgetWarnings: budgetWarnings,
+		LimitWarning:   limitWarning,
 	}, nil
 }
 
@@ -102,13 +146,19 @@ func (s *ExpensesManagementService) GetExpenses(ctx context.Context, query model
 	expensesResponse := make([]model.ExpenseResponse, 0)
 	for _, expense := range expenses {
 		expensesResponse = append(expensesResponse, model.ExpenseResponse{
-			ID:           expense.ID,
-			UserID:       expense.UserID,
-			AmountIDR:    expense.AmountIDR,
-			Description:  expense.Description,
-			ReceiptURL:   expense.ReceiptURL,
-			Status:       util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
-			AutoApproved: expense.AutoApproved,
+			ID:                expense.ID,
+			UserID:            expense.UserID,
+			AmountIDR:         expense.AmountIDR,
+			Description:       expense.Description,
+			ReceiptURL:        expense.ReceiptURL,
+			Category:          expense.Category,
+			CostCenter:        expense.CostCenter,
+			Status:            util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+			AutoApproved:      expense.AutoApproved,
+			OverLimit:         expense.OverLimit,
+			ApprovedAmountIDR: expense.ApprovedAmountIDR,
+			Revision:          expense.Revision,
+			Version:           expense.Version,
 		})
 	}
 
@@ -122,23 +172,55 @@ func (s *ExpensesManagementService) GetExpenses(ctx context.Context, query model
 
 func (s *ExpensesManagementService) GetExpenseByID(ctx context.Context, expenseID int64) (*model.ExpenseResponse, error) {
 	s.logger.WithField("expense_id", expenseID).Info("GetExpenseByID")
-	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, expenseID)
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	expense, err := s.getVisibleExpense(ctx, userInfo, expenseID)
 	if err != nil {
-		s.logger.WithError(err).Error("failed to get expense")
 		return nil, err
 	}
 
 	return &model.ExpenseResponse{
-		ID:           expense.ID,
-		UserID:       expense.UserID,
-		AmountIDR:    expense.AmountIDR,
-		Description:  expense.Description,
-		ReceiptURL:   expense.ReceiptURL,
-		Status:       util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
-		AutoApproved: expense.AutoApproved,
+		ID:                expense.ID,
+		UserID:            expense.UserID,
+		AmountIDR:         expense.AmountIDR,
+		Description:       expense.Description,
+		ReceiptURL:        expense.ReceiptURL,
+		Category:          expense.Category,
+		CostCenter:        expense.CostCenter,
+		Status:            util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+		AutoApproved:      expense.AutoApproved,
+		OverLimit:         expense.OverLimit,
+		ApprovedAmountIDR: expense.ApprovedAmountIDR,
+		Revision:          expense.Revision,
+		Version:           expense.Version,
 	}, nil
 }
 
+// getVisibleExpense loads an expense the user is allowed to see: employees only
+// see their own expenses, managers and admins see all of them.
+func (s *ExpensesManagementService) getVisibleExpense(ctx context.Context, userInfo model.User, expenseID int64) (*entity.Expense, error) {
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	if !canViewExpense(userInfo.ID, userInfo.Role, expense) {
+		s.logger.WithField("user_id", userInfo.ID).Error("user is not allowed to view this expense")
+		return nil, fmt.Errorf("user is not allowed to view this expense")
+	}
+
+	return expense, nil
+}
+
+func canViewExpense(userID int64, role int, expense *entity.Expense) bool {
+	return role != int(util.USER_ROLE_EMPLOYEE) || expense.UserID == userID
+}
+
 func (s *ExpensesManagementService) ApproveExpense(ctx context.Context, req model.ApprovalRequest) (*model.ApprovalResponse, error) {
 	userInfo, err := util.GetUserInfoFromContext(ctx)
 	if err != nil {
@@ -146,25 +228,106 @@ func (s *ExpensesManagementService) ApproveExpense(ctx context.Context, req mode
 		return nil, fmt.Errorf("failed to get user info")
 	}
 
-	if userInfo.Role != int(util.USER_ROLE_MANAGER) {
+	// Admins approve the claims over a spending limit, see canApprove.
+	if userInfo.Role != int(util.USER_ROLE_MANAGER) && userInfo.Role != int(util.USER_ROLE_ADMIN) {
 		s.logger.WithField("user_id", userInfo.ID).Error("user is not a manager")
 		return nil, fmt.Errorf("user is not a manager")
 	}
 
-	util.GoWithRecover(func() {
-		err = s.repo.RabbitMQClient.PublishPayment(&entity.PublishPaymentRequest{
-			ExpenseID:  req.ExpenseID,
-			ApproverID: userInfo.ID,
-			Notes:      req.Notes,
-			Status:     int32(util.APPROVAL_APPROVED),
-		})
+	if req.ApprovedAmountIDR < 0 {
+		s.logger.WithField("approved_amount_idr", req.ApprovedAmountIDR).Error("approved amount is not valid")
+		return nil, fmt.Errorf("approved amount is not valid")
+	}
+
+	if req.ApprovedAmountIDR > 0 && req.AdjustmentReason == "" {
+		s.logger.WithField("expense_id", req.ExpenseID).Error("adjustment reason is required")
+		return nil, fmt.Errorf("adjustment reason is required")
+	}
+
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, req.ExpenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	err = s.checkExpenseVersion(expense, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ApprovedAmountIDR > expense.AmountIDR {
+		s.logger.WithField("approved_amount_idr", req.ApprovedAmountIDR).Error("approved amount exceeds claimed amount")
+		return nil, fmt.Errorf("approved amount exceeds claimed amount")
+	}
+
+	transition := statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventApprove,
+		Actor:   userInfo,
+		Notes:   req.Notes,
+	}
+	approvedAmount := expense.AmountIDR
+	if req.ApprovedAmountIDR > 0 && req.ApprovedAmountIDR < expense.AmountIDR {
+		approvedAmount = req.ApprovedAmountIDR
+		transition.AmountBefore = expense.AmountIDR
+		transition.AmountAfter = approvedAmount
+		transition.Notes = fmt.Sprintf("%s (amount adjusted from %.2f to %.2f: %s)", req.Notes, expense.AmountIDR, approvedAmount, req.AdjustmentReason)
+	}
+
+	var budgetWarnings []string
+	checkBudget := s.budgetCheck(expense.ID, approvedAmount, &budgetWarnings)
+
+	paymentMessage, err := newPaymentOutboxMessage(&entity.PublishPaymentRequest{
+		ExpenseID:  req.ExpenseID,
+		ApproverID: userInfo.ID,
+		Notes:      req.Notes,
+		Status:     int32(util.APPROVAL_APPROVED),
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to build payment message")
+		return nil, fmt.Errorf("failed to approve expense")
+	}
+
+	_, err = s.transitionExpense(ctx, transition, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		approved := *expense
+		approved.Status = int32(to)
+		approved.ApprovedAmountIDR = approvedAmount
+		approvedEvent, err := newExpenseEventMessage(util.EVENT_EXPENSE_APPROVED, &approved, userInfo, transition.Notes)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build expense event")
+			return fmt.Errorf("failed to approve expense")
+		}
+		journalMessages, err := newJournalOutboxMessages(transition, to)
 		if err != nil {
-			s.logger.WithError(err).Error("failed to publish payment")
+			s.logger.WithError(err).Error("failed to build journal entry")
+			return fmt.Errorf("failed to approve expense")
 		}
+
+		err = s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
+			ExpenseID:         req.ExpenseID,
+			ApproverID:        userInfo.ID,
+			Status:            int32(to),
+			Notes:             req.Notes,
+			ApprovedAmountIDR: approvedAmount,
+			AdjustmentReason:  req.AdjustmentReason,
+			Version:           expense.Version,
+		}, auditLog, checkBudget, append([]*entity.OutboxMessage{approvedEvent, paymentMessage}, journalMessages...)...)
+		if errors.Is(err, util.ErrBudgetExceeded) {
+			return err
+		}
+		if err != nil {
+			return persistError(err, "failed to approve expense")
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &model.ApprovalResponse{
-		Message: fmt.Sprintf("Expense %d approved", req.ExpenseID),
+		Message:        fmt.Sprintf("Expense %d approved", req.ExpenseID),
+		Version:        expense.Version + 1,
+		BudgetWarnings: budgetWarnings,
 	}, nil
 }
 
@@ -180,40 +343,105 @@ func (s *ExpensesManagementService) RejectExpense(ctx context.Context, req model
 		return nil, fmt.Errorf("user is not a manager")
 	}
 
-	err = s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
-		ExpenseID:  req.ExpenseID,
-		ApproverID: userInfo.ID,
-		Status:     int32(util.EXPENSE_REJECTED),
-		Notes:      req.Notes,
-	})
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, req.ExpenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	err = s.checkExpenseVersion(expense, req.Version)
 	if err != nil {
-		s.logger.WithError(err).Error("failed to reject expense")
-		return nil, fmt.Errorf("failed to reject expense")
+		return nil, err
 	}
 
-	err = s.repo.ExpensesRepository.WriteAuditLog(ctx, &entity.AuditLog{
-		ExpenseID:    req.ExpenseID,
-		NewStatus:    int32(util.EXPENSE_REJECTED),
-		StatusBefore: int32(util.EXPENSE_PENDING),
-		Notes:        req.Notes,
-		CreatedAt:    time.Now(),
+	_, err = s.transitionExpense(ctx, statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventReject,
+		Actor:   userInfo,
+		Notes:   req.Notes,
+	}, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		rejected := *expense
+		rejected.Status = int32(to)
+		rejectedEvent, err := newExpenseEventMessage(util.EVENT_EXPENSE_REJECTED, &rejected, userInfo, req.Notes)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build expense event")
+			return fmt.Errorf("failed to reject expense")
+		}
+
+		err = s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
+			ExpenseID:  req.ExpenseID,
+			ApproverID: userInfo.ID,
+			Status:     int32(to),
+			Notes:      req.Notes,
+			Version:    expense.Version,
+		}, auditLog, nil, rejectedEvent)
+		if err != nil {
+			return persistError(err, "failed to reject expense")
+		}
+		return nil
 	})
 	if err != nil {
-		s.logger.WithError(err).Error("failed to write audit log")
+		return nil, err
 	}
 
 	return &model.ApprovalResponse{
 		Message: fmt.Sprintf("Expense %d successfully rejected", req.ExpenseID),
+		Version: expense.Version + 1,
 	}, nil
 }
 
-func (s *ExpensesManagementService) HealthCheck(ctx context.Context) error {
+// paymentExternalID derives the payment reference from the expense ID, so every
+// retry of the same payout reaches the provider with the same key.
+func paymentExternalID(expenseID int64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("expense-payment:%d", expenseID))).String()
+}
+
+// paymentInFlight reports whether the provider accepted the payment and its
+// outcome is still to come through the payment webhook, so it must not be sent
+// again.
+func paymentInFlight(payment *entity.Payment) bool {
+	return payment.Status == int32(util.PAYMENT_PROCESSING) && payment.ProviderPaymentID != ""
+}
+
+// payableAmount is the approved amount, or the claimed amount for expenses
+// approved before partial approvals existed.
+func payableAmount(expense *entity.Expense) float64 {
+	if expense.ApprovedAmountIDR == 0 {
+		return expense.AmountIDR
+	}
+	return expense.ApprovedAmountIDR
+}
+
+// HealthCheck fails when the database is down. A broker that is reconnecting
+// or a payment provider whose circuit is open only degrades the service, since
+// payment requests wait in the outbox and are retried.
+func (s *ExpensesManagementService) HealthCheck(ctx context.Context) (*model.HealthResponse, error) {
 	err := s.repo.ExpensesRepository.PingContext(ctx)
 	if err != nil {
 		s.logger.WithError(err).Error("failed to ping database")
-		return err
+		return nil, err
 	}
-	return nil
+
+	response := &model.HealthResponse{
+		Status:   "ok",
+		Database: "up",
+		Broker:   s.repo.MessageBroker.GetConnectionState(),
+	}
+	if response.Broker != "connected" {
+		s.logger.WithField("broker", response.Broker).Warn("broker is not connected")
+		response.Status = "degraded"
+	}
+
+	response.PaymentProviders = make(map[string]string)
+	for _, stats := range s.repo.PaymentProcessor.GetProviderStats() {
+		response.PaymentProviders[stats.Provider] = stats.CircuitState
+		if stats.CircuitState == string(circuitbreaker.StateOpen) {
+			s.logger.WithField("provider", stats.Provider).Warn("payment provider circuit is open")
+			response.Status = "degraded"
+		}
+	}
+
+	return response, nil
 }
 
 func (s *ExpensesManagementService) ProcessPayment(ctx context.Context, req model.ApprovalRequest) error {
@@ -225,42 +453,147 @@ func (s *ExpensesManagementService) ProcessPayment(ctx context.Context, req mode
 		return fmt.Errorf("failed to get expense")
 	}
 
-	if expense.Status != int32(util.EXPENSE_PENDING) && expense.Status != int32(util.EXPENSE_AUTO_APPROVED) {
-		s.logger.WithField("expense_id", req.ExpenseID).Error("expense is not pending")
-		return fmt.Errorf("expense is not pending")
+	if req.Status == int32(util.EXPENSE_AUTO_APPROVED) && expense.Status == int32(util.EXPENSE_PENDING) {
+		transition := statemachine.Request{
+			Expense: expense,
+			Event:   statemachine.EventAutoApprove,
+			Notes:   req.Notes,
+		}
+		_, err = s.transitionExpense(ctx, transition, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+			approved := *expense
+			approved.Status = int32(to)
+			approved.ApprovedAmountIDR = expense.AmountIDR
+			approved.AutoApproved = true
+			approvedEvent, err := newExpenseEventMessage(util.EVENT_EXPENSE_APPROVED, &approved, model.User{}, req.Notes)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to build expense event")
+				return fmt.Errorf("failed to approve expense")
+			}
+			journalMessages, err := newJournalOutboxMessages(transition, to)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to build journal entry")
+				return fmt.Errorf("failed to approve expense")
+			}
+
+			err = s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
+				ExpenseID:         req.ExpenseID,
+				ApproverID:        req.ApproverID,
+				Status:            int32(to),
+				Notes:             req.Notes,
+				ApprovedAmountIDR: expense.AmountIDR,
+				Version:           expense.Version,
+			}, auditLog, nil, append([]*entity.OutboxMessage{approvedEvent}, journalMessages...)...)
+			if err != nil {
+				return persistError(err, "failed to approve expense")
+			}
+			expense.Version++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		expense.ApprovedAmountIDR = expense.AmountIDR
+		s.logger.WithField("expense_id", req.ExpenseID).Info("Expense auto approved")
+	}
+
+	if expense.Status == int32(util.EXPENSE_PAID) {
+		s.logger.WithField("expense_id", req.ExpenseID).Info("Expense already paid, skipping")
+		return nil
 	}
 
-	err = s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
-		ExpenseID:  req.ExpenseID,
-		ApproverID: req.ApproverID,
-		Status:     req.Status,
-		Notes:      req.Notes,
+	if !statemachine.IsPayable(util.ExpenseStatus(expense.Status)) {
+		s.logger.WithField("expense_id", req.ExpenseID).Error("expense is not approved")
+		return fmt.Errorf("expense is not approved")
+	}
+
+	if s.payoutMode == util.PAYOUT_MODE_BATCH {
+		s.logger.WithField("expense_id", req.ExpenseID).Info("Expense left for the next payout batch")
+		return nil
+	}
+
+	payment, err := s.repo.PaymentRepository.StartPaymentAttempt(ctx, &entity.Payment{
+		ExpenseID:  expense.ID,
+		ExternalID: paymentExternalID(expense.ID),
+		AmountIDR:  payableAmount(expense),
 	})
 	if err != nil {
-		s.logger.WithError(err).Error("failed to approve expense")
-		return fmt.Errorf("failed to approve expense")
+		s.logger.WithError(err).Error("failed to record payment attempt")
+		return fmt.Errorf("failed to record payment attempt")
 	}
-	s.logger.WithField("expense_id", req.ExpenseID).Info("Expense approved")
 
-	payment, err := s.repo.PaymentProcessor.ProcessPayment(ctx, &entity.PaymentProcessorRequest{
-		AmountIDR:  int64(expense.AmountIDR),
-		ExternalID: uuid.New().String(),
-	})
+	if payment.Status == int32(util.PAYMENT_SUCCEEDED) {
+		s.logger.WithField("expense_id", req.ExpenseID).WithField("external_id", payment.ExternalID).Info("Payment already processed, skipping")
+		return s.markExpensePaid(ctx, expense, payment)
+	}
+
+	if paymentInFlight(payment) {
+		s.logger.WithField("expense_id", req.ExpenseID).WithField("external_id", payment.ExternalID).Info("Payment already accepted, waiting for confirmation")
+		return nil
+	}
+
+	submitter, err := s.repo.UserRepository.GetUserByID(ctx, expense.UserID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user")
+		return fmt.Errorf("failed to get user")
+	}
+
+	destinations, err := s.getPayoutDestinations(ctx, expense.UserID)
+	if err != nil {
+		return err
+	}
+
+	paymentRequest := &entity.PaymentProcessorRequest{
+		AmountIDR:      int64(payment.AmountIDR),
+		ExternalID:     payment.ExternalID,
+		IdempotencyKey: payment.ExternalID,
+		PayoutMethod:   submitter.PayoutMethod,
+		Provider:       payment.Provider,
+		Destinations:   destinations,
+	}
+	response, err := s.repo.PaymentProcessor.ProcessPayment(ctx, paymentRequest)
+	payment.Provider = paymentRequest.Provider
 	if err != nil {
 		s.logger.WithError(err).Error("failed to process payment")
+		payment.Status = int32(util.PAYMENT_FAILED)
+		payment.LastError = err.Error()
+		if err := s.repo.PaymentRepository.UpdatePayment(ctx, payment); err != nil {
+			s.logger.WithError(err).Error("failed to update payment")
+		}
+		if err := s.markExpensePaymentFailed(ctx, expense, payment); err != nil {
+			s.logger.WithError(err).WithField("expense_id", expense.ID).Error("failed to mark payment as failed")
+		}
 		return fmt.Errorf("failed to process payment")
 	}
-	s.logger.WithField("response", payment).Info("Payment processed")
+	s.logger.WithField("response", response).WithField("provider", payment.Provider).Info("Payment processed")
 
-	err = s.repo.ExpensesRepository.WriteAuditLog(ctx, &entity.AuditLog{
-		ExpenseID:    req.ExpenseID,
-		NewStatus:    int32(req.Status),
-		StatusBefore: expense.Status,
-		Notes:        req.Notes,
-		CreatedAt:    time.Now(),
-	})
+	payment.ProviderPaymentID = response.Data.ID
+	payment.RawResponse = string(response.Raw)
+
+	// An accepted payout is confirmed later through the payment webhook.
+	if status, _ := util.GetPaymentStatusFromProvider(response.Data.Status); status == util.PAYMENT_PROCESSING {
+		err = s.repo.PaymentRepository.UpdatePayment(ctx, payment)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to update payment")
+			return fmt.Errorf("failed to update payment")
+		}
+		s.logger.WithField("expense_id", req.ExpenseID).Info("Payment accepted, waiting for confirmation")
+		return nil
+	}
+
+	completedAt := time.Now()
+	payment.Status = int32(util.PAYMENT_SUCCEEDED)
+	payment.LastError = ""
+	payment.CompletedAt = &completedAt
+	err = s.repo.PaymentRepository.UpdatePayment(ctx, payment)
 	if err != nil {
-		s.logger.WithError(err).Error("failed to write audit log")
+		// The provider deduplicates on the idempotency key, so a redelivery is safe.
+		s.logger.WithError(err).Error("failed to update payment")
+		return fmt.Errorf("failed to update payment")
+	}
+
+	err = s.markExpensePaid(ctx, expense, payment)
+	if err != nil {
+		return err
 	}
 
 	s.logger.WithField("expense_id", req.ExpenseID).Info("Expense processed")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/budsx/expenses-management/entity"
 	"github.com/budsx/expenses-management/model"
@@ -35,19 +36,22 @@ func TestExpensesService_CreateExpense(t *testing.T) {
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					WriteExpense(gomock.Any(), gomock.Any()).
-					Return(int64(123), nil).
+					WriteExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil()), gomock.Any(), gomock.Not(gomock.Nil())).
+					DoAndReturn(func(_ context.Context, _ *entity.Expense, _ *entity.AuditLog, checkLimit entity.SpendingLimitCheck, _ entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int64, error) {
+						overLimit, err := checkLimit(nil)
+						assert.NoError(t, err)
+						assert.False(t, overLimit)
+
+						outbox, err := buildOutbox(123)
+						assert.NoError(t, err)
+						assert.Len(t, outbox, 2)
+						assert.Equal(t, util.EVENT_EXPENSE_CREATED, outbox[0].EventType)
+						assert.Equal(t, int64(123), outbox[1].AggregateID)
+						assert.Equal(t, util.OUTBOX_EVENT_PAYMENT_REQUESTED, outbox[1].EventType)
+						assert.JSONEq(t, `{"expense_id":123,"approver_id":0,"notes":"Auto Approved","status":2}`, string(outbox[1].Payload))
+						return 123, nil
+					}).
 					Times(1)
-
-				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(nil).
-					Times(1)
-
-				server.MockRabbitMQ.EXPECT().
-					PublishPayment(gomock.Any()).
-					Return(nil).
-					AnyTimes()
 			},
 			want: &model.ExpenseResponse{
 				ID:           123,
@@ -66,6 +70,8 @@ func TestExpensesService_CreateExpense(t *testing.T) {
 				AmountIDR:   2000000,
 				Description: "Large Expense",
 				ReceiptURL:  "https://example.com/receipt2.jpg",
+				Category:    util.EXPENSE_CATEGORY_TRAVEL,
+				CostCenter:  " SALES ",
 			},
 			userCtx: model.User{
 				ID:    2,
@@ -74,13 +80,17 @@ func TestExpensesService_CreateExpense(t *testing.T) {
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					WriteExpense(gomock.Any(), gomock.Any()).
-					Return(int64(456), nil).
-					Times(1)
-
-				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(nil).
+					WriteExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil()), gomock.Any(), gomock.Not(gomock.Nil())).
+					DoAndReturn(func(_ context.Context, expense *entity.Expense, _ *entity.AuditLog, _ entity.SpendingLimitCheck, _ entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int64, error) {
+						assert.Equal(t, util.EXPENSE_CATEGORY_TRAVEL, expense.Category)
+						assert.Equal(t, "SALES", expense.CostCenter)
+						outbox, err := buildOutbox(456)
+						assert.NoError(t, err)
+						assert.Len(t, outbox, 1)
+						assert.Equal(t, util.EVENT_EXPENSE_CREATED, outbox[0].EventType)
+						assert.Equal(t, int64(456), outbox[0].AggregateID)
+						return 456, nil
+					}).
 					Times(1)
 			},
 			want: &model.ExpenseResponse{
@@ -95,64 +105,41 @@ func TestExpensesService_CreateExpense(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "write expense error",
+			name: "invalid category",
 			request: model.CreateExpenseRequest{
 				AmountIDR:   50000,
-				Description: "Failed Expense",
-				ReceiptURL:  "https://example.com/receipt3.jpg",
+				Description: "Gift",
+				Category:    "gifts",
 			},
 			userCtx: model.User{
 				ID:    3,
 				Email: "user3@example.com",
 				Role:  int(util.USER_ROLE_EMPLOYEE),
 			},
-			mock: func(server *TestService) {
-				server.MockRepo.EXPECT().
-					WriteExpense(gomock.Any(), gomock.Any()).
-					Return(int64(0), errors.New("database error")).
-					Times(1)
-			},
+			mock:    func(server *TestService) {},
 			want:    nil,
 			wantErr: true,
 		},
 		{
-			name: "audit log error - should not fail",
+			name: "write expense error",
 			request: model.CreateExpenseRequest{
-				AmountIDR:   75000,
-				Description: "Audit Log Error",
-				ReceiptURL:  "https://example.com/receipt4.jpg",
+				AmountIDR:   50000,
+				Description: "Failed Expense",
+				ReceiptURL:  "https://example.com/receipt3.jpg",
 			},
 			userCtx: model.User{
-				ID:    4,
-				Email: "user4@example.com",
+				ID:    3,
+				Email: "user3@example.com",
 				Role:  int(util.USER_ROLE_EMPLOYEE),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					WriteExpense(gomock.Any(), gomock.Any()).
-					Return(int64(789), nil).
-					Times(1)
-
-				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(errors.New("audit log error")).
+					WriteExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("database error")).
 					Times(1)
-
-				server.MockRabbitMQ.EXPECT().
-					PublishPayment(gomock.Any()).
-					Return(nil).
-					AnyTimes()
-			},
-			want: &model.ExpenseResponse{
-				ID:           789,
-				UserID:       4,
-				AmountIDR:    75000,
-				Description:  "Audit Log Error",
-				ReceiptURL:   "https://example.com/receipt4.jpg",
-				Status:       util.GetExpenseStatusString(util.EXPENSE_PENDING),
-				AutoApproved: true,
 			},
-			wantErr: false,
+			want:    nil,
+			wantErr: true,
 		},
 	}
 
@@ -391,9 +378,16 @@ func TestExpensesService_GetExpenses_InvalidContext(t *testing.T) {
 }
 
 func TestExpensesService_GetExpenseByID(t *testing.T) {
+	manager := model.User{
+		ID:    2,
+		Email: "manager@example.com",
+		Role:  int(util.USER_ROLE_MANAGER),
+	}
+
 	tests := []struct {
 		name      string
 		expenseID int64
+		userCtx   model.User
 		mock      func(server *TestService)
 		want      *model.ExpenseResponse
 		wantErr   bool
@@ -401,6 +395,7 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 		{
 			name:      "success",
 			expenseID: 123,
+			userCtx:   manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(123)).
@@ -412,6 +407,7 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 						ReceiptURL:   "https://example.com/receipt.jpg",
 						Status:       int32(util.EXPENSE_APPROVED),
 						AutoApproved: false,
+						OverLimit:    true,
 					}, nil).
 					Times(1)
 			},
@@ -423,12 +419,67 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 				ReceiptURL:   "https://example.com/receipt.jpg",
 				Status:       util.GetExpenseStatusString(util.EXPENSE_APPROVED),
 				AutoApproved: false,
+				OverLimit:    true,
+			},
+			wantErr: false,
+		},
+		{
+			name:      "success - employee views own expense",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:           123,
+						UserID:       1,
+						AmountIDR:    150000,
+						Description:  "Test Expense",
+						ReceiptURL:   "https://example.com/receipt.jpg",
+						Status:       int32(util.EXPENSE_PENDING),
+						AutoApproved: true,
+					}, nil).
+					Times(1)
+			},
+			want: &model.ExpenseResponse{
+				ID:           123,
+				UserID:       1,
+				AmountIDR:    150000,
+				Description:  "Test Expense",
+				ReceiptURL:   "https://example.com/receipt.jpg",
+				Status:       util.GetExpenseStatusString(util.EXPENSE_PENDING),
+				AutoApproved: true,
 			},
 			wantErr: false,
 		},
+		{
+			name:      "failure - employee cannot view other employee's expense",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    5,
+				Email: "other@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+					}, nil).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:      "expense not found",
 			expenseID: 999,
+			userCtx:   manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(999)).
@@ -441,6 +492,7 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 		{
 			name:      "database error",
 			expenseID: 123,
+			userCtx:   manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(123)).
@@ -458,6 +510,10 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 			defer server.MockCtrl.Finish()
 
 			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
 			tt.mock(server)
 
 			got, err := server.Service.GetExpenseByID(ctx, tt.expenseID)
@@ -482,6 +538,15 @@ func TestExpensesService_GetExpenseByID(t *testing.T) {
 }
 
 func TestExpensesService_ApproveExpense(t *testing.T) {
+	submittedAt := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local)
+	periodFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	periodTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)
+	manager := model.User{
+		ID:    2,
+		Email: "manager@example.com",
+		Role:  int(util.USER_ROLE_MANAGER),
+	}
+
 	tests := []struct {
 		name    string
 		request model.ApprovalRequest
@@ -499,16 +564,46 @@ func TestExpensesService_ApproveExpense(t *testing.T) {
 				Notes:      "Approved by manager",
 				Status:     int32(util.APPROVAL_APPROVED),
 			},
-			userCtx: model.User{
-				ID:    2,
-				Email: "manager@example.com",
-				Role:  int(util.USER_ROLE_MANAGER),
-			},
+			userCtx: manager,
 			mock: func(server *TestService) {
-				server.MockRabbitMQ.EXPECT().
-					PublishPayment(gomock.Any()).
-					Return(nil).
-					AnyTimes()
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PENDING),
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), &entity.ExpenseApproval{
+						ExpenseID:         123,
+						ApproverID:        2,
+						Status:            int32(util.EXPENSE_APPROVED),
+						Notes:             "Approved by manager",
+						ApprovedAmountIDR: 1500000,
+					}, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ *entity.ExpenseApproval, auditLog *entity.AuditLog, _ entity.BudgetCheck, outbox ...*entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.EXPENSE_PENDING), auditLog.StatusBefore)
+						assert.Equal(t, int32(util.EXPENSE_APPROVED), auditLog.NewStatus)
+
+						assert.Len(t, outbox, 3)
+						assert.Equal(t, util.EVENT_EXPENSE_APPROVED, outbox[0].EventType)
+						assert.Equal(t, util.OUTBOX_EVENT_PAYMENT_REQUESTED, outbox[1].EventType)
+						assert.Equal(t, int64(123), outbox[1].AggregateID)
+
+						entry := journalOutboxEntry(t, outbox)
+						assert.Equal(t, "expense:123:expense_approved", entry.Reference)
+						assert.Equal(t, int64(1), entry.UserID)
+						assert.Equal(t, []*entity.JournalLine{
+							{Account: util.LEDGER_ACCOUNT_EXPENSE, DebitIDR: 1500000},
+							{Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 1500000},
+						}, entry.Lines)
+						return nil
+					}).
+					Times(1)
+
 			},
 			want: &model.ApprovalResponse{
 				Message: "Expense 123 approved",
@@ -516,177 +611,515 @@ func TestExpensesService_ApproveExpense(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "failure - not a manager",
-			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 1,
-				Notes:      "Should fail",
-				Status:     int32(util.APPROVAL_APPROVED),
-			},
-			userCtx: model.User{
-				ID:    1,
-				Email: "employee@example.com",
-				Role:  int(util.USER_ROLE_EMPLOYEE),
-			},
-			mock:    func(server *TestService) {},
-			want:    nil,
-			wantErr: true,
-			errMsg:  "user is not a manager",
-		},
-		{
-			name: "failure - invalid context",
-			request: model.ApprovalRequest{
-				ExpenseID: 123,
-			},
-			userCtx: model.User{}, // Empty context
-			mock:    func(server *TestService) {},
-			want:    nil,
-			wantErr: true,
-			errMsg:  "failed to get user info",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			server := NewTestServer(t)
-			defer server.MockCtrl.Finish()
-
-			var ctx context.Context = context.Background()
-			if tt.userCtx.ID != 0 {
-				ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
-				ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
-				ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
-			}
-
-			tt.mock(server)
-
-			got, err := server.Service.ApproveExpense(ctx, tt.request)
-
-			if tt.wantErr {
-				assert.Error(t, err)
-				assert.Nil(t, got)
-				if tt.errMsg != "" {
-					assert.Contains(t, err.Error(), tt.errMsg)
-				}
-				return
-			}
-
-			assert.NoError(t, err)
-			assert.NotNil(t, got)
-			assert.Equal(t, tt.want.Message, got.Message)
-		})
-	}
-}
-
-func TestExpensesService_RejectExpense(t *testing.T) {
-	tests := []struct {
-		name    string
-		request model.ApprovalRequest
-		userCtx model.User
-		mock    func(server *TestService)
-		want    *model.ApprovalResponse
-		wantErr bool
-		errMsg  string
-	}{
-		{
-			name: "success - manager rejects expense",
+			name: "success - manager approves adjusted amount",
 			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 2,
-				Notes:      "Insufficient documentation",
-				Status:     int32(util.EXPENSE_REJECTED),
-			},
-			userCtx: model.User{
-				ID:    2,
-				Email: "manager@example.com",
-				Role:  int(util.USER_ROLE_MANAGER),
+				ExpenseID:         123,
+				Notes:             "Approved partially",
+				Status:            int32(util.APPROVAL_APPROVED),
+				ApprovedAmountIDR: 800000,
+				AdjustmentReason:  "Hotel upgrade is not covered",
 			},
+			userCtx: manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(nil).
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:          123,
+						UserID:      1,
+						AmountIDR:   1000000,
+						Category:    util.EXPENSE_CATEGORY_TRAVEL,
+						Status:      int32(util.EXPENSE_PENDING),
+						SubmittedAt: submittedAt,
+					}, nil).
 					Times(1)
 
 				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(nil).
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, approval *entity.ExpenseApproval, auditLog *entity.AuditLog, checkBudget entity.BudgetCheck, outbox ...*entity.OutboxMessage) error {
+						err := checkBudget([]*entity.Budget{
+							{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, ReservedIDR: 3000000, ConsumedIDR: 1500000},
+							{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ReservedIDR: 3000000},
+						})
+						assert.NoError(t, err)
+
+						assert.Equal(t, float64(1000000), auditLog.AmountBefore)
+						assert.Equal(t, float64(800000), auditLog.AmountAfter)
+
+						assert.Equal(t, float64(800000), approval.ApprovedAmountIDR)
+						assert.Equal(t, "Hotel upgrade is not covered", approval.AdjustmentReason)
+
+						entry := journalOutboxEntry(t, outbox)
+						assert.Equal(t, "expense:123:expense_approved", entry.Reference)
+						assert.Equal(t, []*entity.JournalLine{
+							{Account: util.LEDGER_ACCOUNT_EXPENSE, DebitIDR: 800000},
+							{Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 800000},
+						}, entry.Lines)
+						return nil
+					}).
 					Times(1)
+
 			},
 			want: &model.ApprovalResponse{
-				Message: "Expense 123 successfully rejected",
+				Message:        "Expense 123 approved",
+				BudgetWarnings: []string{"the sales budget for travel from 2024-03-01 to 2024-03-31 would reach 5300000.00 of 5000000.00 IDR"},
 			},
 			wantErr: false,
 		},
 		{
-			name: "success - audit log error should not fail",
+			name: "failure - approved amount exceeds claimed amount",
 			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 2,
-				Notes:      "Rejected",
-				Status:     int32(util.EXPENSE_REJECTED),
-			},
-			userCtx: model.User{
-				ID:    2,
-				Email: "manager@example.com",
-				Role:  int(util.USER_ROLE_MANAGER),
+				ExpenseID:         123,
+				ApprovedAmountIDR: 1200000,
+				AdjustmentReason:  "Typo",
 			},
+			userCtx: manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(nil).
-					Times(1)
-
-				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(errors.New("audit log error")).
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1000000,
+						Status:    int32(util.EXPENSE_PENDING),
+					}, nil).
 					Times(1)
 			},
-			want: &model.ApprovalResponse{
-				Message: "Expense 123 successfully rejected",
+			want:    nil,
+			wantErr: true,
+			errMsg:  "approved amount exceeds claimed amount",
+		},
+		{
+			name: "failure - adjusted amount without reason",
+			request: model.ApprovalRequest{
+				ExpenseID:         123,
+				ApprovedAmountIDR: 800000,
 			},
-			wantErr: false,
+			userCtx: manager,
+			mock:    func(server *TestService) {},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "adjustment reason is required",
 		},
 		{
-			name: "failure - not a manager",
+			name: "failure - expense already rejected",
 			request: model.ApprovalRequest{
 				ExpenseID: 123,
 			},
-			userCtx: model.User{
-				ID:    1,
-				Email: "employee@example.com",
-				Role:  int(util.USER_ROLE_EMPLOYEE),
+			userCtx: manager,
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_REJECTED),
+					}, nil).
+					Times(1)
 			},
-			mock:    func(server *TestService) {},
 			want:    nil,
 			wantErr: true,
-			errMsg:  "user is not a manager",
+			errMsg:  "cannot approve expense with status rejected",
 		},
 		{
-			name: "failure - approval expense error",
+			name: "failure - stale expense version",
 			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 2,
-				Notes:      "Should fail",
-				Status:     int32(util.EXPENSE_REJECTED),
-			},
-			userCtx: model.User{
-				ID:    2,
-				Email: "manager@example.com",
-				Role:  int(util.USER_ROLE_MANAGER),
+				ExpenseID: 123,
+				Version:   2,
 			},
+			userCtx: manager,
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(errors.New("database error")).
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PENDING),
+						Version:   3,
+					}, nil).
 					Times(1)
 			},
 			want:    nil,
 			wantErr: true,
-			errMsg:  "failed to reject expense",
+			errMsg:  util.ErrVersionConflict.Error(),
 		},
-	}
-
-	for _, tt := range tests {
+		{
+			name: "failure - budget would be exceeded",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: manager,
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:          123,
+						UserID:      1,
+						AmountIDR:   1500000,
+						Category:    util.EXPENSE_CATEGORY_MEALS,
+						Status:      int32(util.EXPENSE_PENDING),
+						SubmittedAt: submittedAt,
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil()), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ *entity.ExpenseApproval, _ *entity.AuditLog, checkBudget entity.BudgetCheck, _ ...*entity.OutboxMessage) error {
+						return checkBudget([]*entity.Budget{
+							{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 2000000, Policy: util.BUDGET_POLICY_BLOCK, ReservedIDR: 500000, ConsumedIDR: 250000},
+						})
+					}).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "expense would exceed the budget: the sales budget for all categories from 2024-03-01 to 2024-03-31 would reach 2250000.00 of 2000000.00 IDR",
+		},
+		{
+			name: "failure - concurrent update",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Version:   3,
+			},
+			userCtx: manager,
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PENDING),
+						Version:   3,
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, approval *entity.ExpenseApproval, _ *entity.AuditLog, _ entity.BudgetCheck, _ ...*entity.OutboxMessage) error {
+						assert.Equal(t, int32(3), approval.Version)
+						return util.ErrVersionConflict
+					}).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  util.ErrVersionConflict.Error(),
+		},
+		{
+			name: "success - admin approves expense over the spending limit",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Notes:     "Conference trip, over the monthly limit",
+			},
+			userCtx: model.User{
+				ID:    3,
+				Email: "admin@example.com",
+				Role:  int(util.USER_ROLE_ADMIN),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 400000,
+						Status:    int32(util.EXPENSE_PENDING),
+						OverLimit: true,
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, approval *entity.ExpenseApproval, _ *entity.AuditLog, _ entity.BudgetCheck, _ ...*entity.OutboxMessage) error {
+						assert.Equal(t, int64(3), approval.ApproverID)
+						assert.Equal(t, int32(util.EXPENSE_APPROVED), approval.Status)
+						return nil
+					}).
+					Times(1)
+			},
+			want: &model.ApprovalResponse{
+				Message: "Expense 123 approved",
+			},
+			wantErr: false,
+		},
+		{
+			name: "failure - manager approves expense over the spending limit",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: manager,
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 400000,
+						Status:    int32(util.EXPENSE_PENDING),
+						OverLimit: true,
+					}, nil).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "expense over the spending limit requires an admin's approval",
+		},
+		{
+			name: "failure - admin approves expense within the spending limit",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: model.User{
+				ID:    3,
+				Email: "admin@example.com",
+				Role:  int(util.USER_ROLE_ADMIN),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PENDING),
+					}, nil).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "user is not a manager",
+		},
+		{
+			name: "failure - manager approves own expense",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: manager,
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    2,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PENDING),
+					}, nil).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "submitter cannot review own expense",
+		},
+		{
+			name: "failure - not a manager",
+			request: model.ApprovalRequest{
+				ExpenseID:  123,
+				ApproverID: 1,
+				Notes:      "Should fail",
+				Status:     int32(util.APPROVAL_APPROVED),
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock:    func(server *TestService) {},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "user is not a manager",
+		},
+		{
+			name: "failure - invalid context",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: model.User{}, // Empty context
+			mock:    func(server *TestService) {},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "failed to get user info",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			var ctx context.Context = context.Background()
+			if tt.userCtx.ID != 0 {
+				ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+				ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+				ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+			}
+
+			tt.mock(server)
+
+			got, err := server.Service.ApproveExpense(ctx, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				if tt.errMsg != "" {
+					assert.Contains(t, err.Error(), tt.errMsg)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, got)
+			assert.Equal(t, tt.want.Message, got.Message)
+			assert.Equal(t, tt.want.BudgetWarnings, got.BudgetWarnings)
+		})
+	}
+}
+
+func TestExpensesService_RejectExpense(t *testing.T) {
+	pendingExpense := func(server *TestService) {
+		server.MockRepo.EXPECT().
+			GetExpenseByID(gomock.Any(), int64(123)).
+			Return(&entity.Expense{
+				ID:        123,
+				UserID:    1,
+				AmountIDR: 1500000,
+				Status:    int32(util.EXPENSE_PENDING),
+			}, nil).
+			Times(1)
+	}
+
+	tests := []struct {
+		name    string
+		request model.ApprovalRequest
+		userCtx model.User
+		mock    func(server *TestService)
+		want    *model.ApprovalResponse
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "success - manager rejects expense",
+			request: model.ApprovalRequest{
+				ExpenseID:  123,
+				ApproverID: 2,
+				Notes:      "Insufficient documentation",
+				Status:     int32(util.EXPENSE_REJECTED),
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				pendingExpense(server)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			want: &model.ApprovalResponse{
+				Message: "Expense 123 successfully rejected",
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - audit log records the real previous status",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Notes:     "Rejected after revision",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				pendingExpense(server)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ *entity.ExpenseApproval, auditLog *entity.AuditLog, _ entity.BudgetCheck, _ ...*entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.EXPENSE_PENDING), auditLog.StatusBefore)
+						assert.Equal(t, int32(util.EXPENSE_REJECTED), auditLog.NewStatus)
+						assert.Equal(t, "Rejected after revision", auditLog.Notes)
+						return nil
+					}).
+					Times(1)
+			},
+			want: &model.ApprovalResponse{
+				Message: "Expense 123 successfully rejected",
+			},
+			wantErr: false,
+		},
+		{
+			name: "failure - not a manager",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock:    func(server *TestService) {},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "user is not a manager",
+		},
+		{
+			name: "failure - expense already paid out",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_AUTO_APPROVED),
+					}, nil).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "cannot reject expense with status auto_approved",
+		},
+		{
+			name: "failure - approval expense error",
+			request: model.ApprovalRequest{
+				ExpenseID:  123,
+				ApproverID: 2,
+				Notes:      "Should fail",
+				Status:     int32(util.EXPENSE_REJECTED),
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				pendingExpense(server)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("database error")).
+					Times(1)
+			},
+			want:    nil,
+			wantErr: true,
+			errMsg:  "failed to reject expense",
+		},
+	}
+
+	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server := NewTestServer(t)
 			defer server.MockCtrl.Finish()
@@ -722,15 +1155,89 @@ func TestExpensesService_HealthCheck(t *testing.T) {
 	tests := []struct {
 		name    string
 		mock    func(server *TestService)
+		want    *model.HealthResponse
 		wantErr bool
 	}{
 		{
-			name: "success - database is healthy",
+			name: "success - database and broker are healthy",
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					PingContext(gomock.Any()).
 					Return(nil).
 					Times(1)
+
+				server.MockBroker.EXPECT().
+					GetConnectionState().
+					Return("connected").
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "half_open"},
+					}).
+					Times(1)
+			},
+			want: &model.HealthResponse{
+				Status:           "ok",
+				Database:         "up",
+				Broker:           "connected",
+				PaymentProviders: map[string]string{"bank_transfer": "closed", "ewallet": "half_open"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - reconnecting broker degrades the service",
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					PingContext(gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				server.MockBroker.EXPECT().
+					GetConnectionState().
+					Return("reconnecting").
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{}).
+					Times(1)
+			},
+			want: &model.HealthResponse{
+				Status:           "degraded",
+				Database:         "up",
+				Broker:           "reconnecting",
+				PaymentProviders: map[string]string{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - open provider circuit degrades the service",
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					PingContext(gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				server.MockBroker.EXPECT().
+					GetConnectionState().
+					Return("connected").
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "open"},
+					}).
+					Times(1)
+			},
+			want: &model.HealthResponse{
+				Status:           "degraded",
+				Database:         "up",
+				Broker:           "connected",
+				PaymentProviders: map[string]string{"bank_transfer": "open"},
 			},
 			wantErr: false,
 		},
@@ -748,25 +1255,51 @@ func TestExpensesService_HealthCheck(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewTestServer(t)
+			server := NewTestServerWithPaymentProcessor(t)
 			defer server.MockCtrl.Finish()
 
 			ctx := context.Background()
 			tt.mock(server)
 
-			err := server.Service.HealthCheck(ctx)
+			got, err := server.Service.HealthCheck(ctx)
 
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
 			}
 
-			assert.NoError(t, err)
-		})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpensesService_ProcessPayment(t *testing.T) {
+	paymentResponse := &entity.PaymentProcessorResponse{
+		Data: struct {
+			ID         string `json:"id"`
+			ExternalID string `json:"external_id"`
+			Status     string `json:"status"`
+		}{
+			ID:         "TXN123",
+			ExternalID: "EXT123",
+			Status:     "SUCCESS",
+		},
+		Message: "Payment processed successfully",
+	}
+
+	// startAttempt mimics the payments upsert for a payment that is not yet done.
+	startAttempt := func(_ context.Context, payment *entity.Payment) (*entity.Payment, error) {
+		return &entity.Payment{
+			ID:         1,
+			ExpenseID:  payment.ExpenseID,
+			ExternalID: payment.ExternalID,
+			AmountIDR:  payment.AmountIDR,
+			Status:     int32(util.PAYMENT_PROCESSING),
+			Attempts:   1,
+		}, nil
 	}
-}
 
-func TestExpensesService_ProcessPayment(t *testing.T) {
 	tests := []struct {
 		name    string
 		request model.ApprovalRequest
@@ -775,57 +1308,191 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 		errMsg  string
 	}{
 		{
-			name: "success - process payment for pending expense",
+			name: "success - process payment for approved expense",
 			request: model.ApprovalRequest{
 				ExpenseID:  123,
 				ApproverID: 2,
 				Notes:      "Approved for payment",
-				Status:     int32(util.EXPENSE_APPROVED),
+				Status:     int32(util.APPROVAL_APPROVED),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(123)).
 					Return(&entity.Expense{
-						ID:           123,
-						UserID:       1,
-						AmountIDR:    150000,
-						Description:  "Test Expense",
-						ReceiptURL:   "https://example.com/receipt.jpg",
-						Status:       int32(util.EXPENSE_PENDING),
-						AutoApproved: false,
+						ID:                123,
+						UserID:            1,
+						AmountIDR:         1500000,
+						ApprovedAmountIDR: 1500000,
+						Description:       "Test Expense",
+						ReceiptURL:        "https://example.com/receipt.jpg",
+						Status:            int32(util.EXPENSE_APPROVED),
+						AutoApproved:      false,
 					}, nil).
 					Times(1)
 
-				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(nil).
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(startAttempt).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), int64(1)).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_EWALLET}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
+						},
+					}, nil).
 					Times(1)
 
 				server.MockPaymentProcessor.EXPECT().
 					ProcessPayment(gomock.Any(), gomock.Any()).
-					Return(&entity.PaymentProcessorResponse{
-						Data: struct {
-							ID         string `json:"id"`
-							ExternalID string `json:"external_id"`
-							Status     string `json:"status"`
-						}{
-							ID:         "TXN123",
-							ExternalID: "EXT123",
-							Status:     "SUCCESS",
+					DoAndReturn(func(_ context.Context, req *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Equal(t, paymentExternalID(123), req.ExternalID)
+						assert.Equal(t, req.ExternalID, req.IdempotencyKey)
+						assert.Equal(t, util.PAYOUT_METHOD_EWALLET, req.PayoutMethod)
+						assert.Equal(t, "081234567890", req.Destinations[util.PAYOUT_METHOD_EWALLET].EWalletID)
+						req.Provider = "ewallet"
+						return paymentResponse, nil
+					}).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), payment.Status)
+						assert.Equal(t, "TXN123", payment.ProviderPaymentID)
+						assert.Equal(t, "ewallet", payment.Provider)
+						return nil
+					}).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAID), int32(0), gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - redelivery of a payment accepted by the provider is not sent again",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Status:    int32(util.APPROVAL_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:                123,
+						UserID:            1,
+						AmountIDR:         1500000,
+						ApprovedAmountIDR: 1500000,
+						Status:            int32(util.EXPENSE_APPROVED),
+					}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+						started, _ := startAttempt(ctx, payment)
+						started.Provider = "ewallet"
+						started.ProviderPaymentID = "TXN123"
+						return started, nil
+					}).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - retry stays on the provider of the first attempt",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Status:    int32(util.APPROVAL_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:                123,
+						UserID:            1,
+						AmountIDR:         1500000,
+						ApprovedAmountIDR: 1500000,
+						Status:            int32(util.EXPENSE_PAYMENT_FAILED),
+					}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+						started, _ := startAttempt(ctx, payment)
+						started.Attempts = 2
+						started.Provider = "bank_transfer"
+						return started, nil
+					}).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), int64(1)).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_EWALLET}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
 						},
-						Message: "Payment processed successfully",
 					}, nil).
 					Times(1)
 
+				server.MockPaymentProcessor.EXPECT().
+					ProcessPayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Equal(t, "bank_transfer", req.Provider)
+						return paymentResponse, nil
+					}).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, "bank_transfer", payment.Provider)
+						return nil
+					}).
+					Times(1)
+
 				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAID), int32(0), gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
 			},
 			wantErr: false,
 		},
 		{
-			name: "success - process payment for auto-approved expense",
+			name: "success - auto approve pending expense and process payment",
 			request: model.ApprovalRequest{
 				ExpenseID:  124,
 				ApproverID: 0,
@@ -841,39 +1508,272 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 						AmountIDR:    75000,
 						Description:  "Auto Approved Expense",
 						ReceiptURL:   "https://example.com/receipt.jpg",
-						Status:       int32(util.EXPENSE_AUTO_APPROVED),
+						Status:       int32(util.EXPENSE_PENDING),
 						AutoApproved: true,
 					}, nil).
 					Times(1)
 
 				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
+					ApprovalExpense(gomock.Any(), &entity.ExpenseApproval{
+						ExpenseID:         124,
+						ApproverID:        0,
+						Status:            int32(util.EXPENSE_AUTO_APPROVED),
+						Notes:             "Auto approved payment",
+						ApprovedAmountIDR: 75000,
+					}, gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
 
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(startAttempt).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
+						},
+					}, nil).
+					Times(1)
+
 				server.MockPaymentProcessor.EXPECT().
 					ProcessPayment(gomock.Any(), gomock.Any()).
-					Return(&entity.PaymentProcessorResponse{
-						Data: struct {
-							ID         string `json:"id"`
-							ExternalID string `json:"external_id"`
-							Status     string `json:"status"`
-						}{
-							ID:         "TXN124",
-							ExternalID: "EXT124",
-							Status:     "SUCCESS",
+					DoAndReturn(func(_ context.Context, req *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Equal(t, int64(75000), req.AmountIDR)
+						return paymentResponse, nil
+					}).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(124), int32(util.EXPENSE_PAID), int32(1), gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - pays the adjusted amount",
+			request: model.ApprovalRequest{
+				ExpenseID:  125,
+				ApproverID: 2,
+				Notes:      "Approved partially",
+				Status:     int32(util.APPROVAL_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(125)).
+					Return(&entity.Expense{
+						ID:                125,
+						UserID:            1,
+						AmountIDR:         1000000,
+						ApprovedAmountIDR: 800000,
+						Status:            int32(util.EXPENSE_APPROVED),
+					}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+						assert.Equal(t, float64(800000), payment.AmountIDR)
+						return startAttempt(ctx, payment)
+					}).
+					Times(1)
+
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
 						},
-						Message: "Payment processed successfully",
+					}, nil).
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					ProcessPayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Equal(t, int64(800000), req.AmountIDR)
+						return paymentResponse, nil
+					}).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(125), int32(util.EXPENSE_PAID), int32(0), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, _ int32, _ int32, _ *entity.AuditLog, outbox ...*entity.OutboxMessage) error {
+						assert.Len(t, outbox, 2)
+						entry := journalOutboxEntry(t, outbox)
+						assert.Equal(t, "expense:125:payment_sent", entry.Reference)
+						assert.Equal(t, int64(1), entry.UserID)
+						assert.Equal(t, []*entity.JournalLine{
+							{Account: util.LEDGER_ACCOUNT_PAYABLE, DebitIDR: 800000},
+							{Account: util.LEDGER_ACCOUNT_BANK, CreditIDR: 800000},
+						}, entry.Lines)
+						return nil
+					}).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - succeeded payment marks expense paid on redelivery",
+			request: model.ApprovalRequest{
+				ExpenseID: 126,
+				Status:    int32(util.EXPENSE_AUTO_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(126)).
+					Return(&entity.Expense{
+						ID:                126,
+						UserID:            1,
+						AmountIDR:         75000,
+						ApprovedAmountIDR: 75000,
+						Status:            int32(util.EXPENSE_AUTO_APPROVED),
+					}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					Return(&entity.Payment{
+						ID:                1,
+						ExpenseID:         126,
+						ExternalID:        paymentExternalID(126),
+						AmountIDR:         75000,
+						Status:            int32(util.PAYMENT_SUCCEEDED),
+						ProviderPaymentID: "TXN123",
 					}, nil).
 					Times(1)
 
 				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
+					UpdateExpenseStatus(gomock.Any(), int64(126), int32(util.EXPENSE_PAID), int32(0), gomock.Any(), gomock.Any()).
 					Return(nil).
 					Times(1)
 			},
 			wantErr: false,
 		},
+		{
+			name: "success - redelivered message for a paid expense is skipped",
+			request: model.ApprovalRequest{
+				ExpenseID: 127,
+				Status:    int32(util.APPROVAL_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(127)).
+					Return(&entity.Expense{
+						ID:        127,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_PAID),
+					}, nil).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name: "success - accepted payment waits for webhook confirmation",
+			request: model.ApprovalRequest{
+				ExpenseID: 128,
+				Status:    int32(util.APPROVAL_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(128)).
+					Return(&entity.Expense{
+						ID:                128,
+						UserID:            1,
+						AmountIDR:         1500000,
+						ApprovedAmountIDR: 1500000,
+						Status:            int32(util.EXPENSE_APPROVED),
+					}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(startAttempt).
+					Times(1)
+
+				pendingResponse := *paymentResponse
+				pendingResponse.Data.Status = "PENDING"
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
+						},
+					}, nil).
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					ProcessPayment(gomock.Any(), gomock.Any()).
+					Return(&pendingResponse, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_PROCESSING), payment.Status)
+						assert.Equal(t, "TXN123", payment.ProviderPaymentID)
+						assert.Nil(t, payment.CompletedAt)
+						return nil
+					}).
+					Times(1)
+			},
+			wantErr: false,
+		},
 		{
 			name: "failure - expense not found",
 			request: model.ApprovalRequest{
@@ -889,9 +1789,10 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 			errMsg:  "failed to get expense",
 		},
 		{
-			name: "failure - expense already processed",
+			name: "failure - expense not approved",
 			request: model.ApprovalRequest{
 				ExpenseID: 123,
+				Status:    int32(util.APPROVAL_APPROVED),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
@@ -900,23 +1801,63 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 						ID:           123,
 						UserID:       1,
 						AmountIDR:    150000,
-						Description:  "Already Processed Expense",
+						Description:  "Rejected Expense",
 						ReceiptURL:   "https://example.com/receipt.jpg",
-						Status:       int32(util.EXPENSE_APPROVED), // Already approved
+						Status:       int32(util.EXPENSE_REJECTED),
 						AutoApproved: false,
 					}, nil).
 					Times(1)
 			},
 			wantErr: true,
-			errMsg:  "expense is not pending",
+			errMsg:  "expense is not approved",
+		},
+		{
+			name: "failure - auto approval above threshold",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Status:    int32(util.EXPENSE_AUTO_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 2000000,
+						Status:    int32(util.EXPENSE_PENDING),
+					}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "expense amount requires manual approval",
+		},
+		{
+			name: "failure - auto approval over the spending limit",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Status:    int32(util.EXPENSE_AUTO_APPROVED),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 200000,
+						Status:    int32(util.EXPENSE_PENDING),
+						OverLimit: true,
+					}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "expense over the spending limit requires manual approval",
 		},
 		{
 			name: "failure - approval expense error",
 			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 2,
-				Notes:      "Should fail approval",
-				Status:     int32(util.EXPENSE_APPROVED),
+				ExpenseID: 123,
+				Notes:     "Should fail approval",
+				Status:    int32(util.EXPENSE_AUTO_APPROVED),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
@@ -927,12 +1868,12 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 						AmountIDR:    150000,
 						Description:  "Test Expense",
 						Status:       int32(util.EXPENSE_PENDING),
-						AutoApproved: false,
+						AutoApproved: true,
 					}, nil).
 					Times(1)
 
 				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
+					ApprovalExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(errors.New("approval failed")).
 					Times(1)
 			},
@@ -940,87 +1881,101 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 			errMsg:  "failed to approve expense",
 		},
 		{
-			name: "failure - payment processor error",
+			name: "failure - payment attempt cannot be recorded",
 			request: model.ApprovalRequest{
-				ExpenseID:  123,
-				ApproverID: 2,
-				Notes:      "Payment should fail",
-				Status:     int32(util.EXPENSE_APPROVED),
+				ExpenseID: 123,
+				Status:    int32(util.APPROVAL_APPROVED),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(123)).
 					Return(&entity.Expense{
-						ID:           123,
-						UserID:       1,
-						AmountIDR:    150000,
-						Description:  "Test Expense",
-						Status:       int32(util.EXPENSE_PENDING),
-						AutoApproved: false,
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 1500000,
+						Status:    int32(util.EXPENSE_APPROVED),
 					}, nil).
 					Times(1)
 
-				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(nil).
-					Times(1)
-
-				server.MockPaymentProcessor.EXPECT().
-					ProcessPayment(gomock.Any(), gomock.Any()).
-					Return(nil, errors.New("payment processor unavailable")).
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("database error")).
 					Times(1)
 			},
 			wantErr: true,
-			errMsg:  "failed to process payment",
+			errMsg:  "failed to record payment attempt",
 		},
 		{
-			name: "success - audit log error should not fail",
+			name: "failure - payment processor error",
 			request: model.ApprovalRequest{
 				ExpenseID:  123,
 				ApproverID: 2,
-				Notes:      "Audit log will fail",
-				Status:     int32(util.EXPENSE_APPROVED),
+				Notes:      "Payment should fail",
+				Status:     int32(util.APPROVAL_APPROVED),
 			},
 			mock: func(server *TestService) {
 				server.MockRepo.EXPECT().
 					GetExpenseByID(gomock.Any(), int64(123)).
 					Return(&entity.Expense{
-						ID:           123,
-						UserID:       1,
-						AmountIDR:    150000,
-						Description:  "Test Expense",
-						Status:       int32(util.EXPENSE_PENDING),
-						AutoApproved: false,
+						ID:                123,
+						UserID:            1,
+						AmountIDR:         1500000,
+						ApprovedAmountIDR: 1500000,
+						Description:       "Test Expense",
+						Status:            int32(util.EXPENSE_APPROVED),
+						AutoApproved:      false,
 					}, nil).
 					Times(1)
 
-				server.MockRepo.EXPECT().
-					ApprovalExpense(gomock.Any(), gomock.Any()).
-					Return(nil).
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(startAttempt).
 					Times(1)
 
-				server.MockPaymentProcessor.EXPECT().
-					ProcessPayment(gomock.Any(), gomock.Any()).
-					Return(&entity.PaymentProcessorResponse{
-						Data: struct {
-							ID         string `json:"id"`
-							ExternalID string `json:"external_id"`
-							Status     string `json:"status"`
-						}{
-							ID:         "TXN123",
-							ExternalID: "EXT123",
-							Status:     "SUCCESS",
+				server.MockUserRepo.EXPECT().
+					GetUserByID(gomock.Any(), gomock.Any()).
+					Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), int64(1)).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
 						},
-						Message: "Payment processed successfully",
 					}, nil).
 					Times(1)
 
+				server.MockPaymentProcessor.EXPECT().
+					ProcessPayment(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("payment processor unavailable")).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_FAILED), payment.Status)
+						assert.Equal(t, "payment processor unavailable", payment.LastError)
+						return nil
+					}).
+					Times(1)
+
 				server.MockRepo.EXPECT().
-					WriteAuditLog(gomock.Any(), gomock.Any()).
-					Return(errors.New("audit log failed")).
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAYMENT_FAILED), int32(0), gomock.Any(), gomock.Any()).
+					Return(nil).
 					Times(1)
 			},
-			wantErr: false, // Should not fail even if audit log fails
+			wantErr: true,
+			errMsg:  "failed to process payment",
 		},
 	}
 
@@ -1046,3 +2001,8 @@ func TestExpensesService_ProcessPayment(t *testing.T) {
 		})
 	}
 }
+
+func TestPaymentExternalID(t *testing.T) {
+	assert.Equal(t, paymentExternalID(123), paymentExternalID(123))
+	assert.NotEqual(t, paymentExternalID(123), paymentExternalID(124))
+}
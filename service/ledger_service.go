@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultJournalEntryLimit = 50
+
+	advanceKindIssue  = "issue"
+	advanceKindReturn = "return"
+)
+
+// newJournalOutboxMessages books the expense transitions that move money:
+// approval owes the employee, the payout settles it and a reversal brings the
+// money back and cancels the expense. The entry is written to the outbox with
+// the status change and posted by the relay, which retries until the ledger
+// has it.
+func newJournalOutboxMessages(req statemachine.Request, to util.ExpenseStatus) ([]*entity.OutboxMessage, error) {
+	entry := journalEntryForTransition(statemachine.Result{Request: req, From: util.ExpenseStatus(req.Expense.Status), To: to})
+	if entry == nil {
+		return nil, nil
+	}
+	entry.PostedAt = time.Now()
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*entity.OutboxMessage{{
+		AggregateID: req.Expense.ID,
+		EventType:   util.OUTBOX_EVENT_JOURNAL_ENTRY,
+		Payload:     payload,
+	}}, nil
+}
+
+func journalEntryForTransition(result statemachine.Result) *entity.JournalEntry {
+	expense := result.Expense
+	var kind, debit, credit, description string
+	var amount float64
+
+	switch result.To {
+	case util.EXPENSE_APPROVED, util.EXPENSE_AUTO_APPROVED:
+		kind, debit, credit = util.JOURNAL_EXPENSE_APPROVED, util.LEDGER_ACCOUNT_EXPENSE, util.LEDGER_ACCOUNT_PAYABLE
+		amount = expense.AmountIDR
+		if result.AmountAfter > 0 {
+			amount = result.AmountAfter
+		}
+		description = fmt.Sprintf("Expense %d approved", expense.ID)
+	case util.EXPENSE_PAID:
+		kind, debit, credit = util.JOURNAL_PAYMENT_SENT, util.LEDGER_ACCOUNT_PAYABLE, util.LEDGER_ACCOUNT_BANK
+		amount = payableAmount(expense)
+		description = fmt.Sprintf("Expense %d paid out", expense.ID)
+	case util.EXPENSE_REVERSED:
+		kind, debit, credit = util.JOURNAL_PAYMENT_REVERSED, util.LEDGER_ACCOUNT_BANK, util.LEDGER_ACCOUNT_EXPENSE
+		amount = result.AmountBefore
+		description = fmt.Sprintf("Expense %d reversed", expense.ID)
+	default:
+		return nil
+	}
+
+	return &entity.JournalEntry{
+		Reference:   fmt.Sprintf("expense:%d:%s", expense.ID, kind),
+		Kind:        kind,
+		UserID:      expense.UserID,
+		ExpenseID:   expense.ID,
+		Description: description,
+		CreatedBy:   result.Actor.ID,
+		Lines:       journalLines(debit, credit, amount),
+	}
+}
+
+func journalLines(debit, credit string, amount float64) []*entity.JournalLine {
+	return []*entity.JournalLine{
+		{Account: debit, DebitIDR: amount},
+		{Account: credit, CreditIDR: amount},
+	}
+}
+
+// postJournalEntry checks the entry balances before it is posted. An entry
+// that was posted before is left as it is.
+func (s *ExpensesManagementService) postJournalEntry(ctx context.Context, entry *entity.JournalEntry) error {
+	err := validateJournalEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = time.Now()
+	}
+	id, err := s.repo.LedgerRepository.PostJournalEntry(ctx, entry)
+	if errors.Is(err, util.ErrJournalEntryExists) {
+		s.logger.WithField("reference", entry.Reference).Info("Journal entry already posted, skipping")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	return nil
+}
+
+func validateJournalEntry(entry *entity.JournalEntry) error {
+	if len(entry.Lines) < 2 {
+		return util.ErrUnbalancedJournalEntry
+	}
+
+	var debit, credit int64
+	for _, line := range entry.Lines {
+		lineDebit, lineCredit := toCents(line.DebitIDR), toCents(line.CreditIDR)
+		if !util.IsLedgerAccount(line.Account) || lineDebit < 0 || lineCredit < 0 || (lineDebit == 0) == (lineCredit == 0) {
+			return fmt.Errorf("%w: line on %q must either debit or credit a known account", util.ErrUnbalancedJournalEntry, line.Account)
+		}
+		debit += lineDebit
+		credit += lineCredit
+	}
+
+	if debit != credit {
+		return util.ErrUnbalancedJournalEntry
+	}
+	return nil
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// RecordAdvance posts cash handed to an employee ahead of their expenses, or
+// the unspent part returned by them.
+func (s *ExpensesManagementService) RecordAdvance(ctx context.Context, req model.AdvanceRequest) (*model.JournalEntryResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).Info("RecordAdvance")
+
+	if req.UserID <= 0 || req.AmountIDR <= 0 {
+		return nil, fmt.Errorf("%w: user_id and a positive amount_idr are required", util.ErrInvalidAdvance)
+	}
+
+	entry := &entity.JournalEntry{
+		Reference:   "advance:" + uuid.New().String(),
+		UserID:      req.UserID,
+		Description: req.Description,
+		CreatedBy:   userInfo.ID,
+	}
+	description := "Advance issued"
+	switch req.Kind {
+	case "", advanceKindIssue:
+		entry.Kind = util.JOURNAL_ADVANCE_ISSUED
+		entry.Lines = journalLines(util.LEDGER_ACCOUNT_ADVANCE, util.LEDGER_ACCOUNT_BANK, req.AmountIDR)
+	case advanceKindReturn:
+		entry.Kind = util.JOURNAL_ADVANCE_RETURNED
+		entry.Lines = journalLines(util.LEDGER_ACCOUNT_BANK, util.LEDGER_ACCOUNT_ADVANCE, req.AmountIDR)
+		description = "Advance returned"
+	default:
+		return nil, fmt.Errorf("%w: kind must be %s or %s", util.ErrInvalidAdvance, advanceKindIssue, advanceKindReturn)
+	}
+	if entry.Description == "" {
+		entry.Description = description
+	}
+
+	_, err = s.repo.UserRepository.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user")
+		return nil, fmt.Errorf("failed to get user")
+	}
+
+	err = s.postJournalEntry(ctx, entry)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to post journal entry")
+		return nil, fmt.Errorf("failed to record advance")
+	}
+
+	response := toJournalEntryResponse(entry)
+	return &response, nil
+}
+
+func (s *ExpensesManagementService) GetJournalEntries(ctx context.Context, query model.JournalEntryQuery) (*model.JournalEntryListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultJournalEntryLimit
+	}
+
+	entries, err := s.repo.LedgerRepository.GetJournalEntries(ctx, &entity.JournalEntryQuery{
+		UserID:    query.UserID,
+		ExpenseID: query.ExpenseID,
+		Limit:     query.Limit,
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get journal entries")
+		return nil, fmt.Errorf("failed to get journal entries")
+	}
+
+	response := &model.JournalEntryListResponse{Entries: make([]model.JournalEntryResponse, 0)}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, toJournalEntryResponse(entry))
+	}
+
+	return response, nil
+}
+
+// GetAccountBalances returns the balance of every account, of one employee's
+// entries when userID is set.
+func (s *ExpensesManagementService) GetAccountBalances(ctx context.Context, userID int64) (*model.LedgerBalanceResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.getAccountBalances(ctx, userID)
+}
+
+// GetMyLedgerBalance returns the balances of the caller's own entries, such as
+// what the company still owes them.
+func (s *ExpensesManagementService) GetMyLedgerBalance(ctx context.Context) (*model.LedgerBalanceResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	return s.getAccountBalances(ctx, userInfo.ID)
+}
+
+func (s *ExpensesManagementService) getAccountBalances(ctx context.Context, userID int64) (*model.LedgerBalanceResponse, error) {
+	balances, err := s.repo.LedgerRepository.GetAccountBalances(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get account balances")
+		return nil, fmt.Errorf("failed to get account balances")
+	}
+
+	response := &model.LedgerBalanceResponse{UserID: userID, Accounts: make([]model.AccountBalanceResponse, 0)}
+	for _, balance := range balances {
+		response.Accounts = append(response.Accounts, toAccountBalanceResponse(balance))
+	}
+
+	return response, nil
+}
+
+// GetEmployeeBalances returns the account balances of every employee, only of
+// account when it is set.
+func (s *ExpensesManagementService) GetEmployeeBalances(ctx context.Context, account string) (*model.EmployeeBalanceListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if account != "" && !util.IsLedgerAccount(account) {
+		s.logger.WithField("account", account).Error("unknown ledger account")
+		return nil, fmt.Errorf("unknown ledger account")
+	}
+
+	balances, err := s.repo.LedgerRepository.GetEmployeeBalances(ctx, account)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get employee balances")
+		return nil, fmt.Errorf("failed to get employee balances")
+	}
+
+	response := &model.EmployeeBalanceListResponse{Employees: make([]model.LedgerBalanceResponse, 0)}
+	for _, balance := range balances {
+		last := len(response.Employees) - 1
+		if last < 0 || response.Employees[last].UserID != balance.UserID {
+			response.Employees = append(response.Employees, model.LedgerBalanceResponse{UserID: balance.UserID, Accounts: make([]model.AccountBalanceResponse, 0)})
+			last++
+		}
+		response.Employees[last].Accounts = append(response.Employees[last].Accounts, toAccountBalanceResponse(balance))
+	}
+
+	return response, nil
+}
+
+func toAccountBalanceResponse(balance *entity.AccountBalance) model.AccountBalanceResponse {
+	amount := balance.DebitIDR - balance.CreditIDR
+	if util.IsCreditNormal(balance.Account) {
+		amount = -amount
+	}
+	return model.AccountBalanceResponse{
+		Account:    balance.Account,
+		DebitIDR:   balance.DebitIDR,
+		CreditIDR:  balance.CreditIDR,
+		BalanceIDR: amount,
+	}
+}
+
+func toJournalEntryResponse(entry *entity.JournalEntry) model.JournalEntryResponse {
+	lines := make([]model.JournalLineResponse, 0)
+	for _, line := range entry.Lines {
+		lines = append(lines, model.JournalLineResponse{
+			Account:   line.Account,
+			DebitIDR:  line.DebitIDR,
+			CreditIDR: line.CreditIDR,
+		})
+	}
+
+	return model.JournalEntryResponse{
+		ID:          entry.ID,
+		Reference:   entry.Reference,
+		Kind:        entry.Kind,
+		UserID:      entry.UserID,
+		ExpenseID:   entry.ExpenseID,
+		Description: entry.Description,
+		CreatedBy:   entry.CreatedBy,
+		PostedAt:    entry.PostedAt,
+		Lines:       lines,
+	}
+}
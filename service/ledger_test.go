@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalEntryForTransition(t *testing.T) {
+	tests := []struct {
+		name   string
+		result statemachine.Result
+		want   *entity.JournalEntry
+	}{
+		{
+			name: "approval owes the employee the claimed amount",
+			result: statemachine.Result{
+				Request: statemachine.Request{Expense: &entity.Expense{ID: 7, UserID: 3, AmountIDR: 250000}, Actor: model.User{ID: 2}},
+				From:    util.EXPENSE_PENDING,
+				To:      util.EXPENSE_APPROVED,
+			},
+			want: &entity.JournalEntry{
+				Reference:   "expense:7:expense_approved",
+				Kind:        util.JOURNAL_EXPENSE_APPROVED,
+				UserID:      3,
+				ExpenseID:   7,
+				Description: "Expense 7 approved",
+				CreatedBy:   2,
+				Lines: []*entity.JournalLine{
+					{Account: util.LEDGER_ACCOUNT_EXPENSE, DebitIDR: 250000},
+					{Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 250000},
+				},
+			},
+		},
+		{
+			name: "payout settles the approved amount",
+			result: statemachine.Result{
+				Request: statemachine.Request{Expense: &entity.Expense{ID: 7, UserID: 3, AmountIDR: 250000, ApprovedAmountIDR: 200000}},
+				From:    util.EXPENSE_APPROVED,
+				To:      util.EXPENSE_PAID,
+			},
+			want: &entity.JournalEntry{
+				Reference:   "expense:7:payment_sent",
+				Kind:        util.JOURNAL_PAYMENT_SENT,
+				UserID:      3,
+				ExpenseID:   7,
+				Description: "Expense 7 paid out",
+				Lines: []*entity.JournalLine{
+					{Account: util.LEDGER_ACCOUNT_PAYABLE, DebitIDR: 200000},
+					{Account: util.LEDGER_ACCOUNT_BANK, CreditIDR: 200000},
+				},
+			},
+		},
+		{
+			name: "reversal brings the payout back",
+			result: statemachine.Result{
+				Request: statemachine.Request{Expense: &entity.Expense{ID: 7, UserID: 3, AmountIDR: 250000}, Actor: model.User{ID: 1}, AmountBefore: 200000},
+				From:    util.EXPENSE_PAID,
+				To:      util.EXPENSE_REVERSED,
+			},
+			want: &entity.JournalEntry{
+				Reference:   "expense:7:payment_reversed",
+				Kind:        util.JOURNAL_PAYMENT_REVERSED,
+				UserID:      3,
+				ExpenseID:   7,
+				Description: "Expense 7 reversed",
+				CreatedBy:   1,
+				Lines: []*entity.JournalLine{
+					{Account: util.LEDGER_ACCOUNT_BANK, DebitIDR: 200000},
+					{Account: util.LEDGER_ACCOUNT_EXPENSE, CreditIDR: 200000},
+				},
+			},
+		},
+		{
+			name: "rejection moves no money",
+			result: statemachine.Result{
+				Request: statemachine.Request{Expense: &entity.Expense{ID: 7, UserID: 3, AmountIDR: 250000}},
+				From:    util.EXPENSE_PENDING,
+				To:      util.EXPENSE_REJECTED,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, journalEntryForTransition(tt.result))
+		})
+	}
+}
+
+func TestValidateJournalEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []*entity.JournalLine
+		wantErr bool
+	}{
+		{
+			name:  "balanced",
+			lines: journalLines(util.LEDGER_ACCOUNT_EXPENSE, util.LEDGER_ACCOUNT_PAYABLE, 150000.5),
+		},
+		{
+			name: "debits exceed credits",
+			lines: []*entity.JournalLine{
+				{Account: util.LEDGER_ACCOUNT_EXPENSE, DebitIDR: 150000},
+				{Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 100000},
+			},
+			wantErr: true,
+		},
+		{
+			name: "line with both sides",
+			lines: []*entity.JournalLine{
+				{Account: util.LEDGER_ACCOUNT_EXPENSE, DebitIDR: 100, CreditIDR: 100},
+				{Account: util.LEDGER_ACCOUNT_PAYABLE, DebitIDR: 100, CreditIDR: 100},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown account",
+			lines:   journalLines("petty_cash", util.LEDGER_ACCOUNT_BANK, 100),
+			wantErr: true,
+		},
+		{
+			name:    "single line",
+			lines:   []*entity.JournalLine{{Account: util.LEDGER_ACCOUNT_BANK, DebitIDR: 100}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJournalEntry(&entity.JournalEntry{Lines: tt.lines})
+			if tt.wantErr {
+				assert.ErrorIs(t, err, util.ErrUnbalancedJournalEntry)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewJournalOutboxMessages(t *testing.T) {
+	expense := &entity.Expense{ID: 7, UserID: 3, AmountIDR: 250000, ApprovedAmountIDR: 250000, Status: int32(util.EXPENSE_APPROVED)}
+
+	messages, err := newJournalOutboxMessages(statemachine.Request{Expense: expense, Event: statemachine.EventPaymentSent}, util.EXPENSE_PAID)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, int64(7), messages[0].AggregateID)
+	assert.Equal(t, util.OUTBOX_EVENT_JOURNAL_ENTRY, messages[0].EventType)
+
+	var entry entity.JournalEntry
+	assert.NoError(t, json.Unmarshal(messages[0].Payload, &entry))
+	assert.Equal(t, "expense:7:payment_sent", entry.Reference)
+	assert.False(t, entry.PostedAt.IsZero())
+	assert.NoError(t, validateJournalEntry(&entry))
+
+	// A transition that moves no money has nothing to post.
+	messages, err = newJournalOutboxMessages(statemachine.Request{Expense: expense, Event: statemachine.EventPaymentFailed}, util.EXPENSE_PAYMENT_FAILED)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+// journalOutboxEntry decodes the journal entry queued among the outbox messages of a transition.
+func journalOutboxEntry(t *testing.T, messages []*entity.OutboxMessage) *entity.JournalEntry {
+	t.Helper()
+	for _, message := range messages {
+		if message.EventType != util.OUTBOX_EVENT_JOURNAL_ENTRY {
+			continue
+		}
+		var entry entity.JournalEntry
+		assert.NoError(t, json.Unmarshal(message.Payload, &entry))
+		return &entry
+	}
+	t.Fatal("no journal entry in the outbox messages")
+	return nil
+}
+
+func TestLedgerService_RecordAdvance(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.AdvanceRequest
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.JournalEntryResponse)
+	}{
+		{
+			name:    "success - advance issued",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AdvanceRequest{UserID: 3, AmountIDR: 1000000},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().GetUserByID(gomock.Any(), int64(3)).Return(&entity.User{ID: 3}, nil).Times(1)
+				server.MockLedgerRepo.EXPECT().
+					PostJournalEntry(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, entry *entity.JournalEntry) (int64, error) {
+						assert.Equal(t, util.JOURNAL_ADVANCE_ISSUED, entry.Kind)
+						assert.Equal(t, int64(1), entry.CreatedBy)
+						assert.Contains(t, entry.Reference, "advance:")
+						return 9, nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.JournalEntryResponse) {
+				assert.Equal(t, int64(9), got.ID)
+				assert.Equal(t, "Advance issued", got.Description)
+				assert.Equal(t, []model.JournalLineResponse{
+					{Account: util.LEDGER_ACCOUNT_ADVANCE, DebitIDR: 1000000},
+					{Account: util.LEDGER_ACCOUNT_BANK, CreditIDR: 1000000},
+				}, got.Lines)
+			},
+		},
+		{
+			name:    "success - unspent advance returned",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AdvanceRequest{UserID: 3, AmountIDR: 200000, Kind: "return", Description: "Trip cancelled"},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().GetUserByID(gomock.Any(), int64(3)).Return(&entity.User{ID: 3}, nil).Times(1)
+				server.MockLedgerRepo.EXPECT().PostJournalEntry(gomock.Any(), gomock.Any()).Return(int64(10), nil).Times(1)
+			},
+			validate: func(t *testing.T, got *model.JournalEntryResponse) {
+				assert.Equal(t, util.JOURNAL_ADVANCE_RETURNED, got.Kind)
+				assert.Equal(t, "Trip cancelled", got.Description)
+				assert.Equal(t, []model.JournalLineResponse{
+					{Account: util.LEDGER_ACCOUNT_BANK, DebitIDR: 200000},
+					{Account: util.LEDGER_ACCOUNT_ADVANCE, CreditIDR: 200000},
+				}, got.Lines)
+			},
+		},
+		{
+			name:    "failure - amount is required",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AdvanceRequest{UserID: 3},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("advance request is not valid: user_id and a positive amount_idr are required"),
+		},
+		{
+			name:    "failure - unknown kind",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AdvanceRequest{UserID: 3, AmountIDR: 1000, Kind: "loan"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("advance request is not valid: kind must be issue or return"),
+		},
+		{
+			name:    "failure - ledger error",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.AdvanceRequest{UserID: 3, AmountIDR: 1000},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().GetUserByID(gomock.Any(), int64(3)).Return(&entity.User{ID: 3}, nil).Times(1)
+				server.MockLedgerRepo.EXPECT().PostJournalEntry(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db down")).Times(1)
+			},
+			wantErr: errors.New("failed to record advance"),
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.AdvanceRequest{UserID: 3, AmountIDR: 1000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+			tt.mock(server)
+
+			got, err := server.Service.RecordAdvance(roleContext(tt.role), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestLedgerService_GetEmployeeBalances(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.MockCtrl.Finish()
+
+	server.MockLedgerRepo.EXPECT().
+		GetEmployeeBalances(gomock.Any(), "").
+		Return([]*entity.AccountBalance{
+			{UserID: 2, Account: util.LEDGER_ACCOUNT_BANK, CreditIDR: 150000},
+			{UserID: 2, Account: util.LEDGER_ACCOUNT_PAYABLE, DebitIDR: 150000, CreditIDR: 400000},
+			{UserID: 3, Account: util.LEDGER_ACCOUNT_ADVANCE, DebitIDR: 1000000, CreditIDR: 200000},
+		}, nil).
+		Times(1)
+
+	got, err := server.Service.GetEmployeeBalances(roleContext(util.USER_ROLE_ADMIN), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.EmployeeBalanceListResponse{
+		Employees: []model.LedgerBalanceResponse{
+			{
+				UserID: 2,
+				Accounts: []model.AccountBalanceResponse{
+					{Account: util.LEDGER_ACCOUNT_BANK, CreditIDR: 150000, BalanceIDR: -150000},
+					{Account: util.LEDGER_ACCOUNT_PAYABLE, DebitIDR: 150000, CreditIDR: 400000, BalanceIDR: 250000},
+				},
+			},
+			{
+				UserID: 3,
+				Accounts: []model.AccountBalanceResponse{
+					{Account: util.LEDGER_ACCOUNT_ADVANCE, DebitIDR: 1000000, CreditIDR: 200000, BalanceIDR: 800000},
+				},
+			},
+		},
+	}, got)
+
+	_, err = server.Service.GetEmployeeBalances(roleContext(util.USER_ROLE_ADMIN), "petty_cash")
+	assert.EqualError(t, err, "unknown ledger account")
+}
+
+func TestLedgerService_GetMyLedgerBalance(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.MockCtrl.Finish()
+
+	server.MockLedgerRepo.EXPECT().
+		GetAccountBalances(gomock.Any(), int64(1)).
+		Return([]*entity.AccountBalance{
+			{UserID: 1, Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 75000},
+		}, nil).
+		Times(1)
+
+	got, err := server.Service.GetMyLedgerBalance(roleContext(util.USER_ROLE_EMPLOYEE))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.LedgerBalanceResponse{
+		UserID:   1,
+		Accounts: []model.AccountBalanceResponse{{Account: util.LEDGER_ACCOUNT_PAYABLE, CreditIDR: 75000, BalanceIDR: 75000}},
+	}, got)
+}
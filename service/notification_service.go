@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+func (s *ExpensesManagementService) GetNotifications(ctx context.Context, query model.NotificationQuery) (*model.NotificationListResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	notifications, err := s.repo.NotificationRepository.GetNotifications(ctx, userInfo.ID, query.UnreadOnly)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get notifications")
+		return nil, fmt.Errorf("failed to get notifications")
+	}
+
+	notificationsResponse := make([]model.NotificationResponse, 0)
+	for _, notification := range notifications {
+		notificationsResponse = append(notificationsResponse, model.NotificationResponse{
+			ID:        notification.ID,
+			ExpenseID: notification.ExpenseID,
+			CommentID: notification.CommentID,
+			Type:      util.GetNotificationTypeString(util.NotificationType(notification.Type)),
+			Message:   notification.Message,
+			Read:      notification.ReadAt != nil,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+
+	return &model.NotificationListResponse{
+		Notifications: notificationsResponse,
+	}, nil
+}
+
+func (s *ExpensesManagementService) MarkNotificationRead(ctx context.Context, notificationID int64) error {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return fmt.Errorf("failed to get user info")
+	}
+
+	err = s.repo.NotificationRepository.MarkNotificationRead(ctx, userInfo.ID, notificationID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to mark notification as read")
+		return fmt.Errorf("failed to mark notification as read")
+	}
+
+	return nil
+}
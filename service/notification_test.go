@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationService_GetNotifications(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name    string
+		query   model.NotificationQuery
+		mock    func(server *TestService)
+		want    *model.NotificationListResponse
+		wantErr bool
+	}{
+		{
+			name:  "success - unread only",
+			query: model.NotificationQuery{UnreadOnly: true},
+			mock: func(server *TestService) {
+				server.MockNotificationRepo.EXPECT().
+					GetNotifications(gomock.Any(), int64(1), true).
+					Return([]*entity.Notification{
+						{ID: 1, UserID: 1, ExpenseID: 123, CommentID: 10, Type: int32(util.NOTIFICATION_MENTION), Message: "manager@company.com mentioned you on expense 123", CreatedAt: createdAt},
+					}, nil).
+					Times(1)
+			},
+			want: &model.NotificationListResponse{
+				Notifications: []model.NotificationResponse{
+					{ID: 1, ExpenseID: 123, CommentID: 10, Type: "mention", Message: "manager@company.com mentioned you on expense 123", Read: false, CreatedAt: createdAt},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:  "failure - database error",
+			query: model.NotificationQuery{},
+			mock: func(server *TestService) {
+				server.MockNotificationRepo.EXPECT().
+					GetNotifications(gomock.Any(), int64(1), false).
+					Return(nil, errors.New("database error")).
+					Times(1)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithComments(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", int64(1))
+			ctx = context.WithValue(ctx, "user_email", "john.doe@company.com")
+			ctx = context.WithValue(ctx, "user_role", int(util.USER_ROLE_EMPLOYEE))
+
+			tt.mock(server)
+
+			got, err := server.Service.GetNotifications(ctx, tt.query)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNotificationService_MarkNotificationRead(t *testing.T) {
+	server := NewTestServerWithComments(t)
+	defer server.MockCtrl.Finish()
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "user_id", int64(1))
+	ctx = context.WithValue(ctx, "user_email", "john.doe@company.com")
+	ctx = context.WithValue(ctx, "user_role", int(util.USER_ROLE_EMPLOYEE))
+
+	server.MockNotificationRepo.EXPECT().
+		MarkNotificationRead(gomock.Any(), int64(1), int64(7)).
+		Return(errors.New("notification not found")).
+		Times(1)
+
+	err := server.Service.MarkNotificationRead(ctx, 7)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to mark notification as read")
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+const (
+	outboxLease       = 30 * time.Second
+	outboxBaseBackoff = time.Second
+	outboxMaxBackoff  = 5 * time.Minute
+)
+
+func newPaymentOutboxMessage(request *entity.PublishPaymentRequest) (*entity.OutboxMessage, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.OutboxMessage{
+		AggregateID: request.ExpenseID,
+		EventType:   util.OUTBOX_EVENT_PAYMENT_REQUESTED,
+		Payload:     payload,
+	}, nil
+}
+
+// RelayOutboxMessages publishes due outbox messages to the broker and returns
+// how many were published. Failed messages are retried with exponential backoff
+// until OutboxMaxAttempts is reached. Journal entries are never given up on, so
+// the ledger cannot miss a transition.
+func (s *ExpensesManagementService) RelayOutboxMessages(ctx context.Context, limit int) (int, error) {
+	messages, err := s.repo.OutboxRepository.ClaimOutboxMessages(ctx, limit, outboxLease)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to claim outbox messages")
+		return 0, fmt.Errorf("failed to claim outbox messages")
+	}
+
+	published := 0
+	for _, message := range messages {
+		err := s.relayOutboxMessage(ctx, message)
+		if err != nil {
+			s.retryOutboxMessage(ctx, message, err)
+			continue
+		}
+
+		err = s.repo.OutboxRepository.MarkOutboxPublished(ctx, message.ID)
+		if err != nil {
+			s.logger.WithError(err).WithField("outbox_id", message.ID).Error("failed to mark outbox message as published")
+			continue
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// relayOutboxMessage posts journal entries to the ledger and publishes every
+// other message to the broker.
+func (s *ExpensesManagementService) relayOutboxMessage(ctx context.Context, message *entity.OutboxMessage) error {
+	if message.EventType != util.OUTBOX_EVENT_JOURNAL_ENTRY {
+		return s.repo.MessageBroker.PublishMessage(message)
+	}
+
+	var entry entity.JournalEntry
+	if err := json.Unmarshal(message.Payload, &entry); err != nil {
+		return err
+	}
+	return s.postJournalEntry(ctx, &entry)
+}
+
+func (s *ExpensesManagementService) retryOutboxMessage(ctx context.Context, message *entity.OutboxMessage, publishErr error) {
+	message.Attempts++
+	message.LastError = publishErr.Error()
+	message.NextAttemptAt = time.Now().Add(outboxBackoff(message.Attempts))
+	logger := s.logger.WithError(publishErr).
+		WithField("outbox_id", message.ID).
+		WithField("attempts", message.Attempts)
+	if message.Attempts >= util.OutboxMaxAttempts {
+		if message.EventType == util.OUTBOX_EVENT_JOURNAL_ENTRY {
+			logger.WithField("expense_id", message.AggregateID).Error("journal entry keeps failing to post, the ledger is behind")
+		} else {
+			message.Status = int32(util.OUTBOX_FAILED)
+		}
+	}
+
+	logger.Error("failed to publish outbox message")
+
+	err := s.repo.OutboxRepository.UpdateOutboxAttempt(ctx, message)
+	if err != nil {
+		s.logger.WithError(err).WithField("outbox_id", message.ID).Error("failed to update outbox message")
+	}
+}
+
+func outboxBackoff(attempts int32) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := int32(1); i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
+
+func (s *ExpensesManagementService) GetOutboxStats(ctx context.Context) (*model.OutboxStatsResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.OutboxRepository.GetOutboxStats(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get outbox stats")
+		return nil, fmt.Errorf("failed to get outbox stats")
+	}
+
+	response := &model.OutboxStatsResponse{
+		Pending:         stats.Pending,
+		Failed:          stats.Failed,
+		Published:       stats.Published,
+		OldestPendingAt: stats.OldestPendingAt,
+	}
+	if stats.OldestPendingAt != nil {
+		response.LagSeconds = time.Since(*stats.OldestPendingAt).Seconds()
+	}
+
+	return response, nil
+}
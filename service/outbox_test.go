@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxService_RelayOutboxMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		mock    func(server *TestService)
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "success - publishes and marks messages",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 123, EventType: util.OUTBOX_EVENT_PAYMENT_REQUESTED, Payload: []byte(`{"expense_id":123}`)},
+						{ID: 2, AggregateID: 124, EventType: util.OUTBOX_EVENT_PAYMENT_REQUESTED, Payload: []byte(`{"expense_id":124}`)},
+					}, nil).
+					Times(1)
+
+				server.MockBroker.EXPECT().
+					PublishMessage(gomock.Any()).
+					Return(nil).
+					Times(2)
+
+				server.MockOutboxRepo.EXPECT().
+					MarkOutboxPublished(gomock.Any(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					MarkOutboxPublished(gomock.Any(), int64(2)).
+					Return(nil).
+					Times(1)
+			},
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name: "success - journal entries are posted to the ledger",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 7, EventType: util.OUTBOX_EVENT_JOURNAL_ENTRY, Payload: []byte(`{"Reference":"expense:7:payment_sent","Kind":"payment_sent","UserID":3,"ExpenseID":7,"PostedAt":"2024-03-01T10:00:00Z","Lines":[{"Account":"employee_payable","DebitIDR":250000},{"Account":"bank","CreditIDR":250000}]}`)},
+						{ID: 2, AggregateID: 8, EventType: util.OUTBOX_EVENT_JOURNAL_ENTRY, Payload: []byte(`{"Reference":"expense:8:payment_sent","Kind":"payment_sent","UserID":3,"ExpenseID":8,"Lines":[{"Account":"employee_payable","DebitIDR":100000},{"Account":"bank","CreditIDR":100000}]}`)},
+					}, nil).
+					Times(1)
+
+				server.MockLedgerRepo.EXPECT().
+					PostJournalEntry(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, entry *entity.JournalEntry) (int64, error) {
+						assert.Equal(t, "expense:7:payment_sent", entry.Reference)
+						assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), entry.PostedAt)
+						assert.Len(t, entry.Lines, 2)
+						return 5, nil
+					}).
+					Times(1)
+
+				// A redelivered entry that is already in the ledger is done.
+				server.MockLedgerRepo.EXPECT().
+					PostJournalEntry(gomock.Any(), gomock.Any()).
+					Return(int64(0), util.ErrJournalEntryExists).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					MarkOutboxPublished(gomock.Any(), int64(1)).
+					Return(nil).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					MarkOutboxPublished(gomock.Any(), int64(2)).
+					Return(nil).
+					Times(1)
+			},
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name: "success - failed journal entry is scheduled for retry",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 7, EventType: util.OUTBOX_EVENT_JOURNAL_ENTRY, Status: int32(util.OUTBOX_PENDING), Payload: []byte(`{"Reference":"expense:7:payment_sent","Lines":[{"Account":"employee_payable","DebitIDR":250000},{"Account":"bank","CreditIDR":250000}]}`)},
+					}, nil).
+					Times(1)
+
+				server.MockLedgerRepo.EXPECT().
+					PostJournalEntry(gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("database error")).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					UpdateOutboxAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, message *entity.OutboxMessage) error {
+						assert.Equal(t, int32(1), message.Attempts)
+						assert.Equal(t, "database error", message.LastError)
+						return nil
+					}).
+					Times(1)
+			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name: "success - failed publish is scheduled for retry",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 123, EventType: util.OUTBOX_EVENT_PAYMENT_REQUESTED, Status: int32(util.OUTBOX_PENDING), Attempts: 2},
+					}, nil).
+					Times(1)
+
+				server.MockBroker.EXPECT().
+					PublishMessage(gomock.Any()).
+					Return(errors.New("broker unavailable")).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					UpdateOutboxAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, message *entity.OutboxMessage) error {
+						assert.Equal(t, int32(3), message.Attempts)
+						assert.Equal(t, int32(util.OUTBOX_PENDING), message.Status)
+						assert.Equal(t, "broker unavailable", message.LastError)
+						assert.True(t, message.NextAttemptAt.After(time.Now().Add(3*time.Second)))
+						return nil
+					}).
+					Times(1)
+			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name: "success - message is marked failed after max attempts",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 123, EventType: util.OUTBOX_EVENT_PAYMENT_REQUESTED, Status: int32(util.OUTBOX_PENDING), Attempts: util.OutboxMaxAttempts - 1},
+					}, nil).
+					Times(1)
+
+				server.MockBroker.EXPECT().
+					PublishMessage(gomock.Any()).
+					Return(errors.New("broker unavailable")).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					UpdateOutboxAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, message *entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.OUTBOX_FAILED), message.Status)
+						return nil
+					}).
+					Times(1)
+			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name: "success - journal entry keeps retrying after max attempts",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return([]*entity.OutboxMessage{
+						{ID: 1, AggregateID: 7, EventType: util.OUTBOX_EVENT_JOURNAL_ENTRY, Status: int32(util.OUTBOX_PENDING), Attempts: util.OutboxMaxAttempts + 5, Payload: []byte(`{"Reference":"expense:7:payment_sent","Lines":[{"Account":"employee_payable","DebitIDR":250000},{"Account":"bank","CreditIDR":250000}]}`)},
+					}, nil).
+					Times(1)
+
+				server.MockLedgerRepo.EXPECT().
+					PostJournalEntry(gomock.Any(), gomock.Any()).
+					Return(int64(0), errors.New("database error")).
+					Times(1)
+
+				server.MockOutboxRepo.EXPECT().
+					UpdateOutboxAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, message *entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.OUTBOX_PENDING), message.Status)
+						assert.Equal(t, int32(util.OutboxMaxAttempts+6), message.Attempts)
+						assert.True(t, message.NextAttemptAt.Before(time.Now().Add(outboxMaxBackoff+time.Second)))
+						return nil
+					}).
+					Times(1)
+			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name: "failure - claim error",
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					ClaimOutboxMessages(gomock.Any(), 10, outboxLease).
+					Return(nil, errors.New("database error")).
+					Times(1)
+			},
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithOutbox(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.RelayOutboxMessages(context.Background(), 10)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOutboxService_GetOutboxStats(t *testing.T) {
+	oldestPendingAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		userCtx model.User
+		mock    func(server *TestService)
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "success - admin gets outbox stats",
+			userCtx: model.User{ID: 1, Email: "admin@example.com", Role: int(util.USER_ROLE_ADMIN)},
+			mock: func(server *TestService) {
+				server.MockOutboxRepo.EXPECT().
+					GetOutboxStats(gomock.Any()).
+					Return(&entity.OutboxStats{Pending: 3, Failed: 1, Published: 20, OldestPendingAt: &oldestPendingAt}, nil).
+					Times(1)
+			},
+			wantErr: false,
+		},
+		{
+			name:    "failure - not an admin",
+			userCtx: model.User{ID: 2, Email: "manager@example.com", Role: int(util.USER_ROLE_MANAGER)},
+			mock:    func(server *TestService) {},
+			wantErr: true,
+			errMsg:  "user is not an admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithOutbox(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.WithValue(context.Background(), "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+			tt.mock(server)
+
+			got, err := server.Service.GetOutboxStats(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), got.Pending)
+			assert.Equal(t, int64(1), got.Failed)
+			assert.GreaterOrEqual(t, got.LagSeconds, float64(60))
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+)
+
+// markExpensePaid moves the expense to PAID once its payment has succeeded. It
+// is a no-op when the expense is already paid, so redeliveries are safe.
+func (s *ExpensesManagementService) markExpensePaid(ctx context.Context, expense *entity.Expense, payment *entity.Payment) error {
+	if expense.Status == int32(util.EXPENSE_PAID) {
+		return nil
+	}
+
+	transition := statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventPaymentSent,
+		Notes:   fmt.Sprintf("Payment %s sent", payment.ExternalID),
+	}
+	_, err := s.transitionExpense(ctx, transition, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		paymentEvent, err := newPaymentEventMessage(util.EVENT_PAYMENT_SUCCEEDED, expense, payment)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build payment event")
+			return fmt.Errorf("failed to update expense status")
+		}
+		journalMessages, err := newJournalOutboxMessages(transition, to)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build journal entry")
+			return fmt.Errorf("failed to update expense status")
+		}
+
+		err = s.repo.ExpensesRepository.UpdateExpenseStatus(ctx, expense.ID, int32(to), expense.Version, auditLog, append([]*entity.OutboxMessage{paymentEvent}, journalMessages...)...)
+		if err != nil {
+			return persistError(err, "failed to update expense status")
+		}
+		expense.Version++
+		return nil
+	})
+	return err
+}
+
+// markExpensePaymentFailed records a failed payout on the expense. It is a
+// no-op when the expense is already marked as failed.
+func (s *ExpensesManagementService) markExpensePaymentFailed(ctx context.Context, expense *entity.Expense, payment *entity.Payment) error {
+	if expense.Status == int32(util.EXPENSE_PAYMENT_FAILED) {
+		return nil
+	}
+
+	_, err := s.transitionExpense(ctx, statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventPaymentFailed,
+		Notes:   fmt.Sprintf("Payment %s failed: %s", payment.ExternalID, payment.LastError),
+	}, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		paymentEvent, err := newPaymentEventMessage(util.EVENT_PAYMENT_FAILED, expense, payment)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build payment event")
+			return fmt.Errorf("failed to update expense status")
+		}
+
+		err = s.repo.ExpensesRepository.UpdateExpenseStatus(ctx, expense.ID, int32(to), expense.Version, auditLog, paymentEvent)
+		if err != nil {
+			return persistError(err, "failed to update expense status")
+		}
+		expense.Version++
+		return nil
+	})
+	return err
+}
+
+// HandlePaymentWebhook applies a payment result reported asynchronously by the
+// provider, for a single expense or a payout batch transfer. Callbacks may be
+// repeated or arrive out of order, so an unchanged status is not written again
+// and a succeeded payment is never downgraded.
+func (s *ExpensesManagementService) HandlePaymentWebhook(ctx context.Context, req model.PaymentWebhookRequest, raw []byte) (*model.PaymentWebhookResponse, error) {
+	s.logger.WithField("request", req).Info("HandlePaymentWebhook")
+
+	status, ok := util.GetPaymentStatusFromProvider(req.Status)
+	if !ok {
+		s.logger.WithField("status", req.Status).Error("unknown payment status")
+		return nil, util.ErrUnknownPaymentStatus
+	}
+
+	payment, err := s.repo.PaymentRepository.GetPaymentByExternalID(ctx, req.ExternalID)
+	if err != nil {
+		if errors.Is(err, util.ErrPaymentNotFound) {
+			// Transfers of a payout batch are known by their item's external ID.
+			return s.handlePayoutBatchItemWebhook(ctx, req, status, raw)
+		}
+		s.logger.WithError(err).Error("failed to get payment")
+		return nil, fmt.Errorf("failed to get payment")
+	}
+
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, payment.ExpenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	if payment.ReversalOf != 0 {
+		return s.handleReversalWebhook(ctx, expense, payment, req, status, raw)
+	}
+
+	switch {
+	case payment.Status == int32(util.PAYMENT_SUCCEEDED) && status != util.PAYMENT_SUCCEEDED:
+		s.logger.WithField("external_id", payment.ExternalID).Warn("Payment already succeeded, ignoring callback")
+	case status == util.PAYMENT_PROCESSING:
+		s.logger.WithField("external_id", payment.ExternalID).Info("Payment still processing")
+	default:
+		if payment.Status != int32(status) {
+			completedAt := time.Now()
+			payment.Status = int32(status)
+			if req.ID != "" {
+				payment.ProviderPaymentID = req.ID
+			}
+			payment.LastError = ""
+			if status == util.PAYMENT_FAILED {
+				payment.LastError = req.FailureReason
+			}
+			payment.RawResponse = string(raw)
+			payment.CompletedAt = &completedAt
+			err = s.repo.PaymentRepository.UpdatePayment(ctx, payment)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to update payment")
+				return nil, fmt.Errorf("failed to update payment")
+			}
+		}
+
+		if status == util.PAYMENT_SUCCEEDED {
+			err = s.markExpensePaid(ctx, expense, payment)
+		} else {
+			err = s.markExpensePaymentFailed(ctx, expense, payment)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.PaymentWebhookResponse{
+		ExternalID:    payment.ExternalID,
+		PaymentStatus: util.GetPaymentStatusString(util.PaymentStatus(payment.Status)),
+		ExpenseStatus: util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+	}, nil
+}
+
+func (s *ExpensesManagementService) GetExpensePayments(ctx context.Context, expenseID int64) (*model.PaymentListResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", expenseID).Info("GetExpensePayments")
+
+	expense, err := s.getVisibleExpense(ctx, userInfo, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.repo.PaymentRepository.GetPaymentsByExpenseID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get payments")
+		return nil, fmt.Errorf("failed to get payments")
+	}
+
+	paymentsResponse := make([]model.PaymentResponse, 0)
+	for _, payment := range payments {
+		paymentsResponse = append(paymentsResponse, toPaymentResponse(payment))
+	}
+
+	return &model.PaymentListResponse{
+		ExpenseID:     expenseID,
+		ExpenseStatus: util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+		Payments:      paymentsResponse,
+	}, nil
+}
+
+func (s *ExpensesManagementService) GetPaymentProviderStats(ctx context.Context) []model.PaymentProviderStatus {
+	providers := make([]model.PaymentProviderStatus, 0)
+	for _, stats := range s.repo.PaymentProcessor.GetProviderStats() {
+		providers = append(providers, model.PaymentProviderStatus{
+			Provider:       stats.Provider,
+			CircuitState:   stats.CircuitState,
+			Requests:       stats.Requests,
+			Failures:       stats.Failures,
+			Retries:        stats.Retries,
+			ShortCircuited: stats.ShortCircuited,
+		})
+	}
+	return providers
+}
+
+func toPaymentResponse(payment *entity.Payment) model.PaymentResponse {
+	return model.PaymentResponse{
+		ID:                payment.ID,
+		ExternalID:        payment.ExternalID,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		AmountIDR:         payment.AmountIDR,
+		Status:            util.GetPaymentStatusString(util.PaymentStatus(payment.Status)),
+		Attempts:          payment.Attempts,
+		Provider:          payment.Provider,
+		LastError:         payment.LastError,
+		ReversalOf:        payment.ReversalOf,
+		CreatedAt:         payment.CreatedAt,
+		SentAt:            payment.SentAt,
+		CompletedAt:       payment.CompletedAt,
+		UpdatedAt:         payment.UpdatedAt,
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentService_GetExpensePayments(t *testing.T) {
+	createdAt := time.Now().Add(-time.Hour)
+	completedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		expenseID int64
+		userCtx   model.User
+		mock      func(server *TestService)
+		want      *model.PaymentListResponse
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "success - employee sees payments of own expense",
+			expenseID: 123,
+			userCtx:   model.User{ID: 1, Email: "employee@example.com", Role: int(util.USER_ROLE_EMPLOYEE)},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_PAID)}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Payment{
+						{
+							ID:                1,
+							ExpenseID:         123,
+							ExternalID:        "EXT123",
+							ProviderPaymentID: "TXN123",
+							AmountIDR:         150000,
+							Status:            int32(util.PAYMENT_SUCCEEDED),
+							Attempts:          2,
+							RawResponse:       `{"data":{"id":"TXN123"}}`,
+							CreatedAt:         createdAt,
+							SentAt:            &createdAt,
+							CompletedAt:       &completedAt,
+							UpdatedAt:         completedAt,
+						},
+					}, nil).
+					Times(1)
+			},
+			want: &model.PaymentListResponse{
+				ExpenseID:     123,
+				ExpenseStatus: "paid",
+				Payments: []model.PaymentResponse{
+					{
+						ID:                1,
+						ExternalID:        "EXT123",
+						ProviderPaymentID: "TXN123",
+						AmountIDR:         150000,
+						Status:            "succeeded",
+						Attempts:          2,
+						CreatedAt:         createdAt,
+						SentAt:            &createdAt,
+						CompletedAt:       &completedAt,
+						UpdatedAt:         completedAt,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failure - employee cannot see other expense payments",
+			expenseID: 123,
+			userCtx:   model.User{ID: 5, Email: "other@example.com", Role: int(util.USER_ROLE_EMPLOYEE)},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_PAID)}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "user is not allowed to view this expense",
+		},
+		{
+			name:      "failure - database error",
+			expenseID: 123,
+			userCtx:   model.User{ID: 2, Email: "manager@example.com", Role: int(util.USER_ROLE_MANAGER)},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_APPROVED)}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(123)).
+					Return(nil, errors.New("database error")).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "failed to get payments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.WithValue(context.Background(), "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+			tt.mock(server)
+
+			got, err := server.Service.GetExpensePayments(ctx, tt.expenseID)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPaymentService_HandlePaymentWebhook(t *testing.T) {
+	externalID := paymentExternalID(123)
+	processingPayment := func() *entity.Payment {
+		return &entity.Payment{
+			ID:         1,
+			ExpenseID:  123,
+			ExternalID: externalID,
+			AmountIDR:  1500000,
+			Status:     int32(util.PAYMENT_PROCESSING),
+			Attempts:   1,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		request model.PaymentWebhookRequest
+		mock    func(server *TestService)
+		want    *model.PaymentWebhookResponse
+		wantErr error
+	}{
+		{
+			name:    "success - completed payment marks expense paid",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "COMPLETED"},
+			mock: func(server *TestService) {
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(processingPayment(), nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_APPROVED), Version: 2}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), payment.Status)
+						assert.Equal(t, "TXN123", payment.ProviderPaymentID)
+						assert.NotNil(t, payment.CompletedAt)
+						return nil
+					}).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAID), int32(2), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, _ int32, _ int32, auditLog *entity.AuditLog, _ ...*entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.EXPENSE_APPROVED), auditLog.StatusBefore)
+						assert.Equal(t, int32(util.EXPENSE_PAID), auditLog.NewStatus)
+						return nil
+					}).
+					Times(1)
+			},
+			want: &model.PaymentWebhookResponse{ExternalID: externalID, PaymentStatus: "succeeded", ExpenseStatus: "paid"},
+		},
+		{
+			name:    "success - failed payment marks expense payment failed",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "FAILED", FailureReason: "account closed"},
+			mock: func(server *TestService) {
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(processingPayment(), nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_AUTO_APPROVED), Version: 2}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_FAILED), payment.Status)
+						assert.Equal(t, "account closed", payment.LastError)
+						return nil
+					}).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAYMENT_FAILED), int32(2), gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+			},
+			want: &model.PaymentWebhookResponse{ExternalID: externalID, PaymentStatus: "failed", ExpenseStatus: "payment_failed"},
+		},
+		{
+			name:    "success - repeated callback is a no-op",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "COMPLETED"},
+			mock: func(server *TestService) {
+				payment := processingPayment()
+				payment.Status = int32(util.PAYMENT_SUCCEEDED)
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(payment, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_PAID), Version: 3}, nil).
+					Times(1)
+			},
+			want: &model.PaymentWebhookResponse{ExternalID: externalID, PaymentStatus: "succeeded", ExpenseStatus: "paid"},
+		},
+		{
+			name:    "success - late failure does not downgrade a succeeded payment",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "FAILED"},
+			mock: func(server *TestService) {
+				payment := processingPayment()
+				payment.Status = int32(util.PAYMENT_SUCCEEDED)
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(payment, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_PAID), Version: 3}, nil).
+					Times(1)
+			},
+			want: &model.PaymentWebhookResponse{ExternalID: externalID, PaymentStatus: "succeeded", ExpenseStatus: "paid"},
+		},
+		{
+			name:    "success - pending callback changes nothing",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "PENDING"},
+			mock: func(server *TestService) {
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(processingPayment(), nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_APPROVED), Version: 2}, nil).
+					Times(1)
+			},
+			want: &model.PaymentWebhookResponse{ExternalID: externalID, PaymentStatus: "processing", ExpenseStatus: "approved"},
+		},
+		{
+			name:    "failure - unknown status",
+			request: model.PaymentWebhookRequest{ExternalID: externalID, Status: "ON_HOLD"},
+			mock:    func(server *TestService) {},
+			wantErr: util.ErrUnknownPaymentStatus,
+		},
+		{
+			name:    "failure - payment not found",
+			request: model.PaymentWebhookRequest{ExternalID: "unknown", Status: "COMPLETED"},
+			mock: func(server *TestService) {
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), "unknown").
+					Return(nil, util.ErrPaymentNotFound).
+					Times(1)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					GetPayoutBatchItemByExternalID(gomock.Any(), "unknown").
+					Return(nil, util.ErrPaymentNotFound).
+					Times(1)
+			},
+			wantErr: util.ErrPaymentNotFound,
+		},
+		{
+			name:    "failure - concurrent expense update",
+			request: model.PaymentWebhookRequest{ID: "TXN123", ExternalID: externalID, Status: "COMPLETED"},
+			mock: func(server *TestService) {
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentByExternalID(gomock.Any(), externalID).
+					Return(processingPayment(), nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Status: int32(util.EXPENSE_APPROVED), Version: 2}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(123), int32(util.EXPENSE_PAID), int32(2), gomock.Any(), gomock.Any()).
+					Return(util.ErrVersionConflict).
+					Times(1)
+			},
+			wantErr: util.ErrVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.HandlePaymentWebhook(context.Background(), tt.request, []byte(`{}`))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
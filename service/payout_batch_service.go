@@ -0,0 +1,498 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/google/uuid"
+)
+
+const defaultPayoutBatchLimit = 20
+
+// RunPayoutBatch starts a payout run on request of an admin.
+func (s *ExpensesManagementService) RunPayoutBatch(ctx context.Context, req model.RunPayoutBatchRequest) (*model.PayoutBatchResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	return s.runPayoutBatch(ctx, userInfo.ID, req.Channel)
+}
+
+// RunScheduledPayoutBatch starts the daily payout run with the configured
+// channel.
+func (s *ExpensesManagementService) RunScheduledPayoutBatch(ctx context.Context) (*model.PayoutBatchResponse, error) {
+	return s.runPayoutBatch(ctx, 0, "")
+}
+
+// runPayoutBatch groups every payable expense into one transfer per employee.
+// With the api channel the transfers are submitted to the payment processor
+// right away; with the file channel they wait for the bank file to be uploaded
+// and their results to be recorded.
+func (s *ExpensesManagementService) runPayoutBatch(ctx context.Context, createdBy int64, channel string) (*model.PayoutBatchResponse, error) {
+	if s.payoutMode != util.PAYOUT_MODE_BATCH {
+		s.logger.Error("payout batches are disabled")
+		return nil, util.ErrPayoutBatchDisabled
+	}
+
+	if channel == "" {
+		channel = s.payoutChannel
+	}
+	if !util.IsPayoutChannel(channel) {
+		s.logger.WithField("channel", channel).Error("invalid payout channel")
+		return nil, fmt.Errorf("invalid payout channel")
+	}
+
+	s.logger.WithField("channel", channel).WithField("created_by", createdBy).Info("RunPayoutBatch")
+
+	batchID, err := s.repo.PayoutBatchRepository.CreatePayoutBatch(ctx, &entity.PayoutBatch{
+		Status:    int32(util.PAYOUT_BATCH_PROCESSING),
+		Channel:   channel,
+		CreatedBy: createdBy,
+	}, buildPayoutBatchItems)
+	if err != nil {
+		if errors.Is(err, util.ErrNoPayableExpenses) {
+			s.logger.Info("No approved expenses to pay out")
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to create payout batch")
+		return nil, fmt.Errorf("failed to create payout batch")
+	}
+
+	batch, err := s.repo.PayoutBatchRepository.GetPayoutBatch(ctx, batchID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get payout batch")
+		return nil, fmt.Errorf("failed to get payout batch")
+	}
+
+	if channel == util.PAYOUT_CHANNEL_API {
+		for _, item := range batch.Items {
+			// A failed transfer is recorded on its item, the other employees
+			// are still paid.
+			if err := s.submitPayoutBatchItem(ctx, item); err != nil {
+				s.logger.WithError(err).WithField("external_id", item.ExternalID).Error("failed to submit payout batch item")
+			}
+		}
+
+		batch.Status, err = s.refreshPayoutBatchStatus(ctx, batch.ID, batch.Items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.WithField("batch_id", batch.ID).WithField("items", len(batch.Items)).Info("Payout batch created")
+	response := toPayoutBatchResponse(batch)
+	return &response, nil
+}
+
+// buildPayoutBatchItems groups the expenses per employee. Every item gets a new
+// external ID, so a transfer that failed in an earlier batch is not taken for
+// a duplicate by the provider.
+func buildPayoutBatchItems(expenses []*entity.Expense) ([]*entity.PayoutBatchItem, error) {
+	items := make([]*entity.PayoutBatchItem, 0)
+	byUser := make(map[int64]*entity.PayoutBatchItem)
+	for _, expense := range expenses {
+		item, ok := byUser[expense.UserID]
+		if !ok {
+			item = &entity.PayoutBatchItem{
+				UserID:     expense.UserID,
+				ExternalID: uuid.New().String(),
+				Status:     int32(util.PAYMENT_PROCESSING),
+			}
+			byUser[expense.UserID] = item
+			items = append(items, item)
+		}
+
+		amount := payableAmount(expense)
+		item.AmountIDR += amount
+		item.Payments = append(item.Payments, &entity.Payment{
+			ExpenseID:  expense.ID,
+			ExternalID: paymentExternalID(expense.ID),
+			AmountIDR:  amount,
+		})
+	}
+
+	return items, nil
+}
+
+func (s *ExpensesManagementService) submitPayoutBatchItem(ctx context.Context, item *entity.PayoutBatchItem) error {
+	destinations, err := s.getPayoutDestinations(ctx, item.UserID)
+	if err != nil {
+		// Nothing was sent, the next run picks the expenses up again.
+		return s.applyPayoutBatchItemResult(ctx, item, util.PAYMENT_FAILED, "", err.Error(), nil)
+	}
+
+	paymentRequest := &entity.PaymentProcessorRequest{
+		AmountIDR:      int64(item.AmountIDR),
+		ExternalID:     item.ExternalID,
+		IdempotencyKey: item.ExternalID,
+		PayoutMethod:   item.PayoutMethod,
+		Provider:       item.Provider,
+		Destinations:   destinations,
+	}
+	response, err := s.repo.PaymentProcessor.ProcessPayment(ctx, paymentRequest)
+	item.Provider = paymentRequest.Provider
+	if err != nil {
+		s.logger.WithError(err).WithField("external_id", item.ExternalID).Error("failed to process payout")
+		return s.applyPayoutBatchItemResult(ctx, item, util.PAYMENT_FAILED, "", err.Error(), nil)
+	}
+
+	// An accepted transfer is confirmed later through the payment webhook.
+	if status, _ := util.GetPaymentStatusFromProvider(response.Data.Status); status == util.PAYMENT_PROCESSING {
+		item.ProviderPaymentID = response.Data.ID
+		item.RawResponse = string(response.Raw)
+		err = s.repo.PayoutBatchRepository.UpdatePayoutBatchItem(ctx, item)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to update payout batch item")
+			return fmt.Errorf("failed to update payout batch item")
+		}
+		return nil
+	}
+
+	return s.applyPayoutBatchItemResult(ctx, item, util.PAYMENT_SUCCEEDED, response.Data.ID, "", response.Raw)
+}
+
+// applyPayoutBatchItemResult records the result of a transfer on the item and
+// moves every expense it pays to PAID or PAYMENT_FAILED. Like payment
+// callbacks, a succeeded transfer is never downgraded and repeated results
+// are safe. The caller refreshes the batch status.
+func (s *ExpensesManagementService) applyPayoutBatchItemResult(ctx context.Context, item *entity.PayoutBatchItem, status util.PaymentStatus, providerPaymentID, failureReason string, raw []byte) error {
+	if item.Status == int32(util.PAYMENT_SUCCEEDED) && status != util.PAYMENT_SUCCEEDED {
+		s.logger.WithField("external_id", item.ExternalID).Warn("Payout already succeeded, ignoring result")
+		return nil
+	}
+
+	if item.Status != int32(status) {
+		completedAt := time.Now()
+		item.Status = int32(status)
+		if providerPaymentID != "" {
+			item.ProviderPaymentID = providerPaymentID
+		}
+		item.LastError = ""
+		if status == util.PAYMENT_FAILED {
+			item.LastError = failureReason
+		}
+		if raw != nil {
+			item.RawResponse = string(raw)
+		}
+		item.CompletedAt = &completedAt
+		err := s.repo.PayoutBatchRepository.UpdatePayoutBatchItem(ctx, item)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to update payout batch item")
+			return fmt.Errorf("failed to update payout batch item")
+		}
+	}
+
+	var lastErr error
+	for _, payment := range item.Payments {
+		payment.Status = item.Status
+		payment.Provider = item.Provider
+		payment.ProviderPaymentID = item.ProviderPaymentID
+		payment.LastError = item.LastError
+		payment.CompletedAt = item.CompletedAt
+
+		expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, payment.ExpenseID)
+		if err != nil {
+			s.logger.WithError(err).WithField("expense_id", payment.ExpenseID).Error("failed to get expense")
+			lastErr = fmt.Errorf("failed to get expense")
+			continue
+		}
+
+		if status == util.PAYMENT_SUCCEEDED {
+			err = s.markExpensePaid(ctx, expense, payment)
+		} else {
+			err = s.markExpensePaymentFailed(ctx, expense, payment)
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// refreshPayoutBatchStatus derives the batch status from its items. items may
+// be nil, then they are read from the repository.
+func (s *ExpensesManagementService) refreshPayoutBatchStatus(ctx context.Context, batchID int64, items []*entity.PayoutBatchItem) (int32, error) {
+	current := int32(0)
+	if items == nil {
+		batch, err := s.repo.PayoutBatchRepository.GetPayoutBatch(ctx, batchID)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to get payout batch")
+			return 0, fmt.Errorf("failed to get payout batch")
+		}
+		current = batch.Status
+		items = batch.Items
+	}
+
+	status := int32(payoutBatchStatus(items))
+	if status == current {
+		return status, nil
+	}
+
+	err := s.repo.PayoutBatchRepository.UpdatePayoutBatchStatus(ctx, batchID, status)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to update payout batch status")
+		return 0, fmt.Errorf("failed to update payout batch status")
+	}
+	return status, nil
+}
+
+func payoutBatchStatus(items []*entity.PayoutBatchItem) util.PayoutBatchStatus {
+	succeeded, failed := 0, 0
+	for _, item := range items {
+		switch util.PaymentStatus(item.Status) {
+		case util.PAYMENT_SUCCEEDED:
+			succeeded++
+		case util.PAYMENT_FAILED:
+			failed++
+		default:
+			return util.PAYOUT_BATCH_PROCESSING
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return util.PAYOUT_BATCH_COMPLETED
+	case succeeded == 0:
+		return util.PAYOUT_BATCH_FAILED
+	}
+	return util.PAYOUT_BATCH_PARTIALLY_FAILED
+}
+
+// handlePayoutBatchItemWebhook applies a payment callback for a transfer of a
+// payout batch.
+func (s *ExpensesManagementService) handlePayoutBatchItemWebhook(ctx context.Context, req model.PaymentWebhookRequest, status util.PaymentStatus, raw []byte) (*model.PaymentWebhookResponse, error) {
+	item, err := s.repo.PayoutBatchRepository.GetPayoutBatchItemByExternalID(ctx, req.ExternalID)
+	if err != nil {
+		if errors.Is(err, util.ErrPaymentNotFound) {
+			s.logger.WithField("external_id", req.ExternalID).Error("payment not found")
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to get payout batch item")
+		return nil, fmt.Errorf("failed to get payment")
+	}
+
+	if status == util.PAYMENT_PROCESSING {
+		s.logger.WithField("external_id", item.ExternalID).Info("Payout still processing")
+	} else {
+		err = s.applyPayoutBatchItemResult(ctx, item, status, req.ID, req.FailureReason, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = s.refreshPayoutBatchStatus(ctx, item.BatchID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.PaymentWebhookResponse{
+		ExternalID:    item.ExternalID,
+		PaymentStatus: util.GetPaymentStatusString(util.PaymentStatus(item.Status)),
+		BatchID:       item.BatchID,
+	}, nil
+}
+
+// RecordPayoutBatchItemResult records the result of a transfer made from the
+// bank file, for banks that do not call back.
+func (s *ExpensesManagementService) RecordPayoutBatchItemResult(ctx context.Context, batchID, itemID int64, req model.PayoutBatchItemResultRequest) (*model.PayoutBatchItemResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("batch_id", batchID).WithField("item_id", itemID).WithField("request", req).Info("RecordPayoutBatchItemResult")
+
+	status, ok := util.GetPaymentStatusFromProvider(req.Status)
+	if !ok || status == util.PAYMENT_PROCESSING {
+		s.logger.WithField("status", req.Status).Error("unknown payment status")
+		return nil, util.ErrUnknownPaymentStatus
+	}
+
+	batch, err := s.repo.PayoutBatchRepository.GetPayoutBatch(ctx, batchID)
+	if err != nil {
+		if errors.Is(err, util.ErrPayoutBatchNotFound) {
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to get payout batch")
+		return nil, fmt.Errorf("failed to get payout batch")
+	}
+
+	for _, item := range batch.Items {
+		if item.ID != itemID {
+			continue
+		}
+
+		err = s.applyPayoutBatchItemResult(ctx, item, status, "", req.FailureReason, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = s.refreshPayoutBatchStatus(ctx, batch.ID, batch.Items)
+		if err != nil {
+			return nil, err
+		}
+		response := toPayoutBatchItemResponse(item)
+		return &response, nil
+	}
+
+	s.logger.WithField("item_id", itemID).Error("payout batch item not found")
+	return nil, util.ErrPaymentNotFound
+}
+
+func (s *ExpensesManagementService) GetPayoutBatches(ctx context.Context, limit int) (*model.PayoutBatchListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultPayoutBatchLimit
+	}
+
+	batches, err := s.repo.PayoutBatchRepository.GetPayoutBatches(ctx, limit)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get payout batches")
+		return nil, fmt.Errorf("failed to get payout batches")
+	}
+
+	response := &model.PayoutBatchListResponse{Batches: make([]model.PayoutBatchResponse, 0)}
+	for _, batch := range batches {
+		response.Batches = append(response.Batches, toPayoutBatchResponse(batch))
+	}
+
+	return response, nil
+}
+
+func (s *ExpensesManagementService) GetPayoutBatch(ctx context.Context, id int64) (*model.PayoutBatchResponse, error) {
+	batch, err := s.getPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toPayoutBatchResponse(batch)
+	return &response, nil
+}
+
+// GetPayoutBatchFile returns the batch as a bulk transfer CSV, one row per
+// employee, for banks without an API. The reference is the item's external ID,
+// which the bank reports back with the transfer.
+func (s *ExpensesManagementService) GetPayoutBatchFile(ctx context.Context, id int64) ([]byte, error) {
+	batch, err := s.getPayoutBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"reference", "employee_id", "employee_name", "employee_email", "payout_method", "bank_code", "account_number", "account_holder_name", "ewallet_id", "amount", "currency", "description"})
+	for _, item := range batch.Items {
+		if item.Status != int32(util.PAYMENT_PROCESSING) {
+			continue
+		}
+
+		destinations, err := s.getPayoutDestinations(ctx, item.UserID)
+		if err != nil {
+			return nil, err
+		}
+		destination := destinations[item.PayoutMethod]
+		if destination == nil {
+			destination = &entity.PayoutDestination{}
+		}
+
+		expenseIDs := make([]string, 0, len(item.Payments))
+		for _, payment := range item.Payments {
+			expenseIDs = append(expenseIDs, strconv.FormatInt(payment.ExpenseID, 10))
+		}
+
+		writer.Write([]string{
+			item.ExternalID,
+			strconv.FormatInt(item.UserID, 10),
+			item.UserName,
+			item.UserEmail,
+			item.PayoutMethod,
+			destination.BankCode,
+			destination.AccountNumber,
+			destination.AccountHolderName,
+			destination.EWalletID,
+			strconv.FormatFloat(item.AmountIDR, 'f', 0, 64),
+			"IDR",
+			fmt.Sprintf("Expense reimbursement %s", strings.Join(expenseIDs, ", ")),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		s.logger.WithError(err).Error("failed to write payout batch file")
+		return nil, fmt.Errorf("failed to write payout batch file")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *ExpensesManagementService) getPayoutBatch(ctx context.Context, id int64) (*entity.PayoutBatch, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	batch, err := s.repo.PayoutBatchRepository.GetPayoutBatch(ctx, id)
+	if err != nil {
+		if errors.Is(err, util.ErrPayoutBatchNotFound) {
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to get payout batch")
+		return nil, fmt.Errorf("failed to get payout batch")
+	}
+
+	return batch, nil
+}
+
+func toPayoutBatchResponse(batch *entity.PayoutBatch) model.PayoutBatchResponse {
+	response := model.PayoutBatchResponse{
+		ID:             batch.ID,
+		Status:         util.GetPayoutBatchStatusString(util.PayoutBatchStatus(batch.Status)),
+		Channel:        batch.Channel,
+		TotalAmountIDR: batch.TotalAmountIDR,
+		CreatedBy:      batch.CreatedBy,
+		CreatedAt:      batch.CreatedAt,
+		CompletedAt:    batch.CompletedAt,
+	}
+	for _, item := range batch.Items {
+		response.Items = append(response.Items, toPayoutBatchItemResponse(item))
+	}
+	return response
+}
+
+func toPayoutBatchItemResponse(item *entity.PayoutBatchItem) model.PayoutBatchItemResponse {
+	expenseIDs := make([]int64, 0, len(item.Payments))
+	for _, payment := range item.Payments {
+		expenseIDs = append(expenseIDs, payment.ExpenseID)
+	}
+
+	return model.PayoutBatchItemResponse{
+		ID:                item.ID,
+		UserID:            item.UserID,
+		UserName:          item.UserName,
+		UserEmail:         item.UserEmail,
+		PayoutMethod:      item.PayoutMethod,
+		ExternalID:        item.ExternalID,
+		AmountIDR:         item.AmountIDR,
+		Status:            util.GetPaymentStatusString(util.PaymentStatus(item.Status)),
+		Provider:          item.Provider,
+		ProviderPaymentID: item.ProviderPaymentID,
+		LastError:         item.LastError,
+		ExpenseIDs:        expenseIDs,
+		CompletedAt:       item.CompletedAt,
+	}
+}
@@ -0,0 +1,580 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPayoutBatchItems(t *testing.T) {
+	items, err := buildPayoutBatchItems([]*entity.Expense{
+		{ID: 10, UserID: 2, AmountIDR: 150000},
+		{ID: 11, UserID: 2, AmountIDR: 500000, ApprovedAmountIDR: 300000},
+		{ID: 12, UserID: 3, AmountIDR: 200000},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	assert.Equal(t, int64(2), items[0].UserID)
+	assert.Equal(t, float64(450000), items[0].AmountIDR)
+	assert.Len(t, items[0].Payments, 2)
+	assert.Equal(t, paymentExternalID(11), items[0].Payments[1].ExternalID)
+	assert.Equal(t, float64(300000), items[0].Payments[1].AmountIDR)
+
+	assert.Equal(t, int64(3), items[1].UserID)
+	assert.Equal(t, float64(200000), items[1].AmountIDR)
+	assert.NotEmpty(t, items[0].ExternalID)
+	assert.NotEqual(t, items[0].ExternalID, items[1].ExternalID)
+}
+
+func TestPayoutBatchService_RunPayoutBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		batch    bool
+		role     util.UserRole
+		request  model.RunPayoutBatchRequest
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.PayoutBatchResponse)
+	}{
+		{
+			name:    "success - api channel pays every employee",
+			batch:   true,
+			role:    util.USER_ROLE_ADMIN,
+			request: model.RunPayoutBatchRequest{},
+			mock: func(server *TestService) {
+				server.MockPayoutBatchRepo.EXPECT().
+					CreatePayoutBatch(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, batch *entity.PayoutBatch, build entity.PayoutBatchBuilder) (int64, error) {
+						assert.Equal(t, util.PAYOUT_CHANNEL_API, batch.Channel)
+						assert.Equal(t, int64(1), batch.CreatedBy)
+						items, err := build([]*entity.Expense{{ID: 10, UserID: 2, AmountIDR: 150000}})
+						assert.NoError(t, err)
+						assert.Len(t, items, 1)
+						return 7, nil
+					}).
+					Times(1)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					GetPayoutBatch(gomock.Any(), int64(7)).
+					Return(&entity.PayoutBatch{
+						ID:             7,
+						Status:         int32(util.PAYOUT_BATCH_PROCESSING),
+						Channel:        util.PAYOUT_CHANNEL_API,
+						TotalAmountIDR: 650000,
+						CreatedBy:      1,
+						Items: []*entity.PayoutBatchItem{
+							{
+								ID:           1,
+								BatchID:      7,
+								UserID:       2,
+								UserName:     "John Doe",
+								UserEmail:    "john.doe@company.com",
+								PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER,
+								ExternalID:   "item-1",
+								AmountIDR:    450000,
+								Status:       int32(util.PAYMENT_PROCESSING),
+								Payments: []*entity.Payment{
+									{ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, BatchItemID: 1},
+									{ExpenseID: 11, ExternalID: paymentExternalID(11), AmountIDR: 300000, BatchItemID: 1},
+								},
+							},
+							{
+								ID:           2,
+								BatchID:      7,
+								UserID:       3,
+								UserName:     "Jane Roe",
+								UserEmail:    "jane.roe@company.com",
+								PayoutMethod: util.PAYOUT_METHOD_EWALLET,
+								ExternalID:   "item-2",
+								AmountIDR:    200000,
+								Status:       int32(util.PAYMENT_PROCESSING),
+								Payments: []*entity.Payment{
+									{ExpenseID: 12, ExternalID: paymentExternalID(12), AmountIDR: 200000, BatchItemID: 2},
+								},
+							},
+						},
+					}, nil).
+					Times(1)
+
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), gomock.Any()).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
+						},
+					}, nil).
+					Times(2)
+
+				server.MockPaymentProcessor.EXPECT().
+					ProcessPayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req *entity.PaymentProcessorRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Len(t, req.Destinations, 2)
+						req.Provider = "bank_transfer"
+						response := &entity.PaymentProcessorResponse{}
+						response.Data.ID = "TRF-" + req.ExternalID
+						response.Data.Status = "COMPLETED"
+						return response, nil
+					}).
+					Times(2)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					UpdatePayoutBatchItem(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, item *entity.PayoutBatchItem) error {
+						assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), item.Status)
+						assert.Equal(t, "bank_transfer", item.Provider)
+						assert.Equal(t, "TRF-"+item.ExternalID, item.ProviderPaymentID)
+						return nil
+					}).
+					Times(2)
+
+				for _, id := range []int64{10, 11, 12} {
+					server.MockRepo.EXPECT().
+						GetExpenseByID(gomock.Any(), id).
+						Return(&entity.Expense{ID: id, Status: int32(util.EXPENSE_APPROVED), Version: 1}, nil).
+						Times(1)
+					server.MockRepo.EXPECT().
+						UpdateExpenseStatus(gomock.Any(), id, int32(util.EXPENSE_PAID), int32(1), gomock.Any(), gomock.Any()).
+						Return(nil).
+						Times(1)
+				}
+
+				server.MockPayoutBatchRepo.EXPECT().
+					UpdatePayoutBatchStatus(gomock.Any(), int64(7), int32(util.PAYOUT_BATCH_COMPLETED)).
+					Return(nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.PayoutBatchResponse) {
+				assert.Equal(t, "completed", got.Status)
+				assert.Len(t, got.Items, 2)
+				assert.Equal(t, []int64{10, 11}, got.Items[0].ExpenseIDs)
+				assert.Equal(t, "succeeded", got.Items[0].Status)
+			},
+		},
+		{
+			name:    "success - a failed transfer leaves the batch partially failed",
+			batch:   true,
+			role:    util.USER_ROLE_ADMIN,
+			request: model.RunPayoutBatchRequest{Channel: util.PAYOUT_CHANNEL_API},
+			mock: func(server *TestService) {
+				server.MockPayoutBatchRepo.EXPECT().
+					CreatePayoutBatch(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(7), nil).
+					Times(1)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					GetPayoutBatch(gomock.Any(), int64(7)).
+					Return(&entity.PayoutBatch{
+						ID:             7,
+						Status:         int32(util.PAYOUT_BATCH_PROCESSING),
+						Channel:        util.PAYOUT_CHANNEL_API,
+						TotalAmountIDR: 650000,
+						CreatedBy:      1,
+						Items: []*entity.PayoutBatchItem{
+							{
+								ID:           1,
+								BatchID:      7,
+								UserID:       2,
+								UserName:     "John Doe",
+								UserEmail:    "john.doe@company.com",
+								PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER,
+								ExternalID:   "item-1",
+								AmountIDR:    450000,
+								Status:       int32(util.PAYMENT_PROCESSING),
+								Payments: []*entity.Payment{
+									{ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, BatchItemID: 1},
+									{ExpenseID: 11, ExternalID: paymentExternalID(11), AmountIDR: 300000, BatchItemID: 1},
+								},
+							},
+							{
+								ID:           2,
+								BatchID:      7,
+								UserID:       3,
+								UserName:     "Jane Roe",
+								UserEmail:    "jane.roe@company.com",
+								PayoutMethod: util.PAYOUT_METHOD_EWALLET,
+								ExternalID:   "item-2",
+								AmountIDR:    200000,
+								Status:       int32(util.PAYMENT_PROCESSING),
+								Payments: []*entity.Payment{
+									{ExpenseID: 12, ExternalID: paymentExternalID(12), AmountIDR: 200000, BatchItemID: 2},
+								},
+							},
+						},
+					}, nil).
+					Times(1)
+
+				response := &entity.PaymentProcessorResponse{}
+				response.Data.ID = "TRF-1"
+				response.Data.Status = "COMPLETED"
+				server.MockDestinationRepo.EXPECT().
+					GetPayoutDestinations(gomock.Any(), gomock.Any()).
+					Return([]*entity.PayoutDestination{
+						{
+							UserID:            1,
+							Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+							BankCode:          "BCA",
+							AccountNumber:     "1234567890",
+							AccountHolderName: "John Doe",
+						},
+						{
+							UserID:    1,
+							Method:    util.PAYOUT_METHOD_EWALLET,
+							EWalletID: "081234567890",
+						},
+					}, nil).
+					Times(2)
+
+				gomock.InOrder(
+					server.MockPaymentProcessor.EXPECT().
+						ProcessPayment(gomock.Any(), gomock.Any()).
+						Return(response, nil),
+					server.MockPaymentProcessor.EXPECT().
+						ProcessPayment(gomock.Any(), gomock.Any()).
+						Return(nil, errors.New("payment declined by provider: 422 Unprocessable Entity")),
+				)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					UpdatePayoutBatchItem(gomock.Any(), gomock.Any()).
+					Return(nil).
+					Times(2)
+
+				for _, id := range []int64{10, 11, 12} {
+					server.MockRepo.EXPECT().
+						GetExpenseByID(gomock.Any(), id).
+						Return(&entity.Expense{ID: id, Status: int32(util.EXPENSE_APPROVED), Version: 1}, nil).
+						Times(1)
+				}
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(10), int32(util.EXPENSE_PAID), int32(1), gomock.Any(), gomock.Any()).
+					Return(nil)
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(11), int32(util.EXPENSE_PAID), int32(1), gomock.Any(), gomock.Any()).
+					Return(nil)
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(12), int32(util.EXPENSE_PAYMENT_FAILED), int32(1), gomock.Any(), gomock.Any()).
+					Return(nil)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					UpdatePayoutBatchStatus(gomock.Any(), int64(7), int32(util.PAYOUT_BATCH_PARTIALLY_FAILED)).
+					Return(nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.PayoutBatchResponse) {
+				assert.Equal(t, "partially_failed", got.Status)
+				assert.Equal(t, "failed", got.Items[1].Status)
+				assert.Equal(t, "payment declined by provider: 422 Unprocessable Entity", got.Items[1].LastError)
+			},
+		},
+		{
+			name:    "success - file channel waits for the bank upload",
+			batch:   true,
+			role:    util.USER_ROLE_ADMIN,
+			request: model.RunPayoutBatchRequest{Channel: util.PAYOUT_CHANNEL_FILE},
+			mock: func(server *TestService) {
+				batch := &entity.PayoutBatch{
+					ID:             7,
+					Status:         int32(util.PAYOUT_BATCH_PROCESSING),
+					Channel:        util.PAYOUT_CHANNEL_FILE,
+					TotalAmountIDR: 650000,
+					CreatedBy:      1,
+					Items: []*entity.PayoutBatchItem{
+						{
+							ID:           1,
+							BatchID:      7,
+							UserID:       2,
+							UserName:     "John Doe",
+							UserEmail:    "john.doe@company.com",
+							PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER,
+							ExternalID:   "item-1",
+							AmountIDR:    450000,
+							Status:       int32(util.PAYMENT_PROCESSING),
+							Payments: []*entity.Payment{
+								{ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, BatchItemID: 1},
+								{ExpenseID: 11, ExternalID: paymentExternalID(11), AmountIDR: 300000, BatchItemID: 1},
+							},
+						},
+						{
+							ID:           2,
+							BatchID:      7,
+							UserID:       3,
+							UserName:     "Jane Roe",
+							UserEmail:    "jane.roe@company.com",
+							PayoutMethod: util.PAYOUT_METHOD_EWALLET,
+							ExternalID:   "item-2",
+							AmountIDR:    200000,
+							Status:       int32(util.PAYMENT_PROCESSING),
+							Payments: []*entity.Payment{
+								{ExpenseID: 12, ExternalID: paymentExternalID(12), AmountIDR: 200000, BatchItemID: 2},
+							},
+						},
+					},
+				}
+
+				server.MockPayoutBatchRepo.EXPECT().
+					CreatePayoutBatch(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(7), nil).
+					Times(1)
+
+				server.MockPayoutBatchRepo.EXPECT().
+					GetPayoutBatch(gomock.Any(), int64(7)).
+					Return(batch, nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.PayoutBatchResponse) {
+				assert.Equal(t, "processing", got.Status)
+				assert.Equal(t, util.PAYOUT_CHANNEL_FILE, got.Channel)
+				assert.Equal(t, "processing", got.Items[0].Status)
+			},
+		},
+		{
+			name:    "failure - nothing to pay out",
+			batch:   true,
+			role:    util.USER_ROLE_ADMIN,
+			request: model.RunPayoutBatchRequest{},
+			mock: func(server *TestService) {
+				server.MockPayoutBatchRepo.EXPECT().
+					CreatePayoutBatch(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), util.ErrNoPayableExpenses).
+					Times(1)
+			},
+			wantErr: util.ErrNoPayableExpenses,
+		},
+		{
+			name:    "failure - immediate payout mode",
+			batch:   false,
+			role:    util.USER_ROLE_ADMIN,
+			request: model.RunPayoutBatchRequest{},
+			mock:    func(server *TestService) {},
+			wantErr: util.ErrPayoutBatchDisabled,
+		},
+		{
+			name:    "failure - not an admin",
+			batch:   true,
+			role:    util.USER_ROLE_MANAGER,
+			request: model.RunPayoutBatchRequest{},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.batch {
+				options = append(options, WithBatchPayouts(util.PAYOUT_CHANNEL_API))
+			}
+			server := NewTestServerWithPaymentProcessor(t, options...)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.RunPayoutBatch(roleContext(tt.role), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestPayoutBatchService_HandlePaymentWebhook(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t, WithBatchPayouts(util.PAYOUT_CHANNEL_API))
+	defer server.MockCtrl.Finish()
+
+	batch := &entity.PayoutBatch{
+		ID:             7,
+		Status:         int32(util.PAYOUT_BATCH_PROCESSING),
+		Channel:        util.PAYOUT_CHANNEL_API,
+		TotalAmountIDR: 650000,
+		CreatedBy:      1,
+		Items: []*entity.PayoutBatchItem{
+			{
+				ID:           1,
+				BatchID:      7,
+				UserID:       2,
+				UserName:     "John Doe",
+				UserEmail:    "john.doe@company.com",
+				PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER,
+				ExternalID:   "item-1",
+				AmountIDR:    450000,
+				Status:       int32(util.PAYMENT_SUCCEEDED),
+				Payments: []*entity.Payment{
+					{ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, BatchItemID: 1},
+					{ExpenseID: 11, ExternalID: paymentExternalID(11), AmountIDR: 300000, BatchItemID: 1},
+				},
+			},
+			{
+				ID:           2,
+				BatchID:      7,
+				UserID:       3,
+				UserName:     "Jane Roe",
+				UserEmail:    "jane.roe@company.com",
+				PayoutMethod: util.PAYOUT_METHOD_EWALLET,
+				ExternalID:   "item-2",
+				AmountIDR:    200000,
+				Status:       int32(util.PAYMENT_PROCESSING),
+				Payments: []*entity.Payment{
+					{ExpenseID: 12, ExternalID: paymentExternalID(12), AmountIDR: 200000, BatchItemID: 2},
+				},
+			},
+		},
+	}
+	item := batch.Items[1]
+
+	server.MockPaymentRepo.EXPECT().
+		GetPaymentByExternalID(gomock.Any(), "item-2").
+		Return(nil, util.ErrPaymentNotFound).
+		Times(1)
+
+	server.MockPayoutBatchRepo.EXPECT().
+		GetPayoutBatchItemByExternalID(gomock.Any(), "item-2").
+		Return(item, nil).
+		Times(1)
+
+	server.MockPayoutBatchRepo.EXPECT().
+		UpdatePayoutBatchItem(gomock.Any(), item).
+		Return(nil).
+		Times(1)
+
+	server.MockRepo.EXPECT().
+		GetExpenseByID(gomock.Any(), int64(12)).
+		Return(&entity.Expense{ID: 12, Status: int32(util.EXPENSE_APPROVED), Version: 1}, nil).
+		Times(1)
+
+	server.MockRepo.EXPECT().
+		UpdateExpenseStatus(gomock.Any(), int64(12), int32(util.EXPENSE_PAID), int32(1), gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	server.MockPayoutBatchRepo.EXPECT().
+		GetPayoutBatch(gomock.Any(), int64(7)).
+		Return(batch, nil).
+		Times(1)
+
+	server.MockPayoutBatchRepo.EXPECT().
+		UpdatePayoutBatchStatus(gomock.Any(), int64(7), int32(util.PAYOUT_BATCH_COMPLETED)).
+		Return(nil).
+		Times(1)
+
+	got, err := server.Service.HandlePaymentWebhook(context.Background(), model.PaymentWebhookRequest{
+		ID:         "TRF-2",
+		ExternalID: "item-2",
+		Status:     "COMPLETED",
+	}, []byte(`{}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.PaymentWebhookResponse{ExternalID: "item-2", PaymentStatus: "succeeded", BatchID: 7}, got)
+	assert.Equal(t, "TRF-2", item.ProviderPaymentID)
+	assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), item.Payments[0].Status)
+}
+
+func TestPayoutBatchService_GetPayoutBatchFile(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t, WithBatchPayouts(util.PAYOUT_CHANNEL_FILE))
+	defer server.MockCtrl.Finish()
+
+	batch := &entity.PayoutBatch{
+		ID:             7,
+		Status:         int32(util.PAYOUT_BATCH_PROCESSING),
+		Channel:        util.PAYOUT_CHANNEL_API,
+		TotalAmountIDR: 650000,
+		CreatedBy:      1,
+		Items: []*entity.PayoutBatchItem{
+			{
+				ID:           1,
+				BatchID:      7,
+				UserID:       2,
+				UserName:     "John Doe",
+				UserEmail:    "john.doe@company.com",
+				PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER,
+				ExternalID:   "item-1",
+				AmountIDR:    450000,
+				Status:       int32(util.PAYMENT_PROCESSING),
+				Payments: []*entity.Payment{
+					{ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, BatchItemID: 1},
+					{ExpenseID: 11, ExternalID: paymentExternalID(11), AmountIDR: 300000, BatchItemID: 1},
+				},
+			},
+			{
+				ID:           2,
+				BatchID:      7,
+				UserID:       3,
+				UserName:     "Jane Roe",
+				UserEmail:    "jane.roe@company.com",
+				PayoutMethod: util.PAYOUT_METHOD_EWALLET,
+				ExternalID:   "item-2",
+				AmountIDR:    200000,
+				Status:       int32(util.PAYMENT_SUCCEEDED),
+				Payments: []*entity.Payment{
+					{ExpenseID: 12, ExternalID: paymentExternalID(12), AmountIDR: 200000, BatchItemID: 2},
+				},
+			},
+		},
+	}
+
+	server.MockPayoutBatchRepo.EXPECT().
+		GetPayoutBatch(gomock.Any(), int64(7)).
+		Return(batch, nil).
+		Times(1)
+
+	server.MockDestinationRepo.EXPECT().
+		GetPayoutDestinations(gomock.Any(), int64(2)).
+		Return([]*entity.PayoutDestination{
+			{
+				UserID:            1,
+				Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+				BankCode:          "BCA",
+				AccountNumber:     "1234567890",
+				AccountHolderName: "John Doe",
+			},
+			{
+				UserID:    1,
+				Method:    util.PAYOUT_METHOD_EWALLET,
+				EWalletID: "081234567890",
+			},
+		}, nil).
+		Times(1)
+
+	got, err := server.Service.GetPayoutBatchFile(roleContext(util.USER_ROLE_ADMIN), 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "reference,employee_id,employee_name,employee_email,payout_method,bank_code,account_number,account_holder_name,ewallet_id,amount,currency,description\n"+
+		"item-1,2,John Doe,john.doe@company.com,bank_transfer,BCA,1234567890,John Doe,,450000,IDR,\"Expense reimbursement 10, 11\"\n", string(got))
+}
+
+func TestPayoutBatchService_ProcessPaymentInBatchMode(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t, WithBatchPayouts(util.PAYOUT_CHANNEL_API))
+	defer server.MockCtrl.Finish()
+
+	server.MockRepo.EXPECT().
+		GetExpenseByID(gomock.Any(), int64(123)).
+		Return(&entity.Expense{ID: 123, UserID: 2, AmountIDR: 150000, Status: int32(util.EXPENSE_APPROVED), Version: 2}, nil).
+		Times(1)
+
+	err := server.Service.ProcessPayment(context.Background(), model.ApprovalRequest{
+		ExpenseID: 123,
+		Status:    int32(util.EXPENSE_APPROVED),
+	})
+
+	assert.NoError(t, err)
+}
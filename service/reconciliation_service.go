@@ -0,0 +1,497 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+const (
+	defaultReconciliationLimit = 20
+	maxReconciliationDays      = 31
+)
+
+type settlementFileRecord struct {
+	ExternalID        string     `json:"external_id"`
+	ProviderPaymentID string     `json:"provider_payment_id"`
+	AmountIDR         *float64   `json:"amount_idr"`
+	Status            string     `json:"status"`
+	SettledAt         *time.Time `json:"settled_at"`
+}
+
+// settlementColumns maps the CSV header of a statement to its fields. Bank
+// files echo the reference and amount of the payout batch file.
+var settlementColumns = map[string]string{
+	"external_id":         "external_id",
+	"reference":           "external_id",
+	"provider_payment_id": "provider_payment_id",
+	"amount_idr":          "amount_idr",
+	"amount":              "amount_idr",
+	"status":              "status",
+	"settled_at":          "settled_at",
+}
+
+// ImportSettlement reconciles a settlement statement uploaded by an admin.
+func (s *ExpensesManagementService) ImportSettlement(ctx context.Context, req model.ReconcileRequest, file []byte) (*model.ReconciliationResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).WithField("size", len(file)).Info("ImportSettlement")
+
+	reconciliation, err := s.newReconciliation(req, util.RECONCILIATION_SOURCE_FILE, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, err := parseSettlementFile(file)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to parse settlement file")
+		return nil, err
+	}
+
+	return s.reconcile(ctx, reconciliation, statement)
+}
+
+// ReconcileProvider pulls the settlement statement from the provider's API on
+// request of an admin.
+func (s *ExpensesManagementService) ReconcileProvider(ctx context.Context, req model.ReconcileRequest) (*model.ReconciliationResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).Info("ReconcileProvider")
+
+	reconciliation, err := s.newReconciliation(req, util.RECONCILIATION_SOURCE_API, userInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pullSettlement(ctx, reconciliation)
+}
+
+// RunScheduledReconciliations reconciles yesterday's statement of every
+// configured provider. A provider that fails does not stop the others.
+func (s *ExpensesManagementService) RunScheduledReconciliations(ctx context.Context) ([]*model.ReconciliationResponse, error) {
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+
+	responses := make([]*model.ReconciliationResponse, 0)
+	var lastErr error
+	for _, stats := range s.repo.PaymentProcessor.GetProviderStats() {
+		response, err := s.pullSettlement(ctx, &entity.Reconciliation{
+			Provider:   stats.Provider,
+			Source:     util.RECONCILIATION_SOURCE_API,
+			PeriodFrom: yesterday,
+			PeriodTo:   yesterday,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, lastErr
+}
+
+func (s *ExpensesManagementService) GetReconciliations(ctx context.Context, limit int) (*model.ReconciliationListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultReconciliationLimit
+	}
+
+	reconciliations, err := s.repo.ReconciliationRepository.GetReconciliations(ctx, limit)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get reconciliations")
+		return nil, fmt.Errorf("failed to get reconciliations")
+	}
+
+	response := &model.ReconciliationListResponse{Reconciliations: make([]model.ReconciliationResponse, 0)}
+	for _, reconciliation := range reconciliations {
+		response.Reconciliations = append(response.Reconciliations, toReconciliationResponse(reconciliation))
+	}
+
+	return response, nil
+}
+
+func (s *ExpensesManagementService) GetReconciliation(ctx context.Context, id int64) (*model.ReconciliationResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	reconciliation, err := s.repo.ReconciliationRepository.GetReconciliation(ctx, id)
+	if err != nil {
+		if errors.Is(err, util.ErrReconciliationNotFound) {
+			return nil, err
+		}
+		s.logger.WithError(err).Error("failed to get reconciliation")
+		return nil, fmt.Errorf("failed to get reconciliation")
+	}
+
+	response := toReconciliationResponse(reconciliation)
+	return &response, nil
+}
+
+// newReconciliation validates the provider and the period of a request.
+// Transfers made from a payout batch file can only be reconciled against an
+// uploaded statement.
+func (s *ExpensesManagementService) newReconciliation(req model.ReconcileRequest, source string, createdBy int64) (*entity.Reconciliation, error) {
+	known := source == util.RECONCILIATION_SOURCE_FILE && req.Provider == util.PAYOUT_CHANNEL_FILE
+	for _, stats := range s.repo.PaymentProcessor.GetProviderStats() {
+		known = known || stats.Provider == req.Provider
+	}
+	if !known {
+		s.logger.WithField("provider", req.Provider).Error("unknown payment provider")
+		return nil, fmt.Errorf("%w: provider %q is not configured", util.ErrInvalidReconciliation, req.Provider)
+	}
+
+	from, to, err := parsePeriod(req.From, req.To, maxReconciliationDays)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", util.ErrInvalidReconciliation, err)
+	}
+
+	return &entity.Reconciliation{
+		Provider:   req.Provider,
+		Source:     source,
+		PeriodFrom: from,
+		PeriodTo:   to,
+		CreatedBy:  createdBy,
+	}, nil
+}
+
+func (s *ExpensesManagementService) pullSettlement(ctx context.Context, reconciliation *entity.Reconciliation) (*model.ReconciliationResponse, error) {
+	statement, err := s.repo.PaymentProcessor.ListPayments(ctx, reconciliation.Provider, reconciliation.PeriodFrom, reconciliation.PeriodTo.AddDate(0, 0, 1))
+	if err != nil {
+		s.logger.WithError(err).WithField("provider", reconciliation.Provider).Error("failed to get settlement statement")
+		return nil, fmt.Errorf("failed to get settlement statement")
+	}
+
+	return s.reconcile(ctx, reconciliation, statement)
+}
+
+// parsePeriod parses the days from and to, inclusive as YYYY-MM-DD, of a
+// period of at most maxDays. to defaults to from.
+func parsePeriod(fromDate, toDate string, maxDays int) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation(time.DateOnly, fromDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("from must be a date as YYYY-MM-DD")
+	}
+
+	to := from
+	if toDate != "" {
+		to, err = time.ParseInLocation(time.DateOnly, toDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date as YYYY-MM-DD")
+		}
+	}
+
+	if to.Before(from) || to.After(from.AddDate(0, 0, maxDays-1)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period must run forward and cover at most %d days", maxDays)
+	}
+	return from, to, nil
+}
+
+// reconcile matches the settled entries of the statement against the payouts
+// we recorded as succeeded for the provider in the period, by external ID.
+// An entry we settled just outside the period is looked up on its own, so a
+// callback that arrived after midnight does not show up as a discrepancy.
+func (s *ExpensesManagementService) reconcile(ctx context.Context, reconciliation *entity.Reconciliation, statement []*entity.SettlementRecord) (*model.ReconciliationResponse, error) {
+	expected, err := s.repo.ReconciliationRepository.GetSettledPayouts(ctx, reconciliation.Provider, reconciliation.PeriodFrom, reconciliation.PeriodTo.AddDate(0, 0, 1))
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get settled payouts")
+		return nil, fmt.Errorf("failed to get settled payouts")
+	}
+
+	expectedByID := make(map[string]*entity.PayoutRecord, len(expected))
+	for _, payout := range expected {
+		expectedByID[payout.ExternalID] = payout
+		reconciliation.ExpectedTotalIDR += payout.AmountIDR
+	}
+
+	seen := make(map[string]bool, len(statement))
+	unsettled := make(map[string]string)
+	unknown := make([]*entity.SettlementRecord, 0)
+	for _, record := range statement {
+		if status, _ := util.GetPaymentStatusFromProvider(record.Status); record.Status != "" && status != util.PAYMENT_SUCCEEDED {
+			unsettled[record.ExternalID] = strings.ToLower(record.Status)
+			continue
+		}
+		reconciliation.StatementTotalIDR += record.AmountIDR
+
+		if seen[record.ExternalID] {
+			addExtraItem(reconciliation, record, "listed more than once in the statement")
+			continue
+		}
+		seen[record.ExternalID] = true
+
+		payout, ok := expectedByID[record.ExternalID]
+		if !ok {
+			unknown = append(unknown, record)
+			continue
+		}
+		compareSettlement(reconciliation, payout, record)
+	}
+
+	if len(unknown) > 0 {
+		externalIDs := make([]string, 0, len(unknown))
+		for _, record := range unknown {
+			externalIDs = append(externalIDs, record.ExternalID)
+		}
+
+		payouts, err := s.repo.ReconciliationRepository.GetPayoutsByExternalIDs(ctx, externalIDs)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to get payouts")
+			return nil, fmt.Errorf("failed to get payouts")
+		}
+
+		payoutsByID := make(map[string]*entity.PayoutRecord, len(payouts))
+		for _, payout := range payouts {
+			payoutsByID[payout.ExternalID] = payout
+		}
+
+		for _, record := range unknown {
+			payout := payoutsByID[record.ExternalID]
+			switch {
+			case payout == nil:
+				addExtraItem(reconciliation, record, "no payout with this reference")
+			case payout.Provider != reconciliation.Provider:
+				addExtraItem(reconciliation, record, fmt.Sprintf("paid out through %s", payout.Provider))
+			case payout.Status != int32(util.PAYMENT_SUCCEEDED):
+				addExtraItem(reconciliation, record, fmt.Sprintf("payout is %s in our records", util.GetPaymentStatusString(util.PaymentStatus(payout.Status))))
+			default:
+				compareSettlement(reconciliation, payout, record)
+			}
+		}
+	}
+
+	for _, payout := range expected {
+		if seen[payout.ExternalID] {
+			continue
+		}
+		note := "not in the provider statement"
+		if status, ok := unsettled[payout.ExternalID]; ok {
+			note = fmt.Sprintf("%s in the provider statement", status)
+		}
+		reconciliation.MissingCount++
+		reconciliation.Items = append(reconciliation.Items, &entity.ReconciliationItem{
+			Kind:              util.RECONCILIATION_MISSING,
+			ExternalID:        payout.ExternalID,
+			ProviderPaymentID: payout.ProviderPaymentID,
+			ExpectedAmountIDR: payout.AmountIDR,
+			Note:              note,
+		})
+	}
+
+	id, err := s.repo.ReconciliationRepository.CreateReconciliation(ctx, reconciliation)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to create reconciliation")
+		return nil, fmt.Errorf("failed to create reconciliation")
+	}
+	reconciliation.ID = id
+	reconciliation.CreatedAt = time.Now()
+
+	s.logger.
+		WithField("reconciliation_id", id).
+		WithField("provider", reconciliation.Provider).
+		WithField("matched", reconciliation.MatchedCount).
+		WithField("missing", reconciliation.MissingCount).
+		WithField("extra", reconciliation.ExtraCount).
+		WithField("amount_mismatch", reconciliation.AmountMismatchCount).
+		Info("Reconciliation completed")
+
+	response := toReconciliationResponse(reconciliation)
+	return &response, nil
+}
+
+func compareSettlement(reconciliation *entity.Reconciliation, payout *entity.PayoutRecord, record *entity.SettlementRecord) {
+	if math.Round(payout.AmountIDR*100) == math.Round(record.AmountIDR*100) {
+		reconciliation.MatchedCount++
+		return
+	}
+
+	reconciliation.AmountMismatchCount++
+	reconciliation.Items = append(reconciliation.Items, &entity.ReconciliationItem{
+		Kind:              util.RECONCILIATION_AMOUNT_MISMATCH,
+		ExternalID:        record.ExternalID,
+		ProviderPaymentID: record.ProviderPaymentID,
+		ExpectedAmountIDR: payout.AmountIDR,
+		ActualAmountIDR:   record.AmountIDR,
+	})
+}
+
+func addExtraItem(reconciliation *entity.Reconciliation, record *entity.SettlementRecord, note string) {
+	reconciliation.ExtraCount++
+	reconciliation.Items = append(reconciliation.Items, &entity.ReconciliationItem{
+		Kind:              util.RECONCILIATION_EXTRA,
+		ExternalID:        record.ExternalID,
+		ProviderPaymentID: record.ProviderPaymentID,
+		ActualAmountIDR:   record.AmountIDR,
+		Note:              note,
+	})
+}
+
+// parseSettlementFile reads a statement uploaded as a JSON array or as CSV with
+// a header row. external_id and amount_idr are required, settled_at is
+// RFC 3339.
+func parseSettlementFile(file []byte) ([]*entity.SettlementRecord, error) {
+	file = bytes.TrimSpace(file)
+	if len(file) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", util.ErrInvalidReconciliation)
+	}
+
+	fileRecords := make([]settlementFileRecord, 0)
+	if file[0] == '[' {
+		err := json.Unmarshal(file, &fileRecords)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", util.ErrInvalidReconciliation, err)
+		}
+	} else {
+		var err error
+		fileRecords, err = parseSettlementCSV(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]*entity.SettlementRecord, 0, len(fileRecords))
+	for i, record := range fileRecords {
+		if record.ExternalID == "" || record.AmountIDR == nil {
+			return nil, fmt.Errorf("%w: record %d needs external_id and amount_idr", util.ErrInvalidReconciliation, i+1)
+		}
+		if _, ok := util.GetPaymentStatusFromProvider(record.Status); record.Status != "" && !ok {
+			return nil, fmt.Errorf("%w: record %d has unknown status %q", util.ErrInvalidReconciliation, i+1, record.Status)
+		}
+
+		records = append(records, &entity.SettlementRecord{
+			ExternalID:        record.ExternalID,
+			ProviderPaymentID: record.ProviderPaymentID,
+			AmountIDR:         *record.AmountIDR,
+			Status:            record.Status,
+			SettledAt:         record.SettledAt,
+		})
+	}
+
+	return records, nil
+}
+
+func parseSettlementCSV(file []byte) ([]settlementFileRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(file))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", util.ErrInvalidReconciliation, err)
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = settlementColumns[strings.ToLower(strings.TrimSpace(name))]
+	}
+
+	records := make([]settlementFileRecord, 0)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", util.ErrInvalidReconciliation, err)
+		}
+
+		var record settlementFileRecord
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			switch columns[i] {
+			case "external_id":
+				record.ExternalID = value
+			case "provider_payment_id":
+				record.ProviderPaymentID = value
+			case "amount_idr":
+				amount, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: invalid amount %q", util.ErrInvalidReconciliation, line, value)
+				}
+				record.AmountIDR = &amount
+			case "status":
+				record.Status = value
+			case "settled_at":
+				settledAt, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: invalid settled_at %q", util.ErrInvalidReconciliation, line, value)
+				}
+				record.SettledAt = &settledAt
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func toReconciliationResponse(reconciliation *entity.Reconciliation) model.ReconciliationResponse {
+	response := model.ReconciliationResponse{
+		ID:                  reconciliation.ID,
+		Provider:            reconciliation.Provider,
+		Source:              reconciliation.Source,
+		PeriodFrom:          reconciliation.PeriodFrom.Format(time.DateOnly),
+		PeriodTo:            reconciliation.PeriodTo.Format(time.DateOnly),
+		MatchedCount:        reconciliation.MatchedCount,
+		MissingCount:        reconciliation.MissingCount,
+		ExtraCount:          reconciliation.ExtraCount,
+		AmountMismatchCount: reconciliation.AmountMismatchCount,
+		ExpectedTotalIDR:    reconciliation.ExpectedTotalIDR,
+		StatementTotalIDR:   reconciliation.StatementTotalIDR,
+		CreatedBy:           reconciliation.CreatedBy,
+		CreatedAt:           reconciliation.CreatedAt,
+	}
+	for _, item := range reconciliation.Items {
+		itemResponse := model.ReconciliationItemResponse{
+			Kind:              item.Kind,
+			ExternalID:        item.ExternalID,
+			ProviderPaymentID: item.ProviderPaymentID,
+			Note:              item.Note,
+		}
+		if item.Kind != util.RECONCILIATION_EXTRA {
+			expected := item.ExpectedAmountIDR
+			itemResponse.ExpectedAmountIDR = &expected
+		}
+		if item.Kind != util.RECONCILIATION_MISSING {
+			actual := item.ActualAmountIDR
+			itemResponse.ActualAmountIDR = &actual
+		}
+		response.Items = append(response.Items, itemResponse)
+	}
+	return response
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSettlementFile(t *testing.T) {
+	settledAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []*entity.SettlementRecord
+		wantErr string
+	}{
+		{
+			name: "success - csv with the bank file columns",
+			file: "reference,amount,status,settled_at\n" +
+				"ext-1,150000,COMPLETED,2024-03-01T09:30:00Z\n" +
+				"reversal-1,-150000,,\n",
+			want: []*entity.SettlementRecord{
+				{ExternalID: "ext-1", AmountIDR: 150000, Status: "COMPLETED", SettledAt: &settledAt},
+				{ExternalID: "reversal-1", AmountIDR: -150000},
+			},
+		},
+		{
+			name: "success - json array",
+			file: `[{"external_id":"ext-1","provider_payment_id":"TRF-1","amount_idr":150000,"status":"success","settled_at":"2024-03-01T09:30:00Z"}]`,
+			want: []*entity.SettlementRecord{
+				{ExternalID: "ext-1", ProviderPaymentID: "TRF-1", AmountIDR: 150000, Status: "success", SettledAt: &settledAt},
+			},
+		},
+		{
+			name:    "failure - empty file",
+			file:    "  \n",
+			wantErr: "reconciliation request is not valid: file is empty",
+		},
+		{
+			name:    "failure - missing amount",
+			file:    "external_id,status\next-1,COMPLETED\n",
+			wantErr: "reconciliation request is not valid: record 1 needs external_id and amount_idr",
+		},
+		{
+			name:    "failure - invalid amount",
+			file:    "external_id,amount_idr\next-1,IDR 150000\n",
+			wantErr: "reconciliation request is not valid: line 2: invalid amount \"IDR 150000\"",
+		},
+		{
+			name:    "failure - malformed csv",
+			file:    "external_id,amount_idr\next-1,150.000,00\n",
+			wantErr: "reconciliation request is not valid: record on line 2: wrong number of fields",
+		},
+		{
+			name:    "failure - unknown status",
+			file:    `[{"external_id":"ext-1","amount_idr":150000,"status":"ON_HOLD"}]`,
+			wantErr: "reconciliation request is not valid: record 1 has unknown status \"ON_HOLD\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSettlementFile([]byte(tt.file))
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, util.ErrInvalidReconciliation)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconciliationService_ImportSettlement(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	settledAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.ReconcileRequest
+		file     string
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.ReconciliationResponse)
+	}{
+		{
+			name:    "success - reports missing, extra and amount mismatch",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReconcileRequest{Provider: "bank_transfer", From: "2024-03-01"},
+			file: "external_id,provider_payment_id,amount_idr,status\n" +
+				"ext-1,TRF-1,150000,COMPLETED\n" +
+				"ext-2,TRF-2,250000,COMPLETED\n" +
+				"reversal-1,REV-1,-150000,COMPLETED\n" +
+				"ext-3,TRF-3,75000,FAILED\n" +
+				"ext-4,TRF-4,90000,COMPLETED\n" +
+				"ext-5,TRF-5,60000,COMPLETED\n" +
+				"ext-6,TRF-6,80000,COMPLETED\n" +
+				"ext-1,TRF-1,150000,COMPLETED\n",
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					GetSettledPayouts(gomock.Any(), "bank_transfer", from, from.AddDate(0, 0, 1)).
+					Return([]*entity.PayoutRecord{
+						{ExternalID: "ext-1", Provider: "bank_transfer", ProviderPaymentID: "TRF-1", AmountIDR: 150000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "ext-2", Provider: "bank_transfer", ProviderPaymentID: "TRF-2", AmountIDR: 200000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "ext-3", Provider: "bank_transfer", ProviderPaymentID: "TRF-3", AmountIDR: 75000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "reversal-1", Provider: "bank_transfer", ProviderPaymentID: "REV-1", AmountIDR: -150000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+					}, nil).
+					Times(1)
+
+				// ext-5 was confirmed after midnight, ext-6 failed on our side.
+				completedAt := from.AddDate(0, 0, 1).Add(time.Minute)
+				server.MockReconcileRepo.EXPECT().
+					GetPayoutsByExternalIDs(gomock.Any(), []string{"ext-4", "ext-5", "ext-6"}).
+					Return([]*entity.PayoutRecord{
+						{ExternalID: "ext-5", Provider: "bank_transfer", AmountIDR: 60000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &completedAt},
+						{ExternalID: "ext-6", Provider: "bank_transfer", AmountIDR: 80000, Status: int32(util.PAYMENT_FAILED)},
+					}, nil).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					CreateReconciliation(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, reconciliation *entity.Reconciliation) (int64, error) {
+						assert.Equal(t, util.RECONCILIATION_SOURCE_FILE, reconciliation.Source)
+						assert.Equal(t, int64(1), reconciliation.CreatedBy)
+						assert.Equal(t, from, reconciliation.PeriodFrom)
+						assert.Equal(t, from, reconciliation.PeriodTo)
+						assert.Len(t, reconciliation.Items, 5)
+						return 3, nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.ReconciliationResponse) {
+				assert.Equal(t, int64(3), got.ID)
+				assert.Equal(t, "2024-03-01", got.PeriodFrom)
+				assert.Equal(t, "2024-03-01", got.PeriodTo)
+				assert.Equal(t, int32(3), got.MatchedCount)
+				assert.Equal(t, int32(1), got.MissingCount)
+				assert.Equal(t, int32(3), got.ExtraCount)
+				assert.Equal(t, int32(1), got.AmountMismatchCount)
+				assert.Equal(t, float64(275000), got.ExpectedTotalIDR)
+				assert.Equal(t, float64(630000), got.StatementTotalIDR)
+
+				expected, actual := float64(200000), float64(250000)
+				assert.Equal(t, model.ReconciliationItemResponse{
+					Kind:              util.RECONCILIATION_AMOUNT_MISMATCH,
+					ExternalID:        "ext-2",
+					ProviderPaymentID: "TRF-2",
+					ExpectedAmountIDR: &expected,
+					ActualAmountIDR:   &actual,
+				}, got.Items[0])
+
+				notes := make(map[string]string)
+				for _, item := range got.Items[1:] {
+					notes[item.Kind+" "+item.ExternalID] = item.Note
+				}
+				assert.Equal(t, map[string]string{
+					"extra ext-1":   "listed more than once in the statement",
+					"extra ext-4":   "no payout with this reference",
+					"extra ext-6":   "payout is failed in our records",
+					"missing ext-3": "failed in the provider statement",
+				}, notes)
+			},
+		},
+		{
+			name:    "success - transfers made from a batch file",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReconcileRequest{Provider: util.PAYOUT_CHANNEL_FILE, From: "2024-03-01", To: "2024-03-03"},
+			file:    `[{"external_id":"item-1","amount_idr":450000}]`,
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					GetSettledPayouts(gomock.Any(), util.PAYOUT_CHANNEL_FILE, from, from.AddDate(0, 0, 3)).
+					Return([]*entity.PayoutRecord{{ExternalID: "item-1", Provider: util.PAYOUT_CHANNEL_FILE, AmountIDR: 450000, Status: int32(util.PAYMENT_SUCCEEDED)}}, nil).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					CreateReconciliation(gomock.Any(), gomock.Any()).
+					Return(int64(4), nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.ReconciliationResponse) {
+				assert.Equal(t, int32(1), got.MatchedCount)
+				assert.Empty(t, got.Items)
+			},
+		},
+		{
+			name:    "failure - provider is not configured",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReconcileRequest{Provider: "cash", From: "2024-03-01"},
+			file:    `[]`,
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+			},
+			wantErr: errors.New("reconciliation request is not valid: provider \"cash\" is not configured"),
+		},
+		{
+			name:    "failure - period runs backwards",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReconcileRequest{Provider: "bank_transfer", From: "2024-03-02", To: "2024-03-01"},
+			file:    `[]`,
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+			},
+			wantErr: errors.New("reconciliation request is not valid: the period must run forward and cover at most 31 days"),
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.ReconcileRequest{Provider: "bank_transfer", From: "2024-03-01"},
+			file:    `[]`,
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.ImportSettlement(roleContext(tt.role), tt.request, []byte(tt.file))
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestReconciliationService_ReconcileProvider(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	settledAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.ReconciliationResponse)
+	}{
+		{
+			name: "success - statement pulled from the provider",
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					ListPayments(gomock.Any(), "bank_transfer", from, from.AddDate(0, 0, 2)).
+					Return([]*entity.SettlementRecord{
+						{ExternalID: "ext-1", AmountIDR: 150000, Status: "COMPLETED"},
+						{ExternalID: "ext-2", AmountIDR: 200000, Status: "COMPLETED"},
+						{ExternalID: "ext-3", AmountIDR: 75000, Status: "COMPLETED"},
+						{ExternalID: "reversal-1", AmountIDR: -150000, Status: "COMPLETED"},
+					}, nil).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					GetSettledPayouts(gomock.Any(), "bank_transfer", from, from.AddDate(0, 0, 2)).
+					Return([]*entity.PayoutRecord{
+						{ExternalID: "ext-1", Provider: "bank_transfer", ProviderPaymentID: "TRF-1", AmountIDR: 150000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "ext-2", Provider: "bank_transfer", ProviderPaymentID: "TRF-2", AmountIDR: 200000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "ext-3", Provider: "bank_transfer", ProviderPaymentID: "TRF-3", AmountIDR: 75000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+						{ExternalID: "reversal-1", Provider: "bank_transfer", ProviderPaymentID: "REV-1", AmountIDR: -150000, Status: int32(util.PAYMENT_SUCCEEDED), CompletedAt: &settledAt},
+					}, nil).
+					Times(1)
+
+				server.MockReconcileRepo.EXPECT().
+					CreateReconciliation(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, reconciliation *entity.Reconciliation) (int64, error) {
+						assert.Equal(t, util.RECONCILIATION_SOURCE_API, reconciliation.Source)
+						return 5, nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.ReconciliationResponse) {
+				assert.Equal(t, int64(5), got.ID)
+				assert.Equal(t, util.RECONCILIATION_SOURCE_API, got.Source)
+				assert.Equal(t, int32(4), got.MatchedCount)
+				assert.Equal(t, got.ExpectedTotalIDR, got.StatementTotalIDR)
+				assert.Empty(t, got.Items)
+			},
+		},
+		{
+			name: "failure - provider statement unavailable",
+			mock: func(server *TestService) {
+				server.MockPaymentProcessor.EXPECT().
+					GetProviderStats().
+					Return([]*entity.PaymentProviderStats{
+						{Provider: "bank_transfer", CircuitState: "closed"},
+						{Provider: "ewallet", CircuitState: "closed"},
+					}).
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					ListPayments(gomock.Any(), "bank_transfer", from, from.AddDate(0, 0, 2)).
+					Return(nil, errors.New("payment provider unavailable: 503 Service Unavailable")).
+					Times(1)
+			},
+			wantErr: errors.New("failed to get settlement statement"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.ReconcileProvider(roleContext(util.USER_ROLE_ADMIN), model.ReconcileRequest{
+				Provider: "bank_transfer",
+				From:     "2024-03-01",
+				To:       "2024-03-02",
+			})
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestReconciliationService_RunScheduledReconciliations(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t)
+	defer server.MockCtrl.Finish()
+
+	now := time.Now()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+
+	server.MockPaymentProcessor.EXPECT().
+		GetProviderStats().
+		Return([]*entity.PaymentProviderStats{
+			{Provider: "bank_transfer", CircuitState: "closed"},
+			{Provider: "ewallet", CircuitState: "closed"},
+		}).
+		Times(1)
+
+	server.MockPaymentProcessor.EXPECT().
+		ListPayments(gomock.Any(), "bank_transfer", yesterday, yesterday.AddDate(0, 0, 1)).
+		Return(nil, errors.New("connection refused")).
+		Times(1)
+
+	server.MockPaymentProcessor.EXPECT().
+		ListPayments(gomock.Any(), "ewallet", yesterday, yesterday.AddDate(0, 0, 1)).
+		Return([]*entity.SettlementRecord{}, nil).
+		Times(1)
+
+	server.MockReconcileRepo.EXPECT().
+		GetSettledPayouts(gomock.Any(), "ewallet", yesterday, yesterday.AddDate(0, 0, 1)).
+		Return([]*entity.PayoutRecord{}, nil).
+		Times(1)
+
+	server.MockReconcileRepo.EXPECT().
+		CreateReconciliation(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, reconciliation *entity.Reconciliation) (int64, error) {
+			assert.Equal(t, int64(0), reconciliation.CreatedBy)
+			return 6, nil
+		}).
+		Times(1)
+
+	// The bank failing does not keep the e-wallet from being reconciled.
+	got, err := server.Service.RunScheduledReconciliations(context.Background())
+	assert.EqualError(t, err, "failed to get settlement statement")
+	assert.Len(t, got, 1)
+	assert.Equal(t, "ewallet", got[0].Provider)
+}
+
+func TestReconciliationService_GetReconciliation(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t)
+	defer server.MockCtrl.Finish()
+
+	server.MockReconcileRepo.EXPECT().
+		GetReconciliation(gomock.Any(), int64(9)).
+		Return(nil, util.ErrReconciliationNotFound).
+		Times(1)
+
+	_, err := server.Service.GetReconciliation(roleContext(util.USER_ROLE_ADMIN), 9)
+	assert.ErrorIs(t, err, util.ErrReconciliationNotFound)
+}
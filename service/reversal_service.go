@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+	"github.com/google/uuid"
+)
+
+// ReverseExpense pulls a paid out expense back, for fraud or a wrong amount.
+// The refund goes to the provider that made the payout and is recorded as a
+// negative payment entry. The expense moves to REVERSED once the provider
+// confirms the refund, which may happen later through the payment webhook.
+func (s *ExpensesManagementService) ReverseExpense(ctx context.Context, req model.ReverseExpenseRequest) (*model.ReverseExpenseResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", req.ExpenseID).WithField("user_id", userInfo.ID).Info("ReverseExpense")
+
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, req.ExpenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	err = s.checkExpenseVersion(expense, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The refund cannot be taken back, so the transition is checked before
+	// the provider is called.
+	err = s.machine.Check(statemachine.Request{Expense: expense, Event: statemachine.EventReverse, Actor: userInfo})
+	if err != nil {
+		s.logger.WithError(err).WithField("expense_id", expense.ID).Error("expense cannot be reversed")
+		return nil, err
+	}
+
+	payments, err := s.repo.PaymentRepository.GetPaymentsByExpenseID(ctx, expense.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get payments")
+		return nil, fmt.Errorf("failed to get payments")
+	}
+
+	payment := reversiblePayment(payments)
+	if payment == nil {
+		s.logger.WithField("expense_id", expense.ID).Error("expense has no succeeded payment")
+		return nil, util.ErrNothingToReverse
+	}
+
+	reversal, err := s.repo.PaymentRepository.StartPaymentAttempt(ctx, &entity.Payment{
+		ExpenseID:  expense.ID,
+		ExternalID: reversalExternalID(payment.ID),
+		AmountIDR:  -payment.AmountIDR,
+		ReversalOf: payment.ID,
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to record payment reversal")
+		return nil, fmt.Errorf("failed to record payment reversal")
+	}
+
+	err = s.repo.ExpensesRepository.WriteAuditLog(ctx, &entity.AuditLog{
+		ExpenseID:    expense.ID,
+		NewStatus:    expense.Status,
+		StatusBefore: expense.Status,
+		AmountBefore: payment.AmountIDR,
+		AmountAfter:  0, // the paid amount is taken back
+		Notes:        fmt.Sprintf("Reversal %s of payment %s requested by %s: %s", reversal.ExternalID, payment.ExternalID, userInfo.Email, req.Reason),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to write audit log")
+	}
+
+	if reversal.Status != int32(util.PAYMENT_SUCCEEDED) && !paymentInFlight(reversal) {
+		err = s.refundPayment(ctx, payment, reversal, req.Reason)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if reversal.Status == int32(util.PAYMENT_SUCCEEDED) {
+		err = s.markExpenseReversed(ctx, expense, payment, reversal, userInfo, req.Reason)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.ReverseExpenseResponse{
+		ExpenseID:     expense.ID,
+		ExpenseStatus: util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+		Version:       expense.Version,
+		Reversal:      toPaymentResponse(reversal),
+	}, nil
+}
+
+// refundPayment asks the provider of payment to refund it and records the
+// answer on the reversal entry.
+func (s *ExpensesManagementService) refundPayment(ctx context.Context, payment, reversal *entity.Payment, reason string) error {
+	reversal.Provider = payment.Provider
+	response, err := s.repo.PaymentProcessor.RefundPayment(ctx, &entity.RefundRequest{
+		AmountIDR:         int64(payment.AmountIDR),
+		ExternalID:        reversal.ExternalID,
+		IdempotencyKey:    reversal.ExternalID,
+		PaymentExternalID: payment.ExternalID,
+		ProviderPaymentID: payment.ProviderPaymentID,
+		Reason:            reason,
+		Provider:          payment.Provider,
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("external_id", payment.ExternalID).Error("failed to refund payment")
+		reversal.Status = int32(util.PAYMENT_FAILED)
+		reversal.LastError = err.Error()
+		if err := s.repo.PaymentRepository.UpdatePayment(ctx, reversal); err != nil {
+			s.logger.WithError(err).Error("failed to update payment")
+		}
+		return fmt.Errorf("failed to reverse payment")
+	}
+	s.logger.WithField("response", response).WithField("provider", reversal.Provider).Info("Refund processed")
+
+	reversal.ProviderPaymentID = response.Data.ID
+	reversal.RawResponse = string(response.Raw)
+	reversal.LastError = ""
+
+	// An accepted refund is confirmed later through the payment webhook.
+	if status, _ := util.GetPaymentStatusFromProvider(response.Data.Status); status != util.PAYMENT_PROCESSING {
+		completedAt := time.Now()
+		reversal.Status = int32(util.PAYMENT_SUCCEEDED)
+		reversal.CompletedAt = &completedAt
+	}
+
+	err = s.repo.PaymentRepository.UpdatePayment(ctx, reversal)
+	if err != nil {
+		// The provider deduplicates on the idempotency key, so reversing again is safe.
+		s.logger.WithError(err).Error("failed to update payment")
+		return fmt.Errorf("failed to update payment")
+	}
+	return nil
+}
+
+// handleReversalWebhook applies the provider's answer to a refund that was
+// still processing. The expense is only touched when the refund succeeded.
+func (s *ExpensesManagementService) handleReversalWebhook(ctx context.Context, expense *entity.Expense, reversal *entity.Payment, req model.PaymentWebhookRequest, status util.PaymentStatus, raw []byte) (*model.PaymentWebhookResponse, error) {
+	switch {
+	case reversal.Status == int32(util.PAYMENT_SUCCEEDED) && status != util.PAYMENT_SUCCEEDED:
+		s.logger.WithField("external_id", reversal.ExternalID).Warn("Reversal already succeeded, ignoring callback")
+	case status == util.PAYMENT_PROCESSING:
+		s.logger.WithField("external_id", reversal.ExternalID).Info("Reversal still processing")
+	default:
+		if reversal.Status != int32(status) {
+			completedAt := time.Now()
+			reversal.Status = int32(status)
+			if req.ID != "" {
+				reversal.ProviderPaymentID = req.ID
+			}
+			reversal.LastError = ""
+			if status == util.PAYMENT_FAILED {
+				reversal.LastError = req.FailureReason
+			}
+			reversal.RawResponse = string(raw)
+			reversal.CompletedAt = &completedAt
+			err := s.repo.PaymentRepository.UpdatePayment(ctx, reversal)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to update payment")
+				return nil, fmt.Errorf("failed to update payment")
+			}
+		}
+
+		if status == util.PAYMENT_SUCCEEDED {
+			payments, err := s.repo.PaymentRepository.GetPaymentsByExpenseID(ctx, expense.ID)
+			if err != nil {
+				s.logger.WithError(err).Error("failed to get payments")
+				return nil, fmt.Errorf("failed to get payments")
+			}
+
+			payment := &entity.Payment{ID: reversal.ReversalOf}
+			for _, candidate := range payments {
+				if candidate.ID == reversal.ReversalOf {
+					payment = candidate
+				}
+			}
+
+			err = s.markExpenseReversed(ctx, expense, payment, reversal, model.User{}, "")
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			s.logger.WithField("expense_id", expense.ID).WithField("external_id", reversal.ExternalID).Warn("Reversal failed, expense stays paid")
+		}
+	}
+
+	return &model.PaymentWebhookResponse{
+		ExternalID:    reversal.ExternalID,
+		PaymentStatus: util.GetPaymentStatusString(util.PaymentStatus(reversal.Status)),
+		ExpenseStatus: util.GetExpenseStatusString(util.ExpenseStatus(expense.Status)),
+	}, nil
+}
+
+// markExpenseReversed moves the expense to REVERSED once the refund of payment
+// has succeeded. It is a no-op when the expense is already reversed.
+func (s *ExpensesManagementService) markExpenseReversed(ctx context.Context, expense *entity.Expense, payment, reversal *entity.Payment, actor model.User, reason string) error {
+	if expense.Status == int32(util.EXPENSE_REVERSED) {
+		return nil
+	}
+
+	notes := fmt.Sprintf("Payment %s reversed by %s", payment.ExternalID, reversal.ExternalID)
+	if reason != "" {
+		notes += ": " + reason
+	}
+
+	transition := statemachine.Request{
+		Expense:      expense,
+		Event:        statemachine.EventReverse,
+		Actor:        actor,
+		Notes:        notes,
+		AmountBefore: payment.AmountIDR,
+	}
+	_, err := s.transitionExpense(ctx, transition, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		reversedEvent, err := newEventOutboxMessage(util.EVENT_PAYMENT_REVERSED, expense.ID, eventActor(actor), &entity.PaymentEventData{
+			ExpenseID:         expense.ID,
+			UserID:            expense.UserID,
+			ExternalID:        reversal.ExternalID,
+			ProviderPaymentID: reversal.ProviderPaymentID,
+			AmountIDR:         reversal.AmountIDR,
+			ReversalOf:        payment.ExternalID,
+		})
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build payment event")
+			return fmt.Errorf("failed to update expense status")
+		}
+		journalMessages, err := newJournalOutboxMessages(transition, to)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to build journal entry")
+			return fmt.Errorf("failed to update expense status")
+		}
+
+		err = s.repo.ExpensesRepository.UpdateExpenseStatus(ctx, expense.ID, int32(to), expense.Version, auditLog, append([]*entity.OutboxMessage{reversedEvent}, journalMessages...)...)
+		if err != nil {
+			return persistError(err, "failed to update expense status")
+		}
+		expense.Version++
+		return nil
+	})
+	return err
+}
+
+// reversiblePayment returns the succeeded payout of the expense, or nil.
+func reversiblePayment(payments []*entity.Payment) *entity.Payment {
+	for i := len(payments) - 1; i >= 0; i-- {
+		payment := payments[i]
+		if payment.ReversalOf == 0 && payment.Status == int32(util.PAYMENT_SUCCEEDED) {
+			return payment
+		}
+	}
+	return nil
+}
+
+// reversalExternalID derives the refund reference from the payment ID, so a
+// repeated reversal reaches the provider with the same key.
+func reversalExternalID(paymentID int64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("payment-reversal:%d", paymentID))).String()
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReversalService_ReverseExpense(t *testing.T) {
+	paidExpense := func() *entity.Expense {
+		return &entity.Expense{ID: 10, UserID: 2, AmountIDR: 150000, Status: int32(util.EXPENSE_PAID), Version: 3}
+	}
+	succeededPayment := func() *entity.Payment {
+		return &entity.Payment{
+			ID:                5,
+			ExpenseID:         10,
+			ExternalID:        paymentExternalID(10),
+			AmountIDR:         150000,
+			Status:            int32(util.PAYMENT_SUCCEEDED),
+			Provider:          "bank_transfer",
+			ProviderPaymentID: "TRF-1",
+		}
+	}
+	startReversal := func(_ context.Context, payment *entity.Payment) (*entity.Payment, error) {
+		started := *payment
+		started.ID = 6
+		started.Status = int32(util.PAYMENT_PROCESSING)
+		started.Attempts = 1
+		return &started, nil
+	}
+	refundResponse := func(status string) *entity.PaymentProcessorResponse {
+		response := &entity.PaymentProcessorResponse{}
+		response.Data.ID = "REV-1"
+		response.Data.Status = status
+		return response
+	}
+
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.ReverseExpenseRequest
+		mock     func(server *TestService)
+		validate func(t *testing.T, got *model.ReverseExpenseResponse)
+		wantErr  error
+	}{
+		{
+			name:    "success - refund completed reverses the expense",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "duplicate claim", Version: 3},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(10)).
+					Return(paidExpense(), nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(10)).
+					Return([]*entity.Payment{succeededPayment()}, nil).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					StartPaymentAttempt(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+						assert.Equal(t, reversalExternalID(5), payment.ExternalID)
+						assert.Equal(t, float64(-150000), payment.AmountIDR)
+						assert.Equal(t, int64(5), payment.ReversalOf)
+						return startReversal(ctx, payment)
+					}).
+					Times(1)
+
+				server.MockPaymentProcessor.EXPECT().
+					RefundPayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, refund *entity.RefundRequest) (*entity.PaymentProcessorResponse, error) {
+						assert.Equal(t, "bank_transfer", refund.Provider)
+						assert.Equal(t, "TRF-1", refund.ProviderPaymentID)
+						assert.Equal(t, int64(150000), refund.AmountIDR)
+						assert.Equal(t, reversalExternalID(5), refund.IdempotencyKey)
+						assert.Equal(t, "duplicate claim", refund.Reason)
+						return refundResponse("COMPLETED"), nil
+					}).
+					Times(1)
+
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), payment.Status)
+						assert.Equal(t, "REV-1", payment.ProviderPaymentID)
+						assert.Equal(t, "bank_transfer", payment.Provider)
+						return nil
+					}).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					UpdateExpenseStatus(gomock.Any(), int64(10), int32(util.EXPENSE_REVERSED), int32(3), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, _ int32, _ int32, auditLog *entity.AuditLog, messages ...*entity.OutboxMessage) error {
+						assert.Equal(t, int32(util.EXPENSE_PAID), auditLog.StatusBefore)
+						assert.Equal(t, int32(util.EXPENSE_REVERSED), auditLog.NewStatus)
+						assert.Equal(t, float64(150000), auditLog.AmountBefore)
+						assert.Equal(t, float64(0), auditLog.AmountAfter)
+
+						assert.Len(t, messages, 2)
+						assert.Equal(t, util.EVENT_PAYMENT_REVERSED, messages[0].EventType)
+
+						entry := journalOutboxEntry(t, messages)
+						assert.Equal(t, "expense:10:payment_reversed", entry.Reference)
+						assert.Equal(t, int64(2), entry.UserID)
+						assert.Equal(t, []*entity.JournalLine{
+							{Account: util.LEDGER_ACCOUNT_BANK, DebitIDR: 150000},
+							{Account: util.LEDGER_ACCOUNT_EXPENSE, CreditIDR: 150000},
+						}, entry.Lines)
+						return nil
+					}).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					WriteAuditLog(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, auditLog *entity.AuditLog) error {
+						assert.Equal(t, int32(util.EXPENSE_PAID), auditLog.NewStatus)
+						assert.Contains(t, auditLog.Notes, "duplicate claim")
+						assert.Equal(t, float64(150000), auditLog.AmountBefore)
+						assert.Equal(t, float64(0), auditLog.AmountAfter)
+						return nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.ReverseExpenseResponse) {
+				assert.Equal(t, "reversed", got.ExpenseStatus)
+				assert.Equal(t, int32(4), got.Version)
+				assert.Equal(t, float64(-150000), got.Reversal.AmountIDR)
+				assert.Equal(t, "succeeded", got.Reversal.Status)
+				assert.Equal(t, int64(5), got.Reversal.ReversalOf)
+			},
+		},
+		{
+			name:    "success - pending refund keeps the expense paid",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "wrong amount"},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().GetExpenseByID(gomock.Any(), int64(10)).Return(paidExpense(), nil).Times(1)
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(10)).
+					Return([]*entity.Payment{succeededPayment()}, nil).
+					Times(1)
+				server.MockPaymentRepo.EXPECT().StartPaymentAttempt(gomock.Any(), gomock.Any()).DoAndReturn(startReversal).Times(1)
+				server.MockRepo.EXPECT().WriteAuditLog(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				server.MockPaymentProcessor.EXPECT().
+					RefundPayment(gomock.Any(), gomock.Any()).
+					Return(refundResponse("PENDING"), nil).
+					Times(1)
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_PROCESSING), payment.Status)
+						assert.Nil(t, payment.CompletedAt)
+						return nil
+					}).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.ReverseExpenseResponse) {
+				assert.Equal(t, "paid", got.ExpenseStatus)
+				assert.Equal(t, "processing", got.Reversal.Status)
+			},
+		},
+		{
+			name:    "failure - provider declines the refund",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud"},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().GetExpenseByID(gomock.Any(), int64(10)).Return(paidExpense(), nil).Times(1)
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(10)).
+					Return([]*entity.Payment{succeededPayment()}, nil).
+					Times(1)
+				server.MockPaymentRepo.EXPECT().StartPaymentAttempt(gomock.Any(), gomock.Any()).DoAndReturn(startReversal).Times(1)
+				server.MockRepo.EXPECT().WriteAuditLog(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				server.MockPaymentProcessor.EXPECT().
+					RefundPayment(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("payment declined by provider: insufficient balance")).
+					Times(1)
+				server.MockPaymentRepo.EXPECT().
+					UpdatePayment(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payment *entity.Payment) error {
+						assert.Equal(t, int32(util.PAYMENT_FAILED), payment.Status)
+						assert.Contains(t, payment.LastError, "insufficient balance")
+						return nil
+					}).
+					Times(1)
+			},
+			wantErr: fmt.Errorf("failed to reverse payment"),
+		},
+		{
+			name:    "failure - expense is not paid",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud"},
+			mock: func(server *TestService) {
+				expense := paidExpense()
+				expense.Status = int32(util.EXPENSE_APPROVED)
+				server.MockRepo.EXPECT().GetExpenseByID(gomock.Any(), int64(10)).Return(expense, nil).Times(1)
+			},
+			wantErr: statemachine.ErrIllegalTransition,
+		},
+		{
+			name:    "failure - no succeeded payment",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud"},
+			mock: func(server *TestService) {
+				failed := succeededPayment()
+				failed.Status = int32(util.PAYMENT_FAILED)
+				server.MockRepo.EXPECT().GetExpenseByID(gomock.Any(), int64(10)).Return(paidExpense(), nil).Times(1)
+				server.MockPaymentRepo.EXPECT().
+					GetPaymentsByExpenseID(gomock.Any(), int64(10)).
+					Return([]*entity.Payment{failed}, nil).
+					Times(1)
+			},
+			wantErr: util.ErrNothingToReverse,
+		},
+		{
+			name:    "failure - stale version",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud", Version: 2},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().GetExpenseByID(gomock.Any(), int64(10)).Return(paidExpense(), nil).Times(1)
+			},
+			wantErr: util.ErrVersionConflict,
+		},
+		{
+			name:    "failure - managers cannot reverse payments",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud"},
+			mock:    func(server *TestService) {},
+			wantErr: fmt.Errorf("user is not an admin"),
+		},
+		{
+			name:    "failure - employees cannot reverse payments",
+			role:    util.USER_ROLE_EMPLOYEE,
+			request: model.ReverseExpenseRequest{ExpenseID: 10, Reason: "fraud"},
+			mock:    func(server *TestService) {},
+			wantErr: fmt.Errorf("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.ReverseExpense(roleContext(tt.role), tt.request)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					assert.EqualError(t, err, tt.wantErr.Error())
+				}
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestReversalService_ReversalWebhook(t *testing.T) {
+	server := NewTestServerWithPaymentProcessor(t)
+	defer server.MockCtrl.Finish()
+
+	payment := &entity.Payment{ID: 5, ExpenseID: 10, ExternalID: paymentExternalID(10), AmountIDR: 150000, Status: int32(util.PAYMENT_SUCCEEDED)}
+	reversal := &entity.Payment{ID: 6, ExpenseID: 10, ExternalID: reversalExternalID(5), AmountIDR: -150000, Status: int32(util.PAYMENT_PROCESSING), ReversalOf: 5}
+
+	server.MockPaymentRepo.EXPECT().
+		GetPaymentByExternalID(gomock.Any(), reversal.ExternalID).
+		Return(reversal, nil).
+		Times(1)
+	server.MockRepo.EXPECT().
+		GetExpenseByID(gomock.Any(), int64(10)).
+		Return(&entity.Expense{ID: 10, UserID: 2, Status: int32(util.EXPENSE_PAID), Version: 3}, nil).
+		Times(1)
+	server.MockPaymentRepo.EXPECT().
+		UpdatePayment(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, updated *entity.Payment) error {
+			assert.Equal(t, int32(util.PAYMENT_SUCCEEDED), updated.Status)
+			assert.Equal(t, "REV-1", updated.ProviderPaymentID)
+			return nil
+		}).
+		Times(1)
+	server.MockPaymentRepo.EXPECT().
+		GetPaymentsByExpenseID(gomock.Any(), int64(10)).
+		Return([]*entity.Payment{payment, reversal}, nil).
+		Times(1)
+	server.MockRepo.EXPECT().
+		UpdateExpenseStatus(gomock.Any(), int64(10), int32(util.EXPENSE_REVERSED), int32(3), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int64, _ int32, _ int32, auditLog *entity.AuditLog, _ ...*entity.OutboxMessage) error {
+			assert.Equal(t, int32(util.EXPENSE_REVERSED), auditLog.NewStatus)
+			assert.Contains(t, auditLog.Notes, payment.ExternalID)
+			return nil
+		}).
+		Times(1)
+
+	got, err := server.Service.HandlePaymentWebhook(context.Background(), model.PaymentWebhookRequest{
+		ID:         "REV-1",
+		ExternalID: reversal.ExternalID,
+		Status:     "COMPLETED",
+	}, []byte(`{}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.PaymentWebhookResponse{ExternalID: reversal.ExternalID, PaymentStatus: "succeeded", ExpenseStatus: "reversed"}, got)
+}
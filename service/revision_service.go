@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+)
+
+func (s *ExpensesManagementService) RequestExpenseChanges(ctx context.Context, req model.ApprovalRequest) (*model.ApprovalResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	if userInfo.Role != int(util.USER_ROLE_MANAGER) {
+		s.logger.WithField("user_id", userInfo.ID).Error("user is not a manager")
+		return nil, fmt.Errorf("user is not a manager")
+	}
+
+	if req.Notes == "" {
+		s.logger.WithField("expense_id", req.ExpenseID).Error("notes are required when requesting changes")
+		return nil, fmt.Errorf("notes are required when requesting changes")
+	}
+
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, req.ExpenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	err = s.checkExpenseVersion(expense, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.transitionExpense(ctx, statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventRequestChanges,
+		Actor:   userInfo,
+		Notes:   req.Notes,
+	}, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		err := s.repo.ExpensesRepository.ApprovalExpense(ctx, &entity.ExpenseApproval{
+			ExpenseID:  req.ExpenseID,
+			ApproverID: userInfo.ID,
+			Status:     int32(to),
+			Notes:      req.Notes,
+			Version:    expense.Version,
+		}, auditLog, nil)
+		if err != nil {
+			return persistError(err, "failed to request expense changes")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ApprovalResponse{
+		Message: fmt.Sprintf("Expense %d returned for revision", req.ExpenseID),
+		Version: expense.Version + 1,
+	}, nil
+}
+
+func (s *ExpensesManagementService) ResubmitExpense(ctx context.Context, expenseID int64, req model.ResubmitExpenseRequest) (*model.ExpenseResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", expenseID).Info("ResubmitExpense")
+
+	expense, err := s.repo.ExpensesRepository.GetExpenseByID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense")
+		return nil, fmt.Errorf("failed to get expense")
+	}
+
+	err = s.checkExpenseVersion(expense, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, autoApproved := util.AmountValidation(req.AmountIDR)
+	if !valid {
+		s.logger.WithField("amount_id", req.AmountIDR).Error("amount is not valid")
+		return nil, fmt.Errorf("amount is not valid")
+	}
+
+	// The revision is submitted again today, so it is held against today's
+	// budgets like a new claim.
+	var limitWarning string
+	var budgetWarnings []string
+	checkLimit := s.spendingLimitCheck(expense.UserID, req.AmountIDR, &limitWarning)
+	checkBudget := s.budgetCheck(expenseID, req.AmountIDR, &budgetWarnings)
+
+	buildOutbox := func(expenseID int64) ([]*entity.OutboxMessage, error) {
+		// A claim over the spending limit needs a manager's approval however
+		// small it is.
+		if !autoApproved || limitWarning != "" {
+			autoApproved = false
+			return nil, nil
+		}
+
+		message, err := newPaymentOutboxMessage(&entity.PublishPaymentRequest{
+			ExpenseID:  expenseID,
+			ApproverID: 0, // Auto approved, no approver
+			Notes:      "Auto Approved",
+			Status:     int32(util.EXPENSE_AUTO_APPROVED),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []*entity.OutboxMessage{message}, nil
+	}
+
+	var revision int32
+	_, err = s.transitionExpense(ctx, statemachine.Request{
+		Expense: expense,
+		Event:   statemachine.EventResubmit,
+		Actor:   userInfo,
+		Notes:   fmt.Sprintf("Expense resubmitted as revision %d", expense.Revision+1),
+	}, func(ctx context.Context, to util.ExpenseStatus, auditLog *entity.AuditLog) error {
+		revision, err = s.repo.ExpensesRepository.ReviseExpense(ctx, &entity.Expense{
+			ID:          expenseID,
+			UserID:      expense.UserID,
+			AmountIDR:   req.AmountIDR,
+			Category:    expense.Category,
+			Description: req.Description,
+			ReceiptURL:  req.ReceiptURL,
+			Status:      int32(to),
+			Version:     expense.Version,
+		}, req.Notes, auditLog, checkLimit, checkBudget, buildOutbox)
+		if errors.Is(err, util.ErrSpendingLimitExceeded) || errors.Is(err, util.ErrBudgetExceeded) {
+			return err
+		}
+		if err != nil {
+			return persistError(err, "failed to revise expense")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ExpenseResponse{
+		ID:             expenseID,
+		UserID:         expense.UserID,
+		AmountIDR:      req.AmountIDR,
+		Description:    req.Description,
+		ReceiptURL:     req.ReceiptURL,
+		Category:       expense.Category,
+		CostCenter:     expense.CostCenter,
+		Status:         util.GetExpenseStatusString(util.EXPENSE_PENDING),
+		AutoApproved:   autoApproved,
+		OverLimit:      limitWarning != "",
+		Revision:       revision,
+		Version:        expense.Version + 1,
+		BudgetWarnings: budgetWarnings,
+		LimitWarning:   limitWarning,
+	}, nil
+}
+
+func (s *ExpensesManagementService) GetExpenseHistory(ctx context.Context, expenseID int64) (*model.ExpenseHistoryResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("expense_id", expenseID).Info("GetExpenseHistory")
+
+	expense, err := s.getVisibleExpense(ctx, userInfo, expenseID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.repo.ExpensesRepository.GetExpenseVersions(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get expense versions")
+		return nil, fmt.Errorf("failed to get expense versions")
+	}
+
+	approvals, err := s.repo.ExpensesRepository.GetApprovalsByExpenseID(ctx, expenseID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get approvals")
+		return nil, fmt.Errorf("failed to get approvals")
+	}
+
+	versionsResponse := make([]model.ExpenseVersionResponse, 0)
+	for _, version := range versions {
+		versionsResponse = append(versionsResponse, model.ExpenseVersionResponse{
+			Revision:    version.Revision,
+			AmountIDR:   version.AmountIDR,
+			Description: version.Description,
+			ReceiptURL:  version.ReceiptURL,
+			Notes:       version.Notes,
+			CreatedAt:   version.CreatedAt,
+		})
+	}
+
+	feedbackResponse := make([]model.ApprovalFeedbackResponse, 0)
+	for _, approval := range approvals {
+		feedbackResponse = append(feedbackResponse, model.ApprovalFeedbackResponse{
+			ApproverID:        approval.ApproverID,
+			Revision:          approval.Revision,
+			Status:            util.GetApprovalStatusString(util.ApprovalStatus(approval.Status)),
+			Notes:             approval.Notes,
+			ApprovedAmountIDR: approval.ApprovedAmountIDR,
+			AdjustmentReason:  approval.AdjustmentReason,
+			CreatedAt:         approval.CreatedAt,
+		})
+	}
+
+	return &model.ExpenseHistoryResponse{
+		ExpenseID:       expenseID,
+		CurrentRevision: expense.Revision,
+		Versions:        versionsResponse,
+		Feedback:        feedbackResponse,
+	}, nil
+}
@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevisionService_RequestExpenseChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		request model.ApprovalRequest
+		userCtx model.User
+		mock    func(server *TestService)
+		want    *model.ApprovalResponse
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "success - manager returns expense for revision",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Notes:     "Please attach the hotel invoice",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_PENDING),
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ApprovalExpense(gomock.Any(), &entity.ExpenseApproval{
+						ExpenseID:  123,
+						ApproverID: 2,
+						Status:     int32(util.EXPENSE_NEEDS_REVISION),
+						Notes:      "Please attach the hotel invoice",
+					}, gomock.Any(), gomock.Nil()).
+					Return(nil).
+					Times(1)
+			},
+			want: &model.ApprovalResponse{
+				Message: "Expense 123 returned for revision",
+			},
+			wantErr: false,
+		},
+		{
+			name: "failure - not a manager",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Notes:     "Should fail",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock:    func(server *TestService) {},
+			wantErr: true,
+			errMsg:  "user is not a manager",
+		},
+		{
+			name: "failure - notes are required",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock:    func(server *TestService) {},
+			wantErr: true,
+			errMsg:  "notes are required",
+		},
+		{
+			name: "failure - expense is not pending",
+			request: model.ApprovalRequest{
+				ExpenseID: 123,
+				Notes:     "Please fix",
+			},
+			userCtx: model.User{
+				ID:    2,
+				Email: "manager@example.com",
+				Role:  int(util.USER_ROLE_MANAGER),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_REJECTED),
+					}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "cannot request_changes expense with status rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
+			tt.mock(server)
+
+			got, err := server.Service.RequestExpenseChanges(ctx, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				if tt.errMsg != "" {
+					assert.Contains(t, err.Error(), tt.errMsg)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.Message, got.Message)
+		})
+	}
+}
+
+func TestRevisionService_ResubmitExpense(t *testing.T) {
+	periodFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periodTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expenseID int64
+		request   model.ResubmitExpenseRequest
+		userCtx   model.User
+		mock      func(server *TestService)
+		want      *model.ExpenseResponse
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "success - submitter resubmits revised expense",
+			expenseID: 123,
+			request: model.ResubmitExpenseRequest{
+				AmountIDR:   2500000,
+				Description: "Hotel stay with invoice",
+				ReceiptURL:  "https://example.com/invoice.pdf",
+				Notes:       "Invoice attached",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:        123,
+						UserID:    1,
+						AmountIDR: 3000000,
+						Category:  util.EXPENSE_CATEGORY_TRAVEL,
+						Status:    int32(util.EXPENSE_NEEDS_REVISION),
+						Revision:  1,
+						Version:   3,
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ReviseExpense(gomock.Any(), gomock.Any(), "Invoice attached", gomock.Any(), gomock.Not(gomock.Nil()), gomock.Not(gomock.Nil()), gomock.Not(gomock.Nil())).
+					DoAndReturn(func(_ context.Context, expense *entity.Expense, _ string, _ *entity.AuditLog, checkLimit entity.SpendingLimitCheck, checkBudget entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int32, error) {
+						assert.Equal(t, int32(3), expense.Version)
+						assert.Equal(t, int64(1), expense.UserID)
+						assert.Equal(t, util.EXPENSE_CATEGORY_TRAVEL, expense.Category)
+
+						err := checkBudget([]*entity.Budget{
+							{ID: 4, Department: "sales", Category: util.EXPENSE_CATEGORY_TRAVEL, PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 5000000, Policy: util.BUDGET_POLICY_WARN, ReservedIDR: 3000000},
+						})
+						assert.NoError(t, err)
+
+						overLimit, err := checkLimit(nil)
+						assert.NoError(t, err)
+						assert.False(t, overLimit)
+
+						outbox, err := buildOutbox(123)
+						assert.NoError(t, err)
+						assert.Empty(t, outbox)
+						return 2, nil
+					}).
+					Times(1)
+			},
+			want: &model.ExpenseResponse{
+				ID:             123,
+				UserID:         1,
+				AmountIDR:      2500000,
+				Description:    "Hotel stay with invoice",
+				ReceiptURL:     "https://example.com/invoice.pdf",
+				Category:       util.EXPENSE_CATEGORY_TRAVEL,
+				Status:         util.GetExpenseStatusString(util.EXPENSE_PENDING),
+				Revision:       2,
+				Version:        4,
+				BudgetWarnings: []string{"the sales budget for travel from 2024-03-01 to 2024-03-31 would reach 5500000.00 of 5000000.00 IDR"},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failure - not the submitter",
+			expenseID: 123,
+			request: model.ResubmitExpenseRequest{
+				AmountIDR:   2500000,
+				Description: "Hotel stay",
+			},
+			userCtx: model.User{
+				ID:    5,
+				Email: "other@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_NEEDS_REVISION),
+					}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "user is not the submitter",
+		},
+		{
+			name:      "failure - expense is not awaiting revision",
+			expenseID: 123,
+			request: model.ResubmitExpenseRequest{
+				AmountIDR:   2500000,
+				Description: "Hotel stay",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_REJECTED),
+					}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "cannot resubmit expense with status rejected",
+		},
+		{
+			name:      "failure - revision exceeds a block budget",
+			expenseID: 123,
+			request: model.ResubmitExpenseRequest{
+				AmountIDR:   2500000,
+				Description: "Hotel stay",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_NEEDS_REVISION),
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ReviseExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Not(gomock.Nil()), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ *entity.Expense, _ string, _ *entity.AuditLog, _ entity.SpendingLimitCheck, checkBudget entity.BudgetCheck, _ entity.OutboxMessageBuilder) (int32, error) {
+						return 0, checkBudget([]*entity.Budget{
+							{ID: 5, Department: "sales", PeriodFrom: periodFrom, PeriodTo: periodTo, AmountIDR: 20000000, Policy: util.BUDGET_POLICY_BLOCK, ConsumedIDR: 18000000},
+						})
+					}).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "expense would exceed the budget: the sales budget for all categories from 2024-03-01 to 2024-03-31 would reach 20500000.00 of 20000000.00 IDR",
+		},
+		{
+			name:      "failure - revise expense error",
+			expenseID: 123,
+			request: model.ResubmitExpenseRequest{
+				AmountIDR:   2500000,
+				Description: "Hotel stay",
+			},
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{
+						ID:     123,
+						UserID: 1,
+						Status: int32(util.EXPENSE_NEEDS_REVISION),
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					ReviseExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int32(0), errors.New("database error")).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "failed to revise expense",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
+			tt.mock(server)
+
+			got, err := server.Service.ResubmitExpense(ctx, tt.expenseID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				if tt.errMsg != "" {
+					assert.Contains(t, err.Error(), tt.errMsg)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRevisionService_GetExpenseHistory(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name      string
+		expenseID int64
+		userCtx   model.User
+		mock      func(server *TestService)
+		want      *model.ExpenseHistoryResponse
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:      "success - submitter sees versions and feedback",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    1,
+				Email: "employee@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1, Revision: 2}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetExpenseVersions(gomock.Any(), int64(123)).
+					Return([]*entity.ExpenseVersion{
+						{ExpenseID: 123, Revision: 1, AmountIDR: 3000000, Description: "Hotel stay", CreatedAt: createdAt},
+						{ExpenseID: 123, Revision: 2, AmountIDR: 2500000, Description: "Hotel stay with invoice", Notes: "Invoice attached", CreatedAt: createdAt},
+					}, nil).
+					Times(1)
+
+				server.MockRepo.EXPECT().
+					GetApprovalsByExpenseID(gomock.Any(), int64(123)).
+					Return([]*entity.Approval{
+						{ExpenseID: 123, ApproverID: 2, Revision: 1, Status: int32(util.APPROVAL_NEEDS_CHANGES), Notes: "Please attach the invoice", CreatedAt: createdAt},
+					}, nil).
+					Times(1)
+			},
+			want: &model.ExpenseHistoryResponse{
+				ExpenseID:       123,
+				CurrentRevision: 2,
+				Versions: []model.ExpenseVersionResponse{
+					{Revision: 1, AmountIDR: 3000000, Description: "Hotel stay", CreatedAt: createdAt},
+					{Revision: 2, AmountIDR: 2500000, Description: "Hotel stay with invoice", Notes: "Invoice attached", CreatedAt: createdAt},
+				},
+				Feedback: []model.ApprovalFeedbackResponse{
+					{ApproverID: 2, Revision: 1, Status: "needs_changes", Notes: "Please attach the invoice", CreatedAt: createdAt},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "failure - employee cannot see other employee's expense",
+			expenseID: 123,
+			userCtx: model.User{
+				ID:    5,
+				Email: "other@example.com",
+				Role:  int(util.USER_ROLE_EMPLOYEE),
+			},
+			mock: func(server *TestService) {
+				server.MockRepo.EXPECT().
+					GetExpenseByID(gomock.Any(), int64(123)).
+					Return(&entity.Expense{ID: 123, UserID: 1}, nil).
+					Times(1)
+			},
+			wantErr: true,
+			errMsg:  "user is not allowed to view this expense",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServer(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", tt.userCtx.ID)
+			ctx = context.WithValue(ctx, "user_email", tt.userCtx.Email)
+			ctx = context.WithValue(ctx, "user_role", tt.userCtx.Role)
+
+			tt.mock(server)
+
+			got, err := server.Service.GetExpenseHistory(ctx, tt.expenseID)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				if tt.errMsg != "" {
+					assert.Contains(t, err.Error(), tt.errMsg)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,15 +1,69 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
 	repo "github.com/budsx/expenses-management/repository"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
 	"github.com/sirupsen/logrus"
 )
 
 type ExpensesManagementService struct {
-	repo   *repo.Repository
-	logger *logrus.Logger
+	repo          *repo.Repository
+	logger        *logrus.Logger
+	machine       *statemachine.Machine
+	payoutMode    string
+	payoutChannel string
+	payableGLCode string
+}
+
+type Option func(*ExpensesManagementService)
+
+// WithBatchPayouts leaves approved expenses to the payout batch runs instead of
+// paying them out one by one. channel is the default channel of a run.
+func WithBatchPayouts(channel string) Option {
+	return func(s *ExpensesManagementService) {
+		s.payoutMode = util.PAYOUT_MODE_BATCH
+		s.payoutChannel = channel
+	}
+}
+
+// WithPayableGLAccount sets the GL account code the accounting exports credit
+// for the amounts owed to employees.
+func WithPayableGLAccount(code string) Option {
+	return func(s *ExpensesManagementService) {
+		s.payableGLCode = code
+	}
+}
+
+func NewExpensesManagementService(repo *repo.Repository, logger *logrus.Logger, options ...Option) *ExpensesManagementService {
+	s := &ExpensesManagementService{
+		repo:          repo,
+		logger:        logger,
+		machine:       statemachine.NewExpenseMachine(),
+		payoutMode:    util.PAYOUT_MODE_IMMEDIATE,
+		payoutChannel: util.PAYOUT_CHANNEL_API,
+		payableGLCode: defaultPayableGLAccount,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
-func NewExpensesManagementService(repo *repo.Repository, logger *logrus.Logger) *ExpensesManagementService {
-	return &ExpensesManagementService{repo: repo, logger: logger}
+func (s *ExpensesManagementService) requireAdmin(ctx context.Context) error {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return fmt.Errorf("failed to get user info")
+	}
+
+	if userInfo.Role != int(util.USER_ROLE_ADMIN) {
+		s.logger.WithField("user_id", userInfo.ID).Error("user is not an admin")
+		return fmt.Errorf("user is not an admin")
+	}
+
+	return nil
 }
@@ -13,9 +13,20 @@ import (
 type TestService struct {
 	MockCtrl             *gomock.Controller
 	MockRepo             *_interface.MockExpensesRepository
-	MockRabbitMQ         *_interface.MockRabbitMQClient
+	MockBroker           *_interface.MockMessageBroker
 	MockUserRepo         *_interface.MockUserRepository
 	MockPaymentProcessor *_interface.MockPaymentProcessor
+	MockCommentRepo      *_interface.MockCommentRepository
+	MockNotificationRepo *_interface.MockNotificationRepository
+	MockOutboxRepo       *_interface.MockOutboxRepository
+	MockPaymentRepo      *_interface.MockPaymentRepository
+	MockPayoutBatchRepo  *_interface.MockPayoutBatchRepository
+	MockDestinationRepo  *_interface.MockPayoutDestinationRepository
+	MockReconcileRepo    *_interface.MockReconciliationRepository
+	MockLedgerRepo       *_interface.MockLedgerRepository
+	MockExportRepo       *_interface.MockAccountingExportRepository
+	MockBudgetRepo       *_interface.MockBudgetRepository
+	MockLimitRepo        *_interface.MockSpendingLimitRepository
 	MockLogger           *logrus.Logger
 	Service              *ExpensesManagementService
 }
@@ -23,65 +34,151 @@ type TestService struct {
 func NewTestServer(t *testing.T) *TestService {
 	ctrl := gomock.NewController(t)
 	mockRepo := _interface.NewMockExpensesRepository(ctrl)
-	mockRabbitMQ := _interface.NewMockRabbitMQClient(ctrl)
+	mockBroker := _interface.NewMockMessageBroker(ctrl)
+	mockLedgerRepo := _interface.NewMockLedgerRepository(ctrl)
+	mockBudgetRepo := _interface.NewMockBudgetRepository(ctrl)
+	mockLimitRepo := _interface.NewMockSpendingLimitRepository(ctrl)
 	mockLogger := util.NewLogger(-1)
 	service := NewExpensesManagementService(&repo.Repository{
-		ExpensesRepository: mockRepo,
-		RabbitMQClient:     mockRabbitMQ,
+		ExpensesRepository:      mockRepo,
+		LedgerRepository:        mockLedgerRepo,
+		BudgetRepository:        mockBudgetRepo,
+		SpendingLimitRepository: mockLimitRepo,
+		MessageBroker:           mockBroker,
 	}, mockLogger)
 
 	return &TestService{
-		MockCtrl:     ctrl,
-		MockRepo:     mockRepo,
-		MockRabbitMQ: mockRabbitMQ,
-		MockLogger:   mockLogger,
-		Service:      service,
+		MockCtrl:       ctrl,
+		MockRepo:       mockRepo,
+		MockBroker:     mockBroker,
+		MockLedgerRepo: mockLedgerRepo,
+		MockBudgetRepo: mockBudgetRepo,
+		MockLimitRepo:  mockLimitRepo,
+		MockLogger:     mockLogger,
+		Service:        service,
 	}
 }
 
 func NewTestServerWithUserRepo(t *testing.T) *TestService {
 	ctrl := gomock.NewController(t)
 	mockRepo := _interface.NewMockExpensesRepository(ctrl)
-	mockRabbitMQ := _interface.NewMockRabbitMQClient(ctrl)
+	mockBroker := _interface.NewMockMessageBroker(ctrl)
 	mockUserRepo := _interface.NewMockUserRepository(ctrl)
+	mockDestinationRepo := _interface.NewMockPayoutDestinationRepository(ctrl)
 	mockLogger := util.NewLogger(-1)
 	service := NewExpensesManagementService(&repo.Repository{
-		ExpensesRepository: mockRepo,
-		RabbitMQClient:     mockRabbitMQ,
-		UserRepository:     mockUserRepo,
+		ExpensesRepository:          mockRepo,
+		MessageBroker:               mockBroker,
+		UserRepository:              mockUserRepo,
+		PayoutDestinationRepository: mockDestinationRepo,
 	}, mockLogger)
 
 	return &TestService{
-		MockCtrl:     ctrl,
-		MockRepo:     mockRepo,
-		MockRabbitMQ: mockRabbitMQ,
-		MockUserRepo: mockUserRepo,
-		MockLogger:   mockLogger,
-		Service:      service,
+		MockCtrl:            ctrl,
+		MockRepo:            mockRepo,
+		MockBroker:          mockBroker,
+		MockUserRepo:        mockUserRepo,
+		MockDestinationRepo: mockDestinationRepo,
+		MockLogger:          mockLogger,
+		Service:             service,
 	}
 }
 
-func NewTestServerWithPaymentProcessor(t *testing.T) *TestService {
+func NewTestServerWithPaymentProcessor(t *testing.T, options ...Option) *TestService {
 	ctrl := gomock.NewController(t)
 	mockRepo := _interface.NewMockExpensesRepository(ctrl)
-	mockRabbitMQ := _interface.NewMockRabbitMQClient(ctrl)
+	mockBroker := _interface.NewMockMessageBroker(ctrl)
 	mockUserRepo := _interface.NewMockUserRepository(ctrl)
 	mockPaymentProcessor := _interface.NewMockPaymentProcessor(ctrl)
+	mockPaymentRepo := _interface.NewMockPaymentRepository(ctrl)
+	mockPayoutBatchRepo := _interface.NewMockPayoutBatchRepository(ctrl)
+	mockDestinationRepo := _interface.NewMockPayoutDestinationRepository(ctrl)
+	mockReconcileRepo := _interface.NewMockReconciliationRepository(ctrl)
+	mockLedgerRepo := _interface.NewMockLedgerRepository(ctrl)
+	mockExportRepo := _interface.NewMockAccountingExportRepository(ctrl)
+	mockBudgetRepo := _interface.NewMockBudgetRepository(ctrl)
+	mockLimitRepo := _interface.NewMockSpendingLimitRepository(ctrl)
 	mockLogger := util.NewLogger(-1)
 	service := NewExpensesManagementService(&repo.Repository{
-		ExpensesRepository: mockRepo,
-		RabbitMQClient:     mockRabbitMQ,
-		UserRepository:     mockUserRepo,
-		PaymentProcessor:   mockPaymentProcessor,
-	}, mockLogger)
+		ExpensesRepository:          mockRepo,
+		MessageBroker:               mockBroker,
+		UserRepository:              mockUserRepo,
+		PayoutDestinationRepository: mockDestinationRepo,
+		PaymentProcessor:            mockPaymentProcessor,
+		PaymentRepository:           mockPaymentRepo,
+		PayoutBatchRepository:       mockPayoutBatchRepo,
+		ReconciliationRepository:    mockReconcileRepo,
+		LedgerRepository:            mockLedgerRepo,
+		AccountingExportRepository:  mockExportRepo,
+		BudgetRepository:            mockBudgetRepo,
+		SpendingLimitRepository:     mockLimitRepo,
+	}, mockLogger, options...)
 
 	return &TestService{
 		MockCtrl:             ctrl,
 		MockRepo:             mockRepo,
-		MockRabbitMQ:         mockRabbitMQ,
+		MockBroker:           mockBroker,
 		MockUserRepo:         mockUserRepo,
 		MockPaymentProcessor: mockPaymentProcessor,
+		MockPaymentRepo:      mockPaymentRepo,
+		MockPayoutBatchRepo:  mockPayoutBatchRepo,
+		MockDestinationRepo:  mockDestinationRepo,
+		MockReconcileRepo:    mockReconcileRepo,
+		MockLedgerRepo:       mockLedgerRepo,
+		MockExportRepo:       mockExportRepo,
+		MockBudgetRepo:       mockBudgetRepo,
+		MockLimitRepo:        mockLimitRepo,
 		MockLogger:           mockLogger,
 		Service:              service,
 	}
 }
+
+func NewTestServerWithComments(t *testing.T) *TestService {
+	ctrl := gomock.NewController(t)
+	mockRepo := _interface.NewMockExpensesRepository(ctrl)
+	mockUserRepo := _interface.NewMockUserRepository(ctrl)
+	mockCommentRepo := _interface.NewMockCommentRepository(ctrl)
+	mockNotificationRepo := _interface.NewMockNotificationRepository(ctrl)
+	mockLogger := util.NewLogger(-1)
+	service := NewExpensesManagementService(&repo.Repository{
+		ExpensesRepository:     mockRepo,
+		UserRepository:         mockUserRepo,
+		CommentRepository:      mockCommentRepo,
+		NotificationRepository: mockNotificationRepo,
+	}, mockLogger)
+
+	return &TestService{
+		MockCtrl:             ctrl,
+		MockRepo:             mockRepo,
+		MockUserRepo:         mockUserRepo,
+		MockCommentRepo:      mockCommentRepo,
+		MockNotificationRepo: mockNotificationRepo,
+		MockLogger:           mockLogger,
+		Service:              service,
+	}
+}
+
+func NewTestServerWithOutbox(t *testing.T) *TestService {
+	ctrl := gomock.NewController(t)
+	mockRepo := _interface.NewMockExpensesRepository(ctrl)
+	mockBroker := _interface.NewMockMessageBroker(ctrl)
+	mockOutboxRepo := _interface.NewMockOutboxRepository(ctrl)
+	mockLedgerRepo := _interface.NewMockLedgerRepository(ctrl)
+	mockLogger := util.NewLogger(-1)
+	service := NewExpensesManagementService(&repo.Repository{
+		ExpensesRepository: mockRepo,
+		OutboxRepository:   mockOutboxRepo,
+		LedgerRepository:   mockLedgerRepo,
+		MessageBroker:      mockBroker,
+	}, mockLogger)
+
+	return &TestService{
+		MockCtrl:       ctrl,
+		MockRepo:       mockRepo,
+		MockBroker:     mockBroker,
+		MockOutboxRepo: mockOutboxRepo,
+		MockLedgerRepo: mockLedgerRepo,
+		MockLogger:     mockLogger,
+		Service:        service,
+	}
+}
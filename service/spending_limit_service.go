@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+// SetSpendingLimit creates or replaces the spending limit of a user or a role,
+// and returns every limit.
+func (s *ExpensesManagementService) SetSpendingLimit(ctx context.Context, req model.SpendingLimitRequest) (*model.SpendingLimitListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	s.logger.WithField("request", req).Info("SetSpendingLimit")
+
+	if (req.UserID == 0) == (req.Role == 0) {
+		return nil, fmt.Errorf("%w: set either user_id or role", util.ErrInvalidSpendingLimit)
+	}
+	if req.Role != 0 && util.GetUserRoleString(util.UserRole(req.Role)) == "Unknown" {
+		return nil, fmt.Errorf("%w: role %d is not valid", util.ErrInvalidSpendingLimit, req.Role)
+	}
+	if req.AmountIDR <= 0 {
+		return nil, fmt.Errorf("%w: amount_idr must be positive", util.ErrInvalidSpendingLimit)
+	}
+
+	period := req.Period
+	if period == "" {
+		period = util.SPENDING_LIMIT_PERIOD_MONTH
+	}
+	if !util.IsSpendingLimitPeriod(period) {
+		return nil, fmt.Errorf("%w: period must be week, month or year", util.ErrInvalidSpendingLimit)
+	}
+
+	action := req.Action
+	if action == "" {
+		action = util.SPENDING_LIMIT_REJECT
+	}
+	if !util.IsSpendingLimitAction(action) {
+		return nil, fmt.Errorf("%w: action must be reject or review", util.ErrInvalidSpendingLimit)
+	}
+
+	_, err = s.repo.SpendingLimitRepository.UpsertSpendingLimit(ctx, &entity.SpendingLimit{
+		UserID:    req.UserID,
+		Role:      int32(req.Role),
+		AmountIDR: req.AmountIDR,
+		Period:    period,
+		Action:    action,
+		UpdatedBy: userInfo.ID,
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("failed to set spending limit")
+		return nil, fmt.Errorf("failed to set spending limit")
+	}
+
+	return s.GetSpendingLimits(ctx)
+}
+
+func (s *ExpensesManagementService) GetSpendingLimits(ctx context.Context) (*model.SpendingLimitListResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.repo.SpendingLimitRepository.GetSpendingLimits(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get spending limits")
+		return nil, fmt.Errorf("failed to get spending limits")
+	}
+
+	response := &model.SpendingLimitListResponse{Limits: make([]model.SpendingLimitResponse, 0, len(limits))}
+	for _, limit := range limits {
+		item := model.SpendingLimitResponse{
+			ID:        limit.ID,
+			UserID:    limit.UserID,
+			AmountIDR: limit.AmountIDR,
+			Period:    limit.Period,
+			Action:    limit.Action,
+			UpdatedBy: limit.UpdatedBy,
+			UpdatedAt: limit.UpdatedAt,
+		}
+		if limit.Role != 0 {
+			item.Role = util.GetUserRoleString(util.UserRole(limit.Role))
+		}
+		response.Limits = append(response.Limits, item)
+	}
+
+	return response, nil
+}
+
+// GetMyAllowance returns what the user may still claim before reaching their
+// spending limit in the current period.
+func (s *ExpensesManagementService) GetMyAllowance(ctx context.Context) (*model.AllowanceResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	limit, err := s.repo.SpendingLimitRepository.GetSpendingLimit(ctx, userInfo.ID, int32(userInfo.Role), time.Now())
+	if err != nil {
+		if errors.Is(err, util.ErrSpendingLimitNotFound) {
+			return &model.AllowanceResponse{}, nil
+		}
+		s.logger.WithError(err).Error("failed to get spending limit")
+		return nil, fmt.Errorf("failed to get spending limit")
+	}
+
+	remaining := limit.AmountIDR - limit.ClaimedIDR
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &model.AllowanceResponse{
+		Limited:      true,
+		LimitIDR:     limit.AmountIDR,
+		Period:       limit.Period,
+		Action:       limit.Action,
+		PeriodFrom:   limit.WindowStart.Format(time.DateOnly),
+		PeriodTo:     limit.WindowEnd.AddDate(0, 0, -1).Format(time.DateOnly),
+		ClaimedIDR:   limit.ClaimedIDR,
+		RemainingIDR: remaining,
+	}, nil
+}
+
+// spendingLimitCheck holds amount against the spending limit of the user the
+// repository reads while the expense is written. Over a reject limit it fails
+// with ErrSpendingLimitExceeded; over a review limit it sets warning and flags
+// the expense, so the claim is left to a manager.
+func (s *ExpensesManagementService) spendingLimitCheck(userID int64, amount float64, warning *string) entity.SpendingLimitCheck {
+	return func(limit *entity.SpendingLimit) (bool, error) {
+		*warning = ""
+		if limit == nil {
+			return false, nil
+		}
+
+		claimed := limit.ClaimedIDR + amount
+		if toCents(claimed) <= toCents(limit.AmountIDR) {
+			return false, nil
+		}
+
+		message := fmt.Sprintf("claims from %s to %s would reach %.2f of the %.2f IDR limit",
+			limit.WindowStart.Format(time.DateOnly), limit.WindowEnd.AddDate(0, 0, -1).Format(time.DateOnly), claimed, limit.AmountIDR)
+		if limit.Action == util.SPENDING_LIMIT_REJECT {
+			s.logger.WithField("user_id", userID).WithField("spending_limit_id", limit.ID).Error("spending limit exceeded")
+			return false, fmt.Errorf("%w: %s", util.ErrSpendingLimitExceeded, message)
+		}
+		s.logger.WithField("user_id", userID).WithField("spending_limit_id", limit.ID).Warn("spending limit exceeded")
+		*warning = message
+		return true, nil
+	}
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpendingLimitService_SetSpendingLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     util.UserRole
+		request  model.SpendingLimitRequest
+		mock     func(server *TestService)
+		wantErr  error
+		validate func(t *testing.T, got *model.SpendingLimitListResponse)
+	}{
+		{
+			name:    "success - monthly limit of employees",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.SpendingLimitRequest{Role: int(util.USER_ROLE_EMPLOYEE), AmountIDR: 5000000},
+			mock: func(server *TestService) {
+				server.MockLimitRepo.EXPECT().
+					UpsertSpendingLimit(gomock.Any(), &entity.SpendingLimit{
+						Role:      int32(util.USER_ROLE_EMPLOYEE),
+						AmountIDR: 5000000,
+						Period:    util.SPENDING_LIMIT_PERIOD_MONTH,
+						Action:    util.SPENDING_LIMIT_REJECT,
+						UpdatedBy: 1,
+					}).
+					Return(int64(2), nil).
+					Times(1)
+
+				server.MockLimitRepo.EXPECT().
+					GetSpendingLimits(gomock.Any()).
+					Return([]*entity.SpendingLimit{
+						{ID: 2, Role: int32(util.USER_ROLE_EMPLOYEE), AmountIDR: 5000000, Period: util.SPENDING_LIMIT_PERIOD_MONTH, Action: util.SPENDING_LIMIT_REJECT, UpdatedBy: 1},
+						{ID: 3, UserID: 7, AmountIDR: 8000000, Period: util.SPENDING_LIMIT_PERIOD_MONTH, Action: util.SPENDING_LIMIT_REVIEW, UpdatedBy: 1},
+					}, nil).
+					Times(1)
+			},
+			validate: func(t *testing.T, got *model.SpendingLimitListResponse) {
+				assert.Len(t, got.Limits, 2)
+				assert.Equal(t, "employee", got.Limits[0].Role)
+				assert.Equal(t, int64(7), got.Limits[1].UserID)
+				assert.Empty(t, got.Limits[1].Role)
+			},
+		},
+		{
+			name:    "failure - both user and role",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.SpendingLimitRequest{UserID: 7, Role: int(util.USER_ROLE_EMPLOYEE), AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("spending limit is not valid: set either user_id or role"),
+		},
+		{
+			name:    "failure - unknown role",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.SpendingLimitRequest{Role: 9, AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("spending limit is not valid: role 9 is not valid"),
+		},
+		{
+			name:    "failure - unknown period",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.SpendingLimitRequest{UserID: 7, AmountIDR: 5000000, Period: "quarter"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("spending limit is not valid: period must be week, month or year"),
+		},
+		{
+			name:    "failure - unknown action",
+			role:    util.USER_ROLE_ADMIN,
+			request: model.SpendingLimitRequest{UserID: 7, AmountIDR: 5000000, Action: "notify"},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("spending limit is not valid: action must be reject or review"),
+		},
+		{
+			name:    "failure - not an admin",
+			role:    util.USER_ROLE_MANAGER,
+			request: model.SpendingLimitRequest{Role: int(util.USER_ROLE_EMPLOYEE), AmountIDR: 5000000},
+			mock:    func(server *TestService) {},
+			wantErr: errors.New("user is not an admin"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			tt.mock(server)
+
+			got, err := server.Service.SetSpendingLimit(roleContext(tt.role), tt.request)
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validate(t, got)
+		})
+	}
+}
+
+func TestSpendingLimitService_GetMyAllowance(t *testing.T) {
+	windowStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		limit   *entity.SpendingLimit
+		err     error
+		want    *model.AllowanceResponse
+		wantErr error
+	}{
+		{
+			name: "within the limit",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REJECT,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  1500000,
+			},
+			want: &model.AllowanceResponse{
+				Limited:      true,
+				LimitIDR:     5000000,
+				Period:       util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:       util.SPENDING_LIMIT_REJECT,
+				PeriodFrom:   "2024-03-01",
+				PeriodTo:     "2024-03-31",
+				ClaimedIDR:   1500000,
+				RemainingIDR: 3500000,
+			},
+		},
+		{
+			name: "over a review limit",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REVIEW,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  6000000,
+			},
+			want: &model.AllowanceResponse{
+				Limited:    true,
+				LimitIDR:   5000000,
+				Period:     util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:     util.SPENDING_LIMIT_REVIEW,
+				PeriodFrom: "2024-03-01",
+				PeriodTo:   "2024-03-31",
+				ClaimedIDR: 6000000,
+			},
+		},
+		{
+			name: "no limit",
+			err:  util.ErrSpendingLimitNotFound,
+			want: &model.AllowanceResponse{},
+		},
+		{
+			name:    "repository error",
+			err:     errors.New("database error"),
+			wantErr: errors.New("failed to get spending limit"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			server.MockLimitRepo.EXPECT().
+				GetSpendingLimit(gomock.Any(), int64(1), int32(util.USER_ROLE_EMPLOYEE), gomock.Any()).
+				Return(tt.limit, tt.err).
+				Times(1)
+
+			got, err := server.Service.GetMyAllowance(roleContext(util.USER_ROLE_EMPLOYEE))
+			if tt.wantErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSpendingLimitService_CreateExpenseOverLimit(t *testing.T) {
+	windowStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		limit            *entity.SpendingLimit
+		wantAutoApproved bool
+		wantWarning      string
+		wantErr          error
+	}{
+		{
+			name:             "no limit",
+			wantAutoApproved: true,
+		},
+		{
+			name: "within the limit",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REJECT,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4800000,
+			},
+			wantAutoApproved: true,
+		},
+		{
+			name: "review limit exceeded",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REVIEW,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4900000,
+			},
+			wantWarning: "claims from 2024-03-01 to 2024-03-31 would reach 5100000.00 of the 5000000.00 IDR limit",
+		},
+		{
+			name: "reject limit exceeded",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REJECT,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4900000,
+			},
+			wantErr: errors.New("expense would exceed the spending limit: claims from 2024-03-01 to 2024-03-31 would reach 5100000.00 of the 5000000.00 IDR limit"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			server.MockRepo.EXPECT().
+				WriteExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ *entity.Expense, _ *entity.AuditLog, checkLimit entity.SpendingLimitCheck, _ entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int64, error) {
+					overLimit, err := checkLimit(tt.limit)
+					if err != nil {
+						return 0, err
+					}
+					assert.Equal(t, tt.wantWarning != "", overLimit)
+
+					outbox, err := buildOutbox(42)
+					assert.NoError(t, err)
+					if tt.wantAutoApproved {
+						assert.Len(t, outbox, 2)
+					} else {
+						assert.Len(t, outbox, 1)
+					}
+					return 42, nil
+				}).
+				Times(1)
+
+			got, err := server.Service.CreateExpense(roleContext(util.USER_ROLE_EMPLOYEE), model.CreateExpenseRequest{
+				AmountIDR:   200000,
+				Description: "Taxi to the client",
+				Category:    util.EXPENSE_CATEGORY_TRANSPORT,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, util.ErrSpendingLimitExceeded)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAutoApproved, got.AutoApproved)
+			assert.Equal(t, tt.wantWarning != "", got.OverLimit)
+			assert.Equal(t, tt.wantWarning, got.LimitWarning)
+		})
+	}
+}
+
+func TestSpendingLimitService_ResubmitExpenseOverLimit(t *testing.T) {
+	windowStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		limit            *entity.SpendingLimit
+		wantAutoApproved bool
+		wantWarning      string
+		wantErr          error
+	}{
+		{
+			name: "within the limit",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REVIEW,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4800000,
+			},
+			wantAutoApproved: true,
+		},
+		{
+			name: "review limit exceeded",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REVIEW,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4900000,
+			},
+			wantWarning: "claims from 2024-03-01 to 2024-03-31 would reach 5100000.00 of the 5000000.00 IDR limit",
+		},
+		{
+			name: "reject limit exceeded",
+			limit: &entity.SpendingLimit{
+				ID:          2,
+				Role:        int32(util.USER_ROLE_EMPLOYEE),
+				AmountIDR:   5000000,
+				Period:      util.SPENDING_LIMIT_PERIOD_MONTH,
+				Action:      util.SPENDING_LIMIT_REJECT,
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+				ClaimedIDR:  4900000,
+			},
+			wantErr: errors.New("expense would exceed the spending limit: claims from 2024-03-01 to 2024-03-31 would reach 5100000.00 of the 5000000.00 IDR limit"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithPaymentProcessor(t)
+			defer server.MockCtrl.Finish()
+
+			server.MockRepo.EXPECT().
+				GetExpenseByID(gomock.Any(), int64(42)).
+				Return(&entity.Expense{
+					ID:        42,
+					UserID:    1,
+					AmountIDR: 900000,
+					Category:  util.EXPENSE_CATEGORY_TRANSPORT,
+					Status:    int32(util.EXPENSE_NEEDS_REVISION),
+					Revision:  1,
+					Version:   2,
+				}, nil).
+				Times(1)
+
+			server.MockRepo.EXPECT().
+				ReviseExpense(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, expense *entity.Expense, _ string, _ *entity.AuditLog, checkLimit entity.SpendingLimitCheck, _ entity.BudgetCheck, buildOutbox entity.OutboxMessageBuilder) (int32, error) {
+					assert.Equal(t, int64(1), expense.UserID)
+					overLimit, err := checkLimit(tt.limit)
+					if err != nil {
+						return 0, err
+					}
+					assert.Equal(t, tt.wantWarning != "", overLimit)
+
+					outbox, err := buildOutbox(42)
+					assert.NoError(t, err)
+					if tt.wantAutoApproved {
+						assert.Len(t, outbox, 1)
+						assert.Equal(t, util.OUTBOX_EVENT_PAYMENT_REQUESTED, outbox[0].EventType)
+					} else {
+						assert.Empty(t, outbox)
+					}
+					return 2, nil
+				}).
+				Times(1)
+
+			got, err := server.Service.ResubmitExpense(roleContext(util.USER_ROLE_EMPLOYEE), 42, model.ResubmitExpenseRequest{
+				AmountIDR:   200000,
+				Description: "Taxi to the client, receipt attached",
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, util.ErrSpendingLimitExceeded)
+				assert.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAutoApproved, got.AutoApproved)
+			assert.Equal(t, tt.wantWarning != "", got.OverLimit)
+			assert.Equal(t, tt.wantWarning, got.LimitWarning)
+		})
+	}
+}
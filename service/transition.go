@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/util"
+	"github.com/budsx/expenses-management/util/statemachine"
+)
+
+// transitionExpense fires the transition and hands persist the new status with
+// the audit log to write in the same transaction.
+func (s *ExpensesManagementService) transitionExpense(ctx context.Context, req statemachine.Request, persist func(context.Context, util.ExpenseStatus, *entity.AuditLog) error) (*statemachine.Result, error) {
+	from := util.ExpenseStatus(req.Expense.Status)
+	result, err := s.machine.Fire(ctx, req, func(ctx context.Context, to util.ExpenseStatus) error {
+		return persist(ctx, to, transitionAuditLog(req, from, to))
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("expense_id", req.Expense.ID).WithField("event", req.Event).Error("failed to transition expense")
+		return nil, err
+	}
+	return result, nil
+}
+
+func transitionAuditLog(req statemachine.Request, from, to util.ExpenseStatus) *entity.AuditLog {
+	return &entity.AuditLog{
+		ExpenseID:    req.Expense.ID,
+		NewStatus:    int32(to),
+		StatusBefore: int32(from),
+		AmountBefore: req.AmountBefore,
+		AmountAfter:  req.AmountAfter,
+		Notes:        req.Notes,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// checkExpenseVersion rejects a change based on a stale copy of the expense.
+// A zero version means the client did not send one.
+func (s *ExpensesManagementService) checkExpenseVersion(expense *entity.Expense, version int32) error {
+	if version != 0 && version != expense.Version {
+		s.logger.WithField("expense_id", expense.ID).WithField("version", version).Error("expense version is stale")
+		return util.ErrVersionConflict
+	}
+	return nil
+}
+
+// persistError hides repository errors behind message but keeps version
+// conflicts so the handler can report them.
+func persistError(err error, message string) error {
+	if errors.Is(err, util.ErrVersionConflict) {
+		return err
+	}
+	return errors.New(message)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+// UpdatePayoutMethod sets how the caller's expenses are paid out. It is used
+// by the payment router to pick a provider.
+func (s *ExpensesManagementService) UpdatePayoutMethod(ctx context.Context, req model.UpdatePayoutMethodRequest) (*model.PayoutMethodResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	if !util.IsPayoutMethod(req.PayoutMethod) {
+		s.logger.WithField("payout_method", req.PayoutMethod).Error("payout method is not valid")
+		return nil, fmt.Errorf("payout method is not valid")
+	}
+
+	err = s.repo.UserRepository.UpdatePayoutMethod(ctx, userInfo.ID, req.PayoutMethod)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to update payout method")
+		return nil, fmt.Errorf("failed to update payout method")
+	}
+
+	return &model.PayoutMethodResponse{
+		UserID:       userInfo.ID,
+		PayoutMethod: req.PayoutMethod,
+	}, nil
+}
+
+// UpdateUserDepartment moves an employee to the department whose budgets
+// their expenses count against. An empty department leaves them out of every
+// budget.
+func (s *ExpensesManagementService) UpdateUserDepartment(ctx context.Context, userID int64, req model.UpdateDepartmentRequest) (*model.DepartmentResponse, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	department := strings.TrimSpace(req.Department)
+	err := s.repo.UserRepository.UpdateDepartment(ctx, userID, department)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to update department")
+		return nil, fmt.Errorf("failed to update department")
+	}
+
+	return &model.DepartmentResponse{
+		UserID:     userID,
+		Department: department,
+	}, nil
+}
+
+// GetPayoutDestinations lists the caller's destinations with account numbers
+// and e-wallet IDs masked.
+func (s *ExpensesManagementService) GetPayoutDestinations(ctx context.Context) (*model.PayoutDestinationListResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	user, err := s.repo.UserRepository.GetUserByID(ctx, userInfo.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user")
+		return nil, fmt.Errorf("failed to get user")
+	}
+
+	destinations, err := s.repo.PayoutDestinationRepository.GetPayoutDestinations(ctx, userInfo.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get payout destinations")
+		return nil, fmt.Errorf("failed to get payout destinations")
+	}
+
+	response := &model.PayoutDestinationListResponse{
+		PayoutMethod: user.PayoutMethod,
+		Destinations: make([]model.PayoutDestinationResponse, 0, len(destinations)),
+	}
+	for _, destination := range destinations {
+		response.Destinations = append(response.Destinations, toPayoutDestinationResponse(destination))
+	}
+	return response, nil
+}
+
+// UpdatePayoutDestination replaces the caller's destination for one payout
+// method. The caller's password is checked first so a stolen token alone
+// cannot redirect payouts.
+func (s *ExpensesManagementService) UpdatePayoutDestination(ctx context.Context, req model.UpdatePayoutDestinationRequest) (*model.PayoutDestinationResponse, error) {
+	userInfo, err := util.GetUserInfoFromContext(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user info")
+		return nil, fmt.Errorf("failed to get user info")
+	}
+
+	err = validatePayoutDestination(req)
+	if err != nil {
+		s.logger.WithField("method", req.Method).WithError(err).Error("payout destination is not valid")
+		return nil, err
+	}
+
+	user, err := s.repo.UserRepository.GetUserWithPassword(ctx, userInfo.Email)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to get user")
+		return nil, fmt.Errorf("failed to get user")
+	}
+	if !util.CheckPasswordHash(req.Password, user.PasswordHash) {
+		s.logger.WithField("user_id", userInfo.ID).Warn("payout destination change with wrong password")
+		return nil, util.ErrInvalidPassword
+	}
+
+	destination := &entity.PayoutDestination{
+		UserID: userInfo.ID,
+		Method: req.Method,
+	}
+	switch req.Method {
+	case util.PAYOUT_METHOD_BANK_TRANSFER:
+		destination.BankCode = strings.TrimSpace(req.BankCode)
+		destination.AccountNumber = strings.TrimSpace(req.AccountNumber)
+		destination.AccountHolderName = strings.TrimSpace(req.AccountHolderName)
+	case util.PAYOUT_METHOD_EWALLET:
+		destination.EWalletID = strings.TrimSpace(req.EWalletID)
+	}
+
+	err = s.repo.PayoutDestinationRepository.UpsertPayoutDestination(ctx, destination)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to update payout destination")
+		return nil, fmt.Errorf("failed to update payout destination")
+	}
+
+	destination.UpdatedAt = time.Now()
+	response := toPayoutDestinationResponse(destination)
+	return &response, nil
+}
+
+func validatePayoutDestination(req model.UpdatePayoutDestinationRequest) error {
+	switch req.Method {
+	case util.PAYOUT_METHOD_BANK_TRANSFER:
+		if strings.TrimSpace(req.BankCode) == "" || strings.TrimSpace(req.AccountNumber) == "" || strings.TrimSpace(req.AccountHolderName) == "" {
+			return fmt.Errorf("%w: bank_code, account_number and account_holder_name are required for bank_transfer", util.ErrInvalidDestination)
+		}
+	case util.PAYOUT_METHOD_EWALLET:
+		if strings.TrimSpace(req.EWalletID) == "" {
+			return fmt.Errorf("%w: ewallet_id is required for ewallet", util.ErrInvalidDestination)
+		}
+	default:
+		return fmt.Errorf("%w: payout method is not valid", util.ErrInvalidDestination)
+	}
+	return nil
+}
+
+func toPayoutDestinationResponse(destination *entity.PayoutDestination) model.PayoutDestinationResponse {
+	return model.PayoutDestinationResponse{
+		Method:            destination.Method,
+		BankCode:          destination.BankCode,
+		AccountNumber:     util.MaskTail(destination.AccountNumber),
+		AccountHolderName: destination.AccountHolderName,
+		EWalletID:         util.MaskTail(destination.EWalletID),
+		UpdatedAt:         destination.UpdatedAt,
+	}
+}
+
+// getPayoutDestinations returns the user's destinations keyed by payout
+// method, ready for PaymentProcessorRequest.Destinations.
+func (s *ExpensesManagementService) getPayoutDestinations(ctx context.Context, userID int64) (map[string]*entity.PayoutDestination, error) {
+	destinations, err := s.repo.PayoutDestinationRepository.GetPayoutDestinations(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).WithField("user_id", userID).Error("failed to get payout destinations")
+		return nil, fmt.Errorf("failed to get payout destinations")
+	}
+
+	byMethod := make(map[string]*entity.PayoutDestination, len(destinations))
+	for _, destination := range destinations {
+		byMethod[destination.Method] = destination
+	}
+	return byMethod, nil
+}
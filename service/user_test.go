@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_UpdatePayoutMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		request model.UpdatePayoutMethodRequest
+		mock    func(server *TestService)
+		want    *model.PayoutMethodResponse
+		wantErr bool
+	}{
+		{
+			name:    "success - switch to e-wallet",
+			request: model.UpdatePayoutMethodRequest{PayoutMethod: util.PAYOUT_METHOD_EWALLET},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().
+					UpdatePayoutMethod(gomock.Any(), int64(1), util.PAYOUT_METHOD_EWALLET).
+					Return(nil).
+					Times(1)
+			},
+			want:    &model.PayoutMethodResponse{UserID: 1, PayoutMethod: util.PAYOUT_METHOD_EWALLET},
+			wantErr: false,
+		},
+		{
+			name:    "failure - unknown payout method",
+			request: model.UpdatePayoutMethodRequest{PayoutMethod: "cash"},
+			mock:    func(server *TestService) {},
+			wantErr: true,
+		},
+		{
+			name:    "failure - database error",
+			request: model.UpdatePayoutMethodRequest{PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().
+					UpdatePayoutMethod(gomock.Any(), int64(1), util.PAYOUT_METHOD_BANK_TRANSFER).
+					Return(errors.New("database error")).
+					Times(1)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithUserRepo(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", int64(1))
+			ctx = context.WithValue(ctx, "user_email", "john.doe@company.com")
+			ctx = context.WithValue(ctx, "user_role", int(util.USER_ROLE_EMPLOYEE))
+
+			tt.mock(server)
+
+			got, err := server.Service.UpdatePayoutMethod(ctx, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserService_GetPayoutDestinations(t *testing.T) {
+	server := NewTestServerWithUserRepo(t)
+	defer server.MockCtrl.Finish()
+
+	server.MockUserRepo.EXPECT().
+		GetUserByID(gomock.Any(), int64(1)).
+		Return(&entity.User{ID: 1, PayoutMethod: util.PAYOUT_METHOD_BANK_TRANSFER}, nil).
+		Times(1)
+	server.MockDestinationRepo.EXPECT().
+		GetPayoutDestinations(gomock.Any(), int64(1)).
+		Return([]*entity.PayoutDestination{
+			{
+				UserID:            1,
+				Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+				BankCode:          "BCA",
+				AccountNumber:     "1234567890",
+				AccountHolderName: "John Doe",
+			},
+			{
+				UserID:    1,
+				Method:    util.PAYOUT_METHOD_EWALLET,
+				EWalletID: "081234567890",
+			},
+		}, nil).
+		Times(1)
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, "user_id", int64(1))
+	ctx = context.WithValue(ctx, "user_email", "john.doe@company.com")
+	ctx = context.WithValue(ctx, "user_role", int(util.USER_ROLE_EMPLOYEE))
+
+	got, err := server.Service.GetPayoutDestinations(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, util.PAYOUT_METHOD_BANK_TRANSFER, got.PayoutMethod)
+	assert.Len(t, got.Destinations, 2)
+	assert.Equal(t, "******7890", got.Destinations[0].AccountNumber)
+	assert.Equal(t, "********7890", got.Destinations[1].EWalletID)
+}
+
+func TestUserService_UpdatePayoutDestination(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &entity.User{ID: 1, Email: "john.doe@company.com", PasswordHash: string(hashedPassword)}
+
+	tests := []struct {
+		name    string
+		request model.UpdatePayoutDestinationRequest
+		mock    func(server *TestService)
+		want    *model.PayoutDestinationResponse
+		wantErr error
+	}{
+		{
+			name: "success - set bank account",
+			request: model.UpdatePayoutDestinationRequest{
+				Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+				BankCode:          "BCA",
+				AccountNumber:     " 1234567890 ",
+				AccountHolderName: "John Doe",
+				Password:          "password123",
+			},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().
+					GetUserWithPassword(gomock.Any(), "john.doe@company.com").
+					Return(user, nil).
+					Times(1)
+				server.MockDestinationRepo.EXPECT().
+					UpsertPayoutDestination(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, destination *entity.PayoutDestination) error {
+						assert.Equal(t, int64(1), destination.UserID)
+						assert.Equal(t, "1234567890", destination.AccountNumber)
+						assert.Empty(t, destination.EWalletID)
+						return nil
+					}).
+					Times(1)
+			},
+			want: &model.PayoutDestinationResponse{
+				Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+				BankCode:          "BCA",
+				AccountNumber:     "******7890",
+				AccountHolderName: "John Doe",
+			},
+		},
+		{
+			name: "failure - wrong password",
+			request: model.UpdatePayoutDestinationRequest{
+				Method:    util.PAYOUT_METHOD_EWALLET,
+				EWalletID: "081234567890",
+				Password:  "wrong-password",
+			},
+			mock: func(server *TestService) {
+				server.MockUserRepo.EXPECT().
+					GetUserWithPassword(gomock.Any(), "john.doe@company.com").
+					Return(user, nil).
+					Times(1)
+			},
+			wantErr: util.ErrInvalidPassword,
+		},
+		{
+			name: "failure - bank account without account number",
+			request: model.UpdatePayoutDestinationRequest{
+				Method:            util.PAYOUT_METHOD_BANK_TRANSFER,
+				BankCode:          "BCA",
+				AccountHolderName: "John Doe",
+				Password:          "password123",
+			},
+			mock:    func(server *TestService) {},
+			wantErr: util.ErrInvalidDestination,
+		},
+		{
+			name: "failure - unknown method",
+			request: model.UpdatePayoutDestinationRequest{
+				Method:   "cash",
+				Password: "password123",
+			},
+			mock:    func(server *TestService) {},
+			wantErr: util.ErrInvalidDestination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTestServerWithUserRepo(t)
+			defer server.MockCtrl.Finish()
+
+			ctx := context.Background()
+			ctx = context.WithValue(ctx, "user_id", int64(1))
+			ctx = context.WithValue(ctx, "user_email", "john.doe@company.com")
+			ctx = context.WithValue(ctx, "user_role", int(util.USER_ROLE_EMPLOYEE))
+
+			tt.mock(server)
+
+			got, err := server.Service.UpdatePayoutDestination(ctx, tt.request)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.False(t, got.UpdatedAt.IsZero())
+			got.UpdatedAt = tt.want.UpdatedAt
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
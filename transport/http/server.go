@@ -34,17 +34,74 @@ func NewExpensesManagementServer(service *service.ExpensesManagementService, exp
 		return c.Next()
 	})
 
+	app.Get("/metrics", expensesHandler.Metrics)
+
 	api := app.Group("/api")
 	api.Get("/health", expensesHandler.HealthCheck)
 	api.Post("/auth/login", authHandler.Login)
+	api.Post("/webhooks/payments", expensesHandler.PaymentWebhook)
 
 	expenses := api.Group("/expenses")
 	expenses.Use(handler.AuthMiddleware())
 	expenses.Post("/", expensesHandler.CreateExpense)
 	expenses.Get("/", expensesHandler.GetExpenses)
 	expenses.Get("/:id", expensesHandler.GetExpenseByID)
+	expenses.Put("/:id", expensesHandler.ResubmitExpense)
+	expenses.Get("/:id/history", expensesHandler.GetExpenseHistory)
 	expenses.Put("/:id/approve", expensesHandler.ApproveExpense)
 	expenses.Put("/:id/reject", expensesHandler.RejectExpense)
+	expenses.Put("/:id/request-changes", expensesHandler.RequestExpenseChanges)
+	expenses.Get("/:id/comments", expensesHandler.GetComments)
+	expenses.Post("/:id/comments", expensesHandler.CreateComment)
+	expenses.Get("/:id/payments", expensesHandler.GetExpensePayments)
+	expenses.Post("/:id/reverse", expensesHandler.ReverseExpense)
+
+	users := api.Group("/users")
+	users.Use(handler.AuthMiddleware())
+	users.Put("/me/payout-method", expensesHandler.UpdatePayoutMethod)
+	users.Get("/me/payout-destinations", expensesHandler.GetPayoutDestinations)
+	users.Put("/me/payout-destinations", expensesHandler.UpdatePayoutDestination)
+	users.Get("/me/ledger-balance", expensesHandler.GetMyLedgerBalance)
+	users.Get("/me/allowance", expensesHandler.GetMyAllowance)
+
+	notifications := api.Group("/notifications")
+	notifications.Use(handler.AuthMiddleware())
+	notifications.Get("/", expensesHandler.GetNotifications)
+	notifications.Put("/:id/read", expensesHandler.MarkNotificationRead)
+
+	admin := api.Group("/admin")
+	admin.Use(handler.AuthMiddleware())
+	admin.Get("/outbox", expensesHandler.GetOutboxStats)
+	admin.Get("/dead-letters", expensesHandler.GetDeadLetters)
+	admin.Post("/dead-letters/:id/replay", expensesHandler.ReplayDeadLetter)
+	admin.Delete("/dead-letters/:id", expensesHandler.DiscardDeadLetter)
+	admin.Post("/payout-batches", expensesHandler.RunPayoutBatch)
+	admin.Get("/payout-batches", expensesHandler.GetPayoutBatches)
+	admin.Get("/payout-batches/:id", expensesHandler.GetPayoutBatch)
+	admin.Get("/payout-batches/:id/file", expensesHandler.GetPayoutBatchFile)
+	admin.Put("/payout-batches/:id/items/:item_id", expensesHandler.RecordPayoutBatchItemResult)
+	admin.Post("/reconciliations", expensesHandler.ReconcileProvider)
+	admin.Post("/reconciliations/import", expensesHandler.ImportSettlement)
+	admin.Get("/reconciliations", expensesHandler.GetReconciliations)
+	admin.Get("/reconciliations/:id", expensesHandler.GetReconciliation)
+	admin.Get("/ledger/entries", expensesHandler.GetJournalEntries)
+	admin.Get("/ledger/accounts", expensesHandler.GetAccountBalances)
+	admin.Get("/ledger/employees", expensesHandler.GetEmployeeBalances)
+	admin.Post("/ledger/advances", expensesHandler.RecordAdvance)
+	admin.Post("/accounting-exports", expensesHandler.CreateAccountingExport)
+	admin.Get("/accounting-exports", expensesHandler.GetAccountingExports)
+	admin.Get("/accounting-exports/:id", expensesHandler.GetAccountingExport)
+	admin.Get("/accounting-exports/:id/file", expensesHandler.GetAccountingExportFile)
+	admin.Get("/gl-mappings", expensesHandler.GetGLAccountMappings)
+	admin.Put("/gl-mappings", expensesHandler.UpdateGLAccountMapping)
+	admin.Put("/users/:id/department", expensesHandler.UpdateUserDepartment)
+	admin.Put("/budgets", expensesHandler.SetBudget)
+	admin.Get("/spending-limits", expensesHandler.GetSpendingLimits)
+	admin.Put("/spending-limits", expensesHandler.SetSpendingLimit)
+
+	budgets := api.Group("/budgets")
+	budgets.Use(handler.AuthMiddleware())
+	budgets.Get("/", expensesHandler.GetBudgets)
 
 	return &ExpensesManagementServer{
 		app:             app,
@@ -3,14 +3,12 @@ package messaging
 import (
 	"log"
 
-	_interface "github.com/budsx/expenses-management/repository/interface"
 	"github.com/budsx/expenses-management/service"
+	"github.com/budsx/expenses-management/util/broker"
 )
 
-func NewTransportListener(service *service.ExpensesManagementService, rabbitmqClient _interface.RabbitMQClient, exchangeName string, queueName string) {
-	client := rabbitmqClient.GetClient()
-
-	if err := client.Subscribe(exchangeName, queueName, ProcessPaymentListener(service)); err != nil {
+func NewTransportListener(service *service.ExpensesManagementService, subscriber broker.Subscriber, exchangeName string, queueName string) {
+	if err := subscriber.Subscribe(exchangeName, queueName, ProcessPaymentListener(service), PaymentOrderingKey); err != nil {
 		log.Printf("Failed to subscribe to %s: %v", queueName, err)
 		return
 	}
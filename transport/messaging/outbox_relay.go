@@ -0,0 +1,67 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/budsx/expenses-management/service"
+)
+
+type OutboxRelay struct {
+	service   *service.ExpensesManagementService
+	interval  time.Duration
+	batchSize int
+	stop      chan struct{}
+	done      sync.WaitGroup
+}
+
+func NewOutboxRelay(service *service.ExpensesManagementService, interval time.Duration, batchSize int) *OutboxRelay {
+	return &OutboxRelay{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (r *OutboxRelay) Start() {
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.relay()
+			}
+		}
+	}()
+}
+
+// relay drains the outbox in batches until nothing due is left.
+func (r *OutboxRelay) relay() {
+	for {
+		published, err := r.service.RelayOutboxMessages(context.Background(), r.batchSize)
+		if err != nil || published < r.batchSize {
+			return
+		}
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+	}
+}
+
+// Stop waits for the batch in progress to finish.
+func (r *OutboxRelay) Stop() {
+	close(r.stop)
+	r.done.Wait()
+}
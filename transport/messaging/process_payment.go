@@ -3,10 +3,12 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 
 	"github.com/budsx/expenses-management/entity"
 	"github.com/budsx/expenses-management/model"
 	"github.com/budsx/expenses-management/service"
+	"github.com/budsx/expenses-management/util/broker"
 )
 
 func ProcessPaymentListener(service *service.ExpensesManagementService) func([]byte) error {
@@ -14,7 +16,7 @@ func ProcessPaymentListener(service *service.ExpensesManagementService) func([]b
 		var payment *entity.PublishPaymentRequest
 		err := json.Unmarshal(paymentResponse, &payment)
 		if err != nil {
-			return err
+			return broker.Permanent(err)
 		}
 
 		err = service.ProcessPayment(context.Background(), model.ApprovalRequest{
@@ -30,3 +32,12 @@ func ProcessPaymentListener(service *service.ExpensesManagementService) func([]b
 		return nil
 	}
 }
+
+// PaymentOrderingKey keeps the payment messages of one expense in order.
+func PaymentOrderingKey(body []byte) string {
+	var payment entity.PublishPaymentRequest
+	if err := json.Unmarshal(body, &payment); err != nil || payment.ExpenseID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(payment.ExpenseID, 10)
+}
@@ -0,0 +1,97 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/budsx/expenses-management/service"
+	"github.com/budsx/expenses-management/util"
+)
+
+// DailyScheduler runs a job once a day at a fixed local time.
+type DailyScheduler struct {
+	job    func(context.Context)
+	hour   int
+	minute int
+	stop   chan struct{}
+	done   sync.WaitGroup
+}
+
+// NewDailyScheduler parses runAt as HH:MM.
+func NewDailyScheduler(runAt string, job func(context.Context)) (*DailyScheduler, error) {
+	at, err := time.Parse("15:04", runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DailyScheduler{
+		job:    job,
+		hour:   at.Hour(),
+		minute: at.Minute(),
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// NewPayoutScheduler runs the payout batch every day at runAt.
+func NewPayoutScheduler(service *service.ExpensesManagementService, runAt string) (*DailyScheduler, error) {
+	return NewDailyScheduler(runAt, func(ctx context.Context) {
+		batch, err := service.RunScheduledPayoutBatch(ctx)
+		if err != nil {
+			if !errors.Is(err, util.ErrNoPayableExpenses) {
+				log.Printf("Scheduled payout batch failed: %v", err)
+			}
+			return
+		}
+		log.Printf("Scheduled payout batch %d created with %d transfers", batch.ID, len(batch.Items))
+	})
+}
+
+// NewReconciliationScheduler reconciles the previous day's provider statements
+// every day at runAt.
+func NewReconciliationScheduler(service *service.ExpensesManagementService, runAt string) (*DailyScheduler, error) {
+	return NewDailyScheduler(runAt, func(ctx context.Context) {
+		reconciliations, err := service.RunScheduledReconciliations(ctx)
+		if err != nil {
+			log.Printf("Scheduled reconciliation failed: %v", err)
+		}
+		for _, reconciliation := range reconciliations {
+			log.Printf("Reconciliation %d of %s: %d missing, %d extra, %d amount mismatches",
+				reconciliation.ID, reconciliation.Provider, reconciliation.MissingCount, reconciliation.ExtraCount, reconciliation.AmountMismatchCount)
+		}
+	})
+}
+
+func (s *DailyScheduler) Start() {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+
+		for {
+			timer := time.NewTimer(time.Until(s.next(time.Now())))
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.job(context.Background())
+			}
+		}
+	}()
+}
+
+func (s *DailyScheduler) next(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Stop waits for the run in progress to finish.
+func (s *DailyScheduler) Stop() {
+	close(s.stop)
+	s.done.Wait()
+}
@@ -0,0 +1,116 @@
+// Package broker defines the messaging abstraction used by the service. The
+// rabbitmq package provides the AMQP implementation and Memory an in-process
+// one for single-node deployments and tests.
+package broker
+
+import (
+	"errors"
+	"time"
+)
+
+type State string
+
+const (
+	StateConnected    State = "connected"
+	StateReconnecting State = "reconnecting"
+	StateClosed       State = "closed"
+)
+
+var (
+	ErrNotConnected = errors.New("broker is not connected")
+	ErrUnroutable   = errors.New("message could not be routed to any queue")
+)
+
+type Handler func([]byte) error
+
+// KeyFunc returns the ordering key of a message. Messages with the same key
+// are handled one at a time in delivery order.
+type KeyFunc func([]byte) string
+
+type Publisher interface {
+	// Publish returns once the broker has accepted the message for every queue
+	// subscribed to topic.
+	Publish(topic string, body []byte) error
+	// PublishEvent publishes body to the events exchange with routingKey.
+	// Unlike Publish it does not fail when no queue is bound to routingKey.
+	PublishEvent(exchange, routingKey string, body []byte) error
+}
+
+type Subscriber interface {
+	// Subscribe consumes queue, which receives every message published to
+	// topic. A failed message is retried with backoff and then dead-lettered.
+	Subscribe(topic, queue string, handler Handler, key KeyFunc) error
+}
+
+type DeadLetterStore interface {
+	DeadLetters(queue string, limit int) ([]DeadLetter, error)
+	ReplayDeadLetter(queue, id string) (bool, error)
+	DiscardDeadLetter(queue, id string) (bool, error)
+}
+
+type Broker interface {
+	Publisher
+	Subscriber
+	DeadLetterStore
+	State() State
+	// Drain stops consuming and waits up to timeout for the messages being
+	// handled to finish.
+	Drain(timeout time.Duration) error
+	Close()
+}
+
+type DeadLetter struct {
+	ID             string
+	Queue          string
+	Body           []byte
+	RetryCount     int
+	LastError      string
+	DeadLetteredAt time.Time
+}
+
+// RetryPolicy controls how often a failed message is redelivered before it is
+// dead-lettered.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// Delay returns the backoff before the given retry, doubling from BaseDelay up
+// to MaxDelay.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not retriable, e.g. a message that cannot be decoded.
+// The message is dead-lettered right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{retry: 1, want: time.Second},
+		{retry: 2, want: 2 * time.Second},
+		{retry: 3, want: 4 * time.Second},
+		{retry: 4, want: 5 * time.Second},
+		{retry: 5, want: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("retry %d", tt.retry), func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Delay(tt.retry))
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("invalid payload")
+
+	assert.Nil(t, Permanent(nil))
+	assert.True(t, IsPermanent(Permanent(err)))
+	assert.True(t, IsPermanent(fmt.Errorf("decode: %w", Permanent(err))))
+	assert.False(t, IsPermanent(err))
+	assert.ErrorIs(t, Permanent(err), err)
+}
@@ -0,0 +1,285 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const memoryQueueSize = 1024
+
+// Memory is an in-process Broker. Messages only live in memory, so it suits
+// single-node deployments and tests: messages that were not handled yet are
+// lost when the process exits.
+type Memory struct {
+	retry   RetryPolicy
+	workers int
+
+	mu          sync.RWMutex
+	bindings    map[string][]*memoryQueue
+	queues      map[string]*memoryQueue
+	deadLetters map[string][]DeadLetter
+	state       State
+
+	inflight  sync.WaitGroup
+	draining  chan struct{}
+	drainOnce sync.Once
+}
+
+type memoryQueue struct {
+	name    string
+	handler Handler
+	key     KeyFunc
+	workers []chan memoryMessage
+
+	mu   sync.Mutex
+	next int
+}
+
+type memoryMessage struct {
+	id        string
+	body      []byte
+	retries   int
+	lastError string
+}
+
+var _ Broker = (*Memory)(nil)
+
+func NewMemory(retry RetryPolicy, workers int) *Memory {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	return &Memory{
+		retry:       retry,
+		workers:     workers,
+		bindings:    make(map[string][]*memoryQueue),
+		queues:      make(map[string]*memoryQueue),
+		deadLetters: make(map[string][]DeadLetter),
+		state:       StateConnected,
+		draining:    make(chan struct{}),
+	}
+}
+
+func (m *Memory) Publish(topic string, body []byte) error {
+	m.mu.RLock()
+	state := m.state
+	queues := m.bindings[topic]
+	m.mu.RUnlock()
+
+	if state != StateConnected || m.isDraining() {
+		return ErrNotConnected
+	}
+	if len(queues) == 0 {
+		return fmt.Errorf("%w: no queue subscribed to %s", ErrUnroutable, topic)
+	}
+
+	id := uuid.New().String()
+	for _, q := range queues {
+		m.enqueue(q, memoryMessage{id: id, body: append([]byte(nil), body...)})
+	}
+	return nil
+}
+
+// PublishEvent delivers the event to every queue subscribed to exchange. The
+// memory broker has no routing keys, and an event without subscribers is
+// dropped.
+func (m *Memory) PublishEvent(exchange, routingKey string, body []byte) error {
+	err := m.Publish(exchange, body)
+	if errors.Is(err, ErrUnroutable) {
+		return nil
+	}
+	return err
+}
+
+func (m *Memory) Subscribe(topic, queue string, handler Handler, key KeyFunc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.queues[queue]; ok {
+		return fmt.Errorf("queue %s is already subscribed", queue)
+	}
+
+	q := &memoryQueue{
+		name:    queue,
+		handler: handler,
+		key:     key,
+		workers: make([]chan memoryMessage, m.workers),
+	}
+	for i := range q.workers {
+		q.workers[i] = make(chan memoryMessage, memoryQueueSize)
+		m.inflight.Add(1)
+		go m.work(q, q.workers[i])
+	}
+
+	m.queues[queue] = q
+	m.bindings[topic] = append(m.bindings[topic], q)
+	return nil
+}
+
+func (m *Memory) work(q *memoryQueue, messages <-chan memoryMessage) {
+	defer m.inflight.Done()
+
+	for {
+		select {
+		case <-m.draining:
+			return
+		case msg := <-messages:
+			m.handle(q, msg)
+		}
+	}
+}
+
+// handle retries a failed message after the backoff of its next retry, or
+// dead-letters it when it is not retriable or out of retries.
+func (m *Memory) handle(q *memoryQueue, msg memoryMessage) {
+	err := q.handler(msg.body)
+	if err == nil {
+		return
+	}
+
+	msg.lastError = err.Error()
+	if IsPermanent(err) || msg.retries >= m.retry.MaxRetries {
+		log.Printf("dead-lettering message from %s after %d retries: %v", q.name, msg.retries, err)
+		m.mu.Lock()
+		m.deadLetters[q.name] = append(m.deadLetters[q.name], DeadLetter{
+			ID:             msg.id,
+			Queue:          q.name,
+			Body:           msg.body,
+			RetryCount:     msg.retries,
+			LastError:      msg.lastError,
+			DeadLetteredAt: time.Now(),
+		})
+		m.mu.Unlock()
+		return
+	}
+
+	msg.retries++
+	delay := m.retry.Delay(msg.retries)
+	log.Printf("handler error, retry %d in %s: %v", msg.retries, delay, err)
+	time.AfterFunc(delay, func() {
+		m.enqueue(q, msg)
+	})
+}
+
+func (m *Memory) enqueue(q *memoryQueue, msg memoryMessage) {
+	worker := q.pick(msg.body)
+	select {
+	case q.workers[worker] <- msg:
+	case <-m.draining:
+	}
+}
+
+func (q *memoryQueue) pick(body []byte) int {
+	if q.key != nil {
+		if key := q.key(body); key != "" {
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			return int(h.Sum32() % uint32(len(q.workers)))
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	worker := q.next % len(q.workers)
+	q.next++
+	return worker
+}
+
+func (m *Memory) DeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deadLetters := m.deadLetters[queue]
+	if len(deadLetters) > limit {
+		deadLetters = deadLetters[:limit]
+	}
+	return append([]DeadLetter{}, deadLetters...), nil
+}
+
+func (m *Memory) ReplayDeadLetter(queue, id string) (bool, error) {
+	m.mu.RLock()
+	q, ok := m.queues[queue]
+	m.mu.RUnlock()
+	if !ok {
+		return false, fmt.Errorf("%w: queue %s is not subscribed", ErrUnroutable, queue)
+	}
+
+	deadLetter, found := m.removeDeadLetter(queue, id)
+	if !found {
+		return false, nil
+	}
+
+	m.enqueue(q, memoryMessage{id: deadLetter.ID, body: deadLetter.Body})
+	return true, nil
+}
+
+func (m *Memory) DiscardDeadLetter(queue, id string) (bool, error) {
+	_, found := m.removeDeadLetter(queue, id)
+	return found, nil
+}
+
+func (m *Memory) removeDeadLetter(queue, id string) (DeadLetter, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deadLetters := m.deadLetters[queue]
+	for i, deadLetter := range deadLetters {
+		if deadLetter.ID == id {
+			m.deadLetters[queue] = append(deadLetters[:i:i], deadLetters[i+1:]...)
+			return deadLetter, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+func (m *Memory) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *Memory) isDraining() bool {
+	select {
+	case <-m.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// Drain stops the workers once their current message is handled. Queued
+// messages are dropped.
+func (m *Memory) Drain(timeout time.Duration) error {
+	m.drainOnce.Do(func() {
+		close(m.draining)
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s waiting for in-flight messages", timeout)
+	}
+}
+
+func (m *Memory) Close() {
+	m.drainOnce.Do(func() {
+		close(m.draining)
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = StateClosed
+}
@@ -0,0 +1,305 @@
+package broker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type received struct {
+	mu     sync.Mutex
+	bodies map[string][]string
+}
+
+func (r *received) handler(queue string, err error) Handler {
+	return func(body []byte) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.bodies == nil {
+			r.bodies = map[string][]string{}
+		}
+		r.bodies[queue] = append(r.bodies[queue], string(body))
+		return err
+	}
+}
+
+func (r *received) count(queue string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies[queue])
+}
+
+func TestMemory_Publish(t *testing.T) {
+	tests := []struct {
+		name      string
+		publish   func(m *Memory) error
+		wantErr   error
+		wantCount map[string]int
+	}{
+		{
+			name: "every queue subscribed to the topic receives the message",
+			publish: func(m *Memory) error {
+				return m.Publish("expenses", []byte("7"))
+			},
+			wantCount: map[string]int{"ledger.queue": 1, "notifications.queue": 1, "payments.queue": 0},
+		},
+		{
+			name: "event goes to the subscribers of the exchange",
+			publish: func(m *Memory) error {
+				return m.PublishEvent("expenses", "expense.created", []byte("7"))
+			},
+			wantCount: map[string]int{"ledger.queue": 1, "notifications.queue": 1, "payments.queue": 0},
+		},
+		{
+			name: "topic without subscribers is unroutable",
+			publish: func(m *Memory) error {
+				return m.Publish("reports", []byte("7"))
+			},
+			wantErr:   ErrUnroutable,
+			wantCount: map[string]int{"ledger.queue": 0, "notifications.queue": 0, "payments.queue": 0},
+		},
+		{
+			name: "event without subscribers is dropped",
+			publish: func(m *Memory) error {
+				return m.PublishEvent("reports", "report.created", []byte("7"))
+			},
+			wantCount: map[string]int{"ledger.queue": 0, "notifications.queue": 0, "payments.queue": 0},
+		},
+		{
+			name: "closed broker refuses messages",
+			publish: func(m *Memory) error {
+				m.Close()
+				return m.Publish("expenses", []byte("7"))
+			},
+			wantErr:   ErrNotConnected,
+			wantCount: map[string]int{"ledger.queue": 0, "notifications.queue": 0, "payments.queue": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(RetryPolicy{}, 2)
+			defer m.Close()
+
+			var got received
+			assert.NoError(t, m.Subscribe("expenses", "ledger.queue", got.handler("ledger.queue", nil), nil))
+			assert.NoError(t, m.Subscribe("expenses", "notifications.queue", got.handler("notifications.queue", nil), nil))
+			assert.NoError(t, m.Subscribe("payments", "payments.queue", got.handler("payments.queue", nil), nil))
+
+			err := tt.publish(m)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Eventually(t, func() bool {
+				for queue, count := range tt.wantCount {
+					if got.count(queue) != count {
+						return false
+					}
+				}
+				return true
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestMemory_Subscribe_QueueTwice(t *testing.T) {
+	m := NewMemory(RetryPolicy{}, 1)
+	defer m.Close()
+
+	assert.NoError(t, m.Subscribe("expenses", "ledger.queue", func([]byte) error { return nil }, nil))
+	assert.EqualError(t, m.Subscribe("expenses", "ledger.queue", func([]byte) error { return nil }, nil),
+		"queue ledger.queue is already subscribed")
+}
+
+func TestMemory_Retry(t *testing.T) {
+	handlerErr := errors.New("ledger unavailable")
+
+	tests := []struct {
+		name            string
+		failures        int
+		err             error
+		wantCalls       int
+		wantDeadLetters []DeadLetter
+	}{
+		{
+			name:      "succeeds on a retry",
+			failures:  1,
+			err:       handlerErr,
+			wantCalls: 2,
+		},
+		{
+			name:      "dead-lettered after the last retry",
+			failures:  10,
+			err:       handlerErr,
+			wantCalls: 3,
+			wantDeadLetters: []DeadLetter{
+				{Queue: "ledger.queue", Body: []byte("7"), RetryCount: 2, LastError: "ledger unavailable"},
+			},
+		},
+		{
+			name:      "permanent error skips retries",
+			failures:  10,
+			err:       Permanent(handlerErr),
+			wantCalls: 1,
+			wantDeadLetters: []DeadLetter{
+				{Queue: "ledger.queue", Body: []byte("7"), RetryCount: 0, LastError: "ledger unavailable"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}, 1)
+			defer m.Close()
+
+			var mu sync.Mutex
+			calls := 0
+			err := m.Subscribe("expenses", "ledger.queue", func([]byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}, nil)
+			assert.NoError(t, err)
+
+			assert.NoError(t, m.Publish("expenses", []byte("7")))
+
+			assert.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return calls == tt.wantCalls
+			}, time.Second, time.Millisecond)
+
+			// Wait out the longest backoff to catch a retry too many.
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			assert.Equal(t, tt.wantCalls, calls)
+			mu.Unlock()
+
+			deadLetters, err := m.DeadLetters("ledger.queue", 10)
+			assert.NoError(t, err)
+			if assert.Len(t, deadLetters, len(tt.wantDeadLetters)) {
+				for i := range deadLetters {
+					assert.NotEmpty(t, deadLetters[i].ID)
+					assert.False(t, deadLetters[i].DeadLetteredAt.IsZero())
+					deadLetters[i].ID = ""
+					deadLetters[i].DeadLetteredAt = time.Time{}
+				}
+				assert.Equal(t, append([]DeadLetter{}, tt.wantDeadLetters...), deadLetters)
+			}
+		})
+	}
+}
+
+func TestMemory_ReplayDeadLetter(t *testing.T) {
+	m := NewMemory(RetryPolicy{}, 1)
+	defer m.Close()
+
+	var mu sync.Mutex
+	calls := 0
+	err := m.Subscribe("expenses", "ledger.queue", func([]byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return Permanent(errors.New("ledger unavailable"))
+		}
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Publish("expenses", []byte("7")))
+
+	var deadLetters []DeadLetter
+	assert.Eventually(t, func() bool {
+		deadLetters, _ = m.DeadLetters("ledger.queue", 10)
+		return len(deadLetters) == 1
+	}, time.Second, time.Millisecond)
+
+	found, err := m.ReplayDeadLetter("ledger.queue", "unknown")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = m.ReplayDeadLetter("ledger.queue", deadLetters[0].ID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 2
+	}, time.Second, time.Millisecond)
+
+	deadLetters, err = m.DeadLetters("ledger.queue", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestMemory_Drain(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		releaseIn time.Duration
+		wantErr   string
+		wantDone  bool
+	}{
+		{
+			name:      "waits for the in-flight message",
+			timeout:   time.Second,
+			releaseIn: 20 * time.Millisecond,
+			wantDone:  true,
+		},
+		{
+			name:      "times out while the message is still handled",
+			timeout:   20 * time.Millisecond,
+			releaseIn: time.Second,
+			wantErr:   "timed out after 20ms waiting for in-flight messages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(RetryPolicy{}, 2)
+			defer m.Close()
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+
+			var mu sync.Mutex
+			done := false
+			err := m.Subscribe("expenses", "ledger.queue", func([]byte) error {
+				close(started)
+				<-release
+				mu.Lock()
+				defer mu.Unlock()
+				done = true
+				return nil
+			}, nil)
+			assert.NoError(t, err)
+
+			assert.NoError(t, m.Publish("expenses", []byte("7")))
+			<-started
+
+			timer := time.AfterFunc(tt.releaseIn, func() { release <- struct{}{} })
+			defer timer.Stop()
+
+			err = m.Drain(tt.timeout)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mu.Lock()
+			assert.Equal(t, tt.wantDone, done)
+			mu.Unlock()
+			assert.ErrorIs(t, m.Publish("expenses", []byte("8")), ErrNotConnected)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package circuitbreaker stops calls to a dependency that keeps failing and
+// lets a single probe call through once it may have recovered.
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker opens after threshold consecutive failures. Once openTimeout has
+// passed one call is let through: it closes the breaker when it succeeds and
+// opens it again when it fails.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func New(threshold int, openTimeout time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	return &Breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		state:       StateClosed,
+	}
+}
+
+// Allow returns ErrOpen when the call must not be made. A caller that was
+// allowed must report the outcome with Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		return ErrOpen
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState()
+}
+
+func (b *Breaker) currentState() State {
+	if b.state == StateOpen && time.Now().Sub(b.openedAt) >= b.openTimeout {
+		b.state = StateHalfOpen
+	}
+	return b.state
+}
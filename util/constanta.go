@@ -1,24 +1,132 @@
 package util
 
+import "strings"
+
 type ExpenseStatus int32
 
 type ApprovalStatus int32
 
 type UserRole int32
 
+type NotificationType int32
+
+type OutboxStatus int32
+
+type PaymentStatus int32
+
+type PayoutBatchStatus int32
+
 const (
-	EXPENSE_PENDING       ExpenseStatus = 3
-	EXPENSE_APPROVED      ExpenseStatus = 1
-	EXPENSE_REJECTED      ExpenseStatus = -1
-	EXPENSE_AUTO_APPROVED ExpenseStatus = 2
+	EXPENSE_PENDING        ExpenseStatus = 3
+	EXPENSE_APPROVED       ExpenseStatus = 1
+	EXPENSE_REJECTED       ExpenseStatus = -1
+	EXPENSE_AUTO_APPROVED  ExpenseStatus = 2
+	EXPENSE_NEEDS_REVISION ExpenseStatus = 4
+	EXPENSE_PAID           ExpenseStatus = 5
+	EXPENSE_PAYMENT_FAILED ExpenseStatus = 6
+	EXPENSE_REVERSED       ExpenseStatus = 7
 
-	APPROVAL_APPROVED ApprovalStatus = 1
-	APPROVAL_REJECTED ApprovalStatus = -1
+	APPROVAL_APPROVED      ApprovalStatus = 1
+	APPROVAL_REJECTED      ApprovalStatus = -1
+	APPROVAL_NEEDS_CHANGES ApprovalStatus = 4
 
 	USER_ROLE_ADMIN    UserRole = 1
 	USER_ROLE_MANAGER  UserRole = 2
 	USER_ROLE_EMPLOYEE UserRole = 3
 
+	NOTIFICATION_COMMENT NotificationType = 1
+	NOTIFICATION_MENTION NotificationType = 2
+
+	OUTBOX_PENDING   OutboxStatus = 1
+	OUTBOX_PUBLISHED OutboxStatus = 2
+	OUTBOX_FAILED    OutboxStatus = -1
+
+	OUTBOX_EVENT_PAYMENT_REQUESTED = "payment.requested"
+	OUTBOX_EVENT_JOURNAL_ENTRY     = "ledger.journal_entry"
+
+	// Domain events are published to the events exchange with the event type
+	// as routing key.
+	EVENT_EXPENSE_CREATED   = "expense.created"
+	EVENT_EXPENSE_APPROVED  = "expense.approved"
+	EVENT_EXPENSE_REJECTED  = "expense.rejected"
+	EVENT_PAYMENT_SUCCEEDED = "payment.succeeded"
+	EVENT_PAYMENT_FAILED    = "payment.failed"
+	EVENT_PAYMENT_REVERSED  = "payment.reversed"
+
+	EventSchemaVersion = 1
+
+	OutboxMaxAttempts = 10
+
+	PAYMENT_PROCESSING PaymentStatus = 1
+	PAYMENT_SUCCEEDED  PaymentStatus = 2
+	PAYMENT_FAILED     PaymentStatus = -1
+
+	PAYOUT_METHOD_BANK_TRANSFER = "bank_transfer"
+	PAYOUT_METHOD_EWALLET       = "ewallet"
+
+	PAYOUT_MODE_IMMEDIATE = "immediate"
+	PAYOUT_MODE_BATCH     = "batch"
+
+	// A batch is paid out through the payment processor, or exported as a
+	// bulk transfer file for banks without an API.
+	PAYOUT_CHANNEL_API  = "api"
+	PAYOUT_CHANNEL_FILE = "file"
+
+	PAYOUT_BATCH_PROCESSING       PayoutBatchStatus = 1
+	PAYOUT_BATCH_COMPLETED        PayoutBatchStatus = 2
+	PAYOUT_BATCH_PARTIALLY_FAILED PayoutBatchStatus = 3
+	PAYOUT_BATCH_FAILED           PayoutBatchStatus = -1
+
+	// A settlement statement is uploaded as a file or pulled from the
+	// provider's API.
+	RECONCILIATION_SOURCE_FILE = "file"
+	RECONCILIATION_SOURCE_API  = "api"
+
+	// Our payout is not in the statement, the statement has an entry we did
+	// not pay out, or both have it with different amounts.
+	RECONCILIATION_MISSING         = "missing"
+	RECONCILIATION_EXTRA           = "extra"
+	RECONCILIATION_AMOUNT_MISMATCH = "amount_mismatch"
+
+	// Ledger accounts. Expenses and advances are debited on increase, the
+	// payable to employees is credited, bank is credited when money leaves.
+	LEDGER_ACCOUNT_EXPENSE = "reimbursable_expense"
+	LEDGER_ACCOUNT_PAYABLE = "employee_payable"
+	LEDGER_ACCOUNT_BANK    = "bank"
+	LEDGER_ACCOUNT_ADVANCE = "employee_advance"
+
+	JOURNAL_EXPENSE_APPROVED = "expense_approved"
+	JOURNAL_PAYMENT_SENT     = "payment_sent"
+	JOURNAL_PAYMENT_REVERSED = "payment_reversed"
+	JOURNAL_ADVANCE_ISSUED   = "advance_issued"
+	JOURNAL_ADVANCE_RETURNED = "advance_returned"
+
+	// Accounting exports are a generic CSV with one row per expense, or a GL
+	// journal with a debit and a credit line per expense.
+	ACCOUNTING_EXPORT_CSV     = "csv"
+	ACCOUNTING_EXPORT_JOURNAL = "journal"
+
+	EXPENSE_CATEGORY_TRAVEL          = "travel"
+	EXPENSE_CATEGORY_ACCOMMODATION   = "accommodation"
+	EXPENSE_CATEGORY_MEALS           = "meals"
+	EXPENSE_CATEGORY_TRANSPORT       = "transport"
+	EXPENSE_CATEGORY_OFFICE_SUPPLIES = "office_supplies"
+	EXPENSE_CATEGORY_OTHER           = "other"
+
+	// A budget that would be exceeded warns the submitter and the approver, or
+	// blocks the expense.
+	BUDGET_POLICY_WARN  = "warn"
+	BUDGET_POLICY_BLOCK = "block"
+
+	// A spending limit caps what an employee claims in a calendar week, month
+	// or year. A claim over it is rejected, or loses auto-approval and waits
+	// for a manager.
+	SPENDING_LIMIT_PERIOD_WEEK  = "week"
+	SPENDING_LIMIT_PERIOD_MONTH = "month"
+	SPENDING_LIMIT_PERIOD_YEAR  = "year"
+	SPENDING_LIMIT_REJECT       = "reject"
+	SPENDING_LIMIT_REVIEW       = "review"
+
 	MinExpenseAmount  = 10000    // IDR 10,000
 	MaxExpenseAmount  = 50000000 // IDR 50,000,000
 	ApprovalThreshold = 1000000  // IDR 1,000,000
@@ -34,6 +142,14 @@ func GetExpenseStatusString(status ExpenseStatus) string {
 		return "approved"
 	case EXPENSE_REJECTED:
 		return "rejected"
+	case EXPENSE_NEEDS_REVISION:
+		return "needs_revision"
+	case EXPENSE_PAID:
+		return "paid"
+	case EXPENSE_PAYMENT_FAILED:
+		return "payment_failed"
+	case EXPENSE_REVERSED:
+		return "reversed"
 	}
 	return "Unknown"
 }
@@ -44,6 +160,8 @@ func GetApprovalStatusString(status ApprovalStatus) string {
 		return "approved"
 	case APPROVAL_REJECTED:
 		return "rejected"
+	case APPROVAL_NEEDS_CHANGES:
+		return "needs_changes"
 	}
 	return "Unknown"
 }
@@ -60,6 +178,118 @@ func GetUserRoleString(role UserRole) string {
 	return "Unknown"
 }
 
+func GetNotificationTypeString(notificationType NotificationType) string {
+	switch notificationType {
+	case NOTIFICATION_COMMENT:
+		return "comment"
+	case NOTIFICATION_MENTION:
+		return "mention"
+	}
+	return "Unknown"
+}
+
+func GetOutboxStatusString(status OutboxStatus) string {
+	switch status {
+	case OUTBOX_PENDING:
+		return "pending"
+	case OUTBOX_PUBLISHED:
+		return "published"
+	case OUTBOX_FAILED:
+		return "failed"
+	}
+	return "Unknown"
+}
+
+func GetPaymentStatusString(status PaymentStatus) string {
+	switch status {
+	case PAYMENT_PROCESSING:
+		return "processing"
+	case PAYMENT_SUCCEEDED:
+		return "succeeded"
+	case PAYMENT_FAILED:
+		return "failed"
+	}
+	return "Unknown"
+}
+
+func GetPayoutBatchStatusString(status PayoutBatchStatus) string {
+	switch status {
+	case PAYOUT_BATCH_PROCESSING:
+		return "processing"
+	case PAYOUT_BATCH_COMPLETED:
+		return "completed"
+	case PAYOUT_BATCH_PARTIALLY_FAILED:
+		return "partially_failed"
+	case PAYOUT_BATCH_FAILED:
+		return "failed"
+	}
+	return "Unknown"
+}
+
+func IsPayoutChannel(channel string) bool {
+	return channel == PAYOUT_CHANNEL_API || channel == PAYOUT_CHANNEL_FILE
+}
+
+func IsExpenseCategory(category string) bool {
+	switch category {
+	case EXPENSE_CATEGORY_TRAVEL, EXPENSE_CATEGORY_ACCOMMODATION, EXPENSE_CATEGORY_MEALS,
+		EXPENSE_CATEGORY_TRANSPORT, EXPENSE_CATEGORY_OFFICE_SUPPLIES, EXPENSE_CATEGORY_OTHER:
+		return true
+	}
+	return false
+}
+
+func IsBudgetPolicy(policy string) bool {
+	return policy == BUDGET_POLICY_WARN || policy == BUDGET_POLICY_BLOCK
+}
+
+func IsSpendingLimitPeriod(period string) bool {
+	switch period {
+	case SPENDING_LIMIT_PERIOD_WEEK, SPENDING_LIMIT_PERIOD_MONTH, SPENDING_LIMIT_PERIOD_YEAR:
+		return true
+	}
+	return false
+}
+
+func IsSpendingLimitAction(action string) bool {
+	return action == SPENDING_LIMIT_REJECT || action == SPENDING_LIMIT_REVIEW
+}
+
+func IsAccountingExportFormat(format string) bool {
+	return format == ACCOUNTING_EXPORT_CSV || format == ACCOUNTING_EXPORT_JOURNAL
+}
+
+func IsLedgerAccount(account string) bool {
+	switch account {
+	case LEDGER_ACCOUNT_EXPENSE, LEDGER_ACCOUNT_PAYABLE, LEDGER_ACCOUNT_BANK, LEDGER_ACCOUNT_ADVANCE:
+		return true
+	}
+	return false
+}
+
+// IsCreditNormal reports whether the balance of account grows with credits.
+func IsCreditNormal(account string) bool {
+	return account == LEDGER_ACCOUNT_PAYABLE
+}
+
+func IsPayoutMethod(method string) bool {
+	return method == PAYOUT_METHOD_BANK_TRANSFER || method == PAYOUT_METHOD_EWALLET
+}
+
+// GetPaymentStatusFromProvider maps a status reported by the payment provider
+// to a payment status.
+func GetPaymentStatusFromProvider(status string) (PaymentStatus, bool) {
+	switch strings.ToLower(status) {
+	case "success", "succeeded", "completed", "paid":
+		return PAYMENT_SUCCEEDED, true
+	case "failed", "rejected", "cancelled", "canceled", "expired":
+		return PAYMENT_FAILED, true
+	case "pending", "processing":
+		return PAYMENT_PROCESSING, true
+	}
+	return 0, false
+}
+
 func AmountValidation(amountIDR float64) (bool, bool) {
 	autoApproved := false
 	valid := amountIDR >= MinExpenseAmount && amountIDR <= MaxExpenseAmount
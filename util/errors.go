@@ -0,0 +1,34 @@
+package util
+
+import "errors"
+
+// ErrVersionConflict is returned when an expense was changed by another request
+// after the caller read it.
+var ErrVersionConflict = errors.New("expense has been modified by another request")
+
+var (
+	ErrPaymentNotFound          = errors.New("payment not found")
+	ErrUnknownPaymentStatus     = errors.New("unknown payment status")
+	ErrDeadLetterNotFound       = errors.New("dead-lettered message not found")
+	ErrPayoutBatchNotFound      = errors.New("payout batch not found")
+	ErrNoPayableExpenses        = errors.New("no approved expenses to pay out")
+	ErrPayoutBatchDisabled      = errors.New("payout batches are disabled, set PAYOUT_MODE=batch")
+	ErrInvalidPassword          = errors.New("password is incorrect")
+	ErrInvalidDestination       = errors.New("payout destination is not valid")
+	ErrNothingToReverse         = errors.New("expense has no succeeded payment to reverse")
+	ErrInvalidReconciliation    = errors.New("reconciliation request is not valid")
+	ErrReconciliationNotFound   = errors.New("reconciliation not found")
+	ErrUnbalancedJournalEntry   = errors.New("journal entry debits and credits do not balance")
+	ErrJournalEntryExists       = errors.New("journal entry has already been posted")
+	ErrInvalidAdvance           = errors.New("advance request is not valid")
+	ErrInvalidExport            = errors.New("accounting export request is not valid")
+	ErrNothingToExport          = errors.New("no approved expenses to export in the period")
+	ErrAccountingExportNotFound = errors.New("accounting export not found")
+	ErrUnmappedGLAccount        = errors.New("no GL account is mapped for the expense")
+	ErrInvalidGLAccountMapping  = errors.New("GL account mapping is not valid")
+	ErrInvalidBudget            = errors.New("budget is not valid")
+	ErrBudgetExceeded           = errors.New("expense would exceed the budget")
+	ErrInvalidSpendingLimit     = errors.New("spending limit is not valid")
+	ErrSpendingLimitNotFound    = errors.New("spending limit not found")
+	ErrSpendingLimitExceeded    = errors.New("expense would exceed the spending limit")
+)
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// ParseMentions returns the distinct, lower-cased emails mentioned as @email in a comment body.
+func ParseMentions(body string) []string {
+	emails := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(strings.TrimRight(match[1], "."))
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+	return emails
+}
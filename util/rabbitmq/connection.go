@@ -0,0 +1,76 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// connection and channel are the parts of an AMQP connection the client uses.
+// They let tests run the client against a fake broker.
+type connection interface {
+	Channel() (channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	IsClosed() bool
+	Close() error
+}
+
+type channel interface {
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	NotifyReturn(receiver chan amqp.Return) chan amqp.Return
+	Confirm(noWait bool) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error)
+	Close() error
+}
+
+type confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+type dialer func(url string) (connection, error)
+
+// backoff returns the wait before the given reconnect attempt.
+type backoff interface {
+	Delay(attempt int) time.Duration
+}
+
+func dialAMQP(url string) (connection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return amqpConnection{conn}, nil
+}
+
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpConnection) Channel() (channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return amqpChannel{ch}, nil
+}
+
+type amqpChannel struct {
+	*amqp.Channel
+}
+
+func (c amqpChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error) {
+	confirmation, err := c.Channel.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		return nil, err
+	}
+	return confirmation, nil
+}
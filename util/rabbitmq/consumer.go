@@ -0,0 +1,177 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type subscription struct {
+	exchange string
+	queue    string
+	handler  broker.Handler
+	key      broker.KeyFunc
+}
+
+type consumer struct {
+	channel channel
+	tag     string
+}
+
+// Subscribe consumes queue bound to exchange with a pool of workers. Messages
+// with the same key are handled by the same worker in delivery order; a nil
+// key or an empty key spreads messages over all workers. The consumer is
+// restarted after every reconnect.
+func (c *RabbitMQClient) Subscribe(exchange, queue string, handler broker.Handler, key broker.KeyFunc) error {
+	sub := subscription{exchange: exchange, queue: queue, handler: handler, key: key}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	conn, _, err := c.connection()
+	if err != nil {
+		return err
+	}
+
+	if err := c.consume(conn, sub); err != nil {
+		return err
+	}
+	c.subscriptions = append(c.subscriptions, sub)
+
+	return nil
+}
+
+// consume starts a consumer for sub on its own channel of conn.
+func (c *RabbitMQClient) consume(conn connection, sub subscription) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		_ = ch.Close()
+		return err
+	}
+
+	// Retries and dead letters are republished on this channel and must be
+	// confirmed before the delivery is acked.
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return err
+	}
+
+	if err := declareTopology(ch, sub.exchange, sub.queue, c.retry); err != nil {
+		_ = ch.Close()
+		return err
+	}
+
+	tag := fmt.Sprintf("%s-%s", sub.queue, uuid.New().String())
+	msgs, err := ch.Consume(
+		sub.queue,
+		tag,
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return err
+	}
+	c.consumers = append(c.consumers, consumer{channel: ch, tag: tag})
+
+	workers := make([]chan amqp.Delivery, c.workerCount)
+	for i := range workers {
+		workers[i] = make(chan amqp.Delivery)
+		c.workers.Add(1)
+		go func(deliveries <-chan amqp.Delivery) {
+			defer c.workers.Done()
+			for d := range deliveries {
+				c.handleDelivery(ch, sub.exchange, sub.queue, d, sub.handler)
+			}
+		}(workers[i])
+	}
+
+	go func() {
+		next := 0
+		for d := range msgs {
+			worker := next % len(workers)
+			next++
+			if sub.key != nil {
+				if key := sub.key(d.Body); key != "" {
+					worker = workerFor(key, len(workers))
+				}
+			}
+			workers[worker] <- d
+		}
+		for _, deliveries := range workers {
+			close(deliveries)
+		}
+
+		// The deliveries stop when the channel closes or the consumer is
+		// cancelled. A channel that closed on its own is recovered by
+		// reconnecting.
+		select {
+		case <-c.done:
+		case <-c.draining:
+		default:
+			if !conn.IsClosed() {
+				log.Printf("rabbitmq consumer of %s stopped, reconnecting", sub.queue)
+				_ = conn.Close()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func workerFor(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func (c *RabbitMQClient) isDraining() bool {
+	select {
+	case <-c.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// Drain stops every consumer and waits up to timeout for the messages being
+// handled to finish. Prefetched messages that were not handled yet are
+// requeued by the broker when the connection is closed.
+func (c *RabbitMQClient) Drain(timeout time.Duration) error {
+	c.drainOnce.Do(func() {
+		close(c.draining)
+	})
+
+	c.subMu.Lock()
+	for _, consumer := range c.consumers {
+		if err := consumer.channel.Cancel(consumer.tag, false); err != nil {
+			log.Printf("failed to cancel consumer %s: %v", consumer.tag, err)
+		}
+	}
+	c.subMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s waiting for in-flight messages", timeout)
+	}
+}
@@ -0,0 +1,191 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func expenseKey(body []byte) string {
+	key, _, _ := strings.Cut(string(body), ":")
+	return key
+}
+
+func TestRabbitMQClient_Subscribe_OrderPerKey(t *testing.T) {
+	client, fake, _ := newTestClient(t, Options{ConfirmTimeout: time.Second, Workers: 4})
+
+	var mu sync.Mutex
+	handled := map[string][]string{}
+	inFlight := map[string]int{}
+	overlapped := false
+
+	err := client.Subscribe("expenses", "expenses.queue", func(body []byte) error {
+		key := expenseKey(body)
+
+		mu.Lock()
+		inFlight[key]++
+		if inFlight[key] > 1 {
+			overlapped = true
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inFlight[key]--
+		handled[key] = append(handled[key], string(body))
+		mu.Unlock()
+		return nil
+	}, expenseKey)
+	assert.NoError(t, err)
+
+	acknowledger := &fakeAcknowledger{}
+	ch := fake.conn(0).consumer("expenses.queue")
+	want := map[string][]string{}
+	for i := 1; i <= 10; i++ {
+		for _, key := range []string{"7", "8", "9"} {
+			body := fmt.Sprintf("%s:%d", key, i)
+			want[key] = append(want[key], body)
+			ch.deliver(amqp.Delivery{Acknowledger: acknowledger, Body: []byte(body)})
+		}
+	}
+
+	assert.Eventually(t, func() bool {
+		acknowledger.mu.Lock()
+		defer acknowledger.mu.Unlock()
+		return acknowledger.acked == 30
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, want, handled)
+	assert.False(t, overlapped, "messages with the same key were handled concurrently")
+}
+
+func TestRabbitMQClient_Subscribe_WorkerLimit(t *testing.T) {
+	client, fake, _ := newTestClient(t, Options{ConfirmTimeout: time.Second, Workers: 2})
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	release := make(chan struct{})
+
+	err := client.Subscribe("expenses", "expenses.queue", func(body []byte) error {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+
+	acknowledger := &fakeAcknowledger{}
+	ch := fake.conn(0).consumer("expenses.queue")
+	for i := 0; i < 5; i++ {
+		ch.deliver(amqp.Delivery{Acknowledger: acknowledger, Body: []byte(fmt.Sprintf("%d", i))})
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return inFlight == 2
+	}, time.Second, time.Millisecond)
+
+	// Give a third worker the chance to show up.
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 2, maxInFlight)
+	mu.Unlock()
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		acknowledger.mu.Lock()
+		defer acknowledger.mu.Unlock()
+		return acknowledger.acked == 5
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, maxInFlight)
+}
+
+func TestRabbitMQClient_Drain(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		releaseIn time.Duration
+		wantErr   string
+		wantAcked int
+	}{
+		{
+			name:      "waits for the in-flight message",
+			timeout:   time.Second,
+			releaseIn: 20 * time.Millisecond,
+			wantAcked: 1,
+		},
+		{
+			name:      "times out while the message is still handled",
+			timeout:   20 * time.Millisecond,
+			releaseIn: time.Second,
+			wantErr:   "timed out after 20ms waiting for in-flight messages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake, _ := newTestClient(t, Options{ConfirmTimeout: time.Second, Workers: 2})
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+
+			err := client.Subscribe("expenses", "expenses.queue", func(body []byte) error {
+				close(started)
+				<-release
+				return nil
+			}, nil)
+			assert.NoError(t, err)
+
+			acknowledger := &fakeAcknowledger{}
+			conn := fake.conn(0)
+			ch := conn.consumer("expenses.queue")
+			ch.deliver(amqp.Delivery{Acknowledger: acknowledger, Body: []byte("7")})
+			<-started
+
+			timer := time.AfterFunc(tt.releaseIn, func() { release <- struct{}{} })
+			defer timer.Stop()
+
+			err = client.Drain(tt.timeout)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			acknowledger.mu.Lock()
+			assert.Equal(t, tt.wantAcked, acknowledger.acked)
+			acknowledger.mu.Unlock()
+
+			// A drained consumer is cancelled without reconnecting.
+			ch.mu.Lock()
+			assert.True(t, ch.cancelled)
+			ch.mu.Unlock()
+			assert.False(t, conn.IsClosed())
+			assert.Equal(t, broker.StateConnected, client.State())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetters returns up to limit messages from the dead-letter queue of queue
+// without removing them.
+func (c *RabbitMQClient) DeadLetters(queue string, limit int) ([]broker.DeadLetter, error) {
+	conn, _, err := c.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	// Closing the channel requeues every message fetched below.
+	defer ch.Close()
+
+	deadLetters := make([]broker.DeadLetter, 0)
+	for len(deadLetters) < limit {
+		d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		deadLetters = append(deadLetters, toDeadLetter(queue, d))
+	}
+
+	return deadLetters, nil
+}
+
+// ReplayDeadLetter moves the dead-lettered message with the given ID back to
+// its original queue with a fresh retry count. It reports whether the message
+// was found.
+func (c *RabbitMQClient) ReplayDeadLetter(queue, id string) (bool, error) {
+	return c.withDeadLetter(queue, id, func(ch channel, d amqp.Delivery) error {
+		headers := copyHeaders(d.Headers)
+		delete(headers, headerRetryCount)
+		delete(headers, headerLastError)
+		delete(headers, headerDeadLetteredAt)
+		delete(headers, headerOriginalQueue)
+
+		return c.publishConfirmed(
+			ch,
+			"",
+			originalQueue(queue, d.Headers),
+			amqp.Publishing{
+				Headers:      headers,
+				ContentType:  d.ContentType,
+				MessageId:    d.MessageId,
+				Body:         d.Body,
+				DeliveryMode: amqp.Persistent,
+			})
+	})
+}
+
+// DiscardDeadLetter drops the dead-lettered message with the given ID. It
+// reports whether the message was found.
+func (c *RabbitMQClient) DiscardDeadLetter(queue, id string) (bool, error) {
+	return c.withDeadLetter(queue, id, func(channel, amqp.Delivery) error {
+		return nil
+	})
+}
+
+// withDeadLetter scans the dead-letter queue for the message with the given ID,
+// runs action on it and acks it. Other messages stay unacked until the channel
+// is closed, so each is seen once and then requeued.
+func (c *RabbitMQClient) withDeadLetter(queue, id string, action func(channel, amqp.Delivery) error) (bool, error) {
+	conn, _, err := c.connection()
+	if err != nil {
+		return false, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, err
+	}
+	defer ch.Close()
+
+	// A replayed message is acked only once its copy is confirmed.
+	if err := ch.Confirm(false); err != nil {
+		return false, err
+	}
+
+	q, err := ch.QueueDeclarePassive(DeadLetterQueue(queue), true, false, false, false, nil)
+	if err != nil {
+		return false, err
+	}
+
+	for i := 0; i < q.Messages; i++ {
+		d, ok, err := ch.Get(q.Name, false)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			break
+		}
+		if d.MessageId != id {
+			continue
+		}
+
+		if err := action(ch, d); err != nil {
+			return false, err
+		}
+		return true, d.Ack(false)
+	}
+
+	return false, nil
+}
+
+func toDeadLetter(queue string, d amqp.Delivery) broker.DeadLetter {
+	deadLetter := broker.DeadLetter{
+		ID:         d.MessageId,
+		Queue:      originalQueue(queue, d.Headers),
+		Body:       d.Body,
+		RetryCount: retryCount(d.Headers),
+	}
+	if lastError, ok := d.Headers[headerLastError].(string); ok {
+		deadLetter.LastError = lastError
+	}
+	if deadLetteredAt, ok := d.Headers[headerDeadLetteredAt].(time.Time); ok {
+		deadLetter.DeadLetteredAt = deadLetteredAt
+	}
+	return deadLetter
+}
+
+func originalQueue(queue string, headers amqp.Table) string {
+	if original, ok := headers[headerOriginalQueue].(string); ok && original != "" {
+		return original
+	}
+	return queue
+}
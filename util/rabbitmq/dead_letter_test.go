@@ -0,0 +1,62 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRabbitMQClient_ReplayDeadLetter(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		wantFound bool
+	}{
+		{name: "replay to the original queue", id: "message-2", wantFound: true},
+		{name: "unknown message", id: "message-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake, _ := newTestClient(t, Options{ConfirmTimeout: time.Second})
+
+			acknowledger := &fakeAcknowledger{}
+			conn := fake.conn(0)
+			for _, id := range []string{"message-1", "message-2"} {
+				conn.queued = append(conn.queued, amqp.Delivery{
+					Acknowledger: acknowledger,
+					MessageId:    id,
+					Headers: amqp.Table{
+						headerRetryCount:    int32(3),
+						headerLastError:     "ledger unavailable",
+						headerOriginalQueue: "expenses.queue",
+						"x-request-id":      "request-1",
+					},
+					Body: []byte(`{"expense_id":7}`),
+				})
+			}
+
+			found, err := client.ReplayDeadLetter("expenses.queue", tt.id)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFound, found)
+
+			published := conn.channel(1).publishings()
+			if !tt.wantFound {
+				assert.Empty(t, published)
+				assert.Equal(t, 0, acknowledger.acked)
+				return
+			}
+
+			assert.Equal(t, 1, acknowledger.acked)
+			if assert.Len(t, published, 1) {
+				assert.Equal(t, "", published[0].exchange)
+				assert.Equal(t, "expenses.queue", published[0].key)
+				assert.Equal(t, "message-2", published[0].msg.MessageId)
+				assert.Equal(t, amqp.Table{"x-request-id": "request-1"}, published[0].msg.Headers)
+			}
+		})
+	}
+}
@@ -1,46 +1,262 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/budsx/expenses-management/util/broker"
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 30 * time.Second
+)
+
+var (
+	ErrConfirmTimeout = errors.New("timed out waiting for publisher confirm")
+	ErrPublishNacked  = errors.New("message was nacked by the broker")
+)
+
+type Options struct {
+	Retry          broker.RetryPolicy
+	ConfirmTimeout time.Duration
+	// Prefetch limits the unacked deliveries per consumer. It defaults to
+	// Workers.
+	Prefetch int
+	// Workers is the number of deliveries handled concurrently per consumer.
+	Workers int
+}
+
+// RabbitMQClient keeps a connection to the broker alive. When the connection
+// or the publishing channel closes it reconnects with backoff, declares the
+// topology again and restarts every consumer registered with Subscribe.
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url            string
+	dial           dialer
+	backoff        backoff
+	retry          broker.RetryPolicy
+	confirmTimeout time.Duration
+	prefetch       int
+	workerCount    int
+
+	mu      sync.RWMutex
+	conn    connection
+	channel channel
+	returns chan amqp.Return
+	state   broker.State
+
+	// publishMu keeps one publish in flight, so a returned message always
+	// belongs to the publish waiting for its confirm.
+	publishMu sync.Mutex
+
+	// subMu serializes Subscribe with consumer restarts on reconnect.
+	subMu         sync.Mutex
+	subscriptions []subscription
+	consumers     []consumer
+	workers       sync.WaitGroup
+
+	draining  chan struct{}
+	drainOnce sync.Once
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewClient(url string) (*RabbitMQClient, error) {
-	conn, err := amqp.Dial(url)
-	if err != nil {
+var _ broker.Broker = (*RabbitMQClient)(nil)
+
+func NewClient(url string, options Options) (*RabbitMQClient, error) {
+	return newClient(url, options, dialAMQP, broker.RetryPolicy{
+		BaseDelay: reconnectBaseDelay,
+		MaxDelay:  reconnectMaxDelay,
+	})
+}
+
+func newClient(url string, options Options, dial dialer, backoff backoff) (*RabbitMQClient, error) {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Prefetch <= 0 {
+		options.Prefetch = options.Workers
+	}
+
+	c := &RabbitMQClient{
+		url:            url,
+		dial:           dial,
+		backoff:        backoff,
+		retry:          options.Retry,
+		confirmTimeout: options.ConfirmTimeout,
+		prefetch:       options.Prefetch,
+		workerCount:    options.Workers,
+		draining:       make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	if err := c.connect(); err != nil {
 		return nil, err
 	}
 
+	return c, nil
+}
+
+// connect dials the broker, opens the publishing channel, restarts the
+// consumers and starts watching the new connection.
+func (c *RabbitMQClient) connect() error {
+	conn, err := c.dial(c.url)
+	if err != nil {
+		return err
+	}
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		_ = conn.Close()
+		return err
 	}
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	if err := ch.Confirm(false); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 8))
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: ch,
-	}, nil
+	c.consumers = nil
+	if !c.isDraining() {
+		for _, sub := range c.subscriptions {
+			if err := c.consume(conn, sub); err != nil {
+				_ = conn.Close()
+				return err
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == broker.StateClosed {
+		_ = conn.Close()
+		return nil
+	}
+	c.conn = conn
+	c.channel = ch
+	c.returns = returns
+	c.state = broker.StateConnected
+
+	go c.watch(conn, connClosed, channelClosed)
+	return nil
 }
 
-func (c *RabbitMQClient) Close() {
-	if c.channel != nil {
-		_ = c.channel.Close()
+func (c *RabbitMQClient) watch(conn connection, connClosed, channelClosed chan *amqp.Error) {
+	select {
+	case <-c.done:
+		return
+	case err := <-connClosed:
+		log.Printf("rabbitmq connection closed: %v", err)
+	case err := <-channelClosed:
+		log.Printf("rabbitmq channel closed: %v", err)
+		_ = conn.Close()
+	}
+
+	c.reconnect()
+}
+
+func (c *RabbitMQClient) reconnect() {
+	c.setState(broker.StateReconnecting)
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(c.backoff.Delay(attempt)):
+		}
+
+		if err := c.connect(); err != nil {
+			log.Printf("rabbitmq reconnect attempt %d failed: %v", attempt, err)
+			continue
+		}
+
+		log.Printf("rabbitmq reconnected")
+		return
 	}
-	if c.conn != nil {
-		_ = c.conn.Close()
+}
+
+func (c *RabbitMQClient) setState(state broker.State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != broker.StateClosed {
+		c.state = state
 	}
 }
 
+func (c *RabbitMQClient) State() broker.State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// connection returns the current connection, or broker.ErrNotConnected while the
+// client is reconnecting.
+func (c *RabbitMQClient) connection() (connection, channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state != broker.StateConnected {
+		return nil, nil, broker.ErrNotConnected
+	}
+	return c.conn, c.channel, nil
+}
+
+func (c *RabbitMQClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.state = broker.StateClosed
+		if c.channel != nil {
+			_ = c.channel.Close()
+		}
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+	})
+}
+
+// Publish returns once the broker has confirmed the message. It fails when the
+// message is nacked, cannot be routed to any queue or is not confirmed within
+// the confirm timeout.
 func (c *RabbitMQClient) Publish(exchange string, msg []byte) error {
-	if err := c.channel.ExchangeDeclare(
+	return c.publish(exchange, "fanout", "", true, "text/plain", msg)
+}
+
+// PublishEvent publishes to a topic exchange. Events are not mandatory, so an
+// event nobody subscribed to is dropped by the broker instead of returned.
+func (c *RabbitMQClient) PublishEvent(exchange, routingKey string, msg []byte) error {
+	return c.publish(exchange, "topic", routingKey, false, "application/json", msg)
+}
+
+func (c *RabbitMQClient) publish(exchange, kind, routingKey string, mandatory bool, contentType string, msg []byte) error {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	_, ch, err := c.connection()
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	returns := c.returns
+	c.mu.RUnlock()
+
+	if err := ch.ExchangeDeclare(
 		exchange,
-		"fanout",
+		kind,
 		true,
 		false,
 		false,
@@ -50,51 +266,57 @@ func (c *RabbitMQClient) Publish(exchange string, msg []byte) error {
 		return err
 	}
 
-	return c.channel.Publish(
+	ctx, cancel := context.WithTimeout(context.Background(), c.confirmTimeout)
+	defer cancel()
+
+	messageID := uuid.New().String()
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(
+		ctx,
 		exchange,
-		"",
-		false,
+		routingKey,
+		mandatory,
 		false,
 		amqp.Publishing{
-			ContentType:  "text/plain",
+			ContentType:  contentType,
+			MessageId:    messageID,
 			Body:         msg,
 			DeliveryMode: amqp.Persistent,
 		})
-}
-
-func (c *RabbitMQClient) Subscribe(exchange, queue string, handler func([]byte) error) error {
-	if err := declareTopology(c.channel, exchange, queue); err != nil {
+	if err != nil {
 		return err
 	}
 
-	msgs, err := c.channel.Consume(
-		queue,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+	acked, err := confirmation.WaitContext(ctx)
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrConfirmTimeout
+		}
 		return err
 	}
+	if !acked {
+		return ErrPublishNacked
+	}
 
-	go func() {
-		for d := range msgs {
-			if err := handler(d.Body); err != nil {
-				log.Printf("handler error: %v", err)
-				_ = d.Nack(false, true)
-				continue
+	// The broker sends basic.return before the ack of an unroutable message.
+	for {
+		select {
+		case returned, ok := <-returns:
+			if !ok {
+				return nil
+			}
+			if returned.MessageId == messageID {
+				return fmt.Errorf("%w: %s", broker.ErrUnroutable, returned.ReplyText)
 			}
-			_ = d.Ack(false)
+		default:
+			return nil
 		}
-	}()
-
-	return nil
+	}
 }
 
-func declareTopology(ch *amqp.Channel, exchange, queue string) error {
+// declareTopology declares the exchange and queue, one delay queue per retry
+// backoff that dead-letters back into queue, and the dead-letter exchange and
+// queue for messages that cannot be processed.
+func declareTopology(ch channel, exchange, queue string, retry broker.RetryPolicy) error {
 	if err := ch.ExchangeDeclare(
 		exchange,
 		"fanout",
@@ -129,5 +351,53 @@ func declareTopology(ch *amqp.Channel, exchange, queue string) error {
 		return err
 	}
 
-	return nil
+	for i := 1; i <= retry.MaxRetries; i++ {
+		delay := retry.Delay(i)
+		if _, err := ch.QueueDeclare(
+			retryQueue(queue, delay),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := ch.ExchangeDeclare(
+		DeadLetterExchange(exchange),
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return err
+	}
+
+	dlq, err := ch.QueueDeclare(
+		DeadLetterQueue(queue),
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		dlq.Name,
+		queue,
+		DeadLetterExchange(exchange),
+		false,
+		nil,
+	)
 }
@@ -0,0 +1,423 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBroker hands out a new fakeConnection per dial. Every dial waits for
+// its result on dials: nil for a connection or the dial error.
+type fakeBroker struct {
+	dials chan error
+
+	mu      sync.Mutex
+	waiting int
+	conns   []*fakeConnection
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{dials: make(chan error, 8)}
+}
+
+func (b *fakeBroker) dial(url string) (connection, error) {
+	b.mu.Lock()
+	b.waiting++
+	b.mu.Unlock()
+
+	err := <-b.dials
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.waiting--
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &fakeConnection{}
+	b.conns = append(b.conns, conn)
+	return conn, nil
+}
+
+// dialing reports whether a dial is waiting for its result.
+func (b *fakeBroker) dialing() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waiting > 0
+}
+
+func (b *fakeBroker) conn(i int) *fakeConnection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i >= len(b.conns) {
+		return nil
+	}
+	return b.conns[i]
+}
+
+type fakeConnection struct {
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+	// queued is copied to every channel opened afterwards.
+	queued []amqp.Delivery
+}
+
+func (c *fakeConnection) Channel() (channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	ch := &fakeChannel{queued: append([]amqp.Delivery(nil), c.queued...)}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = append(c.notify, receiver)
+	return receiver
+}
+
+func (c *fakeConnection) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConnection) Close() error {
+	return c.shutdown(nil)
+}
+
+// fail closes the connection from the broker side with err.
+func (c *fakeConnection) fail(err *amqp.Error) {
+	_ = c.shutdown(err)
+}
+
+func (c *fakeConnection) shutdown(err *amqp.Error) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return amqp.ErrClosed
+	}
+	c.closed = true
+	channels, notify := c.channels, c.notify
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		_ = ch.shutdown(err)
+	}
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+	return nil
+}
+
+// channel returns the i-th channel opened on the connection. The first one is
+// the publishing channel.
+func (c *fakeConnection) channel(i int) *fakeChannel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channels[i]
+}
+
+// consumer returns the channel consuming queue, or nil.
+func (c *fakeConnection) consumer(queue string) *fakeChannel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.channels {
+		if ch.consumedQueue() == queue {
+			return ch
+		}
+	}
+	return nil
+}
+
+type fakePublishing struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+type fakeChannel struct {
+	mu         sync.Mutex
+	closed     bool
+	notify     []chan *amqp.Error
+	queue      string
+	deliveries chan amqp.Delivery
+	cancelled  bool
+	declared   []string
+	published  []fakePublishing
+	// queued is served by Get.
+	queued []amqp.Delivery
+	// nack makes the broker nack every publish.
+	nack bool
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.notify = append(ch.notify, receiver)
+	return receiver
+}
+
+func (ch *fakeChannel) NotifyReturn(receiver chan amqp.Return) chan amqp.Return {
+	return receiver
+}
+
+func (ch *fakeChannel) Confirm(noWait bool) error {
+	return nil
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return nil
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.declared = append(ch.declared, name)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return amqp.Queue{Name: name, Messages: len(ch.queued)}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.queue = queue
+	ch.deliveries = make(chan amqp.Delivery, 64)
+	return ch.deliveries, nil
+}
+
+func (ch *fakeChannel) Cancel(consumer string, noWait bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.stopConsuming()
+	return nil
+}
+
+func (ch *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.queued) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	d := ch.queued[0]
+	ch.queued = ch.queued[1:]
+	return d, true, nil
+}
+
+func (ch *fakeChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch.published = append(ch.published, fakePublishing{exchange: exchange, key: key, msg: msg})
+	return fakeConfirmation{acked: !ch.nack}, nil
+}
+
+func (ch *fakeChannel) Close() error {
+	return ch.shutdown(nil)
+}
+
+// fail closes the channel from the broker side with err.
+func (ch *fakeChannel) fail(err *amqp.Error) {
+	_ = ch.shutdown(err)
+}
+
+func (ch *fakeChannel) shutdown(err *amqp.Error) error {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return amqp.ErrClosed
+	}
+	ch.closed = true
+	ch.stopConsuming()
+	notify := ch.notify
+	ch.mu.Unlock()
+
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+	return nil
+}
+
+func (ch *fakeChannel) stopConsuming() {
+	if ch.deliveries != nil && !ch.cancelled {
+		ch.cancelled = true
+		close(ch.deliveries)
+	}
+}
+
+func (ch *fakeChannel) consumedQueue() string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.queue
+}
+
+func (ch *fakeChannel) deliver(d amqp.Delivery) {
+	ch.mu.Lock()
+	deliveries := ch.deliveries
+	ch.mu.Unlock()
+	deliveries <- d
+}
+
+func (ch *fakeChannel) publishings() []fakePublishing {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return append([]fakePublishing(nil), ch.published...)
+}
+
+type fakeConfirmation struct {
+	acked bool
+}
+
+func (c fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	return c.acked, nil
+}
+
+type fakeBackoff struct {
+	mu       sync.Mutex
+	attempts []int
+}
+
+func (b *fakeBackoff) Delay(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts = append(b.attempts, attempt)
+	return 0
+}
+
+func (b *fakeBackoff) calls() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int(nil), b.attempts...)
+}
+
+// newTestClient returns a client connected to a fake broker.
+func newTestClient(t *testing.T, options Options) (*RabbitMQClient, *fakeBroker, *fakeBackoff) {
+	t.Helper()
+
+	fake := newFakeBroker()
+	backoff := &fakeBackoff{}
+	fake.dials <- nil
+
+	client, err := newClient("amqp://test", options, fake.dial, backoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	return client, fake, backoff
+}
+
+func TestRabbitMQClient_Reconnect(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(conn *fakeConnection)
+	}{
+		{
+			name: "connection closed by the broker",
+			close: func(conn *fakeConnection) {
+				conn.fail(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"})
+			},
+		},
+		{
+			name: "publishing channel closed",
+			close: func(conn *fakeConnection) {
+				conn.channel(0).fail(&amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED"})
+			},
+		},
+		{
+			name: "consumer cancelled",
+			close: func(conn *fakeConnection) {
+				_ = conn.consumer("expenses.queue").Cancel("", false)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake, backoff := newTestClient(t, Options{ConfirmTimeout: time.Second})
+
+			err := client.Subscribe("expenses", "expenses.queue", func([]byte) error { return nil }, nil)
+			assert.NoError(t, err)
+
+			first := fake.conn(0)
+			assert.NotNil(t, first.consumer("expenses.queue"))
+			assert.Equal(t, broker.StateConnected, client.State())
+
+			tt.close(first)
+
+			assert.Eventually(t, func() bool {
+				return client.State() == broker.StateReconnecting
+			}, time.Second, time.Millisecond)
+			assert.True(t, first.IsClosed())
+			assert.ErrorIs(t, client.Publish("expenses", []byte("{}")), broker.ErrNotConnected)
+
+			fake.dials <- errors.New("connection refused")
+			fake.dials <- nil
+
+			assert.Eventually(t, func() bool {
+				return client.State() == broker.StateConnected
+			}, time.Second, time.Millisecond)
+			assert.Equal(t, []int{1, 2}, backoff.calls())
+
+			second := fake.conn(1)
+			if assert.NotNil(t, second) {
+				assert.NotNil(t, second.consumer("expenses.queue"))
+				assert.False(t, second.IsClosed())
+			}
+			assert.NoError(t, client.Publish("expenses", []byte("{}")))
+		})
+	}
+}
+
+func TestRabbitMQClient_CloseWhileReconnecting(t *testing.T) {
+	client, fake, _ := newTestClient(t, Options{ConfirmTimeout: time.Second})
+
+	fake.conn(0).fail(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"})
+	assert.Eventually(t, fake.dialing, time.Second, time.Millisecond)
+	assert.Equal(t, broker.StateReconnecting, client.State())
+
+	client.Close()
+	assert.Equal(t, broker.StateClosed, client.State())
+
+	// A dial that was already in flight must not bring the client back.
+	fake.dials <- nil
+	assert.Eventually(t, func() bool {
+		conn := fake.conn(1)
+		return conn != nil && conn.IsClosed()
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, broker.StateClosed, client.State())
+}
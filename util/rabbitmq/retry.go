@@ -0,0 +1,160 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	headerRetryCount       = "x-retry-count"
+	headerLastError        = "x-last-error"
+	headerOriginalQueue    = "x-original-queue"
+	headerDeadLetteredAt   = "x-dead-lettered-at"
+	maxLastErrorHeaderSize = 1024
+)
+
+func DeadLetterExchange(exchange string) string {
+	return exchange + ".dlx"
+}
+
+func DeadLetterQueue(queue string) string {
+	return queue + ".dlq"
+}
+
+func retryQueue(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+// handleDelivery acks a handled message. A failed message is republished to
+// the delay queue of its next retry, or to the dead-letter exchange when it is
+// not retriable or out of retries, and acked once the broker confirmed the
+// copy. A copy that is not confirmed leaves the message requeued.
+func (c *RabbitMQClient) handleDelivery(ch channel, exchange, queue string, d amqp.Delivery, handler broker.Handler) {
+	handlerErr := handler(d.Body)
+	if handlerErr == nil {
+		_ = d.Ack(false)
+		return
+	}
+
+	retries := retryCount(d.Headers)
+	if broker.IsPermanent(handlerErr) || retries >= c.retry.MaxRetries {
+		log.Printf("dead-lettering message from %s after %d retries: %v", queue, retries, handlerErr)
+		if err := c.publishDeadLetter(ch, exchange, queue, d, retries, handlerErr); err != nil {
+			log.Printf("failed to dead-letter message: %v", err)
+			_ = d.Nack(false, true)
+			return
+		}
+		_ = d.Ack(false)
+		return
+	}
+
+	delay := c.retry.Delay(retries + 1)
+	log.Printf("handler error, retry %d in %s: %v", retries+1, delay, handlerErr)
+	if err := c.publishRetry(ch, queue, d, retries+1, delay, handlerErr); err != nil {
+		log.Printf("failed to schedule retry: %v", err)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+func (c *RabbitMQClient) publishRetry(ch channel, queue string, d amqp.Delivery, retries int, delay time.Duration, cause error) error {
+	headers := copyHeaders(d.Headers)
+	headers[headerRetryCount] = int32(retries)
+	headers[headerLastError] = truncate(cause.Error(), maxLastErrorHeaderSize)
+
+	return c.publishConfirmed(
+		ch,
+		"",
+		retryQueue(queue, delay),
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.ContentType,
+			MessageId:    d.MessageId,
+			Body:         d.Body,
+			DeliveryMode: amqp.Persistent,
+		})
+}
+
+func (c *RabbitMQClient) publishDeadLetter(ch channel, exchange, queue string, d amqp.Delivery, retries int, cause error) error {
+	headers := copyHeaders(d.Headers)
+	headers[headerRetryCount] = int32(retries)
+	headers[headerLastError] = truncate(cause.Error(), maxLastErrorHeaderSize)
+	headers[headerOriginalQueue] = queue
+	headers[headerDeadLetteredAt] = time.Now()
+
+	messageID := d.MessageId
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	return c.publishConfirmed(
+		ch,
+		DeadLetterExchange(exchange),
+		queue,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.ContentType,
+			MessageId:    messageID,
+			Body:         d.Body,
+			DeliveryMode: amqp.Persistent,
+		})
+}
+
+// publishConfirmed publishes msg on ch, which must be in confirm mode, and
+// returns once the broker has confirmed it or the confirm timeout has passed.
+func (c *RabbitMQClient) publishConfirmed(ch channel, exchange, routingKey string, msg amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.confirmTimeout)
+	defer cancel()
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrConfirmTimeout
+		}
+		return err
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+	return nil
+}
+
+func retryCount(headers amqp.Table) int {
+	switch v := headers[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	table := amqp.Table{}
+	for k, v := range headers {
+		table[k] = v
+	}
+	return table
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
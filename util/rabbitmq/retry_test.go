@@ -0,0 +1,181 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/budsx/expenses-management/util/broker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAcknowledger struct {
+	mu       sync.Mutex
+	acked    int
+	requeued int
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if requeue {
+		a.requeued++
+	}
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestRabbitMQClient_HandleDelivery(t *testing.T) {
+	retry := broker.RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+	handlerErr := errors.New("ledger unavailable")
+
+	tests := []struct {
+		name         string
+		headers      amqp.Table
+		handlerErr   error
+		nack         bool
+		wantExchange string
+		wantKey      string
+		wantRetries  int32
+		wantAcked    int
+		wantRequeued int
+	}{
+		{
+			name:      "handled message is acked",
+			wantAcked: 1,
+		},
+		{
+			name:         "first failure goes to the first retry queue",
+			handlerErr:   handlerErr,
+			wantExchange: "",
+			wantKey:      "expenses.queue.retry.1s",
+			wantRetries:  1,
+			wantAcked:    1,
+		},
+		{
+			name:         "retry queue follows the attempt number",
+			headers:      amqp.Table{headerRetryCount: int32(1)},
+			handlerErr:   handlerErr,
+			wantExchange: "",
+			wantKey:      "expenses.queue.retry.2s",
+			wantRetries:  2,
+			wantAcked:    1,
+		},
+		{
+			name:         "last retry is capped at the max delay",
+			headers:      amqp.Table{headerRetryCount: int64(2)},
+			handlerErr:   handlerErr,
+			wantExchange: "",
+			wantKey:      "expenses.queue.retry.4s",
+			wantRetries:  3,
+			wantAcked:    1,
+		},
+		{
+			name:         "failure after the last retry is dead-lettered",
+			headers:      amqp.Table{headerRetryCount: int32(3)},
+			handlerErr:   handlerErr,
+			wantExchange: "expenses.dlx",
+			wantKey:      "expenses.queue",
+			wantRetries:  3,
+			wantAcked:    1,
+		},
+		{
+			name:         "permanent error skips retries",
+			handlerErr:   broker.Permanent(handlerErr),
+			wantExchange: "expenses.dlx",
+			wantKey:      "expenses.queue",
+			wantRetries:  0,
+			wantAcked:    1,
+		},
+		{
+			name:         "nacked retry leaves the message requeued",
+			handlerErr:   handlerErr,
+			nack:         true,
+			wantExchange: "",
+			wantKey:      "expenses.queue.retry.1s",
+			wantRetries:  1,
+			wantRequeued: 1,
+		},
+		{
+			name:         "nacked dead letter leaves the message requeued",
+			handlerErr:   broker.Permanent(handlerErr),
+			nack:         true,
+			wantExchange: "expenses.dlx",
+			wantKey:      "expenses.queue",
+			wantRetries:  0,
+			wantRequeued: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &RabbitMQClient{retry: retry, confirmTimeout: time.Second}
+			ch := &fakeChannel{nack: tt.nack}
+			acknowledger := &fakeAcknowledger{}
+			handled := 0
+
+			client.handleDelivery(ch, "expenses", "expenses.queue", amqp.Delivery{
+				Acknowledger: acknowledger,
+				MessageId:    "message-1",
+				Headers:      tt.headers,
+				Body:         []byte(`{"expense_id":7}`),
+			}, func(body []byte) error {
+				handled++
+				return tt.handlerErr
+			})
+
+			assert.Equal(t, 1, handled)
+			assert.Equal(t, tt.wantAcked, acknowledger.acked)
+			assert.Equal(t, tt.wantRequeued, acknowledger.requeued)
+
+			published := ch.publishings()
+			if tt.handlerErr == nil {
+				assert.Empty(t, published)
+				return
+			}
+
+			if assert.Len(t, published, 1) {
+				assert.Equal(t, tt.wantExchange, published[0].exchange)
+				assert.Equal(t, tt.wantKey, published[0].key)
+				assert.Equal(t, "message-1", published[0].msg.MessageId)
+				assert.Equal(t, []byte(`{"expense_id":7}`), published[0].msg.Body)
+				assert.Equal(t, tt.wantRetries, published[0].msg.Headers[headerRetryCount])
+				assert.Equal(t, "ledger unavailable", published[0].msg.Headers[headerLastError])
+				if tt.wantExchange != "" {
+					assert.Equal(t, "expenses.queue", published[0].msg.Headers[headerOriginalQueue])
+				}
+			}
+		})
+	}
+}
+
+func TestDeclareTopology(t *testing.T) {
+	ch := &fakeChannel{}
+
+	err := declareTopology(ch, "expenses", "expenses.queue", broker.RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   4 * time.Second,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"expenses.queue",
+		"expenses.queue.retry.1s",
+		"expenses.queue.retry.2s",
+		"expenses.queue.retry.4s",
+		"expenses.queue.dlq",
+	}, ch.declared)
+}
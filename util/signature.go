@@ -0,0 +1,33 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const PaymentWebhookSignatureHeader = "X-Signature"
+
+// SignPayload returns the hex encoded HMAC-SHA256 of body.
+func SignPayload(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks signature against body. A "sha256=" prefix is accepted.
+func VerifySignature(secret, body []byte, signature string) bool {
+	if len(secret) == 0 || signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
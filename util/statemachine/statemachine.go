@@ -0,0 +1,201 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+)
+
+type Event string
+
+const (
+	EventSubmit         Event = "submit"
+	EventAutoApprove    Event = "auto_approve"
+	EventApprove        Event = "approve"
+	EventReject         Event = "reject"
+	EventRequestChanges Event = "request_changes"
+	EventResubmit       Event = "resubmit"
+	EventPaymentSent    Event = "payment_sent"
+	EventPaymentFailed  Event = "payment_failed"
+	EventReverse        Event = "reverse"
+)
+
+// StatusNew is the state of an expense that has not been written yet.
+const StatusNew util.ExpenseStatus = 0
+
+var ErrIllegalTransition = errors.New("illegal expense status transition")
+
+type TransitionError struct {
+	From  util.ExpenseStatus
+	Event Event
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s expense with status %s", e.Event, util.GetExpenseStatusString(e.From))
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+type Request struct {
+	Expense      *entity.Expense
+	Event        Event
+	Actor        model.User
+	Notes        string
+	AmountBefore float64
+	AmountAfter  float64
+}
+
+type Result struct {
+	Request
+	From util.ExpenseStatus
+	To   util.ExpenseStatus
+}
+
+type Guard func(Request) error
+
+type SideEffect func(context.Context, Result)
+
+type Transition struct {
+	From  util.ExpenseStatus
+	Event Event
+	To    util.ExpenseStatus
+	Guard Guard
+}
+
+type Machine struct {
+	transitions map[util.ExpenseStatus]map[Event]Transition
+	effects     []SideEffect
+}
+
+func New(transitions ...Transition) *Machine {
+	m := &Machine{transitions: make(map[util.ExpenseStatus]map[Event]Transition)}
+	for _, t := range transitions {
+		if m.transitions[t.From] == nil {
+			m.transitions[t.From] = make(map[Event]Transition)
+		}
+		m.transitions[t.From][t.Event] = t
+	}
+	return m
+}
+
+func NewExpenseMachine() *Machine {
+	return New(
+		Transition{From: StatusNew, Event: EventSubmit, To: util.EXPENSE_PENDING},
+		Transition{From: util.EXPENSE_PENDING, Event: EventAutoApprove, To: util.EXPENSE_AUTO_APPROVED, Guard: belowApprovalThreshold},
+		Transition{From: util.EXPENSE_PENDING, Event: EventApprove, To: util.EXPENSE_APPROVED, Guard: canApprove},
+		Transition{From: util.EXPENSE_PENDING, Event: EventReject, To: util.EXPENSE_REJECTED, Guard: notSubmitter},
+		Transition{From: util.EXPENSE_PENDING, Event: EventRequestChanges, To: util.EXPENSE_NEEDS_REVISION, Guard: notSubmitter},
+		Transition{From: util.EXPENSE_NEEDS_REVISION, Event: EventResubmit, To: util.EXPENSE_PENDING, Guard: isSubmitter},
+		Transition{From: util.EXPENSE_APPROVED, Event: EventPaymentSent, To: util.EXPENSE_PAID},
+		Transition{From: util.EXPENSE_AUTO_APPROVED, Event: EventPaymentSent, To: util.EXPENSE_PAID},
+		Transition{From: util.EXPENSE_PAYMENT_FAILED, Event: EventPaymentSent, To: util.EXPENSE_PAID},
+		Transition{From: util.EXPENSE_APPROVED, Event: EventPaymentFailed, To: util.EXPENSE_PAYMENT_FAILED},
+		Transition{From: util.EXPENSE_AUTO_APPROVED, Event: EventPaymentFailed, To: util.EXPENSE_PAYMENT_FAILED},
+		Transition{From: util.EXPENSE_PAID, Event: EventReverse, To: util.EXPENSE_REVERSED, Guard: notSubmitter},
+	)
+}
+
+// OnTransition registers a side effect that runs after every successful transition.
+func (m *Machine) OnTransition(effect SideEffect) {
+	m.effects = append(m.effects, effect)
+}
+
+func (m *Machine) Can(from util.ExpenseStatus, event Event) bool {
+	_, ok := m.transitions[from][event]
+	return ok
+}
+
+// Check validates the transition and its guard without firing it, for
+// callers that have to do work outside the database first.
+func (m *Machine) Check(req Request) error {
+	_, err := m.transition(req)
+	return err
+}
+
+// Fire validates the transition and its guard, persists the new status through
+// persist and then runs the registered side effects.
+func (m *Machine) Fire(ctx context.Context, req Request, persist func(context.Context, util.ExpenseStatus) error) (*Result, error) {
+	from := util.ExpenseStatus(req.Expense.Status)
+	t, err := m.transition(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := persist(ctx, t.To); err != nil {
+		return nil, err
+	}
+	req.Expense.Status = int32(t.To)
+
+	result := Result{Request: req, From: from, To: t.To}
+	for _, effect := range m.effects {
+		effect(ctx, result)
+	}
+
+	return &result, nil
+}
+
+func (m *Machine) transition(req Request) (Transition, error) {
+	from := util.ExpenseStatus(req.Expense.Status)
+	t, ok := m.transitions[from][req.Event]
+	if !ok {
+		return Transition{}, &TransitionError{From: from, Event: req.Event}
+	}
+
+	if t.Guard != nil {
+		if err := t.Guard(req); err != nil {
+			return Transition{}, err
+		}
+	}
+	return t, nil
+}
+
+// IsPayable reports whether an expense in the given status may be paid out.
+// A failed payment may be retried.
+func IsPayable(status util.ExpenseStatus) bool {
+	return status == util.EXPENSE_APPROVED || status == util.EXPENSE_AUTO_APPROVED || status == util.EXPENSE_PAYMENT_FAILED
+}
+
+func belowApprovalThreshold(req Request) error {
+	if _, autoApproved := util.AmountValidation(req.Expense.AmountIDR); !autoApproved {
+		return fmt.Errorf("expense amount requires manual approval")
+	}
+	if req.Expense.OverLimit {
+		return fmt.Errorf("expense over the spending limit requires manual approval")
+	}
+	return nil
+}
+
+// canApprove leaves a claim over the spending limit to an admin instead of a
+// manager.
+func canApprove(req Request) error {
+	if err := notSubmitter(req); err != nil {
+		return err
+	}
+	if req.Expense.OverLimit && req.Actor.Role != int(util.USER_ROLE_ADMIN) {
+		return fmt.Errorf("expense over the spending limit requires an admin's approval")
+	}
+	if !req.Expense.OverLimit && req.Actor.Role != int(util.USER_ROLE_MANAGER) {
+		return fmt.Errorf("user is not a manager")
+	}
+	return nil
+}
+
+func notSubmitter(req Request) error {
+	if req.Actor.ID == req.Expense.UserID {
+		return fmt.Errorf("submitter cannot review own expense")
+	}
+	return nil
+}
+
+func isSubmitter(req Request) error {
+	if req.Actor.ID != req.Expense.UserID {
+		return fmt.Errorf("user is not the submitter")
+	}
+	return nil
+}
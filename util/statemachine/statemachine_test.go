@@ -0,0 +1,281 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/budsx/expenses-management/entity"
+	"github.com/budsx/expenses-management/model"
+	"github.com/budsx/expenses-management/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpenseMachine_Transitions(t *testing.T) {
+	submitter := model.User{ID: 1}
+	manager := model.User{ID: 2, Role: int(util.USER_ROLE_MANAGER)}
+	admin := model.User{ID: 3, Role: int(util.USER_ROLE_ADMIN)}
+
+	tests := []struct {
+		name    string
+		expense entity.Expense
+		event   Event
+		actor   model.User
+		want    util.ExpenseStatus
+		wantErr string
+	}{
+		{
+			name:    "submit a new expense",
+			expense: entity.Expense{UserID: 1, Status: int32(StatusNew)},
+			event:   EventSubmit,
+			actor:   submitter,
+			want:    util.EXPENSE_PENDING,
+		},
+		{
+			name:    "auto approve below the threshold",
+			expense: entity.Expense{UserID: 1, AmountIDR: 500000, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventAutoApprove,
+			actor:   submitter,
+			want:    util.EXPENSE_AUTO_APPROVED,
+		},
+		{
+			name:    "auto approve at the threshold is refused",
+			expense: entity.Expense{UserID: 1, AmountIDR: util.ApprovalThreshold, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventAutoApprove,
+			actor:   submitter,
+			wantErr: "expense amount requires manual approval",
+		},
+		{
+			name:    "auto approve over the spending limit is refused",
+			expense: entity.Expense{UserID: 1, AmountIDR: 500000, Status: int32(util.EXPENSE_PENDING), OverLimit: true},
+			event:   EventAutoApprove,
+			actor:   submitter,
+			wantErr: "expense over the spending limit requires manual approval",
+		},
+		{
+			name:    "manager approves",
+			expense: entity.Expense{UserID: 1, AmountIDR: 2000000, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventApprove,
+			actor:   manager,
+			want:    util.EXPENSE_APPROVED,
+		},
+		{
+			name:    "submitter cannot approve own expense",
+			expense: entity.Expense{UserID: 1, AmountIDR: 2000000, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventApprove,
+			actor:   submitter,
+			wantErr: "submitter cannot review own expense",
+		},
+		{
+			name:    "admin approves over the spending limit",
+			expense: entity.Expense{UserID: 1, AmountIDR: 500000, Status: int32(util.EXPENSE_PENDING), OverLimit: true},
+			event:   EventApprove,
+			actor:   admin,
+			want:    util.EXPENSE_APPROVED,
+		},
+		{
+			name:    "manager cannot approve over the spending limit",
+			expense: entity.Expense{UserID: 1, AmountIDR: 500000, Status: int32(util.EXPENSE_PENDING), OverLimit: true},
+			event:   EventApprove,
+			actor:   manager,
+			wantErr: "expense over the spending limit requires an admin's approval",
+		},
+		{
+			name:    "admin cannot approve within the spending limit",
+			expense: entity.Expense{UserID: 1, AmountIDR: 2000000, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventApprove,
+			actor:   admin,
+			wantErr: "user is not a manager",
+		},
+		{
+			name:    "manager rejects",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventReject,
+			actor:   manager,
+			want:    util.EXPENSE_REJECTED,
+		},
+		{
+			name:    "manager requests changes",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventRequestChanges,
+			actor:   manager,
+			want:    util.EXPENSE_NEEDS_REVISION,
+		},
+		{
+			name:    "submitter resubmits",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_NEEDS_REVISION)},
+			event:   EventResubmit,
+			actor:   submitter,
+			want:    util.EXPENSE_PENDING,
+		},
+		{
+			name:    "only the submitter can resubmit",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_NEEDS_REVISION)},
+			event:   EventResubmit,
+			actor:   manager,
+			wantErr: "user is not the submitter",
+		},
+		{
+			name:    "retry a failed payment",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PAYMENT_FAILED)},
+			event:   EventPaymentSent,
+			want:    util.EXPENSE_PAID,
+		},
+		{
+			name:    "payment fails",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_AUTO_APPROVED)},
+			event:   EventPaymentFailed,
+			want:    util.EXPENSE_PAYMENT_FAILED,
+		},
+		{
+			name:    "reverse a paid expense",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PAID)},
+			event:   EventReverse,
+			actor:   manager,
+			want:    util.EXPENSE_REVERSED,
+		},
+		{
+			name:    "submitter cannot reverse own expense",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PAID)},
+			event:   EventReverse,
+			actor:   submitter,
+			wantErr: "submitter cannot review own expense",
+		},
+		{
+			name:    "illegal - approve a rejected expense",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_REJECTED)},
+			event:   EventApprove,
+			actor:   manager,
+			wantErr: "cannot approve expense with status rejected",
+		},
+		{
+			name:    "illegal - pay a pending expense",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PENDING)},
+			event:   EventPaymentSent,
+			wantErr: "cannot payment_sent expense with status pending",
+		},
+		{
+			name:    "illegal - reverse a reversed expense",
+			expense: entity.Expense{UserID: 1, Status: int32(util.EXPENSE_REVERSED)},
+			event:   EventReverse,
+			actor:   manager,
+			wantErr: "cannot reverse expense with status reversed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewExpenseMachine()
+			expense := tt.expense
+			persisted := false
+
+			result, err := m.Fire(context.Background(), Request{Expense: &expense, Event: tt.event, Actor: tt.actor},
+				func(ctx context.Context, to util.ExpenseStatus) error {
+					persisted = true
+					assert.Equal(t, tt.want, to)
+					return nil
+				})
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, result)
+				assert.False(t, persisted)
+				assert.Equal(t, tt.expense.Status, expense.Status)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, persisted)
+			assert.Equal(t, util.ExpenseStatus(tt.expense.Status), result.From)
+			assert.Equal(t, tt.want, result.To)
+			assert.Equal(t, int32(tt.want), expense.Status)
+		})
+	}
+}
+
+func TestMachine_TransitionError(t *testing.T) {
+	m := NewExpenseMachine()
+	expense := &entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PAID)}
+
+	err := m.Check(Request{Expense: expense, Event: EventApprove, Actor: model.User{ID: 2, Role: int(util.USER_ROLE_MANAGER)}})
+
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+
+	var transitionErr *TransitionError
+	if assert.True(t, errors.As(err, &transitionErr)) {
+		assert.Equal(t, util.EXPENSE_PAID, transitionErr.From)
+		assert.Equal(t, EventApprove, transitionErr.Event)
+	}
+
+	guardErr := m.Check(Request{Expense: &entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PENDING)}, Event: EventApprove, Actor: model.User{ID: 1}})
+	assert.Error(t, guardErr)
+	assert.False(t, errors.Is(guardErr, ErrIllegalTransition))
+}
+
+func TestMachine_Fire(t *testing.T) {
+	persistErr := errors.New("database error")
+
+	tests := []struct {
+		name        string
+		persist     error
+		wantErr     error
+		wantEffects int
+		wantStatus  util.ExpenseStatus
+	}{
+		{
+			name:        "success runs side effects",
+			wantEffects: 1,
+			wantStatus:  util.EXPENSE_APPROVED,
+		},
+		{
+			name:       "persist error skips side effects",
+			persist:    persistErr,
+			wantErr:    persistErr,
+			wantStatus: util.EXPENSE_PENDING,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewExpenseMachine()
+			var effects []Result
+			m.OnTransition(func(ctx context.Context, result Result) {
+				effects = append(effects, result)
+			})
+
+			expense := &entity.Expense{UserID: 1, Status: int32(util.EXPENSE_PENDING)}
+			_, err := m.Fire(context.Background(), Request{Expense: expense, Event: EventApprove, Actor: model.User{ID: 2, Role: int(util.USER_ROLE_MANAGER)}},
+				func(ctx context.Context, to util.ExpenseStatus) error {
+					return tt.persist
+				})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Len(t, effects, tt.wantEffects)
+			assert.Equal(t, int32(tt.wantStatus), expense.Status)
+			if tt.wantEffects > 0 {
+				assert.Equal(t, util.EXPENSE_PENDING, effects[0].From)
+				assert.Equal(t, util.EXPENSE_APPROVED, effects[0].To)
+			}
+		})
+	}
+}
+
+func TestIsPayable(t *testing.T) {
+	tests := []struct {
+		status util.ExpenseStatus
+		want   bool
+	}{
+		{status: util.EXPENSE_PENDING, want: false},
+		{status: util.EXPENSE_APPROVED, want: true},
+		{status: util.EXPENSE_AUTO_APPROVED, want: true},
+		{status: util.EXPENSE_PAYMENT_FAILED, want: true},
+		{status: util.EXPENSE_PAID, want: false},
+		{status: util.EXPENSE_REVERSED, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(util.GetExpenseStatusString(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPayable(tt.status))
+		})
+	}
+}